@@ -22,7 +22,9 @@ import (
 	"math/big"
 
 	"github.com/roller-project/roller"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi"
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
 )
 
@@ -41,6 +43,58 @@ var (
 	ErrNoCodeAfterDeploy = errors.New("no contract code after deployment")
 )
 
+// RevertError is returned by call and gas estimation operations which were
+// reverted by the EVM. It carries the raw data returned by the execution and
+// the reason decoded from it, if the contract provided one.
+type RevertError struct {
+	Reason string // Reason passed to revert or require, empty if none given
+	Data   []byte // Raw data returned by the reverted execution
+}
+
+// NewRevertError creates a revert error from the data returned by a reverted
+// execution, decoding the reason string if present.
+func NewRevertError(data []byte) *RevertError {
+	reason, _ := abi.UnpackRevert(data)
+	return &RevertError{Reason: reason, Data: data}
+}
+
+// Error implements the error interface.
+func (e *RevertError) Error() string {
+	if e.Reason == "" {
+		return "execution reverted"
+	}
+	return "execution reverted: " + e.Reason
+}
+
+// ErrorCode returns the JSON-RPC error code of reverted executions.
+func (e *RevertError) ErrorCode() int { return 3 }
+
+// ErrorData returns the hex encoded revert data, as sent over JSON-RPC.
+func (e *RevertError) ErrorData() interface{} { return hexutil.Encode(e.Data) }
+
+// toRevertError converts RPC errors carrying revert data into a RevertError,
+// passing any other error through unmodified.
+func toRevertError(err error) error {
+	de, ok := err.(interface {
+		ErrorData() interface{}
+	})
+	if !ok {
+		return err
+	}
+	if _, ok := err.(*RevertError); ok {
+		return err
+	}
+	hex, ok := de.ErrorData().(string)
+	if !ok {
+		return err
+	}
+	data, decErr := hexutil.Decode(hex)
+	if decErr != nil {
+		return err
+	}
+	return NewRevertError(data)
+}
+
 // ContractCaller defines the methods needed to allow operating with contract on a read
 // only basis.
 type ContractCaller interface {
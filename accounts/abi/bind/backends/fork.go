@@ -0,0 +1,220 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/roller-project/roller"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/state"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+)
+
+// ForkReader is the subset of the ethclient API a forked simulated backend
+// needs to lazily pull accounts, code and storage from a remote chain.
+type ForkReader interface {
+	ethereum.ChainStateReader
+
+	// HeaderByNumber retrieves the header the fork is pinned to. A nil number
+	// requests the latest header.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// deletedMarker is stored in the local tries in place of deleted accounts and
+// storage slots of a forked backend. A plain trie deletion would make the entry
+// fall through to the remote chain again, resurrecting the old value.
+var deletedMarker = []byte{0x80}
+
+// forkDatabase is a state.Database resolving accounts and storage slots missing
+// from the local tries from a remote chain pinned at a given block. Everything
+// fetched is cached, since the remote state at a fixed block never changes.
+type forkDatabase struct {
+	state.Database
+
+	remote ForkReader     // Remote chain to fetch missing state from
+	number *big.Int       // Block number the remote state is pinned to
+	diskdb ethdb.Database // Local database to store fetched contract code in
+
+	lock     sync.Mutex
+	accounts map[common.Address][]byte                 // RLP encoded remote accounts (nil if nonexistent)
+	owners   map[common.Hash]common.Address            // Remote accounts by address hash, for storage lookups
+	slots    map[common.Address]map[common.Hash][]byte // RLP encoded remote storage slots (nil if empty)
+}
+
+// newForkDatabase wraps the state database of db to fall back to remote for
+// any state not available locally.
+func newForkDatabase(db ethdb.Database, remote ForkReader, number *big.Int) *forkDatabase {
+	return &forkDatabase{
+		Database: state.NewDatabase(db),
+		remote:   remote,
+		number:   number,
+		diskdb:   db,
+		accounts: make(map[common.Address][]byte),
+		owners:   make(map[common.Hash]common.Address),
+		slots:    make(map[common.Address]map[common.Hash][]byte),
+	}
+}
+
+// OpenTrie opens the main account trie, resolving missing accounts remotely.
+func (db *forkDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
+	tr, err := db.Database.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	return &forkTrie{Trie: tr, db: db}, nil
+}
+
+// OpenStorageTrie opens the storage trie of an account. Only accounts that were
+// resolved remotely fall back to the remote chain for missing slots.
+func (db *forkDatabase) OpenStorageTrie(addrHash, root common.Hash) (state.Trie, error) {
+	tr, err := db.Database.OpenStorageTrie(addrHash, root)
+	if err != nil {
+		return nil, err
+	}
+	db.lock.Lock()
+	owner, ok := db.owners[addrHash]
+	db.lock.Unlock()
+
+	if !ok {
+		return tr, nil
+	}
+	return &forkTrie{Trie: tr, db: db, owner: &owner}, nil
+}
+
+// CopyTrie returns an independent copy of the given trie.
+func (db *forkDatabase) CopyTrie(t state.Trie) state.Trie {
+	if t, ok := t.(*forkTrie); ok {
+		return &forkTrie{Trie: db.Database.CopyTrie(t.Trie), db: db, owner: t.owner}
+	}
+	return db.Database.CopyTrie(t)
+}
+
+// account retrieves the RLP encoded account from the remote chain, or nil if
+// the account does not exist there.
+func (db *forkDatabase) account(addr common.Address) ([]byte, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if enc, ok := db.accounts[addr]; ok {
+		return enc, nil
+	}
+	ctx := context.Background()
+
+	balance, err := db.remote.BalanceAt(ctx, addr, db.number)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := db.remote.NonceAt(ctx, addr, db.number)
+	if err != nil {
+		return nil, err
+	}
+	code, err := db.remote.CodeAt(ctx, addr, db.number)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Sign() == 0 && nonce == 0 && len(code) == 0 {
+		db.accounts[addr] = nil
+		return nil, nil
+	}
+	codeHash := crypto.Keccak256(code)
+	if len(code) > 0 {
+		if err := db.diskdb.Put(codeHash, code); err != nil {
+			return nil, err
+		}
+	}
+	enc, err := rlp.EncodeToBytes(&state.Account{
+		Nonce:    nonce,
+		Balance:  balance,
+		Root:     types.EmptyRootHash,
+		CodeHash: codeHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+	db.accounts[addr] = enc
+	db.owners[crypto.Keccak256Hash(addr[:])] = addr
+	return enc, nil
+}
+
+// slot retrieves the RLP encoded storage slot of an account from the remote
+// chain, or nil if the slot is empty.
+func (db *forkDatabase) slot(addr common.Address, key common.Hash) ([]byte, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	slots := db.slots[addr]
+	if slots == nil {
+		slots = make(map[common.Hash][]byte)
+		db.slots[addr] = slots
+	}
+	if enc, ok := slots[key]; ok {
+		return enc, nil
+	}
+	val, err := db.remote.StorageAt(context.Background(), addr, key, db.number)
+	if err != nil {
+		return nil, err
+	}
+	var enc []byte
+	if val = bytes.TrimLeft(val, "\x00"); len(val) > 0 {
+		// Encoding []byte cannot fail, ok to ignore the error.
+		enc, _ = rlp.EncodeToBytes(val)
+	}
+	slots[key] = enc
+	return enc, nil
+}
+
+// forkTrie is a state trie falling back to the remote chain for keys not
+// present locally. If owner is nil, it is the account trie, otherwise it is
+// the storage trie of the owner account.
+type forkTrie struct {
+	state.Trie
+
+	db    *forkDatabase
+	owner *common.Address
+}
+
+// TryGet returns the value for key stored locally, or the remote value if the
+// key was never touched locally.
+func (t *forkTrie) TryGet(key []byte) ([]byte, error) {
+	enc, err := t.Trie.TryGet(key)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.Equal(enc, deletedMarker):
+		return nil, nil
+	case len(enc) > 0:
+		return enc, nil
+	case t.owner == nil:
+		return t.db.account(common.BytesToAddress(key))
+	default:
+		return t.db.slot(*t.owner, common.BytesToHash(key))
+	}
+}
+
+// TryDelete shadows the key with a deletion marker, preventing the remote value
+// from becoming visible again.
+func (t *forkTrie) TryDelete(key []byte) error {
+	return t.Trie.TryUpdate(key, deletedMarker)
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/state"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/vm"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth/filters"
 	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
 	"github.com/Ethereum-Reloaded/ETHR-Go/event"
@@ -47,49 +48,123 @@ var _ bind.ContractBackend = (*SimulatedBackend)(nil)
 
 var errBlockNumberUnsupported = errors.New("SimulatedBackend cannot access blocks other than the latest block")
 var errGasEstimationFailed = errors.New("gas required exceeds allowance or always failing transaction")
+var errPendingTransactions = errors.New("could not adjust time on non-empty pending block")
+var errUnknownSnapshot = errors.New("unknown snapshot id")
 
 // SimulatedBackend implements bind.ContractBackend, simulating a blockchain in
 // the background. Its main purpose is to allow easily testing contract bindings.
 type SimulatedBackend struct {
 	database   ethdb.Database   // In memory database to store our testing data
 	blockchain *core.BlockChain // Ethereum blockchain to handle the consensus
+	stateCache state.Database   // State database to build pending and committed states on
 
-	mu           sync.Mutex
-	pendingBlock *types.Block   // Currently pending block that will be imported on request
-	pendingState *state.StateDB // Currently pending state that will be the active on on request
+	mu              sync.Mutex
+	pendingBlock    *types.Block   // Currently pending block that will be imported on request
+	pendingState    *state.StateDB // Currently pending state that will be the active on on request
+	pendingReceipts types.Receipts // Receipts of the transactions in the pending block
+
+	impersonated map[common.Hash]common.Address // Senders of unsigned transactions from impersonated accounts
+	snapshots    []*snapshot                    // Saved chain heads and pending states to revert to
 
 	events *filters.EventSystem // Event system for filtering log events live
 
 	config *params.ChainConfig
 }
 
+// snapshot is a saved simulator state that can be reverted to.
+type snapshot struct {
+	id       int
+	head     uint64
+	block    *types.Block
+	state    *state.StateDB
+	receipts types.Receipts
+}
+
 // NewSimulatedBackend creates a new binding backend using a simulated blockchain
 // for testing purposes.
 func NewSimulatedBackend(alloc core.GenesisAlloc) *SimulatedBackend {
 	database := ethdb.NewMemDatabase()
 	genesis := core.Genesis{Config: params.AllEthashProtocolChanges, Alloc: alloc}
+
+	return newSimulatedBackend(database, state.NewDatabase(database), &genesis)
+}
+
+// NewForkedSimulatedBackend creates a new binding backend using a simulated
+// blockchain that forks off the state of a remote chain at the given block (nil
+// meaning the latest one). Accounts, code and storage are fetched lazily through
+// remote on first access and cached locally; alloc can override any of them.
+//
+// The simulated chain starts numbering its blocks from zero, only the state and
+// the timestamp of the forked block are inherited.
+func NewForkedSimulatedBackend(remote ForkReader, number *big.Int, alloc core.GenesisAlloc) (*SimulatedBackend, error) {
+	header, err := remote.HeaderByNumber(context.Background(), number)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("fork block %v not found", number)
+	}
+	database := ethdb.NewMemDatabase()
+	genesis := core.Genesis{
+		Config:     params.AllEthashProtocolChanges,
+		Timestamp:  header.Time.Uint64(),
+		GasLimit:   header.GasLimit,
+		Difficulty: params.GenesisDifficulty,
+		Alloc:      alloc,
+	}
+	return newSimulatedBackend(database, newForkDatabase(database, remote, header.Number), &genesis), nil
+}
+
+// newSimulatedBackend creates a simulated backend on top of the given genesis,
+// building all states on stateCache.
+func newSimulatedBackend(database ethdb.Database, stateCache state.Database, genesis *core.Genesis) *SimulatedBackend {
 	genesis.MustCommit(database)
 	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, ethash.NewFaker(), vm.Config{})
 
 	backend := &SimulatedBackend{
-		database:   database,
-		blockchain: blockchain,
-		config:     genesis.Config,
-		events:     filters.NewEventSystem(new(event.TypeMux), &filterBackend{database, blockchain}, false),
+		database:     database,
+		blockchain:   blockchain,
+		stateCache:   stateCache,
+		impersonated: make(map[common.Hash]common.Address),
+		config:       genesis.Config,
+		events:       filters.NewEventSystem(new(event.TypeMux), &filterBackend{database, blockchain}, false),
 	}
 	backend.rollback()
 	return backend
 }
 
+// Blockchain returns the underlying blockchain.
+func (b *SimulatedBackend) Blockchain() *core.BlockChain {
+	return b.blockchain
+}
+
 // Commit imports all the pending transactions as a single block and starts a
 // fresh new state.
 func (b *SimulatedBackend) Commit() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if _, err := b.blockchain.InsertChain([]*types.Block{b.pendingBlock}); err != nil {
+	block, err := b.blockchain.Engine().Finalize(b.blockchain, b.pendingBlock.Header(), b.pendingState, b.pendingBlock.Transactions(), nil, b.pendingReceipts)
+	if err != nil {
 		panic(err) // This cannot happen unless the simulator is wrong, fail in that case
 	}
+	if _, err := b.blockchain.WriteBlockWithState(block, b.pendingReceipts, b.pendingState); err != nil {
+		panic(err)
+	}
+	// The state was committed into our own state cache, flush it for the chain
+	if err := b.stateCache.TrieDB().Commit(block.Root(), false); err != nil {
+		panic(err)
+	}
+	var logs []*types.Log
+	for _, receipt := range b.pendingReceipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	events := []interface{}{
+		core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs},
+		core.ChainHeadEvent{Block: block},
+	}
+	b.blockchain.PostChainEvents(events, logs)
+
 	b.rollback()
 }
 
@@ -102,11 +177,116 @@ func (b *SimulatedBackend) Rollback() {
 }
 
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
-	statedb, _ := b.blockchain.State()
+	parent := b.blockchain.CurrentBlock()
+	time := new(big.Int).Add(parent.Time(), big.NewInt(10)) // block time is fixed at 10 seconds
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   parent.Coinbase(),
+		Difficulty: b.blockchain.Engine().CalcDifficulty(b.blockchain, time.Uint64(), parent.Header()),
+		GasLimit:   core.CalcGasLimit(parent),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		Time:       time,
+	}
+	b.pendingBlock = types.NewBlockWithHeader(header)
+	b.pendingState, _ = state.New(parent.Root(), b.stateCache)
+	b.pendingReceipts = nil
+}
+
+// Snapshot saves the current chain head and pending state, returning an id
+// which can be passed to Revert to return to this point.
+func (b *SimulatedBackend) Snapshot() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := 1
+	if n := len(b.snapshots); n > 0 {
+		id = b.snapshots[n-1].id + 1
+	}
+	b.snapshots = append(b.snapshots, &snapshot{
+		id:       id,
+		head:     b.blockchain.CurrentBlock().NumberU64(),
+		block:    b.pendingBlock,
+		state:    b.pendingState.Copy(),
+		receipts: b.pendingReceipts,
+	})
+	return id
+}
+
+// Revert rewinds the chain and the pending state to a previously taken snapshot.
+// The snapshot and all the ones taken after it are discarded.
+func (b *SimulatedBackend) Revert(id int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	idx := len(b.snapshots) - 1
+	for idx >= 0 && b.snapshots[idx].id != id {
+		idx--
+	}
+	if idx < 0 {
+		return errUnknownSnapshot
+	}
+	snap := b.snapshots[idx]
+	b.snapshots = b.snapshots[:idx]
+
+	if b.blockchain.CurrentBlock().NumberU64() != snap.head {
+		if err := b.blockchain.SetHead(snap.head); err != nil {
+			return err
+		}
+	}
+	b.pendingBlock = snap.block
+	b.pendingState = snap.state
+	b.pendingReceipts = snap.receipts
+	return nil
+}
+
+// SetBalance overrides the balance of an account in the pending state.
+func (b *SimulatedBackend) SetBalance(account common.Address, balance *big.Int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pendingState.SetBalance(account, balance)
+}
+
+// SetCode overrides the code of an account in the pending state.
+func (b *SimulatedBackend) SetCode(account common.Address, code []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	b.pendingState.SetCode(account, code)
+}
+
+// SetStorageAt overrides the value of key in the storage of an account in the
+// pending state.
+func (b *SimulatedBackend) SetStorageAt(account common.Address, key, value common.Hash) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pendingState.SetState(account, key, value)
+}
+
+// ImpersonatedTransactor creates transaction options that allow sending
+// transactions as account without holding its key. Transactions are left
+// unsigned, the backend executes them as if they were sent by account.
+func (b *SimulatedBackend) ImpersonatedTransactor(account common.Address) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From: account,
+		Signer: func(signer types.Signer, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != account {
+				return nil, errors.New("not authorized to sign this account")
+			}
+			b.mu.Lock()
+			b.impersonated[tx.Hash()] = account
+			b.mu.Unlock()
+
+			return tx, nil
+		},
+	}
+}
+
+// currentState retrieves the state of the current head block.
+func (b *SimulatedBackend) currentState() (*state.StateDB, error) {
+	return state.New(b.blockchain.CurrentBlock().Root(), b.stateCache)
 }
 
 // CodeAt returns the code associated with a certain account in the blockchain.
@@ -117,7 +297,10 @@ func (b *SimulatedBackend) CodeAt(ctx context.Context, contract common.Address,
 	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
 		return nil, errBlockNumberUnsupported
 	}
-	statedb, _ := b.blockchain.State()
+	statedb, err := b.currentState()
+	if err != nil {
+		return nil, err
+	}
 	return statedb.GetCode(contract), nil
 }
 
@@ -129,7 +312,10 @@ func (b *SimulatedBackend) BalanceAt(ctx context.Context, contract common.Addres
 	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
 		return nil, errBlockNumberUnsupported
 	}
-	statedb, _ := b.blockchain.State()
+	statedb, err := b.currentState()
+	if err != nil {
+		return nil, err
+	}
 	return statedb.GetBalance(contract), nil
 }
 
@@ -141,7 +327,10 @@ func (b *SimulatedBackend) NonceAt(ctx context.Context, contract common.Address,
 	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
 		return 0, errBlockNumberUnsupported
 	}
-	statedb, _ := b.blockchain.State()
+	statedb, err := b.currentState()
+	if err != nil {
+		return 0, err
+	}
 	return statedb.GetNonce(contract), nil
 }
 
@@ -153,7 +342,10 @@ func (b *SimulatedBackend) StorageAt(ctx context.Context, contract common.Addres
 	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
 		return nil, errBlockNumberUnsupported
 	}
-	statedb, _ := b.blockchain.State()
+	statedb, err := b.currentState()
+	if err != nil {
+		return nil, err
+	}
 	val := statedb.GetState(contract, key)
 	return val[:], nil
 }
@@ -180,11 +372,14 @@ func (b *SimulatedBackend) CallContract(ctx context.Context, call ethereum.CallM
 	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
 		return nil, errBlockNumberUnsupported
 	}
-	state, err := b.blockchain.State()
+	state, err := b.currentState()
 	if err != nil {
 		return nil, err
 	}
-	rval, _, _, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), state)
+	rval, _, failed, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), state)
+	if err == nil && failed {
+		return nil, bind.NewRevertError(rval)
+	}
 	return rval, err
 }
 
@@ -194,7 +389,10 @@ func (b *SimulatedBackend) PendingCallContract(ctx context.Context, call ethereu
 	defer b.mu.Unlock()
 	defer b.pendingState.RevertToSnapshot(b.pendingState.Snapshot())
 
-	rval, _, _, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+	rval, _, failed, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+	if err == nil && failed {
+		return nil, bind.NewRevertError(rval)
+	}
 	return rval, err
 }
 
@@ -233,14 +431,16 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 	cap = hi
 
 	// Create a helper to check if a gas allowance results in an executable transaction
+	var revert []byte
 	executable := func(gas uint64) bool {
 		call.Gas = gas
 
 		snapshot := b.pendingState.Snapshot()
-		_, _, failed, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+		rval, _, failed, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
 		b.pendingState.RevertToSnapshot(snapshot)
 
 		if err != nil || failed {
+			revert = rval
 			return false
 		}
 		return true
@@ -257,6 +457,9 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 	// Reject the transaction as invalid if it still fails at the highest allowance
 	if hi == cap {
 		if !executable(hi) {
+			if len(revert) > 0 {
+				return 0, bind.NewRevertError(revert)
+			}
 			return 0, errGasEstimationFailed
 		}
 	}
@@ -297,28 +500,68 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	sender, err := types.Sender(types.HomesteadSigner{}, tx)
-	if err != nil {
-		panic(fmt.Errorf("invalid transaction: %v", err))
+	sender, ok := b.impersonated[tx.Hash()]
+	if !ok {
+		var err error
+		if sender, err = types.Sender(types.HomesteadSigner{}, tx); err != nil {
+			panic(fmt.Errorf("invalid transaction: %v", err))
+		}
 	}
 	nonce := b.pendingState.GetNonce(sender)
 	if tx.Nonce() != nonce {
 		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
 	}
+	receipt, err := b.applyTransaction(tx, sender)
+	if err != nil {
+		return err
+	}
+	header := b.pendingBlock.Header()
+	header.GasUsed = receipt.CumulativeGasUsed
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTxWithChain(b.blockchain, tx)
-		}
-		block.AddTxWithChain(b.blockchain, tx)
-	})
-	statedb, _ := b.blockchain.State()
-
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	b.pendingReceipts = append(b.pendingReceipts, receipt)
+	b.pendingBlock = types.NewBlock(header, append(b.pendingBlock.Transactions(), tx), nil, b.pendingReceipts)
 	return nil
 }
 
+// applyTransaction executes a transaction sent by sender on top of the pending
+// state, returning its receipt. Unlike core.ApplyTransaction, the sender is not
+// derived from the signature, allowing impersonated transactions to execute.
+func (b *SimulatedBackend) applyTransaction(tx *types.Transaction, sender common.Address) (*types.Receipt, error) {
+	header := b.pendingBlock.Header()
+	usedGas := header.GasUsed
+
+	msg := types.NewMessage(sender, tx.To(), tx.Nonce(), tx.Value(), tx.Gas(), tx.GasPrice(), tx.Data(), true)
+	gaspool := new(core.GasPool).AddGas(header.GasLimit - usedGas)
+
+	snapshot := b.pendingState.Snapshot()
+	b.pendingState.Prepare(tx.Hash(), common.Hash{}, len(b.pendingReceipts))
+
+	vmenv := vm.NewEVM(core.NewEVMContext(msg, header, b.blockchain, nil), b.pendingState, b.config, vm.Config{})
+	_, gas, failed, err := core.ApplyMessage(vmenv, msg, gaspool)
+	if err != nil {
+		b.pendingState.RevertToSnapshot(snapshot)
+		return nil, err
+	}
+	var root []byte
+	if b.config.IsByzantium(header.Number) {
+		b.pendingState.Finalise(true)
+	} else {
+		root = b.pendingState.IntermediateRoot(b.config.IsEIP158(header.Number)).Bytes()
+	}
+	usedGas += gas
+
+	receipt := types.NewReceipt(root, failed, usedGas)
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = gas
+	if msg.To() == nil {
+		receipt.ContractAddress = crypto.CreateAddress(sender, tx.Nonce())
+	}
+	receipt.Logs = b.pendingState.GetLogs(tx.Hash())
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+	return receipt, nil
+}
+
 // FilterLogs executes a log filter operation, blocking during execution and
 // returning all the results in one batch.
 //
@@ -381,21 +624,20 @@ func (b *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, query ethere
 	}), nil
 }
 
-// AdjustTime adds a time shift to the simulated clock.
+// AdjustTime adds a time shift to the simulated clock. It can only be called on
+// an empty pending block, since the pending transactions would need re-executing.
 func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTx(tx)
-		}
-		block.OffsetTime(int64(adjustment.Seconds()))
-	})
-	statedb, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	if len(b.pendingBlock.Transactions()) != 0 {
+		return errPendingTransactions
+	}
+	header := b.pendingBlock.Header()
+	header.Time = new(big.Int).Add(header.Time, big.NewInt(int64(adjustment.Seconds())))
+	header.Difficulty = b.blockchain.Engine().CalcDifficulty(b.blockchain, header.Time.Uint64(), b.blockchain.CurrentBlock().Header())
 
+	b.pendingBlock = types.NewBlockWithHeader(header)
 	return nil
 }
 
@@ -0,0 +1,313 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/roller-project/roller"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi/bind"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+)
+
+var (
+	richAddr     = common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	contractAddr = common.HexToAddress("0x1f9840a85d5af5bf1d1762f925bdaddc4201f984")
+)
+
+// fixtureAccount is a remote account recorded in a fork fixture.
+type fixtureAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// fixtureReader is a ForkReader serving state recorded from a remote chain,
+// counting the number of times each account is fetched.
+type fixtureReader struct {
+	Number   uint64                            `json:"number"`
+	Time     uint64                            `json:"time"`
+	GasLimit uint64                            `json:"gasLimit"`
+	Accounts map[common.Address]fixtureAccount `json:"accounts"`
+
+	fetches map[common.Address]int
+}
+
+func newFixtureReader(t *testing.T) *fixtureReader {
+	blob, err := ioutil.ReadFile("testdata/fork.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	reader := &fixtureReader{fetches: make(map[common.Address]int)}
+	if err := json.Unmarshal(blob, reader); err != nil {
+		t.Fatalf("failed to parse fixture: %v", err)
+	}
+	return reader
+}
+
+func (r *fixtureReader) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number != nil && number.Uint64() != r.Number {
+		return nil, nil
+	}
+	return &types.Header{
+		Number:   new(big.Int).SetUint64(r.Number),
+		Time:     new(big.Int).SetUint64(r.Time),
+		GasLimit: r.GasLimit,
+	}, nil
+}
+
+func (r *fixtureReader) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error) {
+	r.fetches[account]++
+	if acc, ok := r.Accounts[account]; ok && acc.Balance != nil {
+		return acc.Balance.ToInt(), nil
+	}
+	return new(big.Int), nil
+}
+
+func (r *fixtureReader) NonceAt(ctx context.Context, account common.Address, number *big.Int) (uint64, error) {
+	return r.Accounts[account].Nonce, nil
+}
+
+func (r *fixtureReader) CodeAt(ctx context.Context, account common.Address, number *big.Int) ([]byte, error) {
+	return r.Accounts[account].Code, nil
+}
+
+func (r *fixtureReader) StorageAt(ctx context.Context, account common.Address, key common.Hash, number *big.Int) ([]byte, error) {
+	val := r.Accounts[account].Storage[key]
+	return val[:], nil
+}
+
+// Tests that a forked backend lazily resolves remote state and caches it.
+func TestForkedBackendState(t *testing.T) {
+	reader := newFixtureReader(t)
+	sim, err := NewForkedSimulatedBackend(reader, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create forked backend: %v", err)
+	}
+	if len(reader.fetches) != 0 {
+		t.Fatalf("state fetched eagerly: %v", reader.fetches)
+	}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		balance, err := sim.BalanceAt(ctx, richAddr, nil)
+		if err != nil {
+			t.Fatalf("failed to retrieve balance: %v", err)
+		}
+		if want := reader.Accounts[richAddr].Balance.ToInt(); balance.Cmp(want) != 0 {
+			t.Fatalf("balance mismatch: have %v, want %v", balance, want)
+		}
+		nonce, err := sim.NonceAt(ctx, richAddr, nil)
+		if err != nil {
+			t.Fatalf("failed to retrieve nonce: %v", err)
+		}
+		if nonce != 7 {
+			t.Fatalf("nonce mismatch: have %d, want %d", nonce, 7)
+		}
+	}
+	if reader.fetches[richAddr] != 1 {
+		t.Errorf("remote account fetched %d times, want 1", reader.fetches[richAddr])
+	}
+	code, err := sim.CodeAt(ctx, contractAddr, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve code: %v", err)
+	}
+	if !bytes.Equal(code, reader.Accounts[contractAddr].Code) {
+		t.Errorf("code mismatch: have %x, want %x", code, reader.Accounts[contractAddr].Code)
+	}
+	val, err := sim.StorageAt(ctx, contractAddr, common.Hash{}, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve storage: %v", err)
+	}
+	if want := common.BigToHash(big.NewInt(42)); !bytes.Equal(val, want[:]) {
+		t.Errorf("storage mismatch: have %x, want %x", val, want)
+	}
+	// Calls should execute remote code against remote storage
+	out, err := sim.CallContract(ctx, ethereum.CallMsg{To: &contractAddr}, nil)
+	if err != nil {
+		t.Fatalf("failed to call contract: %v", err)
+	}
+	if new(big.Int).SetBytes(out).Int64() != 42 {
+		t.Errorf("call result mismatch: have %x, want 42", out)
+	}
+}
+
+// Tests that local overrides shadow the remote state, including deletions.
+func TestForkedBackendOverrides(t *testing.T) {
+	sim, err := NewForkedSimulatedBackend(newFixtureReader(t), big.NewInt(4200000), nil)
+	if err != nil {
+		t.Fatalf("failed to create forked backend: %v", err)
+	}
+	ctx := context.Background()
+
+	sim.SetBalance(richAddr, big.NewInt(1))
+	sim.SetCode(richAddr, []byte{0x00})
+	sim.SetStorageAt(contractAddr, common.Hash{}, common.Hash{})
+	sim.SetStorageAt(contractAddr, common.HexToHash("0x02"), common.HexToHash("0x03"))
+	sim.Commit()
+
+	if balance, _ := sim.BalanceAt(ctx, richAddr, nil); balance.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 1", balance)
+	}
+	if code, _ := sim.CodeAt(ctx, richAddr, nil); !bytes.Equal(code, []byte{0x00}) {
+		t.Errorf("code mismatch: have %x, want 00", code)
+	}
+	tests := []struct {
+		key, want common.Hash
+	}{
+		{common.HexToHash("0x00"), common.Hash{}},
+		{common.HexToHash("0x01"), common.HexToHash("0xdeadbeef")},
+		{common.HexToHash("0x02"), common.HexToHash("0x03")},
+	}
+	for i, tt := range tests {
+		val, err := sim.StorageAt(ctx, contractAddr, tt.key, nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve storage: %v", i, err)
+		}
+		if !bytes.Equal(val, tt.want[:]) {
+			t.Errorf("test %d: storage mismatch: have %x, want %x", i, val, tt.want)
+		}
+	}
+}
+
+// Tests that transactions can be sent from remote accounts without their keys.
+func TestForkedBackendImpersonation(t *testing.T) {
+	reader := newFixtureReader(t)
+	sim, err := NewForkedSimulatedBackend(reader, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create forked backend: %v", err)
+	}
+	ctx := context.Background()
+	recipient := common.HexToAddress("0x0000000000000000000000000000000000000bad")
+
+	opts := sim.ImpersonatedTransactor(richAddr)
+	nonce, _ := sim.PendingNonceAt(ctx, richAddr)
+	tx := types.NewTransaction(nonce, recipient, big.NewInt(1000), 21000, big.NewInt(1), nil)
+	tx, err = opts.Signer(types.HomesteadSigner{}, richAddr, tx)
+	if err != nil {
+		t.Fatalf("failed to impersonate sender: %v", err)
+	}
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	sim.Commit()
+
+	receipt, _ := sim.TransactionReceipt(ctx, tx.Hash())
+	if receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction not executed successfully: %v", receipt)
+	}
+	if balance, _ := sim.BalanceAt(ctx, recipient, nil); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("recipient balance mismatch: have %v, want 1000", balance)
+	}
+	if nonce, _ := sim.NonceAt(ctx, richAddr, nil); nonce != 8 {
+		t.Errorf("sender nonce mismatch: have %d, want 8", nonce)
+	}
+}
+
+// Tests that snapshots revert both committed blocks and the pending state.
+func TestSimulatedBackendSnapshot(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	sim := NewSimulatedBackend(core.GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}})
+	ctx := context.Background()
+
+	sim.Commit()
+	id := sim.Snapshot()
+
+	sim.SetBalance(addr, big.NewInt(1))
+	sim.Commit()
+	sim.Commit()
+
+	if head := sim.blockchain.CurrentBlock().NumberU64(); head != 3 {
+		t.Fatalf("head mismatch: have %d, want 3", head)
+	}
+	if err := sim.Revert(id); err != nil {
+		t.Fatalf("failed to revert: %v", err)
+	}
+	if head := sim.blockchain.CurrentBlock().NumberU64(); head != 1 {
+		t.Errorf("head mismatch after revert: have %d, want 1", head)
+	}
+	if balance, _ := sim.BalanceAt(ctx, addr, nil); balance.Cmp(big.NewInt(1000000000)) != 0 {
+		t.Errorf("balance mismatch after revert: have %v, want 1000000000", balance)
+	}
+	if err := sim.Revert(id); err != errUnknownSnapshot {
+		t.Errorf("reverting twice: have %v, want %v", err, errUnknownSnapshot)
+	}
+	// Ensure the chain keeps working after reverting
+	sim.Commit()
+	if head := sim.blockchain.CurrentBlock().NumberU64(); head != 2 {
+		t.Errorf("head mismatch after new commit: have %d, want 2", head)
+	}
+}
+
+// revertCode returns contract code which always reverts with the given reason.
+func revertCode(reason string) []byte {
+	data := common.Hex2Bytes("08c379a0")
+	data = append(data, common.LeftPadBytes([]byte{0x20}, 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(int64(len(reason))).Bytes(), 32)...)
+	data = append(data, common.RightPadBytes([]byte(reason), (len(reason)+31)/32*32)...)
+
+	// PUSH2 len PUSH1 14 PUSH1 0 CODECOPY PUSH2 len PUSH1 0 REVERT <data>
+	size := []byte{byte(len(data) >> 8), byte(len(data))}
+	code := append([]byte{0x61}, size...)
+	code = append(code, 0x60, 0x0e, 0x60, 0x00, 0x39, 0x61)
+	code = append(code, size...)
+	code = append(code, 0x60, 0x00, 0xfd)
+	return append(code, data...)
+}
+
+// Tests that reverted calls surface the decoded revert reason.
+func TestSimulatedBackendRevertReason(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	contract := common.HexToAddress("0x00000000000000000000000000000000000c0de")
+
+	sim := NewSimulatedBackend(core.GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}})
+	sim.SetCode(contract, revertCode("not allowed"))
+	sim.Commit()
+
+	ctx := context.Background()
+	_, err := sim.CallContract(ctx, ethereum.CallMsg{From: addr, To: &contract}, nil)
+	if err, ok := err.(*bind.RevertError); !ok || err.Reason != "not allowed" {
+		t.Errorf("call error mismatch: have %v, want revert with reason", err)
+	}
+	_, err = sim.EstimateGas(ctx, ethereum.CallMsg{From: addr, To: &contract})
+	if err, ok := err.(*bind.RevertError); !ok || err.Reason != "not allowed" {
+		t.Errorf("estimate error mismatch: have %v, want revert with reason", err)
+	}
+	// Bound contracts should surface the reason too
+	parsed, _ := abi.JSON(strings.NewReader(`[{"constant":true,"inputs":[],"name":"get","outputs":[{"name":"","type":"uint256"}],"type":"function"}]`))
+	bound := bind.NewBoundContract(contract, parsed, sim, sim, sim)
+
+	var out *big.Int
+	err = bound.Call(nil, &out, "get")
+	if err == nil || err.Error() != "execution reverted: not allowed" {
+		t.Errorf("bound call error mismatch: have %v, want %q", err, "execution reverted: not allowed")
+	}
+}
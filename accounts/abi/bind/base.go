@@ -159,7 +159,7 @@ func (c *BoundContract) Call(opts *CallOpts, result interface{}, method string,
 		}
 	}
 	if err != nil {
-		return err
+		return toRevertError(err)
 	}
 	return c.abi.Unpack(result, method, output)
 }
@@ -221,6 +221,9 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 		msg := ethereum.CallMsg{From: opts.From, To: contract, Value: value, Data: input}
 		gasLimit, err = c.transactor.EstimateGas(ensureContext(opts.Context), msg)
 		if err != nil {
+			if err, ok := toRevertError(err).(*RevertError); ok {
+				return nil, err
+			}
 			return nil, fmt.Errorf("failed to estimate gas needed: %v", err)
 		}
 	}
@@ -0,0 +1,265 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements an account backend delegating all signing to an
+// external signer, such as clef, through its JSON-RPC account API.
+package external
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/event"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rpc"
+	ethereum "github.com/roller-project/roller"
+)
+
+// ExternalScheme is the URL scheme of the wallets of external signers.
+const ExternalScheme = "extapi"
+
+const (
+	// listRetryInterval is the time after a failed or denied account listing
+	// during which the signer is not asked again.
+	listRetryInterval = time.Minute
+
+	// listRefreshInterval is the time after which the accounts are listed
+	// again, picking up accounts added to or removed from the signer.
+	listRefreshInterval = time.Minute
+)
+
+// errChainIDMismatch is returned if the external signer signed the transaction
+// for a different chain than requested.
+var errChainIDMismatch = errors.New("external signer uses a different chain id")
+
+// ExternalBackend is an account backend with a single wallet, the accounts of
+// an external signer.
+type ExternalBackend struct {
+	signers []accounts.Wallet
+}
+
+// NewExternalBackend connects to the external signer at the given endpoint,
+// which may be an IPC path or a HTTP or websocket URL.
+func NewExternalBackend(endpoint string) (*ExternalBackend, error) {
+	signer, err := NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalBackend{signers: []accounts.Wallet{signer}}, nil
+}
+
+// Wallets implements accounts.Backend, returning the wallet of the external
+// signer.
+func (eb *ExternalBackend) Wallets() []accounts.Wallet {
+	return eb.signers
+}
+
+// Subscribe implements accounts.Backend. The wallet of an external signer never
+// arrives or departs, so no events are ever sent.
+func (eb *ExternalBackend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// ExternalSigner is a wallet whose accounts are managed by an external signer.
+// The node holds no key material, every signing request is forwarded to the
+// signer for approval.
+type ExternalSigner struct {
+	client   *rpc.Client
+	endpoint string
+
+	cache      []accounts.Account // Accounts approved for listing by the signer
+	listed     time.Time          // Time of the last successful listing
+	listFailed time.Time          // Time of the last failed listing, zero if none
+	cacheMu    sync.RWMutex
+}
+
+// NewExternalSigner connects to the external signer at the given endpoint.
+func NewExternalSigner(endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalSigner{client: client, endpoint: endpoint}, nil
+}
+
+// URL implements accounts.Wallet, returning the endpoint of the signer.
+func (api *ExternalSigner) URL() accounts.URL {
+	return accounts.URL{Scheme: ExternalScheme, Path: api.endpoint}
+}
+
+// Status implements accounts.Wallet.
+func (api *ExternalSigner) Status() (string, error) {
+	return "ok", nil
+}
+
+// Open implements accounts.Wallet. The connection is established on creation,
+// so this is a noop.
+func (api *ExternalSigner) Open(passphrase string) error {
+	return nil
+}
+
+// Close implements accounts.Wallet. The connection is kept for the lifetime of
+// the backend, so this is a noop.
+func (api *ExternalSigner) Close() error {
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the accounts the signer allows
+// the node to see. As listing may require manual approval on the signer, the
+// accounts are requested at most once per listRefreshInterval, and not again
+// within listRetryInterval after a failed or denied listing.
+func (api *ExternalSigner) Accounts() []accounts.Account {
+	api.cacheMu.RLock()
+	cache, listed, failed := api.cache, api.listed, api.listFailed
+	api.cacheMu.RUnlock()
+
+	if cache != nil && time.Since(listed) < listRefreshInterval {
+		return cache
+	}
+	if !failed.IsZero() && time.Since(failed) < listRetryInterval {
+		return nil
+	}
+	accnts, err := api.listAccounts()
+
+	api.cacheMu.Lock()
+	defer api.cacheMu.Unlock()
+	if err != nil {
+		log.Error("Failed to list accounts of external signer", "endpoint", api.endpoint, "err", err)
+		api.cache, api.listFailed = nil, time.Now()
+		return nil
+	}
+	api.cache, api.listed, api.listFailed = accnts, time.Now(), time.Time{}
+	return accnts
+}
+
+// Contains implements accounts.Wallet.
+func (api *ExternalSigner) Contains(account accounts.Account) bool {
+	for _, a := range api.Accounts() {
+		if a.Address == account.Address && (account.URL == (accounts.URL{}) || account.URL == api.URL()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet, but is not supported by external signers.
+func (api *ExternalSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for external signers.
+func (api *ExternalSigner) SelfDerive(base accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	log.Error("Operation not supported on external signers")
+}
+
+// SignHash implements accounts.Wallet, but is not supported: external signers
+// only sign hashes of data they can show to the user for approval.
+func (api *ExternalSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// signTransactionResult is the response of the signer to account_signTransaction.
+type signTransactionResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+// sendTxArgs are the transaction fields sent to the signer for signing.
+type sendTxArgs struct {
+	From     common.MixedcaseAddress  `json:"from"`
+	To       *common.MixedcaseAddress `json:"to"`
+	Gas      hexutil.Uint64           `json:"gas"`
+	GasPrice hexutil.Big              `json:"gasPrice"`
+	Value    hexutil.Big              `json:"value"`
+	Nonce    hexutil.Uint64           `json:"nonce"`
+	Data     *hexutil.Bytes           `json:"data"`
+}
+
+// SignTx implements accounts.Wallet, sending the transaction to the external
+// signer for approval and signing. The signer signs for the chain it is
+// configured with, so the request fails if that differs from chainID.
+func (api *ExternalSigner) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := &sendTxArgs{
+		From:     common.NewMixedcaseAddress(account.Address),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: hexutil.Big(*tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+	}
+	if to := tx.To(); to != nil {
+		recipient := common.NewMixedcaseAddress(*to)
+		args.To = &recipient
+	}
+	data := hexutil.Bytes(tx.Data())
+	args.Data = &data
+
+	var res signTransactionResult
+	if err := api.client.Call(&res, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := rlp.DecodeBytes(res.Raw, signed); err != nil {
+		return nil, fmt.Errorf("invalid transaction from external signer: %v", err)
+	}
+	if chainID != nil && signed.ChainId().Cmp(chainID) != 0 {
+		return nil, errChainIDMismatch
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if signed.Protected() {
+		signer = types.NewEIP155Signer(signed.ChainId())
+	}
+	if from, err := types.Sender(signer, signed); err != nil || from != account.Address {
+		return nil, fmt.Errorf("external signer signed with a different account: %x", from)
+	}
+	return signed, nil
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, but is not supported, the
+// credentials are managed by the external signer.
+func (api *ExternalSigner) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, but is not supported, the
+// credentials are managed by the external signer.
+func (api *ExternalSigner) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// listAccounts requests the accounts the external signer allows to list.
+func (api *ExternalSigner) listAccounts() ([]accounts.Account, error) {
+	var res []struct {
+		Address common.Address `json:"address"`
+	}
+	if err := api.client.Call(&res, "account_list"); err != nil {
+		return nil, err
+	}
+	accnts := make([]accounts.Account, 0, len(res))
+	for _, acc := range res {
+		accnts = append(accnts, accounts.Account{Address: acc.Address, URL: api.URL()})
+	}
+	return accnts, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rpc"
+)
+
+// MockTxArgs are the transaction fields received by the mock signer.
+type MockTxArgs sendTxArgs
+
+// MockSigner mimics the account API of clef, approving every request.
+type MockSigner struct {
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+	lists   int
+	deny    bool
+}
+
+func (s *MockSigner) List() ([]map[string]interface{}, error) {
+	s.lists++
+	if s.deny {
+		return nil, errors.New("request denied")
+	}
+	return []map[string]interface{}{{
+		"type":    "Account",
+		"url":     "keystore:///tmp/key",
+		"address": crypto.PubkeyToAddress(s.key.PublicKey),
+	}}, nil
+}
+
+func (s *MockSigner) SignTransaction(args MockTxArgs, methodSelector *string) (map[string]interface{}, error) {
+	if args.From.Address() != crypto.PubkeyToAddress(s.key.PublicKey) {
+		return nil, errors.New("unknown account")
+	}
+	var tx *types.Transaction
+	if args.To == nil {
+		tx = types.NewContractCreation(uint64(args.Nonce), args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), *args.Data)
+	} else {
+		tx = types.NewTransaction(uint64(args.Nonce), args.To.Address(), args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), *args.Data)
+	}
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(s.chainID), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, _ := rlp.EncodeToBytes(signed)
+	return map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": signed}, nil
+}
+
+// newTestBackend starts a signer serving the account API over IPC and connects
+// an external backend to it.
+func newTestBackend(t *testing.T, chainID int64) (*ExternalBackend, *MockSigner, func()) {
+	if runtime.GOOS == "windows" {
+		t.Skip("IPC endpoint in temporary directory not supported on windows")
+	}
+	dir, err := ioutil.TempDir("", "extapi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := crypto.GenerateKey()
+	signer := &MockSigner{key: key, chainID: big.NewInt(chainID)}
+
+	endpoint := filepath.Join(dir, "clef.ipc")
+	listener, server, err := rpc.StartIPCEndpoint(endpoint, []rpc.API{{Namespace: "account", Public: true, Service: signer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend, err := NewExternalBackend(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend, signer, func() {
+		listener.Close()
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestExternalSignTx(t *testing.T) {
+	backend, signer, cleanup := newTestBackend(t, 1)
+	defer cleanup()
+
+	wallets := backend.Wallets()
+	if len(wallets) != 1 {
+		t.Fatalf("wallet count mismatch: have %d, want 1", len(wallets))
+	}
+	wallet := wallets[0]
+	if wallet.URL().Scheme != ExternalScheme {
+		t.Errorf("wallet scheme mismatch: have %s, want %s", wallet.URL().Scheme, ExternalScheme)
+	}
+	accs := wallet.Accounts()
+	if len(accs) != 1 || accs[0].Address != crypto.PubkeyToAddress(signer.key.PublicKey) {
+		t.Fatalf("accounts mismatch: have %v", accs)
+	}
+	// The listing is cached to avoid repeated approvals on the signer
+	if !wallet.Contains(accs[0]) || signer.lists != 1 {
+		t.Errorf("account listing not cached: %d listings", signer.lists)
+	}
+	tx := types.NewTransaction(3, common.HexToAddress("0x1337"), big.NewInt(100), 21000, big.NewInt(1), []byte{1, 2})
+	signed, err := wallet.SignTx(accs[0], tx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if signed.Hash() == tx.Hash() || signed.Nonce() != 3 || signed.Value().Int64() != 100 {
+		t.Errorf("signed transaction mismatch: %v", signed)
+	}
+	if from, _ := types.Sender(types.NewEIP155Signer(big.NewInt(1)), signed); from != accs[0].Address {
+		t.Errorf("sender mismatch: have %x, want %x", from, accs[0].Address)
+	}
+	// Signing for another chain or with unknown accounts fails
+	if _, err := wallet.SignTx(accs[0], tx, big.NewInt(2)); err != errChainIDMismatch {
+		t.Errorf("chain id mismatch error mismatch: have %v, want %v", err, errChainIDMismatch)
+	}
+	if _, err := wallet.SignTx(accounts.Account{Address: common.HexToAddress("0x01")}, tx, big.NewInt(1)); err == nil {
+		t.Errorf("signed with unknown account")
+	}
+	// Key material never leaves the signer
+	if _, err := wallet.SignHash(accs[0], make([]byte, 32)); err != accounts.ErrNotSupported {
+		t.Errorf("hash signing error mismatch: have %v, want %v", err, accounts.ErrNotSupported)
+	}
+}
+
+// Tests that a denied account listing is not requested again until the retry
+// interval passed.
+func TestExternalListDenied(t *testing.T) {
+	backend, signer, cleanup := newTestBackend(t, 1)
+	defer cleanup()
+
+	signer.deny = true
+	wallet := backend.Wallets()[0].(*ExternalSigner)
+	for i := 0; i < 3; i++ {
+		if accs := wallet.Accounts(); len(accs) != 0 {
+			t.Fatalf("accounts listed despite denial: %v", accs)
+		}
+	}
+	if signer.lists != 1 {
+		t.Fatalf("denied listing requested %d times, want 1", signer.lists)
+	}
+	signer.deny = false
+	wallet.listFailed = time.Now().Add(-listRetryInterval)
+	if accs := wallet.Accounts(); len(accs) != 1 || signer.lists != 2 {
+		t.Fatalf("listing not retried after the interval: %v, %d listings", accs, signer.lists)
+	}
+}
+
+// Tests that the accounts are listed again after the refresh interval, dropping
+// them once the signer stops listing them.
+func TestExternalListRefresh(t *testing.T) {
+	backend, signer, cleanup := newTestBackend(t, 1)
+	defer cleanup()
+
+	wallet := backend.Wallets()[0].(*ExternalSigner)
+	if accs := wallet.Accounts(); len(accs) != 1 {
+		t.Fatalf("accounts mismatch: have %v", accs)
+	}
+	wallet.listed = time.Now().Add(-listRefreshInterval)
+	if accs := wallet.Accounts(); len(accs) != 1 || signer.lists != 2 {
+		t.Fatalf("listing not refreshed after the interval: %v, %d listings", accs, signer.lists)
+	}
+	signer.deny = true
+	wallet.listed = time.Now().Add(-listRefreshInterval)
+	if accs := wallet.Accounts(); len(accs) != 0 {
+		t.Fatalf("accounts listed after the signer stopped listing them: %v", accs)
+	}
+}
+
+// Tests that the external signer's accounts are available through the account
+// manager.
+func TestExternalManager(t *testing.T) {
+	backend, signer, cleanup := newTestBackend(t, 1)
+	defer cleanup()
+
+	am := accounts.NewManager(backend)
+	defer am.Close()
+
+	account := accounts.Account{Address: crypto.PubkeyToAddress(signer.key.PublicKey)}
+	wallet, err := am.Find(account)
+	if err != nil {
+		t.Fatalf("external account not found: %v", err)
+	}
+	if wallet.URL() != backend.Wallets()[0].URL() {
+		t.Errorf("wallet mismatch: have %v", wallet.URL())
+	}
+}
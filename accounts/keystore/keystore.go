@@ -63,6 +63,7 @@ type KeyStore struct {
 	unlocked map[common.Address]*unlocked // Currently unlocked account (decrypted private keys)
 
 	wallets     []accounts.Wallet       // Wallet wrappers around the individual key files
+	hdWallets   []*hdWallet             // HD wallets backed by mnemonics
 	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
 	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
 	updating    bool                    // Whether the event notification loop is running
@@ -113,10 +114,11 @@ func (ks *KeyStore) init(keydir string) {
 	for i := 0; i < len(accs); i++ {
 		ks.wallets[i] = &keystoreWallet{account: accs[i], keystore: ks}
 	}
+	ks.hdWallets = loadHDWallets(ks, keydir)
 }
 
-// Wallets implements accounts.Backend, returning all single-key wallets from the
-// keystore directory.
+// Wallets implements accounts.Backend, returning all single-key wallets and HD
+// wallets from the keystore directory, sorted by URL.
 func (ks *KeyStore) Wallets() []accounts.Wallet {
 	// Make sure the list of wallets is in sync with the account cache
 	ks.refreshWallets()
@@ -124,8 +126,18 @@ func (ks *KeyStore) Wallets() []accounts.Wallet {
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
-	cpy := make([]accounts.Wallet, len(ks.wallets))
+	cpy := make([]accounts.Wallet, len(ks.wallets), len(ks.wallets)+len(ks.hdWallets))
 	copy(cpy, ks.wallets)
+	for _, hd := range ks.hdWallets {
+		// Insert the HD wallet in order of its URL
+		n := len(cpy)
+		for n > 0 && cpy[n-1].URL().Cmp(hd.URL()) > 0 {
+			n--
+		}
+		cpy = append(cpy, nil)
+		copy(cpy[n+1:], cpy[n:])
+		cpy[n] = hd
+	}
 	return cpy
 }
 
@@ -278,10 +290,7 @@ func (ks *KeyStore) SignTx(a accounts.Account, tx *types.Transaction, chainID *b
 		return nil, ErrLocked
 	}
 	// Depending on the presence of the chain ID, sign with EIP155 or homestead
-	if chainID != nil {
-		return types.SignTx(tx, types.NewEIP155Signer(chainID), unlockedKey.PrivateKey)
-	}
-	return types.SignTx(tx, types.HomesteadSigner{}, unlockedKey.PrivateKey)
+	return signTx(tx, chainID, unlockedKey.PrivateKey)
 }
 
 // SignHashWithPassphrase signs hash if the private key matching the given address
@@ -306,10 +315,16 @@ func (ks *KeyStore) SignTxWithPassphrase(a accounts.Account, passphrase string,
 	defer zeroKey(key.PrivateKey)
 
 	// Depending on the presence of the chain ID, sign with EIP155 or homestead
+	return signTx(tx, chainID, key.PrivateKey)
+}
+
+// signTx signs a transaction with EIP155 if a chain ID is given, with homestead
+// rules otherwise.
+func signTx(tx *types.Transaction, chainID *big.Int, key *ecdsa.PrivateKey) (*types.Transaction, error) {
 	if chainID != nil {
-		return types.SignTx(tx, types.NewEIP155Signer(chainID), key.PrivateKey)
+		return types.SignTx(tx, types.NewEIP155Signer(chainID), key)
 	}
-	return types.SignTx(tx, types.HomesteadSigner{}, key.PrivateKey)
+	return types.SignTx(tx, types.HomesteadSigner{}, key)
 }
 
 // Unlock unlocks the given account indefinitely.
@@ -423,12 +438,7 @@ func (ks *KeyStore) Export(a accounts.Account, passphrase, newPassphrase string)
 	if err != nil {
 		return nil, err
 	}
-	var N, P int
-	if store, ok := ks.storage.(*keyStorePassphrase); ok {
-		N, P = store.scryptN, store.scryptP
-	} else {
-		N, P = StandardScryptN, StandardScryptP
-	}
+	N, P := ks.scryptParams()
 	return EncryptKey(key, newPassphrase, N, P)
 }
 
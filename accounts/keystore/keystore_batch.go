@@ -0,0 +1,230 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+)
+
+// bundleVersion is the version of the backup bundle format.
+const bundleVersion = 1
+
+// backupBundle is a backup of many keys, encrypted as a whole with a single
+// passphrase. The MAC of the encrypted payload guards its integrity.
+type backupBundle struct {
+	Version int        `json:"version"`
+	Crypto  cryptoJSON `json:"crypto"`
+}
+
+// ProgressFunc is called by the batch operations after each processed key.
+type ProgressFunc func(done, total int)
+
+// keyFileUpdate is a pending replacement of a key file.
+type keyFileUpdate struct {
+	account accounts.Account
+	old     []byte // Previous content, nil if the file is new
+	new     []byte
+}
+
+// ReEncrypt changes the passphrase and scrypt parameters of all the given
+// accounts, which must share the same passphrase. All keys are re-encrypted
+// before any key file is replaced, and if replacing fails the files already
+// replaced are restored, so either all or none of the accounts are updated
+// unless a *RollbackError is returned.
+func (ks *KeyStore) ReEncrypt(accs []accounts.Account, passphrase, newPassphrase string, scryptN, scryptP int, progress ProgressFunc) error {
+	updates := make([]keyFileUpdate, 0, len(accs))
+	for i, acc := range accs {
+		a, err := ks.Find(acc)
+		if err != nil {
+			return fmt.Errorf("account %x: %v", acc.Address, err)
+		}
+		keyjson, err := ioutil.ReadFile(a.URL.Path)
+		if err != nil {
+			return err
+		}
+		key, err := DecryptKey(keyjson, passphrase)
+		if err != nil {
+			return fmt.Errorf("account %x: %v", a.Address, err)
+		}
+		if key.Address != a.Address {
+			zeroKey(key.PrivateKey)
+			return fmt.Errorf("key content mismatch: have account %x, want %x", key.Address, a.Address)
+		}
+		newjson, err := EncryptKey(key, newPassphrase, scryptN, scryptP)
+		zeroKey(key.PrivateKey)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, keyFileUpdate{account: a, old: keyjson, new: newjson})
+		if progress != nil {
+			progress(i+1, len(accs))
+		}
+	}
+	return writeKeyFiles(updates)
+}
+
+// ExportBundle exports the given accounts, which must share the same
+// passphrase, into a backup bundle encrypted with bundlePassphrase.
+func (ks *KeyStore) ExportBundle(accs []accounts.Account, passphrase, bundlePassphrase string, progress ProgressFunc) ([]byte, error) {
+	keys := make([]*Key, 0, len(accs))
+	defer func() {
+		for _, key := range keys {
+			zeroKey(key.PrivateKey)
+		}
+	}()
+	for i, a := range accs {
+		_, key, err := ks.getDecryptedKey(a, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("account %x: %v", a.Address, err)
+		}
+		keys = append(keys, key)
+		if progress != nil {
+			progress(i+1, len(accs))
+		}
+	}
+	payload, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(payload)
+
+	scryptN, scryptP := ks.scryptParams()
+	cryptoStruct, err := encryptData(payload, bundlePassphrase, scryptN, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&backupBundle{Version: bundleVersion, Crypto: cryptoStruct})
+}
+
+// ImportBundle restores the keys of a backup bundle into the key directory,
+// encrypting them with newPassphrase. Accounts already in the keystore are
+// skipped, the restored ones are returned. Either all or none of the missing
+// keys are restored unless a *RollbackError is returned.
+func (ks *KeyStore) ImportBundle(bundleJSON []byte, bundlePassphrase, newPassphrase string, progress ProgressFunc) ([]accounts.Account, error) {
+	var bundle backupBundle
+	if err := json.Unmarshal(bundleJSON, &bundle); err != nil {
+		return nil, err
+	}
+	if bundle.Version != bundleVersion {
+		return nil, fmt.Errorf("bundle version not supported: %v", bundle.Version)
+	}
+	payload, err := decryptData(bundle.Crypto, bundlePassphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(payload)
+
+	var keys []*Key
+	if err := json.Unmarshal(payload, &keys); err != nil {
+		return nil, fmt.Errorf("invalid bundle contents: %v", err)
+	}
+	defer func() {
+		for _, key := range keys {
+			zeroKey(key.PrivateKey)
+		}
+	}()
+	scryptN, scryptP := ks.scryptParams()
+
+	updates := make([]keyFileUpdate, 0, len(keys))
+	seen := make(map[common.Address]bool)
+	for i, key := range keys {
+		if !seen[key.Address] && !ks.cache.hasAddress(key.Address) {
+			seen[key.Address] = true
+
+			keyjson, err := EncryptKey(key, newPassphrase, scryptN, scryptP)
+			if err != nil {
+				return nil, err
+			}
+			a := accounts.Account{Address: key.Address, URL: accounts.URL{Scheme: KeyStoreScheme, Path: ks.storage.JoinPath(keyFileName(key.Address))}}
+			updates = append(updates, keyFileUpdate{account: a, new: keyjson})
+		}
+		if progress != nil {
+			progress(i+1, len(keys))
+		}
+	}
+	if err := writeKeyFiles(updates); err != nil {
+		return nil, err
+	}
+	imported := make([]accounts.Account, len(updates))
+	for i, update := range updates {
+		ks.cache.add(update.account)
+		imported[i] = update.account
+	}
+	ks.refreshWallets()
+	return imported, nil
+}
+
+// scryptParams returns the scrypt parameters new keys are encrypted with.
+func (ks *KeyStore) scryptParams() (int, int) {
+	if store, ok := ks.storage.(*keyStorePassphrase); ok {
+		return store.scryptN, store.scryptP
+	}
+	return StandardScryptN, StandardScryptP
+}
+
+// RollbackError is returned by the batch operations if writing the key files
+// failed and some of the files already written could not be restored. These
+// files may hold either the previous or the new key.
+type RollbackError struct {
+	Err    error    // error writing the key files
+	Failed []string // paths of the key files which could not be restored
+}
+
+func (err *RollbackError) Error() string {
+	return fmt.Sprintf("%v, rollback failed, key files in unknown state: %s", err.Err, strings.Join(err.Failed, ", "))
+}
+
+// writeKeyFiles writes all the updated key files. If writing fails, the files
+// already written are restored to their previous content or removed. If any of
+// them cannot be restored, a *RollbackError listing them is returned.
+func writeKeyFiles(updates []keyFileUpdate) error {
+	for i, update := range updates {
+		if err := writeKeyFile(update.account.URL.Path, update.new); err != nil {
+			var failed []string
+			for _, done := range updates[:i] {
+				var rerr error
+				if done.old == nil {
+					rerr = os.Remove(done.account.URL.Path)
+				} else {
+					rerr = writeKeyFile(done.account.URL.Path, done.old)
+				}
+				if rerr != nil {
+					failed = append(failed, done.account.URL.Path)
+				}
+			}
+			if len(failed) > 0 {
+				return &RollbackError{Err: err, Failed: failed}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// zeroBytes zeroes a byte slice holding sensitive data.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
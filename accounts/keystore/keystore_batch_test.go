@@ -0,0 +1,227 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts"
+)
+
+// newBatchAccounts creates n accounts sharing the same passphrase.
+func newBatchAccounts(t *testing.T, ks *KeyStore, n int, passphrase string) []accounts.Account {
+	accs := make([]accounts.Account, n)
+	for i := range accs {
+		a, err := ks.NewAccount(passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		accs[i] = a
+	}
+	return accs
+}
+
+func TestReEncrypt(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	accs := newBatchAccounts(t, ks, 3, "foo")
+
+	var calls []int
+	progress := func(done, total int) {
+		if total != len(accs) {
+			t.Errorf("progress total mismatch: have %d, want %d", total, len(accs))
+		}
+		calls = append(calls, done)
+	}
+	if err := ks.ReEncrypt(accs, "foo", "bar", LightScryptN, LightScryptP, progress); err != nil {
+		t.Fatalf("re-encryption failed: %v", err)
+	}
+	if len(calls) != len(accs) || calls[len(calls)-1] != len(accs) {
+		t.Errorf("progress reports mismatch: have %v", calls)
+	}
+	for _, a := range accs {
+		keyjson, err := ioutil.ReadFile(a.URL.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DecryptKey(keyjson, "foo"); err != ErrDecrypt {
+			t.Errorf("account %x: old passphrase error mismatch: have %v, want %v", a.Address, err, ErrDecrypt)
+		}
+		if _, err := DecryptKey(keyjson, "bar"); err != nil {
+			t.Errorf("account %x: new passphrase rejected: %v", a.Address, err)
+		}
+		var k encryptedKeyJSONV3
+		if err := json.Unmarshal(keyjson, &k); err != nil {
+			t.Fatal(err)
+		}
+		if n := int(k.Crypto.KDFParams["n"].(float64)); n != LightScryptN {
+			t.Errorf("account %x: scrypt N mismatch: have %d, want %d", a.Address, n, LightScryptN)
+		}
+	}
+}
+
+// Tests that a wrong passphrase for any of the accounts leaves all key files
+// untouched.
+func TestReEncryptWrongPassphrase(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	accs := newBatchAccounts(t, ks, 2, "foo")
+	accs = append(accs, newBatchAccounts(t, ks, 1, "other")...)
+
+	before := make([][]byte, len(accs))
+	for i, a := range accs {
+		before[i], _ = ioutil.ReadFile(a.URL.Path)
+	}
+	if err := ks.ReEncrypt(accs, "foo", "bar", LightScryptN, LightScryptP, nil); err == nil {
+		t.Fatalf("re-encryption with wrong passphrase succeeded")
+	}
+	for i, a := range accs {
+		after, _ := ioutil.ReadFile(a.URL.Path)
+		if !bytes.Equal(before[i], after) {
+			t.Errorf("account %x: key file modified", a.Address)
+		}
+	}
+}
+
+// Tests that a failed write restores the key files already written and removes
+// the new ones.
+func TestWriteKeyFilesRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eth-keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	existing := filepath.Join(dir, "existing")
+	if err := ioutil.WriteFile(existing, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	created := filepath.Join(dir, "created")
+	// The parent of the last file is a regular file, so writing it fails
+	updates := []keyFileUpdate{
+		{account: accounts.Account{URL: accounts.URL{Scheme: KeyStoreScheme, Path: existing}}, old: []byte("old"), new: []byte("new")},
+		{account: accounts.Account{URL: accounts.URL{Scheme: KeyStoreScheme, Path: created}}, new: []byte("new")},
+		{account: accounts.Account{URL: accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(existing, "key")}}, new: []byte("new")},
+	}
+	if err := writeKeyFiles(updates); err == nil {
+		t.Fatalf("write into invalid path succeeded")
+	}
+	if content, _ := ioutil.ReadFile(existing); string(content) != "old" {
+		t.Errorf("existing file not restored: have %q", content)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("created file not removed: %v", err)
+	}
+
+	// Files which cannot be restored are reported. The second removal of the
+	// same file fails.
+	updates = []keyFileUpdate{updates[1], updates[1], updates[2]}
+	err = writeKeyFiles(updates)
+	rerr, ok := err.(*RollbackError)
+	if !ok {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	if len(rerr.Failed) != 1 || rerr.Failed[0] != created {
+		t.Errorf("unexpected files in unknown state: %v", rerr.Failed)
+	}
+}
+
+func TestBundleRoundtrip(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	accs := newBatchAccounts(t, ks, 3, "foo")
+	bundle, err := ks.ExportBundle(accs, "foo", "bundle", nil)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	// Restore into a keystore already holding one of the accounts
+	dir2, ks2 := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir2)
+
+	keyjson, _ := ioutil.ReadFile(accs[0].URL.Path)
+	if _, err := ks2.Import(keyjson, "foo", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks2.ImportBundle(bundle, "wrong", "baz", nil); err != ErrDecrypt {
+		t.Errorf("wrong bundle passphrase error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	var progress int
+	imported, err := ks2.ImportBundle(bundle, "bundle", "baz", func(done, total int) { progress = done })
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if progress != len(accs) {
+		t.Errorf("progress mismatch: have %d, want %d", progress, len(accs))
+	}
+	if len(imported) != 2 || imported[0].Address != accs[1].Address || imported[1].Address != accs[2].Address {
+		t.Fatalf("imported accounts mismatch: have %v", imported)
+	}
+	for _, a := range imported {
+		if !ks2.HasAddress(a.Address) {
+			t.Errorf("account %x missing after import", a.Address)
+		}
+		if err := ks2.Unlock(a, "baz"); err != nil {
+			t.Errorf("account %x: new passphrase rejected: %v", a.Address, err)
+		}
+	}
+	// The existing account keeps its passphrase
+	if err := ks2.Unlock(accounts.Account{Address: accs[0].Address}, "foo"); err != nil {
+		t.Errorf("existing account modified: %v", err)
+	}
+}
+
+// Tests that tampering with the bundle is detected by its MAC.
+func TestBundleTampered(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	accs := newBatchAccounts(t, ks, 2, "foo")
+	blob, err := ks.ExportBundle(accs, "foo", "bundle", nil)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	var bundle backupBundle
+	if err := json.Unmarshal(blob, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := []byte(bundle.Crypto.CipherText)
+	if ciphertext[0] == '0' {
+		ciphertext[0] = '1'
+	} else {
+		ciphertext[0] = '0'
+	}
+	bundle.Crypto.CipherText = string(ciphertext)
+	tampered, _ := json.Marshal(&bundle)
+
+	dir2, ks2 := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir2)
+
+	if _, err := ks2.ImportBundle(tampered, "bundle", "bar", nil); err != ErrDecrypt {
+		t.Errorf("tampered bundle error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	if len(ks2.Accounts()) != 0 {
+		t.Errorf("accounts imported from tampered bundle")
+	}
+}
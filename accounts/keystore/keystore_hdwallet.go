@@ -0,0 +1,491 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	ethereum "github.com/roller-project/roller"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+)
+
+const (
+	// hdWalletDir is the subdirectory of the key directory holding the HD wallets.
+	// The account cache doesn't descend into it, HD wallet files are no key files.
+	hdWalletDir = "hd"
+
+	// hdWalletVersion is the version of the HD wallet file format.
+	hdWalletVersion = 1
+
+	// selfDeriveThrottling is the minimum time between account discoveries.
+	selfDeriveThrottling = time.Second
+)
+
+// hdWalletJSON is the on-disk format of an HD wallet: the encrypted mnemonic
+// and the accounts pinned by the user.
+type hdWalletJSON struct {
+	Version  int             `json:"version"`
+	Crypto   cryptoJSON      `json:"crypto"`
+	Accounts []hdAccountJSON `json:"accounts"`
+}
+
+type hdAccountJSON struct {
+	Address common.Address `json:"address"`
+	Path    string         `json:"path"`
+}
+
+// hdWallet implements accounts.Wallet for a hierarchical deterministic wallet
+// backed by a BIP-39 mnemonic, which is stored encrypted with the scrypt scheme
+// of the keystore. Accounts can only be derived and used for signing while the
+// wallet is open, or with the passphrase for every signature.
+type hdWallet struct {
+	url      accounts.URL
+	keystore *KeyStore
+
+	crypto   cryptoJSON                                 // Encrypted mnemonic
+	pinned   []hdAccountJSON                            // Accounts persisted in the wallet file
+	accounts []accounts.Account                         // Pinned and self-derived accounts
+	paths    map[common.Address]accounts.DerivationPath // Derivation paths of the accounts
+	seed     []byte                                     // BIP-39 seed while the wallet is open
+
+	deriveNextPath accounts.DerivationPath   // Next derivation path for account auto-discovery
+	deriveChain    ethereum.ChainStateReader // Blockchain state reader to discover used account with
+	deriveTime     time.Time                 // Time of the last account discovery
+	deriving       bool                      // Whether an account discovery is running
+
+	stateLock sync.RWMutex
+}
+
+// loadHDWallet reads an HD wallet file.
+func loadHDWallet(ks *KeyStore, file string) (*hdWallet, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var stored hdWalletJSON
+	if err := json.Unmarshal(blob, &stored); err != nil {
+		return nil, err
+	}
+	if stored.Version != hdWalletVersion {
+		return nil, fmt.Errorf("HD wallet version not supported: %v", stored.Version)
+	}
+	w := &hdWallet{
+		url:      accounts.URL{Scheme: KeyStoreScheme, Path: file},
+		keystore: ks,
+		crypto:   stored.Crypto,
+		paths:    make(map[common.Address]accounts.DerivationPath),
+	}
+	for _, acc := range stored.Accounts {
+		path, err := accounts.ParseDerivationPath(acc.Path)
+		if err != nil {
+			return nil, fmt.Errorf("account %x: %v", acc.Address, err)
+		}
+		w.track(acc.Address, path)
+		w.pinned = append(w.pinned, acc)
+	}
+	return w, nil
+}
+
+// loadHDWallets reads all the HD wallets from the key directory.
+func loadHDWallets(ks *KeyStore, keydir string) []*hdWallet {
+	files, err := ioutil.ReadDir(filepath.Join(keydir, hdWalletDir))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("Failed to read HD wallet directory", "err", err)
+		}
+		return nil
+	}
+	var wallets []*hdWallet
+	for _, fi := range files {
+		if skipKeyFile(fi) {
+			continue
+		}
+		file := filepath.Join(keydir, hdWalletDir, fi.Name())
+		w, err := loadHDWallet(ks, file)
+		if err != nil {
+			log.Warn("Failed to load HD wallet", "path", file, "err", err)
+			continue
+		}
+		wallets = append(wallets, w)
+	}
+	return wallets
+}
+
+// URL implements accounts.Wallet, returning the path of the wallet file.
+func (w *hdWallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet, returning whether the wallet is open.
+func (w *hdWallet) Status() (string, error) {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	if w.seed != nil {
+		return "Unlocked", nil
+	}
+	return "Locked", nil
+}
+
+// Open implements accounts.Wallet, decrypting the mnemonic with the passphrase
+// to allow deriving accounts and signing with them until the wallet is closed.
+func (w *hdWallet) Open(passphrase string) error {
+	seed, err := w.decryptSeed(passphrase)
+	if err != nil {
+		return err
+	}
+	w.stateLock.Lock()
+	if w.seed != nil {
+		w.stateLock.Unlock()
+		zeroBytes(seed)
+		return accounts.ErrWalletAlreadyOpen
+	}
+	w.seed = seed
+	w.stateLock.Unlock()
+
+	w.keystore.updateFeed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletOpened})
+	return nil
+}
+
+// Close implements accounts.Wallet, wiping the decrypted seed from memory.
+func (w *hdWallet) Close() error {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	zeroBytes(w.seed)
+	w.seed = nil
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the pinned accounts and those
+// discovered by self-derivation. Listing the accounts of an open wallet also
+// triggers a throttled account discovery if self-derivation was requested.
+func (w *hdWallet) Accounts() []accounts.Account {
+	w.stateLock.Lock()
+	if w.seed != nil && w.deriveChain != nil && !w.deriving && time.Since(w.deriveTime) > selfDeriveThrottling {
+		w.deriving = true
+		go w.selfDerive()
+	}
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	w.stateLock.Unlock()
+
+	return cpy
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not derived by this wallet instance.
+func (w *hdWallet) Contains(account accounts.Account) bool {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	_, exists := w.paths[account.Address]
+	return exists
+}
+
+// Derive implements accounts.Wallet, deriving the account at the given path from
+// the seed of the open wallet. Pinned accounts are stored in the wallet file.
+func (w *hdWallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if w.seed == nil {
+		return accounts.Account{}, accounts.ErrWalletClosed
+	}
+	key, err := deriveHDKey(w.seed, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	zeroKey(key)
+
+	account := w.accountAt(address, path)
+	if !pin {
+		return account, nil
+	}
+	for _, acc := range w.pinned {
+		if acc.Address == address {
+			return account, nil
+		}
+	}
+	pinned := append(w.pinned, hdAccountJSON{Address: address, Path: path.String()})
+	if err := w.store(w.crypto, pinned); err != nil {
+		return accounts.Account{}, err
+	}
+	w.pinned = pinned
+	w.track(address, path)
+	return account, nil
+}
+
+// SelfDerive implements accounts.Wallet, trying to discover accounts that the
+// user used previously (based on the chain state), but did not pin. Discovery
+// runs while the wallet is open, throttled during account listing.
+func (w *hdWallet) SelfDerive(base accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	w.deriveNextPath = make(accounts.DerivationPath, len(base))
+	copy(w.deriveNextPath[:], base[:])
+
+	w.deriveChain = chain
+	w.deriveTime = time.Time{}
+}
+
+// selfDerive derives accounts from the next derivation path onwards until it
+// finds one without balance and nonce, starting to track all of them.
+func (w *hdWallet) selfDerive() {
+	w.stateLock.RLock()
+	var (
+		seed     = common.CopyBytes(w.seed)
+		chain    = w.deriveChain
+		nextPath = make(accounts.DerivationPath, len(w.deriveNextPath))
+	)
+	copy(nextPath, w.deriveNextPath)
+	w.stateLock.RUnlock()
+
+	var (
+		addrs []common.Address
+		paths []accounts.DerivationPath
+	)
+	for seed != nil && len(nextPath) > 0 {
+		key, err := deriveHDKey(seed, nextPath)
+		if err != nil {
+			log.Warn("HD wallet account derivation failed", "path", nextPath, "err", err)
+			break
+		}
+		address := crypto.PubkeyToAddress(key.PublicKey)
+		zeroKey(key)
+
+		balance, err := chain.BalanceAt(context.Background(), address, nil)
+		if err != nil {
+			log.Warn("HD wallet balance retrieval failed", "err", err)
+			break
+		}
+		nonce, err := chain.NonceAt(context.Background(), address, nil)
+		if err != nil {
+			log.Warn("HD wallet nonce retrieval failed", "err", err)
+			break
+		}
+		path := make(accounts.DerivationPath, len(nextPath))
+		copy(path, nextPath)
+		addrs = append(addrs, address)
+		paths = append(paths, path)
+
+		// Stop at the first empty account, but track it nonetheless
+		if balance.Sign() == 0 && nonce == 0 {
+			break
+		}
+		log.Info("HD wallet discovered account", "address", address, "path", path, "balance", balance, "nonce", nonce)
+		nextPath[len(nextPath)-1]++
+	}
+	zeroBytes(seed)
+
+	w.stateLock.Lock()
+	for i, address := range addrs {
+		w.track(address, paths[i])
+	}
+	if len(w.deriveNextPath) == len(nextPath) {
+		w.deriveNextPath = nextPath
+	}
+	w.deriveTime = time.Now()
+	w.deriving = false
+	w.stateLock.Unlock()
+}
+
+// SignHash implements accounts.Wallet, signing the hash with an account of the
+// open wallet.
+func (w *hdWallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	key, err := w.openKey(account)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return crypto.Sign(hash, key)
+}
+
+// SignTx implements accounts.Wallet, signing the transaction with an account of
+// the open wallet.
+func (w *hdWallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.openKey(account)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return signTx(tx, chainID, key)
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, signing the hash with an
+// account of the wallet, decrypting the mnemonic with the passphrase.
+func (w *hdWallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	key, err := w.passphraseKey(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return crypto.Sign(hash, key)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, signing the transaction with
+// an account of the wallet, decrypting the mnemonic with the passphrase.
+func (w *hdWallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.passphraseKey(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return signTx(tx, chainID, key)
+}
+
+// openKey derives the private key of an account of the open wallet.
+func (w *hdWallet) openKey(account accounts.Account) (*ecdsa.PrivateKey, error) {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	path, ok := w.paths[account.Address]
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	if w.seed == nil {
+		return nil, ErrLocked
+	}
+	return deriveHDKey(w.seed, path)
+}
+
+// passphraseKey derives the private key of an account of the wallet, decrypting
+// the mnemonic with the passphrase.
+func (w *hdWallet) passphraseKey(account accounts.Account, passphrase string) (*ecdsa.PrivateKey, error) {
+	w.stateLock.RLock()
+	path, ok := w.paths[account.Address]
+	w.stateLock.RUnlock()
+
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	seed, err := w.decryptSeed(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(seed)
+	return deriveHDKey(seed, path)
+}
+
+// decryptSeed decrypts the mnemonic and derives its seed.
+func (w *hdWallet) decryptSeed(passphrase string) ([]byte, error) {
+	w.stateLock.RLock()
+	cryptoStruct := w.crypto
+	w.stateLock.RUnlock()
+
+	mnemonic, err := decryptData(cryptoStruct, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(mnemonic)
+	return mnemonicSeed(string(mnemonic), ""), nil
+}
+
+// store writes the wallet file with the given encrypted mnemonic and pinned
+// accounts.
+func (w *hdWallet) store(cryptoStruct cryptoJSON, pinned []hdAccountJSON) error {
+	blob, err := json.Marshal(&hdWalletJSON{Version: hdWalletVersion, Crypto: cryptoStruct, Accounts: pinned})
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(w.url.Path, blob)
+}
+
+// track starts tracking an account at the given path. The caller must hold the
+// state lock.
+func (w *hdWallet) track(address common.Address, path accounts.DerivationPath) {
+	if _, ok := w.paths[address]; ok {
+		return
+	}
+	w.accounts = append(w.accounts, w.accountAt(address, path))
+	w.paths[address] = path
+}
+
+// accountAt returns the account of the given address derived at path.
+func (w *hdWallet) accountAt(address common.Address, path accounts.DerivationPath) accounts.Account {
+	return accounts.Account{
+		Address: address,
+		URL:     accounts.URL{Scheme: w.url.Scheme, Path: fmt.Sprintf("%s/%s", w.url.Path, path)},
+	}
+}
+
+// ImportMnemonic stores a BIP-39 mnemonic as a new HD wallet in the key
+// directory, encrypting it with the passphrase. The first account of the default
+// derivation path is pinned to the wallet.
+func (ks *KeyStore) ImportMnemonic(mnemonic, passphrase string) (accounts.Wallet, error) {
+	mnemonic = normalizeMnemonic(mnemonic)
+	if _, err := mnemonicToEntropy(mnemonic); err != nil {
+		return nil, err
+	}
+	seed := mnemonicSeed(mnemonic, "")
+	defer zeroBytes(seed)
+
+	key, err := deriveHDKey(seed, accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		return nil, err
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	zeroKey(key)
+
+	ks.mu.Lock()
+	for _, w := range ks.hdWallets {
+		if w.Contains(accounts.Account{Address: address}) {
+			ks.mu.Unlock()
+			return nil, fmt.Errorf("mnemonic already imported as %s", w.url)
+		}
+	}
+	ks.mu.Unlock()
+
+	scryptN, scryptP := ks.scryptParams()
+	cryptoStruct, err := encryptData([]byte(mnemonic), passphrase, scryptN, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	path := accounts.DefaultBaseDerivationPath
+	w := &hdWallet{
+		url:      accounts.URL{Scheme: KeyStoreScheme, Path: ks.storage.JoinPath(filepath.Join(hdWalletDir, keyFileName(address)))},
+		keystore: ks,
+		crypto:   cryptoStruct,
+		pinned:   []hdAccountJSON{{Address: address, Path: path.String()}},
+		paths:    make(map[common.Address]accounts.DerivationPath),
+	}
+	w.track(address, path)
+	if err := w.store(w.crypto, w.pinned); err != nil {
+		return nil, err
+	}
+	ks.mu.Lock()
+	ks.hdWallets = append(ks.hdWallets, w)
+	ks.mu.Unlock()
+
+	ks.updateFeed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletArrived})
+	return w, nil
+}
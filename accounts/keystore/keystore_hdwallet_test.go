@@ -0,0 +1,220 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// First accounts of the test mnemonic on the default derivation path.
+var testHDAccounts = []common.Address{
+	common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94"),
+	common.HexToAddress("0x6fac4d18c912343bf86fa7049364dd4e424ab9c0"),
+	common.HexToAddress("0xb6716976a3ebe8d39aceb04372f22ff8e6802d7a"),
+}
+
+func TestHDWalletImport(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	if _, err := ks.ImportMnemonic("abandon abandon", "foo"); err != ErrInvalidMnemonic {
+		t.Errorf("invalid mnemonic error mismatch: have %v, want %v", err, ErrInvalidMnemonic)
+	}
+	wallet, err := ks.ImportMnemonic("  "+testMnemonic+"\n", "foo")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if _, err := ks.ImportMnemonic(testMnemonic, "bar"); err == nil {
+		t.Errorf("duplicate import succeeded")
+	}
+	accs := wallet.Accounts()
+	if len(accs) != 1 || accs[0].Address != testHDAccounts[0] {
+		t.Fatalf("pinned accounts mismatch: have %v", accs)
+	}
+	if accs[0].URL.Path != wallet.URL().Path+"/m/44'/60'/0'/0/0" {
+		t.Errorf("account URL mismatch: have %v", accs[0].URL)
+	}
+	// The HD wallet is listed next to the single key wallets, but is no key file
+	if _, err := ks.NewAccount("foo"); err != nil {
+		t.Fatal(err)
+	}
+	wallets := ks.Wallets()
+	if len(wallets) != 2 {
+		t.Fatalf("wallet count mismatch: have %d, want 2", len(wallets))
+	}
+	if wallets[0].URL().Cmp(wallets[1].URL()) >= 0 {
+		t.Errorf("wallets not sorted: %v, %v", wallets[0].URL(), wallets[1].URL())
+	}
+	if len(ks.Accounts()) != 1 {
+		t.Errorf("HD wallet listed as key file")
+	}
+	// The wallet is loaded again from disk
+	reloaded := NewKeyStore(dir, veryLightScryptN, veryLightScryptP)
+	if len(reloaded.hdWallets) != 1 || !reloaded.hdWallets[0].Contains(accounts.Account{Address: testHDAccounts[0]}) {
+		t.Fatalf("HD wallet not reloaded")
+	}
+}
+
+func TestHDWalletDerive(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	wallet, err := ks.ImportMnemonic(testMnemonic, "foo")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	path, _ := accounts.ParseDerivationPath("m/44'/60'/0'/0/1")
+	if _, err := wallet.Derive(path, true); err != accounts.ErrWalletClosed {
+		t.Errorf("closed derivation error mismatch: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+	if err := wallet.Open("bar"); err != ErrDecrypt {
+		t.Errorf("open error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	if err := wallet.Open("foo"); err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if status, _ := wallet.Status(); status != "Unlocked" {
+		t.Errorf("status mismatch: have %s, want Unlocked", status)
+	}
+	// Unpinned accounts are not tracked, pinned ones are persisted
+	account, err := wallet.Derive(path, false)
+	if err != nil || account.Address != testHDAccounts[1] {
+		t.Fatalf("derived account mismatch: have %x, %v", account.Address, err)
+	}
+	if wallet.Contains(account) {
+		t.Errorf("unpinned account tracked")
+	}
+	if _, err := wallet.Derive(path, true); err != nil || !wallet.Contains(account) {
+		t.Fatalf("pinned account not tracked: %v", err)
+	}
+	reloaded := NewKeyStore(dir, veryLightScryptN, veryLightScryptP)
+	if accs := reloaded.hdWallets[0].Accounts(); len(accs) != 2 || accs[1].Address != testHDAccounts[1] {
+		t.Errorf("pinned account not persisted: %v", accs)
+	}
+	wallet.Close()
+	if status, _ := wallet.Status(); status != "Locked" {
+		t.Errorf("status mismatch: have %s, want Locked", status)
+	}
+}
+
+func TestHDWalletSign(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	wallet, err := ks.ImportMnemonic(testMnemonic, "foo")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	account := wallet.Accounts()[0]
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	if _, err := wallet.SignTx(account, tx, big.NewInt(1)); err != ErrLocked {
+		t.Errorf("locked signing error mismatch: have %v, want %v", err, ErrLocked)
+	}
+	if _, err := wallet.SignTxWithPassphrase(account, "bar", tx, big.NewInt(1)); err != ErrDecrypt {
+		t.Errorf("wrong passphrase error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	signed, err := wallet.SignTxWithPassphrase(account, "foo", tx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("signing with passphrase failed: %v", err)
+	}
+	if from, _ := types.Sender(signer, signed); from != account.Address {
+		t.Errorf("sender mismatch: have %x, want %x", from, account.Address)
+	}
+	if err := wallet.Open("foo"); err != nil {
+		t.Fatal(err)
+	}
+	hash := crypto.Keccak256(nil)
+	sig, err := wallet.SignHash(account, hash)
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != account.Address {
+		t.Errorf("recovered signer mismatch: %v", err)
+	}
+	if _, err := wallet.SignHash(accounts.Account{Address: testHDAccounts[2]}, hash); err != accounts.ErrUnknownAccount {
+		t.Errorf("unknown account error mismatch: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+}
+
+// testChainState is a chain state reader with preset nonces.
+type testChainState map[common.Address]uint64
+
+func (s testChainState) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return new(big.Int), nil
+}
+
+func (s testChainState) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (s testChainState) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (s testChainState) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return s[account], nil
+}
+
+// Tests that used accounts are discovered, up to and including the first unused.
+func TestHDWalletSelfDerive(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	wallet, err := ks.ImportMnemonic(testMnemonic, "foo")
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	chain := testChainState{testHDAccounts[0]: 1, testHDAccounts[1]: 5}
+	wallet.SelfDerive(accounts.DefaultBaseDerivationPath, chain)
+
+	// Nothing is discovered while the wallet is closed
+	wallet.Accounts()
+	time.Sleep(50 * time.Millisecond)
+	if accs := wallet.Accounts(); len(accs) != 1 {
+		t.Fatalf("accounts discovered in closed wallet: %v", accs)
+	}
+	if err := wallet.Open("foo"); err != nil {
+		t.Fatal(err)
+	}
+	var accs []accounts.Account
+	for i := 0; i < 100 && len(accs) < 3; i++ {
+		accs = wallet.Accounts()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(accs) != 3 {
+		t.Fatalf("discovered account count mismatch: have %d, want 3", len(accs))
+	}
+	for i, acc := range accs {
+		if acc.Address != testHDAccounts[i] {
+			t.Errorf("account %d mismatch: have %x, want %x", i, acc.Address, testHDAccounts[i])
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/math"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrInvalidMnemonic is returned if a mnemonic has unknown words, an invalid
+// length or a wrong checksum.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// mnemonicIndex maps the words of the BIP-39 word list to their index.
+var mnemonicIndex = func() map[string]int {
+	index := make(map[string]int, len(mnemonicWords))
+	for i, word := range mnemonicWords {
+		index[word] = i
+	}
+	return index
+}()
+
+// normalizeMnemonic trims and lowercases a mnemonic, separating the words by
+// single spaces.
+func normalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+}
+
+// entropyToMnemonic encodes entropy of 128 to 256 bits as BIP-39 mnemonic.
+func entropyToMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", fmt.Errorf("invalid entropy length %d", bits)
+	}
+	// Append the checksum, the first bits/32 bits of the hash of the entropy
+	checksum := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(bits/32))
+	data.Or(data, big.NewInt(int64(checksum[0]>>(8-uint(bits/32)))))
+
+	// Split the data into 11 bit word indexes
+	words := make([]string, (bits+bits/32)/11)
+	mask := big.NewInt(2047)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = mnemonicWords[new(big.Int).And(data, mask).Int64()]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// mnemonicToEntropy decodes a BIP-39 mnemonic, verifying its checksum.
+func mnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, ErrInvalidMnemonic
+	}
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := mnemonicIndex[strings.ToLower(word)]
+		if !ok {
+			return nil, ErrInvalidMnemonic
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+	checksumBits := uint(len(words) / 3)
+	checksum := new(big.Int).And(data, big.NewInt(1<<checksumBits-1))
+	data.Rsh(data, checksumBits)
+
+	entropy := math.PaddedBigBytes(data, int(checksumBits)*4)
+
+	hash := sha256.Sum256(entropy)
+	if int64(hash[0]>>(8-checksumBits)) != checksum.Int64() {
+		return nil, ErrInvalidMnemonic
+	}
+	return entropy, nil
+}
+
+// mnemonicSeed derives the BIP-39 seed of a mnemonic and an optional password.
+func mnemonicSeed(mnemonic, password string) []byte {
+	return pbkdf2.Key([]byte(normalizeMnemonic(mnemonic)), []byte("mnemonic"+password), 2048, 64, sha512.New)
+}
+
+// hdKey is a BIP-32 extended private key.
+type hdKey struct {
+	key       []byte // 32 byte private key
+	chainCode []byte // 32 byte chain code
+}
+
+var errInvalidHDKey = errors.New("invalid derived key, use the next index")
+
+// newMasterKey derives the BIP-32 master key from a seed.
+func newMasterKey(seed []byte) (*hdKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errInvalidHDKey
+	}
+	return &hdKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// child derives the child key at the given index, hardened if the index is at
+// least 2^31.
+func (k *hdKey) child(index uint32) (*hdKey, error) {
+	var data []byte
+	if index >= 0x80000000 {
+		data = append([]byte{0}, k.key...)
+	} else {
+		priv, err := crypto.ToECDSA(k.key)
+		if err != nil {
+			return nil, err
+		}
+		data = crypto.CompressPubkey(&priv.PublicKey)
+	}
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, errInvalidHDKey
+	}
+	il.Add(il, new(big.Int).SetBytes(k.key))
+	il.Mod(il, n)
+	if il.Sign() == 0 {
+		return nil, errInvalidHDKey
+	}
+	return &hdKey{key: math.PaddedBigBytes(il, 32), chainCode: sum[32:]}, nil
+}
+
+// deriveHDKey derives the private key at the given path from a BIP-39 seed.
+func deriveHDKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	key, err := newMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range path {
+		if key, err = key.child(index); err != nil {
+			return nil, err
+		}
+	}
+	return crypto.ToECDSA(key.key)
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+)
+
+// Tests that the word list matches the one of the BIP-39 specification.
+func TestMnemonicWordList(t *testing.T) {
+	if len(mnemonicWords) != 2048 {
+		t.Fatalf("word count mismatch: have %d, want 2048", len(mnemonicWords))
+	}
+	if sum := crc32.ChecksumIEEE([]byte(strings.Join(mnemonicWords, "\n") + "\n")); sum != 0xc1dbd296 {
+		t.Errorf("word list checksum mismatch: have %x, want c1dbd296", sum)
+	}
+}
+
+// Tests the BIP-39 encoding and seed derivation against the reference vectors.
+func TestMnemonicVectors(t *testing.T) {
+	tests := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			"ffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+		{
+			"808080808080808080808080808080808080808080808080",
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always",
+			"107d7c02a5aa6f38c58083ff74f04c607c2d2c0ecc55501dadd72d025b751bc27fe913ffb796f841c49b1d33b610cf0e91d3aa239027f5e99fe4ce9e5088cd65",
+		},
+		{
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+			"bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+		},
+	}
+	for i, tt := range tests {
+		entropy := common.Hex2Bytes(tt.entropy)
+		mnemonic, err := entropyToMnemonic(entropy)
+		if err != nil {
+			t.Fatalf("test %d: encoding failed: %v", i, err)
+		}
+		if mnemonic != tt.mnemonic {
+			t.Errorf("test %d: mnemonic mismatch: have %q, want %q", i, mnemonic, tt.mnemonic)
+		}
+		decoded, err := mnemonicToEntropy(strings.ToUpper(tt.mnemonic))
+		if err != nil {
+			t.Fatalf("test %d: decoding failed: %v", i, err)
+		}
+		if !bytes.Equal(decoded, entropy) {
+			t.Errorf("test %d: entropy mismatch: have %x, want %x", i, decoded, entropy)
+		}
+		if seed := hex.EncodeToString(mnemonicSeed(tt.mnemonic, "TREZOR")); seed != tt.seed {
+			t.Errorf("test %d: seed mismatch: have %s, want %s", i, seed, tt.seed)
+		}
+	}
+}
+
+func TestMnemonicInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon notaword",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about about",
+	}
+	for i, mnemonic := range tests {
+		if _, err := mnemonicToEntropy(mnemonic); err != ErrInvalidMnemonic {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, ErrInvalidMnemonic)
+		}
+	}
+}
+
+// Tests the BIP-32 key derivation against test vector 1 of the specification.
+func TestHDKeyDerivation(t *testing.T) {
+	master, err := newMasterKey(common.Hex2Bytes("000102030405060708090a0b0c0d0e0f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := hex.EncodeToString(master.key); key != "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35" {
+		t.Errorf("master key mismatch: have %s", key)
+	}
+	if code := hex.EncodeToString(master.chainCode); code != "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508" {
+		t.Errorf("master chain code mismatch: have %s", code)
+	}
+	tests := []struct {
+		index uint32
+		key   string
+	}{
+		{0x80000000, "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{1, "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{0x80000002, "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{2, "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{1000000000, "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	key := master
+	for i, tt := range tests {
+		if key, err = key.child(tt.index); err != nil {
+			t.Fatalf("test %d: derivation failed: %v", i, err)
+		}
+		if have := hex.EncodeToString(key.key); have != tt.key {
+			t.Errorf("test %d: key mismatch: have %s, want %s", i, have, tt.key)
+		}
+	}
+}
+
+// Tests that the default Ethereum derivation path yields the same accounts as
+// other BIP-39 wallets.
+func TestHDKeyEthereum(t *testing.T) {
+	seed := mnemonicSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "")
+	key, err := deriveHDKey(seed, accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	if address := crypto.PubkeyToAddress(key.PublicKey); address != want {
+		t.Errorf("address mismatch: have %x, want %x", address, want)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi/bind"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/keystore"
+	"github.com/Ethereum-Reloaded/ETHR-Go/cmd/utils"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/console"
+	"github.com/Ethereum-Reloaded/ETHR-Go/contracts/checkpointoracle"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/ethclient"
+	"github.com/Ethereum-Reloaded/ETHR-Go/les"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	commandStatus = cli.Command{
+		Name:  "status",
+		Usage: "show the status of the checkpoint oracle",
+		Description: `
+Print the admins of the oracle contract and the latest registered checkpoint.`,
+		Flags: []cli.Flag{
+			rpcFlag,
+			oracleFlag,
+		},
+		Action: showStatus,
+	}
+	commandSign = cli.Command{
+		Name:  "sign",
+		Usage: "sign a checkpoint with an admin key",
+		Description: `
+Sign a checkpoint generated by the light server at --rpc with the key in the
+given keyfile. The checkpoint hash can be given with --hash and --index to sign
+offline. The signature is printed, admins hand it to the publisher.`,
+		Flags: []cli.Flag{
+			rpcFlag,
+			oracleFlag,
+			indexFlag,
+			hashFlag,
+			keyFileFlag,
+			passphraseFlag,
+		},
+		Action: sign,
+	}
+	commandPublish = cli.Command{
+		Name:  "publish",
+		Usage: "register a signed checkpoint in the oracle contract",
+		Description: `
+Submit the checkpoint of the given section, generated by the light server at
+--rpc, together with the admin signatures to the oracle contract. The
+transaction is sent from the admin account in the given keyfile.`,
+		Flags: []cli.Flag{
+			rpcFlag,
+			oracleFlag,
+			indexFlag,
+			signaturesFlag,
+			keyFileFlag,
+			passphraseFlag,
+		},
+		Action: publish,
+	}
+)
+
+func showStatus(ctx *cli.Context) error {
+	client := newRPCClient(ctx)
+	oracle := newOracle(ctx, client)
+
+	admins, err := oracle.Contract().GetAllAdmin(nil)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve oracle admins: %v", err)
+	}
+	for i, admin := range admins {
+		fmt.Printf("Admin %d: %s\n", i+1, admin.Hex())
+	}
+	index, hash, height, err := oracle.Contract().GetLatestCheckpoint(nil)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve latest checkpoint: %v", err)
+	}
+	if height.Sign() == 0 {
+		fmt.Println("No checkpoint registered")
+		return nil
+	}
+	fmt.Printf("Checkpoint: section %d, hash %s, registered at block #%d\n", index, common.Hash(hash).Hex(), height)
+	return nil
+}
+
+func sign(ctx *cli.Context) error {
+	var (
+		index uint64
+		hash  common.Hash
+	)
+	if ctx.IsSet(hashFlag.Name) {
+		if ctx.Int64(indexFlag.Name) < 0 {
+			utils.Fatalf("Offline signing needs the section --index")
+		}
+		index, hash = uint64(ctx.Int64(indexFlag.Name)), common.HexToHash(ctx.String(hashFlag.Name))
+	} else {
+		cp := getCheckpoint(ctx, newRPCClient(ctx))
+		index, hash = uint64(cp.SectionIndex), cp.Hash
+	}
+	key := loadKey(ctx)
+
+	sig, err := crypto.Sign(checkpointoracle.SignedHash(oracleAddress(ctx), index, hash).Bytes(), key.PrivateKey)
+	if err != nil {
+		utils.Fatalf("Failed to sign checkpoint: %v", err)
+	}
+	sig[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+
+	fmt.Printf("Section: %d\n", index)
+	fmt.Printf("Hash:    %s\n", hash.Hex())
+	fmt.Printf("Signer:  %s\n", key.Address.Hex())
+	fmt.Printf("Signature: %s\n", hexutil.Encode(sig))
+	return nil
+}
+
+func publish(ctx *cli.Context) error {
+	client := newRPCClient(ctx)
+	oracle := newOracle(ctx, client)
+	cp := getCheckpoint(ctx, client)
+	index := uint64(cp.SectionIndex)
+
+	if !ctx.IsSet(signaturesFlag.Name) {
+		utils.Fatalf("No checkpoint signatures given, use --signatures")
+	}
+	admins, err := oracle.Contract().GetAllAdmin(nil)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve oracle admins: %v", err)
+	}
+	// Check the signatures and sort them by signer, as the contract requires
+	type vote struct {
+		signer common.Address
+		sig    []byte
+	}
+	var votes []vote
+	for _, s := range strings.Split(ctx.String(signaturesFlag.Name), ",") {
+		sig, err := hexutil.Decode(strings.TrimSpace(s))
+		if err != nil {
+			utils.Fatalf("Invalid signature %q: %v", s, err)
+		}
+		signer, err := checkpointoracle.RecoverSigner(oracle.ContractAddr(), index, cp.Hash, sig)
+		if err != nil {
+			utils.Fatalf("Invalid signature %q: %v", s, err)
+		}
+		if !isAdmin(admins, signer) {
+			utils.Fatalf("Signature %q is not from an oracle admin (signer %s)", s, signer.Hex())
+		}
+		votes = append(votes, vote{signer, sig})
+	}
+	sort.Slice(votes, func(i, j int) bool {
+		return bytes.Compare(votes[i].signer.Bytes(), votes[j].signer.Bytes()) < 0
+	})
+	sigs := make([][]byte, len(votes))
+	for i, v := range votes {
+		if i > 0 && v.signer == votes[i-1].signer {
+			utils.Fatalf("Duplicate signature of %s", v.signer.Hex())
+		}
+		sigs[i] = v.sig
+	}
+	// Reference a recent block for replay protection
+	head, err := ethclient.NewClient(client).HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve head block: %v", err)
+	}
+	key := loadKey(ctx)
+	tx, err := oracle.RegisterCheckpoint(bind.NewKeyedTransactor(key.PrivateKey), index, cp.Hash.Bytes(), head.Number, head.Hash(), sigs)
+	if err != nil {
+		utils.Fatalf("Failed to register checkpoint: %v", err)
+	}
+	fmt.Printf("Registering checkpoint of section %d in transaction %s\n", index, tx.Hash().Hex())
+	return nil
+}
+
+// newRPCClient connects to the node given by the --rpc flag.
+func newRPCClient(ctx *cli.Context) *rpc.Client {
+	client, err := rpc.Dial(ctx.String(rpcFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to connect to %s: %v", ctx.String(rpcFlag.Name), err)
+	}
+	return client
+}
+
+// oracleAddress returns the address given by the --oracle flag.
+func oracleAddress(ctx *cli.Context) common.Address {
+	addr := ctx.String(oracleFlag.Name)
+	if !common.IsHexAddress(addr) {
+		utils.Fatalf("Invalid or missing oracle address, use --oracle")
+	}
+	return common.HexToAddress(addr)
+}
+
+// newOracle binds the oracle contract given by the --oracle flag.
+func newOracle(ctx *cli.Context, client *rpc.Client) *checkpointoracle.CheckpointOracle {
+	oracle, err := checkpointoracle.NewCheckpointOracle(oracleAddress(ctx), ethclient.NewClient(client))
+	if err != nil {
+		utils.Fatalf("Failed to bind oracle contract: %v", err)
+	}
+	return oracle
+}
+
+// getCheckpoint retrieves the checkpoint of the section given by the --index
+// flag, or the latest one, from the light server.
+func getCheckpoint(ctx *cli.Context, client *rpc.Client) *les.CheckpointInfo {
+	var (
+		cp  les.CheckpointInfo
+		err error
+	)
+	if index := ctx.Int64(indexFlag.Name); index >= 0 {
+		err = client.Call(&cp, "les_getCheckpoint", index)
+	} else {
+		err = client.Call(&cp, "les_latestCheckpoint")
+	}
+	if err != nil {
+		utils.Fatalf("Failed to retrieve checkpoint: %v", err)
+	}
+	return &cp
+}
+
+// loadKey decrypts the key in the keyfile given by the --keyfile flag.
+func loadKey(ctx *cli.Context) *keystore.Key {
+	keyfile := ctx.String(keyFileFlag.Name)
+	if keyfile == "" {
+		utils.Fatalf("No keyfile given, use --keyfile")
+	}
+	keyjson, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		utils.Fatalf("Failed to read the keyfile at '%s': %v", keyfile, err)
+	}
+	key, err := keystore.DecryptKey(keyjson, getPassphrase(ctx))
+	if err != nil {
+		utils.Fatalf("Error decrypting key: %v", err)
+	}
+	return key
+}
+
+// getPassphrase obtains a passphrase given by the user. It first checks the
+// --passwordfile command line flag and ultimately prompts the user for a
+// passphrase.
+func getPassphrase(ctx *cli.Context) string {
+	if file := ctx.String(passphraseFlag.Name); file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Failed to read passphrase file '%s': %v", file, err)
+		}
+		return strings.TrimRight(string(content), "\r\n")
+	}
+	passphrase, err := console.Stdin.PromptPassword("Passphrase: ")
+	if err != nil {
+		utils.Fatalf("Failed to read passphrase: %v", err)
+	}
+	return passphrase
+}
+
+func isAdmin(admins []common.Address, addr common.Address) bool {
+	for _, admin := range admins {
+		if admin == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// checkpoint-admin is a utility that can be used to query checkpoint information
+// and register stable checkpoints into an oracle contract.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/cmd/utils"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Git SHA1 commit hash of the release (set via linker flags)
+var gitCommit = ""
+
+var app *cli.App
+
+func init() {
+	app = utils.NewApp(gitCommit, "a light client checkpoint oracle manager")
+	app.Flags = []cli.Flag{
+		verbosityFlag,
+	}
+	app.Before = func(ctx *cli.Context) error {
+		glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+		glogger.Verbosity(log.Lvl(ctx.GlobalInt(verbosityFlag.Name)))
+		log.Root().SetHandler(glogger)
+		return nil
+	}
+	app.Commands = []cli.Command{
+		commandStatus,
+		commandSign,
+		commandPublish,
+	}
+}
+
+// Commonly used command line flags.
+var (
+	verbosityFlag = cli.IntFlag{
+		Name:  "verbosity",
+		Usage: "log verbosity (0-9)",
+		Value: int(log.LvlInfo),
+	}
+	rpcFlag = cli.StringFlag{
+		Name:  "rpc",
+		Value: "http://localhost:8545",
+		Usage: "the rpc endpoint of a local or remote full node with a light server",
+	}
+	oracleFlag = cli.StringFlag{
+		Name:  "oracle",
+		Usage: "address of the checkpoint oracle contract",
+	}
+	indexFlag = cli.Int64Flag{
+		Name:  "index",
+		Value: -1,
+		Usage: "section index of the checkpoint, the latest one by default",
+	}
+	hashFlag = cli.StringFlag{
+		Name:  "hash",
+		Usage: "hash of the checkpoint to sign offline, requires --index",
+	}
+	keyFileFlag = cli.StringFlag{
+		Name:  "keyfile",
+		Usage: "the keyfile of the oracle admin account",
+	}
+	passphraseFlag = cli.StringFlag{
+		Name:  "passwordfile",
+		Usage: "the file that contains the passphrase for the keyfile",
+	}
+	signaturesFlag = cli.StringFlag{
+		Name:  "signatures",
+		Usage: "comma separated checkpoint signatures of the oracle admins",
+	}
+)
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
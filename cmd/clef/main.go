@@ -48,10 +48,10 @@ import (
 )
 
 // ExternalAPIVersion -- see extapi_changelog.md
-const ExternalAPIVersion = "2.0.0"
+const ExternalAPIVersion = "2.1.0"
 
 // InternalAPIVersion -- see intapi_changelog.md
-const InternalAPIVersion = "2.0.0"
+const InternalAPIVersion = "2.1.0"
 
 const legalWarning = `
 WARNING! 
@@ -349,11 +349,13 @@ func signer(c *cli.Context) error {
 		pwkey := crypto.Keccak256([]byte("credentials"), stretchedKey)
 		jskey := crypto.Keccak256([]byte("jsstorage"), stretchedKey)
 		confkey := crypto.Keccak256([]byte("config"), stretchedKey)
+		policykey := crypto.Keccak256([]byte("policy"), stretchedKey)
 
 		// Initialize the encrypted storages
 		pwStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "credentials.json"), pwkey)
 		jsStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "jsstorage.json"), jskey)
 		configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confkey)
+		policyStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "policy.json"), policykey)
 
 		//Do we have a rule-file?
 		ruleJS, err := ioutil.ReadFile(c.String(ruleFlag.Name))
@@ -373,6 +375,7 @@ func signer(c *cli.Context) error {
 					utils.Fatalf(err.Error())
 				}
 				ruleEngine.Init(string(ruleJS))
+				ruleEngine.EnablePolicy(rules.NewPolicy(policyStorage, db))
 				ui = ruleEngine
 				log.Info("Rule engine configured", "file", c.String(ruleFlag.Name))
 			}
@@ -0,0 +1,157 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/cmd/utils"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
+	"github.com/Ethereum-Reloaded/ETHR-Go/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var commandCrawl = cli.Command{
+	Name:      "crawl",
+	Usage:     "crawl the discovery network for node records",
+	ArgsUsage: "<tree-directory>",
+	Description: `
+Crawl the discovery v4 network, requesting the node record of every node found.
+Records are merged into nodes.json in the tree directory, which is created if it
+doesn't exist yet.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "bootnodes",
+			Usage: "comma separated enode URLs to start crawling from (defaults to the main network bootnodes)",
+		},
+		cli.StringFlag{
+			Name:  "addr",
+			Usage: "UDP listen address of the crawler",
+			Value: ":0",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "time to spend crawling",
+			Value: 10 * time.Minute,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		dir := ctx.Args().First()
+		if dir == "" {
+			utils.Fatalf("Need tree directory as argument")
+		}
+		nodes := loadNodes(dir)
+
+		// Start the discovery table to crawl with.
+		urls := params.MainnetBootnodes
+		if ctx.IsSet("bootnodes") {
+			urls = strings.Split(ctx.String("bootnodes"), ",")
+		}
+		var bootnodes []*discover.Node
+		for _, url := range urls {
+			n, err := discover.ParseNode(url)
+			if err != nil {
+				utils.Fatalf("Invalid bootnode %q: %v", url, err)
+			}
+			bootnodes = append(bootnodes, n)
+		}
+		tab := startDiscovery(ctx.String("addr"), bootnodes)
+		defer tab.Close()
+
+		found := crawl(tab, ctx.Duration("timeout"))
+		for id, r := range found {
+			nodes[id] = r
+		}
+		log.Info("Crawl finished", "found", len(found), "total", len(nodes))
+		writeNodes(dir, nodes)
+		return nil
+	},
+}
+
+// startDiscovery starts a discovery table with an ephemeral node key.
+func startDiscovery(addr string, bootnodes []*discover.Node) *discover.Table {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		utils.Fatalf("Failed to generate node key: %v", err)
+	}
+	laddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		utils.Fatalf("Invalid listen address: %v", err)
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		utils.Fatalf("Failed to listen: %v", err)
+	}
+	tab, err := discover.ListenUDP(conn, discover.Config{PrivateKey: key, Bootnodes: bootnodes})
+	if err != nil {
+		utils.Fatalf("Failed to start discovery: %v", err)
+	}
+	return tab
+}
+
+// crawl performs random lookups until the timeout expires, requesting the
+// record of each newly found node. It returns the records by node ID.
+func crawl(tab *discover.Table, timeout time.Duration) map[discover.NodeID]*enr.Record {
+	const workers = 16
+
+	var (
+		seen    = make(map[discover.NodeID]bool)
+		records = make(map[discover.NodeID]*enr.Record)
+		lock    sync.Mutex
+		queue   = make(chan *discover.Node)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range queue {
+				r, err := tab.RequestENR(n)
+				if err != nil {
+					log.Debug("Node record request failed", "id", n.ID, "err", err)
+					continue
+				}
+				lock.Lock()
+				records[n.ID] = r
+				lock.Unlock()
+			}
+		}()
+	}
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		var target discover.NodeID
+		rand.Read(target[:])
+		for _, n := range tab.Lookup(target) {
+			if !seen[n.ID] {
+				seen[n.ID] = true
+				queue <- n
+			}
+		}
+		lock.Lock()
+		log.Info("Crawling", "seen", len(seen), "records", len(records))
+		lock.Unlock()
+	}
+	close(queue)
+	wg.Wait()
+	return records
+}
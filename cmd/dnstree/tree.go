@@ -0,0 +1,339 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/keystore"
+	"github.com/Ethereum-Reloaded/ETHR-Go/cmd/utils"
+	"github.com/Ethereum-Reloaded/ETHR-Go/console"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/dnsdisc"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	nodesFile = "nodes.json"        // node records of the tree, keyed by node ID
+	infoFile  = "enrtree-info.json" // tree metadata and signature
+	zoneFile  = "enrtree.zone"      // BIND zone file of the signed tree
+)
+
+var (
+	commandSign = cli.Command{
+		Name:      "sign",
+		Usage:     "sign a node list",
+		ArgsUsage: "<tree-directory> <keyfile>",
+		Description: `
+Build the tree of the records in nodes.json and sign it with the key in the
+given keyfile, increasing the sequence number. The domain, links, signature and
+URL of the tree are stored in enrtree-info.json.`,
+		Flags: []cli.Flag{
+			passphraseFlag,
+			cli.StringFlag{
+				Name:  "domain",
+				Usage: "domain name of the tree (required when signing for the first time)",
+			},
+			cli.StringFlag{
+				Name:  "links",
+				Usage: "comma separated enrtree:// URLs of linked trees",
+			},
+		},
+		Action: signTree,
+	}
+	commandZonefile = cli.Command{
+		Name:      "to-zonefile",
+		Usage:     "create a BIND zone file of a signed node list",
+		ArgsUsage: "<tree-directory>",
+		Description: `
+Write the TXT records of the signed tree to enrtree.zone in the tree directory.`,
+		Action: writeZonefile,
+	}
+	commandSync = cli.Command{
+		Name:      "sync",
+		Usage:     "download a published node list",
+		ArgsUsage: "<enrtree-url> [<tree-directory>]",
+		Description: `
+Download and verify the tree at the given URL. If a tree directory is given,
+the records and metadata of the tree are written to it.`,
+		Action: syncTree,
+	}
+)
+
+// treeInfo is the content of the tree metadata file.
+type treeInfo struct {
+	Domain    string   `json:"domain"`
+	Seq       uint     `json:"seq"`
+	Signature string   `json:"signature,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	Links     []string `json:"links,omitempty"`
+}
+
+func signTree(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		utils.Fatalf("Need tree directory and keyfile as arguments")
+	}
+	dir, keyfile := ctx.Args().Get(0), ctx.Args().Get(1)
+
+	info := loadInfo(dir)
+	if ctx.IsSet("domain") {
+		info.Domain = ctx.String("domain")
+	}
+	if info.Domain == "" {
+		utils.Fatalf("Tree domain not set, use --domain")
+	}
+	if ctx.IsSet("links") {
+		info.Links = nil
+		if links := ctx.String("links"); links != "" {
+			info.Links = strings.Split(links, ",")
+		}
+	}
+	keyjson, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		utils.Fatalf("Failed to read the keyfile at '%s': %v", keyfile, err)
+	}
+	key, err := keystore.DecryptKey(keyjson, getPassphrase(ctx))
+	if err != nil {
+		utils.Fatalf("Error decrypting key: %v", err)
+	}
+	info.Seq++
+	tree, err := dnsdisc.MakeTree(info.Seq, recordList(loadNodes(dir)), info.Links)
+	if err != nil {
+		utils.Fatalf("Failed to create tree: %v", err)
+	}
+	if info.URL, err = tree.Sign(key.PrivateKey, info.Domain); err != nil {
+		utils.Fatalf("Failed to sign tree: %v", err)
+	}
+	info.Signature = tree.Signature()
+	writeJSON(filepath.Join(dir, infoFile), info)
+
+	fmt.Println(info.URL)
+	return nil
+}
+
+func writeZonefile(ctx *cli.Context) error {
+	dir := ctx.Args().First()
+	if dir == "" {
+		utils.Fatalf("Need tree directory as argument")
+	}
+	tree, info := loadTree(dir)
+
+	out, err := os.Create(filepath.Join(dir, zoneFile))
+	if err != nil {
+		utils.Fatalf("Failed to create zone file: %v", err)
+	}
+	defer out.Close()
+	if err := writeZone(out, tree.ToTXT(info.Domain)); err != nil {
+		utils.Fatalf("Failed to write zone file: %v", err)
+	}
+	return nil
+}
+
+func syncTree(ctx *cli.Context) error {
+	url := ctx.Args().First()
+	if url == "" {
+		utils.Fatalf("Need tree URL as argument")
+	}
+	client, err := dnsdisc.NewClient(dnsdisc.Config{})
+	if err != nil {
+		return err
+	}
+	tree, err := client.SyncTree(url)
+	if err != nil {
+		utils.Fatalf("Failed to sync tree: %v", err)
+	}
+	records := tree.Records()
+	fmt.Printf("Tree %s: sequence number %d, %d records, %d links\n", url, tree.Seq(), len(records), len(tree.Links()))
+
+	if dir := ctx.Args().Get(1); dir != "" {
+		domain, _, _ := dnsdisc.ParseURL(url)
+		nodes := make(map[discover.NodeID]*enr.Record)
+		for _, r := range records {
+			n, err := discover.NodeFromRecord(r)
+			if err != nil {
+				utils.Fatalf("Invalid record in tree: %v", err)
+			}
+			nodes[n.ID] = r
+		}
+		writeNodes(dir, nodes)
+		writeJSON(filepath.Join(dir, infoFile), &treeInfo{
+			Domain:    domain,
+			Seq:       tree.Seq(),
+			Signature: tree.Signature(),
+			URL:       url,
+			Links:     tree.Links(),
+		})
+	}
+	return nil
+}
+
+// loadTree recreates the signed tree stored in the given directory.
+func loadTree(dir string) (*dnsdisc.Tree, *treeInfo) {
+	info := loadInfo(dir)
+	if info.URL == "" || info.Signature == "" {
+		utils.Fatalf("Tree in %s is not signed", dir)
+	}
+	_, pubkey, err := dnsdisc.ParseURL(info.URL)
+	if err != nil {
+		utils.Fatalf("Invalid tree URL: %v", err)
+	}
+	tree, err := dnsdisc.MakeTree(info.Seq, recordList(loadNodes(dir)), info.Links)
+	if err != nil {
+		utils.Fatalf("Failed to create tree: %v", err)
+	}
+	if err := tree.SetSignature(pubkey, info.Signature); err != nil {
+		utils.Fatalf("Invalid signature, tree changed since signing?")
+	}
+	return tree, info
+}
+
+// writeZone writes the given TXT records in BIND zone file format. Records
+// longer than 255 characters are split into multiple strings.
+func writeZone(w io.Writer, records map[string]string) error {
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var txt bytes.Buffer
+		for value := records[name]; len(value) > 0; {
+			n := len(value)
+			if n > 255 {
+				n = 255
+			}
+			if txt.Len() > 0 {
+				txt.WriteByte(' ')
+			}
+			fmt.Fprintf(&txt, "%q", value[:n])
+			value = value[n:]
+		}
+		if _, err := fmt.Fprintf(w, "%s.\t86400\tIN\tTXT\t%s\n", name, txt.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadInfo(dir string) *treeInfo {
+	info := new(treeInfo)
+	loadJSON(filepath.Join(dir, infoFile), info)
+	return info
+}
+
+// loadNodes reads the node records stored in the given directory.
+func loadNodes(dir string) map[discover.NodeID]*enr.Record {
+	var (
+		enc   = make(map[string]string)
+		nodes = make(map[discover.NodeID]*enr.Record)
+	)
+	loadJSON(filepath.Join(dir, nodesFile), &enc)
+	for id, text := range enc {
+		blob, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(text, "enr:"))
+		if err != nil {
+			utils.Fatalf("Invalid record of node %s: %v", id, err)
+		}
+		var r enr.Record
+		if err := rlp.DecodeBytes(blob, &r); err != nil {
+			utils.Fatalf("Invalid record of node %s: %v", id, err)
+		}
+		n, err := discover.NodeFromRecord(&r)
+		if err != nil {
+			utils.Fatalf("Invalid record of node %s: %v", id, err)
+		}
+		nodes[n.ID] = &r
+	}
+	return nodes
+}
+
+// writeNodes stores the given node records in the given directory.
+func writeNodes(dir string, nodes map[discover.NodeID]*enr.Record) {
+	enc := make(map[string]string, len(nodes))
+	for id, r := range nodes {
+		blob, err := rlp.EncodeToBytes(r)
+		if err != nil {
+			utils.Fatalf("Failed to encode record of node %x: %v", id[:8], err)
+		}
+		enc[id.String()] = "enr:" + base64.RawURLEncoding.EncodeToString(blob)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		utils.Fatalf("Failed to create tree directory: %v", err)
+	}
+	writeJSON(filepath.Join(dir, nodesFile), enc)
+}
+
+func recordList(nodes map[discover.NodeID]*enr.Record) []*enr.Record {
+	records := make([]*enr.Record, 0, len(nodes))
+	for _, r := range nodes {
+		records = append(records, r)
+	}
+	return records
+}
+
+// loadJSON decodes the given file into v, leaving v untouched if the file
+// doesn't exist.
+func loadJSON(file string, v interface{}) {
+	blob, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		utils.Fatalf("Failed to read %s: %v", file, err)
+	}
+	if err := json.Unmarshal(blob, v); err != nil {
+		utils.Fatalf("Failed to decode %s: %v", file, err)
+	}
+}
+
+func writeJSON(file string, v interface{}) {
+	blob, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode %s: %v", file, err)
+	}
+	if err := ioutil.WriteFile(file, append(blob, '\n'), 0644); err != nil {
+		utils.Fatalf("Failed to write %s: %v", file, err)
+	}
+}
+
+// getPassphrase obtains the passphrase of the signing key, either from the
+// --passwordfile flag or by prompting the user.
+func getPassphrase(ctx *cli.Context) string {
+	if file := ctx.String(passphraseFlag.Name); file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Failed to read passphrase file '%s': %v", file, err)
+		}
+		return strings.TrimRight(string(content), "\r\n")
+	}
+	passphrase, err := console.Stdin.PromptPassword("Passphrase: ")
+	if err != nil {
+		utils.Fatalf("Failed to read passphrase: %v", err)
+	}
+	return passphrase
+}
@@ -0,0 +1,40 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteZone(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	records := map[string]string{
+		"n":   "enrtree-root:v1",
+		"x.n": long,
+	}
+	var out bytes.Buffer
+	if err := writeZone(&out, records); err != nil {
+		t.Fatal(err)
+	}
+	want := "n.\t86400\tIN\tTXT\t\"enrtree-root:v1\"\n" +
+		"x.n.\t86400\tIN\tTXT\t\"" + long[:255] + "\" \"" + long[255:] + "\"\n"
+	if out.String() != want {
+		t.Errorf("zone file mismatch:\ngot:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/keystore"
+	"github.com/Ethereum-Reloaded/ETHR-Go/cmd/utils"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto/shamir"
+	"github.com/pborman/uuid"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Encoded shares consist of the share version, the threshold, the address of
+// the key, the Shamir part of the private key and a 4 byte checksum of all that.
+const (
+	shareVersion     = 1
+	sharePartLength  = 33 // 32 byte private key and the x coordinate
+	shareLength      = 2 + common.AddressLength + sharePartLength
+	shareChecksumLen = 4
+)
+
+type outputSplit struct {
+	Address   string
+	Threshold int
+	Shares    []string
+}
+
+type outputCombine struct {
+	Address string
+}
+
+// keyShare is a decoded share of a private key.
+type keyShare struct {
+	threshold int
+	address   common.Address
+	part      []byte
+}
+
+var commandSplit = cli.Command{
+	Name:      "split",
+	Usage:     "split a keyfile into Shamir secret shares",
+	ArgsUsage: "<keyfile>",
+	Description: `
+Split the private key of a keyfile into a number of shares, any threshold of
+which are enough to reconstruct the key with the combine command. Fewer shares
+reveal nothing about the key.
+
+Each share is printed as a checksummed hex string. Store the shares in separate
+safe places: anyone holding enough of them controls the key, without needing
+its passphrase.`,
+	Flags: []cli.Flag{
+		passphraseFlag,
+		jsonFlag,
+		cli.IntFlag{
+			Name:  "shares",
+			Usage: "number of shares to create",
+			Value: 5,
+		},
+		cli.IntFlag{
+			Name:  "threshold",
+			Usage: "number of shares required to reconstruct the key",
+			Value: 3,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		keyfilepath := ctx.Args().First()
+
+		// Read key from file.
+		keyjson, err := ioutil.ReadFile(keyfilepath)
+		if err != nil {
+			utils.Fatalf("Failed to read the keyfile at '%s': %v", keyfilepath, err)
+		}
+
+		// Decrypt key with passphrase.
+		passphrase := getPassphrase(ctx)
+		key, err := keystore.DecryptKey(keyjson, passphrase)
+		if err != nil {
+			utils.Fatalf("Error decrypting key: %v", err)
+		}
+
+		// Split the private key and encode the parts.
+		threshold := ctx.Int("threshold")
+		parts, err := shamir.Split(crypto.FromECDSA(key.PrivateKey), ctx.Int("shares"), threshold)
+		if err != nil {
+			utils.Fatalf("Failed to split key: %v", err)
+		}
+		out := outputSplit{
+			Address:   key.Address.Hex(),
+			Threshold: threshold,
+		}
+		for _, part := range parts {
+			out.Shares = append(out.Shares, encodeShare(&keyShare{threshold: threshold, address: key.Address, part: part}))
+		}
+		if ctx.Bool(jsonFlag.Name) {
+			mustPrintJSON(out)
+		} else {
+			fmt.Println("Address:", out.Address)
+			fmt.Printf("Shares required to reconstruct the key: %d of %d\n", threshold, len(out.Shares))
+			for i, share := range out.Shares {
+				fmt.Printf("Share %d: %s\n", i+1, share)
+			}
+		}
+		return nil
+	},
+}
+
+var commandCombine = cli.Command{
+	Name:      "combine",
+	Usage:     "reconstruct a keyfile from Shamir secret shares",
+	ArgsUsage: "<keyfile> <share> <share> [<share> ...]",
+	Description: `
+Reconstruct a private key from shares created by the split command and store it
+in a new keyfile, encrypted with a new passphrase.`,
+	Flags: []cli.Flag{
+		newPassphraseFlag,
+		jsonFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		// Check if keyfile path given and make sure it doesn't already exist.
+		keyfilepath := ctx.Args().First()
+		if keyfilepath == "" {
+			utils.Fatalf("No keyfile specified.")
+		}
+		if _, err := os.Stat(keyfilepath); err == nil {
+			utils.Fatalf("Keyfile already exists at %s.", keyfilepath)
+		} else if !os.IsNotExist(err) {
+			utils.Fatalf("Error checking if keyfile exists: %v", err)
+		}
+
+		// Decode the shares and make sure they belong together.
+		var (
+			shares []*keyShare
+			parts  [][]byte
+		)
+		for i, arg := range ctx.Args().Tail() {
+			share, err := decodeShare(arg)
+			if err != nil {
+				utils.Fatalf("Invalid share %d: %v", i+1, err)
+			}
+			if len(shares) > 0 && (share.address != shares[0].address || share.threshold != shares[0].threshold) {
+				utils.Fatalf("Share %d belongs to a different key.", i+1)
+			}
+			shares = append(shares, share)
+			parts = append(parts, share.part)
+		}
+		if len(shares) == 0 {
+			utils.Fatalf("No shares specified.")
+		}
+		if len(shares) < shares[0].threshold {
+			utils.Fatalf("Not enough shares: have %d, need %d.", len(shares), shares[0].threshold)
+		}
+
+		// Reconstruct the private key and check it against the address.
+		secret, err := shamir.Combine(parts)
+		if err != nil {
+			utils.Fatalf("Failed to combine shares: %v", err)
+		}
+		privateKey, err := crypto.ToECDSA(secret)
+		if err != nil {
+			utils.Fatalf("Invalid reconstructed key: %v", err)
+		}
+		address := crypto.PubkeyToAddress(privateKey.PublicKey)
+		if address != shares[0].address {
+			utils.Fatalf("Reconstructed key doesn't match address %s.", shares[0].address.Hex())
+		}
+		key := &keystore.Key{
+			Id:         uuid.NewRandom(),
+			Address:    address,
+			PrivateKey: privateKey,
+		}
+
+		// Encrypt key with a new passphrase.
+		var passphrase string
+		if passFile := ctx.String(newPassphraseFlag.Name); passFile != "" {
+			content, err := ioutil.ReadFile(passFile)
+			if err != nil {
+				utils.Fatalf("Failed to read new passphrase file '%s': %v", passFile, err)
+			}
+			passphrase = strings.TrimRight(string(content), "\r\n")
+		} else {
+			passphrase = promptPassphrase(true)
+		}
+		keyjson, err := keystore.EncryptKey(key, passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+		if err != nil {
+			utils.Fatalf("Error encrypting key: %v", err)
+		}
+
+		// Store the file to disk.
+		if err := os.MkdirAll(filepath.Dir(keyfilepath), 0700); err != nil {
+			utils.Fatalf("Could not create directory %s", filepath.Dir(keyfilepath))
+		}
+		if err := ioutil.WriteFile(keyfilepath, keyjson, 0600); err != nil {
+			utils.Fatalf("Failed to write keyfile to %s: %v", keyfilepath, err)
+		}
+
+		out := outputCombine{
+			Address: address.Hex(),
+		}
+		if ctx.Bool(jsonFlag.Name) {
+			mustPrintJSON(out)
+		} else {
+			fmt.Println("Address:", out.Address)
+		}
+		return nil
+	},
+}
+
+// encodeShare encodes a share with its checksum as hex string.
+func encodeShare(share *keyShare) string {
+	blob := make([]byte, 0, shareLength+shareChecksumLen)
+	blob = append(blob, shareVersion, byte(share.threshold))
+	blob = append(blob, share.address.Bytes()...)
+	blob = append(blob, share.part...)
+	blob = append(blob, crypto.Keccak256(blob)[:shareChecksumLen]...)
+	return hex.EncodeToString(blob)
+}
+
+// decodeShare decodes a hex encoded share, verifying its checksum.
+func decodeShare(s string) (*keyShare, error) {
+	blob, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) != shareLength+shareChecksumLen {
+		return nil, fmt.Errorf("invalid length %d", len(blob))
+	}
+	payload, checksum := blob[:shareLength], blob[shareLength:]
+	if !bytes.Equal(crypto.Keccak256(payload)[:shareChecksumLen], checksum) {
+		return nil, errors.New("checksum mismatch")
+	}
+	if payload[0] != shareVersion {
+		return nil, fmt.Errorf("unsupported version %d", payload[0])
+	}
+	return &keyShare{
+		threshold: int(payload[1]),
+		address:   common.BytesToAddress(payload[2 : 2+common.AddressLength]),
+		part:      payload[2+common.AddressLength:],
+	}, nil
+}
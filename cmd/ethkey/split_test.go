@@ -0,0 +1,96 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "ethkey-test")
+	if err != nil {
+		t.Fatal("Can't create temporary directory:", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	keyfile := filepath.Join(tmpdir, "the-keyfile")
+
+	// Create the key.
+	generate := runEthkey(t, "generate", keyfile)
+	generate.Expect(`
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Repeat passphrase: {{.InputLine "foobar"}}
+`)
+	_, matches := generate.ExpectRegexp(`Address: (0x[0-9a-fA-F]{40})\n`)
+	address := matches[1]
+	generate.ExpectExit()
+
+	// Split it into 3-of-5 shares.
+	split := runEthkey(t, "split", "--shares", "5", "--threshold", "3", keyfile)
+	split.Expect(`
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Address: ` + address + `
+Shares required to reconstruct the key: 3 of 5
+`)
+	_, matches = split.ExpectRegexp(`Share 1: ([0-9a-f]+)
+Share 2: ([0-9a-f]+)
+Share 3: ([0-9a-f]+)
+Share 4: ([0-9a-f]+)
+Share 5: ([0-9a-f]+)
+`)
+	shares := matches[1:]
+	split.ExpectExit()
+
+	// Too few shares are rejected.
+	combine := runEthkey(t, "combine", filepath.Join(tmpdir, "too-few"), shares[0], shares[1])
+	combine.ExpectRegexp(`Fatal: Not enough shares: have 2, need 3.\n`)
+	combine.ExpectExit()
+
+	// Corrupted shares are rejected.
+	corrupted := shares[2][:10] + "00" + shares[2][12:]
+	if corrupted == shares[2] {
+		corrupted = shares[2][:10] + "11" + shares[2][12:]
+	}
+	combine = runEthkey(t, "combine", filepath.Join(tmpdir, "corrupted"), shares[0], shares[1], corrupted)
+	combine.ExpectRegexp(`Fatal: Invalid share 3: checksum mismatch\n`)
+	combine.ExpectExit()
+
+	// Any three shares reconstruct the key.
+	newfile := filepath.Join(tmpdir, "new-keyfile")
+	combine = runEthkey(t, "combine", newfile, shares[4], shares[0], shares[2])
+	combine.Expect(`
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "barfoo"}}
+Repeat passphrase: {{.InputLine "barfoo"}}
+Address: ` + address + `
+`)
+	combine.ExpectExit()
+
+	inspect := runEthkey(t, "inspect", newfile)
+	inspect.Expect(`
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "barfoo"}}
+Address:        ` + address + `
+`)
+	inspect.ExpectRegexp(`Public key:     [0-9a-f]+\n`)
+	inspect.ExpectExit()
+}
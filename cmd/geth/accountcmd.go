@@ -19,6 +19,7 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/accounts"
 	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/keystore"
@@ -26,6 +27,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/console"
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/node"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -186,6 +188,103 @@ Note:
 As you can directly copy your encrypted accounts to another ethereum instance,
 this import mechanism is not needed when you transfer an account between
 nodes.
+`,
+			},
+			{
+				Name:      "import-mnemonic",
+				Usage:     "Import a BIP-39 mnemonic into a new HD wallet",
+				Action:    utils.MigrateFlags(accountImportMnemonic),
+				ArgsUsage: "[<mnemonicFile>]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+				},
+				Description: `
+    geth account import-mnemonic [<mnemonicFile>]
+
+Imports a BIP-39 mnemonic as a hierarchical deterministic wallet, from which any
+number of accounts can be derived. The mnemonic is read from <mnemonicFile> if
+given, otherwise you are prompted for it. Prints the wallet URL and the address
+of its first account (m/44'/60'/0'/0/0).
+
+The mnemonic is saved in encrypted format, you are prompted for a passphrase.
+Open the wallet with the passphrase to derive further accounts, for example with
+personal.openWallet and personal.deriveAccount in the console.
+
+For non-interactive use the passphrase can be specified with the --password flag:
+
+    geth account import-mnemonic [options] <mnemonicFile>
+`,
+			},
+			{
+				Name:      "rotate",
+				Usage:     "Re-encrypt accounts with a new password and key derivation parameters",
+				Action:    utils.MigrateFlags(accountRotate),
+				ArgsUsage: "[<address> ...]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+				},
+				Description: `
+    geth account rotate [<address> ...]
+
+Re-encrypts the given accounts, or all accounts if none are given, with a new
+password and the current key derivation parameters (see --lightkdf). All the
+accounts must share the same password.
+
+Either all accounts are updated or, if any of them fails, none of them.
+
+For non-interactive use the passwords can be specified with the --password flag,
+the current password on the first line and the new one on the second.
+`,
+			},
+			{
+				Name:      "backup",
+				Usage:     "Export accounts into an encrypted backup bundle",
+				Action:    utils.MigrateFlags(accountBackup),
+				ArgsUsage: "<bundleFile> [<address> ...]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+				},
+				Description: `
+    geth account backup <bundleFile> [<address> ...]
+
+Exports the given accounts, or all accounts if none are given, into a single
+bundle file encrypted with a backup password. All the accounts must share the
+same password. The bundle is integrity protected, it can be restored with
+'geth account restore'.
+
+For non-interactive use the passwords can be specified with the --password flag,
+the account password on the first line and the backup password on the second.
+`,
+			},
+			{
+				Name:      "restore",
+				Usage:     "Restore accounts from an encrypted backup bundle",
+				Action:    utils.MigrateFlags(accountRestore),
+				ArgsUsage: "<bundleFile>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+				},
+				Description: `
+    geth account restore <bundleFile>
+
+Restores the accounts of a backup bundle that are not in the keystore yet,
+encrypting them with a new password. Either all missing accounts are restored
+or none of them.
+
+For non-interactive use the passwords can be specified with the --password flag,
+the backup password on the first line and the new password on the second.
 `,
 			},
 		},
@@ -204,6 +303,16 @@ func accountList(ctx *cli.Context) error {
 	return nil
 }
 
+// fetchKeystore retrieves the local key store of the node, failing if the node
+// uses an external signer instead.
+func fetchKeystore(stack *node.Node) *keystore.KeyStore {
+	keystores := stack.AccountManager().Backends(keystore.KeyStoreType)
+	if len(keystores) == 0 {
+		utils.Fatalf("Local keystore not available when using an external signer")
+	}
+	return keystores[0].(*keystore.KeyStore)
+}
+
 // tries unlocking the specified account a few times.
 func unlockAccount(ctx *cli.Context, ks *keystore.KeyStore, address string, i int, passwords []string) (accounts.Account, string) {
 	account, err := utils.MakeAddress(ks, address)
@@ -323,7 +432,7 @@ func accountUpdate(ctx *cli.Context) error {
 		utils.Fatalf("No accounts specified to update")
 	}
 	stack, _ := makeConfigNode(ctx)
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	ks := fetchKeystore(stack)
 
 	for _, addr := range ctx.Args() {
 		account, oldPassword := unlockAccount(ctx, ks, addr, 0, nil)
@@ -348,7 +457,7 @@ func importWallet(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 	passphrase := getPassPhrase("", false, 0, utils.MakePasswordList(ctx))
 
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	ks := fetchKeystore(stack)
 	acct, err := ks.ImportPreSaleKey(keyJSON, passphrase)
 	if err != nil {
 		utils.Fatalf("%v", err)
@@ -369,7 +478,7 @@ func accountImport(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 	passphrase := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	ks := fetchKeystore(stack)
 	acct, err := ks.ImportECDSA(key, passphrase)
 	if err != nil {
 		utils.Fatalf("Could not create the account: %v", err)
@@ -377,3 +486,141 @@ func accountImport(ctx *cli.Context) error {
 	fmt.Printf("Address: {%x}\n", acct.Address)
 	return nil
 }
+
+// accountImportMnemonic imports a BIP-39 mnemonic into a new HD wallet.
+func accountImportMnemonic(ctx *cli.Context) error {
+	var mnemonic string
+	if file := ctx.Args().First(); file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Could not read mnemonic file: %v", err)
+		}
+		mnemonic = string(content)
+	} else {
+		var err error
+		if mnemonic, err = console.Stdin.PromptPassword("Mnemonic: "); err != nil {
+			utils.Fatalf("Failed to read mnemonic: %v", err)
+		}
+	}
+	stack, _ := makeConfigNode(ctx)
+	passphrase := getPassPhrase("Your new wallet is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	ks := fetchKeystore(stack)
+	wallet, err := ks.ImportMnemonic(mnemonic, passphrase)
+	if err != nil {
+		utils.Fatalf("Could not import the mnemonic: %v", err)
+	}
+	fmt.Printf("Wallet: %s\n", wallet.URL())
+	fmt.Printf("Address: {%x}\n", wallet.Accounts()[0].Address)
+	return nil
+}
+
+// accountsFromArgs returns the keystore accounts of the given addresses, or all
+// accounts if none are given.
+func accountsFromArgs(ks *keystore.KeyStore, args []string) []accounts.Account {
+	if len(args) == 0 {
+		accs := ks.Accounts()
+		if len(accs) == 0 {
+			utils.Fatalf("No accounts in the keystore")
+		}
+		return accs
+	}
+	accs := make([]accounts.Account, 0, len(args))
+	for _, addr := range args {
+		account, err := utils.MakeAddress(ks, addr)
+		if err != nil {
+			utils.Fatalf("Could not find account %s: %v", addr, err)
+		}
+		accs = append(accs, account)
+	}
+	return accs
+}
+
+// printProgress reports the progress of a batch keystore operation.
+func printProgress(action string) keystore.ProgressFunc {
+	return func(done, total int) {
+		fmt.Printf("%s key %d/%d\n", action, done, total)
+	}
+}
+
+// accountRotate re-encrypts accounts with a new password and the configured
+// key derivation parameters.
+func accountRotate(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	ks := fetchKeystore(stack)
+
+	scryptN, scryptP, _, err := cfg.Node.AccountConfig()
+	if err != nil {
+		utils.Fatalf("Failed to read configuration: %v", err)
+	}
+	accs := accountsFromArgs(ks, ctx.Args())
+	passwords := utils.MakePasswordList(ctx)
+
+	oldPassword := getPassPhrase("Please give the current password of the accounts.", false, 0, passwords)
+	newPassword := getPassPhrase("Please give a new password. Do not forget this password.", true, 1, passwords)
+
+	if err := ks.ReEncrypt(accs, oldPassword, newPassword, scryptN, scryptP, printProgress("Re-encrypted")); err != nil {
+		if rerr, ok := err.(*keystore.RollbackError); ok {
+			utils.Fatalf("Could not rotate the accounts: %v\nCheck the key files %s, they may use either password.", rerr.Err, strings.Join(rerr.Failed, ", "))
+		}
+		utils.Fatalf("Could not rotate the accounts, none were modified: %v", err)
+	}
+	fmt.Printf("Rotated %d accounts\n", len(accs))
+	return nil
+}
+
+// accountBackup exports accounts into an encrypted backup bundle.
+func accountBackup(ctx *cli.Context) error {
+	bundlefile := ctx.Args().First()
+	if len(bundlefile) == 0 {
+		utils.Fatalf("bundle file must be given as argument")
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := fetchKeystore(stack)
+
+	accs := accountsFromArgs(ks, ctx.Args().Tail())
+	passwords := utils.MakePasswordList(ctx)
+
+	password := getPassPhrase("Please give the password of the accounts.", false, 0, passwords)
+	bundlePassword := getPassPhrase("Please give a password for the backup. Do not forget this password.", true, 1, passwords)
+
+	bundle, err := ks.ExportBundle(accs, password, bundlePassword, printProgress("Exported"))
+	if err != nil {
+		utils.Fatalf("Could not export the accounts: %v", err)
+	}
+	if err := ioutil.WriteFile(bundlefile, bundle, 0600); err != nil {
+		utils.Fatalf("Could not write the bundle: %v", err)
+	}
+	fmt.Printf("Exported %d accounts to %s\n", len(accs), bundlefile)
+	return nil
+}
+
+// accountRestore restores the accounts of a backup bundle into the keystore.
+func accountRestore(ctx *cli.Context) error {
+	bundlefile := ctx.Args().First()
+	if len(bundlefile) == 0 {
+		utils.Fatalf("bundle file must be given as argument")
+	}
+	bundle, err := ioutil.ReadFile(bundlefile)
+	if err != nil {
+		utils.Fatalf("Could not read the bundle: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := fetchKeystore(stack)
+
+	passwords := utils.MakePasswordList(ctx)
+	bundlePassword := getPassPhrase("Please give the password of the backup.", false, 0, passwords)
+	newPassword := getPassPhrase("Your restored accounts are locked with a password. Please give a password. Do not forget this password.", true, 1, passwords)
+
+	accs, err := ks.ImportBundle(bundle, bundlePassword, newPassword, printProgress("Processed"))
+	if err != nil {
+		if rerr, ok := err.(*keystore.RollbackError); ok {
+			utils.Fatalf("Could not restore the accounts: %v\nThe key files %s could not be removed.", rerr.Err, strings.Join(rerr.Failed, ", "))
+		}
+		utils.Fatalf("Could not restore the accounts, none were restored: %v", err)
+	}
+	for _, a := range accs {
+		fmt.Printf("Address: {%x}\n", a.Address)
+	}
+	return nil
+}
@@ -106,6 +106,88 @@ Repeat passphrase: {{.InputLine "foobar2"}}
 `)
 }
 
+func TestAccountImportMnemonic(t *testing.T) {
+	geth := runGeth(t, "account", "import-mnemonic", "--lightkdf")
+	defer geth.ExpectExit()
+	geth.Expect(`
+!! Unsupported terminal, password will be echoed.
+Mnemonic: {{.InputLine "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"}}
+Your new wallet is locked with a password. Please give a password. Do not forget this password.
+Passphrase: {{.InputLine "foobar"}}
+Repeat passphrase: {{.InputLine "foobar"}}
+`)
+	geth.ExpectRegexp(`Wallet: keystore://.*hd.*9858effd232b4033e47d90003d41ec34ecaeda94
+Address: \{9858effd232b4033e47d90003d41ec34ecaeda94\}
+`)
+}
+
+func TestAccountRotate(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	geth := runGeth(t, "account", "rotate",
+		"--datadir", datadir, "--lightkdf",
+		"f466859ead1932d743d622cb74fc058882e8648a", "289d485d9771714cce91d3393d764e1311907acc")
+	geth.Expect(`
+Please give the current password of the accounts.
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Please give a new password. Do not forget this password.
+Passphrase: {{.InputLine "foobar2"}}
+Repeat passphrase: {{.InputLine "foobar2"}}
+Re-encrypted key 1/2
+Re-encrypted key 2/2
+Rotated 2 accounts
+`)
+	geth.ExpectExit()
+
+	// The rotated account can be unlocked with the new password only
+	geth = runGeth(t, "account", "update",
+		"--datadir", datadir, "--lightkdf",
+		"f466859ead1932d743d622cb74fc058882e8648a")
+	defer geth.ExpectExit()
+	geth.Expect(`
+Unlocking account f466859ead1932d743d622cb74fc058882e8648a | Attempt 1/3
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Unlocking account f466859ead1932d743d622cb74fc058882e8648a | Attempt 2/3
+Passphrase: {{.InputLine "foobar2"}}
+Please give a new password. Do not forget this password.
+Passphrase: {{.InputLine "foobar2"}}
+Repeat passphrase: {{.InputLine "foobar2"}}
+`)
+}
+
+func TestAccountBackupRestore(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	bundle := filepath.Join(datadir, "backup.json")
+	geth := runGeth(t, "account", "backup",
+		"--datadir", datadir, "--lightkdf",
+		bundle, "f466859ead1932d743d622cb74fc058882e8648a")
+	geth.Expect(`
+Please give the password of the accounts.
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Please give a password for the backup. Do not forget this password.
+Passphrase: {{.InputLine "backup"}}
+Repeat passphrase: {{.InputLine "backup"}}
+Exported key 1/1
+`)
+	geth.ExpectRegexp(`Exported 1 accounts to .*backup.json\n`)
+	geth.ExpectExit()
+
+	geth = runGeth(t, "account", "restore", "--lightkdf", bundle)
+	defer geth.ExpectExit()
+	geth.Expect(`
+Please give the password of the backup.
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "backup"}}
+Your restored accounts are locked with a password. Please give a password. Do not forget this password.
+Passphrase: {{.InputLine "restored"}}
+Repeat passphrase: {{.InputLine "restored"}}
+Processed key 1/1
+Address: {f466859ead1932d743d622cb74fc058882e8648a}
+`)
+}
+
 func TestWalletImport(t *testing.T) {
 	geth := runGeth(t, "wallet", "import", "--lightkdf", "testdata/guswallet.json")
 	defer geth.ExpectExit()
@@ -171,6 +171,11 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterShhService(stack, &cfg.Shh)
 	}
 
+	// Add the GraphQL service if requested.
+	if ctx.GlobalBool(utils.GraphQLEnabledFlag.Name) {
+		endpoint, cors, vhosts := utils.MakeGraphQLConfig(ctx)
+		utils.RegisterGraphQLService(stack, endpoint, cors, vhosts)
+	}
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
@@ -213,6 +213,14 @@ func cmdLineOverride(currentConfig *bzzapi.Config, ctx *cli.Context) *bzzapi.Con
 		currentConfig.EnsRoot = common.HexToAddress(ensaddr)
 	}
 
+	if ctx.GlobalIsSet(SwarmStoreSizeQuotaFlag.Name) {
+		currentConfig.DbSizeQuota = ctx.GlobalUint64(SwarmStoreSizeQuotaFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(SwarmStorePinQuotaFlag.Name) {
+		currentConfig.PinQuota = ctx.GlobalUint64(SwarmStorePinQuotaFlag.Name)
+	}
+
 	if cors := ctx.GlobalString(CorsStringFlag.Name); cors != "" {
 		currentConfig.Cors = cors
 	}
@@ -44,6 +44,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/swarm"
 	bzzapi "github.com/Ethereum-Reloaded/ETHR-Go/swarm/api"
 	swarmmetrics "github.com/Ethereum-Reloaded/ETHR-Go/swarm/metrics"
+	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/storage"
 
 	"gopkg.in/urfave/cli.v1"
 )
@@ -136,6 +137,24 @@ var (
 		Name:  "mime",
 		Usage: "force mime type",
 	}
+	SwarmEncryptedFlag = cli.BoolFlag{
+		Name:  "encrypt",
+		Usage: "use encrypted upload",
+	}
+	SwarmResourceFrequencyFlag = cli.Uint64Flag{
+		Name:  "frequency",
+		Usage: "update frequency of a new mutable resource in seconds",
+		Value: 3600,
+	}
+	SwarmStoreSizeQuotaFlag = cli.Uint64Flag{
+		Name:  "store.sizequota",
+		Usage: "maximum size of the local chunk store in bytes, unpinned chunks are garbage collected beyond it (0 = unlimited)",
+	}
+	SwarmStorePinQuotaFlag = cli.Uint64Flag{
+		Name:  "store.pinquota",
+		Usage: "maximum size of the pinned chunks in bytes (0 = unlimited)",
+		Value: storage.DefaultPinQuota,
+	}
 	CorsStringFlag = cli.StringFlag{
 		Name:   "corsdomain",
 		Usage:  "Domain on which to send Access-Control-Allow-Origin header (multiple domains can be supplied separated by a ',')",
@@ -195,6 +214,9 @@ The output of this command is supposed to be machine-readable.
 			ArgsUsage: " <file>",
 			Description: `
 "upload a file or directory to swarm using the HTTP API and prints the root hash",
+
+With --encrypt the content is encrypted, and the printed root hash also carries
+the decryption key, so anyone knowing it can read the content.
 `,
 		},
 		{
@@ -248,6 +270,89 @@ Update the hash for an already existing path in the manifest
 					ArgsUsage: "<MANIFEST> <path>",
 					Description: `
 Removes a path from the manifest
+`,
+				},
+			},
+		},
+		{
+			Name:      "resource",
+			Usage:     "manage mutable resources",
+			ArgsUsage: "resource COMMAND",
+			Description: `
+Mutable resources are updatable references owned by the --bzzaccount key, which
+signs their updates. Time is divided into periods of the update frequency of the
+resource, and the owner can publish any number of versions in each period.
+
+The data of the latest update is served at bzz-resource://<resource>, the data
+of other updates at bzz-resource://<resource>/<period>[/<version>].
+`,
+			Subcommands: []cli.Command{
+				{
+					Action:    resourceCreate,
+					Name:      "create",
+					Usage:     "create a mutable resource with the given name",
+					ArgsUsage: "<name>",
+					Description: `
+Creates a mutable resource updated every --frequency seconds and prints its key.
+`,
+				},
+				{
+					Action:    resourceUpdate,
+					Name:      "update",
+					Usage:     "publish the content of a file as update of a mutable resource (use - to read from stdin)",
+					ArgsUsage: "<resource> <file>",
+					Description: `
+Publishes the content of the file as the next version of the current period of
+the resource and prints the key of the update.
+`,
+				},
+				{
+					Action:    resourceInfo,
+					Name:      "info",
+					Usage:     "print information about a mutable resource",
+					ArgsUsage: "<resource>",
+					Description: `
+Prints the owner, topic, frequency and latest update of the resource.
+`,
+				},
+			},
+		},
+		{
+			Name:      "pin",
+			Usage:     "manage pinned content",
+			ArgsUsage: "pin COMMAND",
+			Description: `
+Pinned content is kept in the local chunk store of the node: its chunks are
+retrieved from the network if necessary and never garbage collected. Pinning a
+manifest pins the content of all its entries. Chunks shared by pinned content
+stay pinned until all of it is unpinned.
+`,
+			Subcommands: []cli.Command{
+				{
+					Action:    pinAdd,
+					Name:      "add",
+					Usage:     "pin the content with the given hash",
+					ArgsUsage: "<hash>",
+					Description: `
+Pins the content with the given hash.
+`,
+				},
+				{
+					Action:    pinRemove,
+					Name:      "rm",
+					Usage:     "unpin the content with the given hash",
+					ArgsUsage: "<hash>",
+					Description: `
+Unpins the content with the given hash, its chunks are garbage collected unless
+they are pinned by other content.
+`,
+				},
+				{
+					Action: pinList,
+					Name:   "ls",
+					Usage:  "list the pinned content",
+					Description: `
+Prints the hash, number of chunks and size of all pinned content.
 `,
 				},
 			},
@@ -348,6 +453,8 @@ DEPRECATED: use 'swarm db clean'.
 		SwarmAccountFlag,
 		SwarmNetworkIdFlag,
 		ChequebookAddrFlag,
+		SwarmStoreSizeQuotaFlag,
+		SwarmStorePinQuotaFlag,
 		// upload flags
 		SwarmApiFlag,
 		SwarmRecursiveUploadFlag,
@@ -355,6 +462,9 @@ DEPRECATED: use 'swarm db clean'.
 		SwarmUploadDefaultPath,
 		SwarmUpFromStdinFlag,
 		SwarmUploadMimeType,
+		SwarmEncryptedFlag,
+		// resource flags
+		SwarmResourceFrequencyFlag,
 		//deprecated flags
 		DeprecatedEthAPIFlag,
 		DeprecatedEnsAddrFlag,
@@ -27,11 +27,18 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/cmd/utils"
 	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/api"
 	swarm "github.com/Ethereum-Reloaded/ETHR-Go/swarm/api/client"
+	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/storage"
 	"gopkg.in/urfave/cli.v1"
 )
 
 const bzzManifestJSON = "application/bzz-manifest+json"
 
+// isEncrypted reports whether the manifest hash refers to an encrypted
+// manifest, which stays encrypted when modified.
+func isEncrypted(mhash string) bool {
+	return len(mhash) == 2*storage.EncryptedKeyLength
+}
+
 func add(ctx *cli.Context) {
 	args := ctx.Args()
 	if len(args) < 3 {
@@ -180,7 +187,7 @@ func addEntryToManifest(ctx *cli.Context, mhash, path, hash, ctype string) strin
 		mroot.Entries = append(mroot.Entries, newEntry)
 	}
 
-	newManifestHash, err := client.UploadManifest(mroot)
+	newManifestHash, err := client.UploadManifest(mroot, isEncrypted(mhash))
 	if err != nil {
 		utils.Fatalf("Manifest upload failed: %v", err)
 	}
@@ -257,7 +264,7 @@ func updateEntryInManifest(ctx *cli.Context, mhash, path, hash, ctype string) st
 		mroot = newMRoot
 	}
 
-	newManifestHash, err := client.UploadManifest(mroot)
+	newManifestHash, err := client.UploadManifest(mroot, isEncrypted(mhash))
 	if err != nil {
 		utils.Fatalf("Manifest upload failed: %v", err)
 	}
@@ -323,7 +330,7 @@ func removeEntryFromManifest(ctx *cli.Context, mhash, path string) string {
 		mroot = newMRoot
 	}
 
-	newManifestHash, err := client.UploadManifest(mroot)
+	newManifestHash, err := client.UploadManifest(mroot, isEncrypted(mhash))
 	if err != nil {
 		utils.Fatalf("Manifest upload failed: %v", err)
 	}
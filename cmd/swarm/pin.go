@@ -0,0 +1,70 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Command pin add/rm/ls
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/cmd/utils"
+	swarm "github.com/Ethereum-Reloaded/ETHR-Go/swarm/api/client"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func pinAdd(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm pin add <hash>")
+	}
+	if err := pinClient(ctx).Pin(args[0]); err != nil {
+		utils.Fatalf("Failed to pin %s: %v", args[0], err)
+	}
+	fmt.Println(args[0])
+}
+
+func pinRemove(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm pin rm <hash>")
+	}
+	if err := pinClient(ctx).Unpin(args[0]); err != nil {
+		utils.Fatalf("Failed to unpin %s: %v", args[0], err)
+	}
+}
+
+func pinList(ctx *cli.Context) {
+	if len(ctx.Args()) != 0 {
+		utils.Fatalf("Usage: swarm pin ls")
+	}
+	pins, err := pinClient(ctx).PinList()
+	if err != nil {
+		utils.Fatalf("Failed to list pinned content: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "HASH\tCHUNKS\tSIZE")
+	for _, pin := range pins {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", pin.Key, pin.Chunks, pin.Size)
+	}
+}
+
+func pinClient(ctx *cli.Context) *swarm.Client {
+	return swarm.NewClient(strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/"))
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestCLISwarmPin tests pinning, listing and unpinning uploaded content with
+// 'swarm pin'
+func TestCLISwarmPin(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+	node := cluster.Nodes[0]
+
+	tmp, err := ioutil.TempFile("", "swarm-pin-test")
+	assertNil(t, err)
+	defer tmp.Close()
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString("data to pin")
+	assertNil(t, err)
+
+	up := runSwarm(t, "--bzzapi", node.URL, "up", tmp.Name())
+	_, matches := up.ExpectRegexp(`[a-f\d]{64}`)
+	up.ExpectExit()
+	hash := matches[0]
+
+	add := runSwarm(t, "--bzzapi", node.URL, "pin", "add", hash)
+	add.ExpectRegexp(hash)
+	add.ExpectExit()
+
+	ls := runSwarm(t, "--bzzapi", node.URL, "pin", "ls")
+	ls.ExpectRegexp(hash + `\s+2\s+\d+`)
+	ls.ExpectExit()
+
+	rm := runSwarm(t, "--bzzapi", node.URL, "pin", "rm", hash)
+	rm.ExpectExit()
+
+	ls = runSwarm(t, "--bzzapi", node.URL, "pin", "ls")
+	ls.ExpectRegexp(`HASH\s+CHUNKS\s+SIZE\s*\n$`)
+	ls.ExpectExit()
+}
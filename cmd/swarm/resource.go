@@ -0,0 +1,122 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Command resource create/update/info
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/cmd/utils"
+	"github.com/Ethereum-Reloaded/ETHR-Go/node"
+	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/api"
+	swarm "github.com/Ethereum-Reloaded/ETHR-Go/swarm/api/client"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func resourceCreate(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm resource create <name>")
+	}
+	frequency := ctx.GlobalUint64(SwarmResourceFrequencyFlag.Name)
+	if frequency == 0 {
+		utils.Fatalf("Resource update frequency must be greater than zero")
+	}
+	key := resourceSigningKey(ctx)
+
+	create := api.NewResourceCreate(api.ResourceTopic(args[0]), uint64(time.Now().Unix()), frequency)
+	if err := create.Sign(key); err != nil {
+		utils.Fatalf("Failed to sign resource: %v", err)
+	}
+	resource, err := resourceClient(ctx).CreateResource(create)
+	if err != nil {
+		utils.Fatalf("Failed to create resource: %v", err)
+	}
+	fmt.Println(resource)
+}
+
+func resourceUpdate(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 2 {
+		utils.Fatalf("Usage: swarm resource update <resource> <file>")
+	}
+	resource, file := args[0], args[1]
+
+	var (
+		data []byte
+		err  error
+	)
+	if file == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		utils.Fatalf("Failed to read update data: %v", err)
+	}
+	key := resourceSigningKey(ctx)
+
+	client := resourceClient(ctx)
+	info, err := client.ResourceInfo(resource)
+	if err != nil {
+		utils.Fatalf("Failed to get resource %s: %v", resource, err)
+	}
+	update := info.NextUpdate(data)
+	if err := update.Sign(key); err != nil {
+		utils.Fatalf("Failed to sign update: %v", err)
+	}
+	hash, err := client.UpdateResource(resource, update)
+	if err != nil {
+		utils.Fatalf("Failed to update resource %s: %v", resource, err)
+	}
+	fmt.Println(hash)
+}
+
+func resourceInfo(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm resource info <resource>")
+	}
+	info, err := resourceClient(ctx).ResourceInfo(args[0])
+	if err != nil {
+		utils.Fatalf("Failed to get resource %s: %v", args[0], err)
+	}
+	infoJSON, _ := json.MarshalIndent(info, "", "  ")
+	fmt.Println(string(infoJSON))
+}
+
+func resourceClient(ctx *cli.Context) *swarm.Client {
+	return swarm.NewClient(strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/"))
+}
+
+// resourceSigningKey loads the key of the resource owner given by --bzzaccount,
+// either a hex key file or an account of the keystore.
+func resourceSigningKey(ctx *cli.Context) *ecdsa.PrivateKey {
+	cfg := defaultNodeConfig
+	utils.SetNodeConfig(ctx, &cfg)
+	stack, err := node.New(&cfg)
+	if err != nil {
+		utils.Fatalf("can't create node: %v", err)
+	}
+	return getAccount(ctx.GlobalString(SwarmAccountFlag.Name), ctx, stack)
+}
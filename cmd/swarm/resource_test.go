@@ -0,0 +1,72 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+)
+
+// TestCLISwarmResource tests creating and updating a mutable resource with
+// 'swarm resource' and getting its latest update via the HTTP API
+func TestCLISwarmResource(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+	node := cluster.Nodes[0]
+
+	dir, err := ioutil.TempDir("", "swarm-resource-test")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+
+	owner, _ := crypto.GenerateKey()
+	keyfile := filepath.Join(dir, "key")
+	assertNil(t, crypto.SaveECDSA(keyfile, owner))
+	flags := []string{"--datadir", dir, "--bzzaccount", keyfile, "--bzzapi", node.URL, "--frequency", "3600", "resource"}
+
+	t.Log("creating resource with 'swarm resource create'")
+	create := runSwarm(t, append(flags, "create", "feed")...)
+	_, matches := create.ExpectRegexp(`[a-f\d]{64}`)
+	create.ExpectExit()
+	resource := matches[0]
+
+	for _, data := range []string{"foo", "bar"} {
+		file := filepath.Join(dir, data)
+		assertNil(t, ioutil.WriteFile(file, []byte(data), 0644))
+
+		t.Logf("updating resource with 'swarm resource update' to %q", data)
+		update := runSwarm(t, append(flags, "update", resource, file)...)
+		update.ExpectRegexp(`[a-f\d]{64}`)
+		update.ExpectExit()
+	}
+
+	res, err := http.Get(node.URL + "/bzz-resource:/" + resource)
+	assertNil(t, err)
+	assertHTTPResponse(t, res, http.StatusOK, "bar")
+
+	info := runSwarm(t, append(flags, "info", resource)...)
+	_, matches = info.ExpectRegexp(`"version": \d+`)
+	info.ExpectExit()
+	if !strings.HasSuffix(matches[0], " 2") {
+		t.Fatalf("expected resource version 2, got %s", matches[0])
+	}
+}
@@ -27,6 +27,16 @@ import (
 // TestCLISwarmUp tests that running 'swarm up' makes the resulting file
 // available from all nodes via the HTTP API
 func TestCLISwarmUp(t *testing.T) {
+	testCLISwarmUp(false, t)
+}
+
+// TestCLISwarmUpEncrypted tests that content uploaded with 'swarm up --encrypt'
+// is available from all nodes with the returned key
+func TestCLISwarmUpEncrypted(t *testing.T) {
+	testCLISwarmUp(true, t)
+}
+
+func testCLISwarmUp(toEncrypt bool, t *testing.T) {
 	// start 3 node cluster
 	t.Log("starting 3 node cluster")
 	cluster := newTestCluster(t, 3)
@@ -42,8 +52,14 @@ func TestCLISwarmUp(t *testing.T) {
 
 	// upload the file with 'swarm up' and expect a hash
 	t.Log("uploading file with 'swarm up'")
-	up := runSwarm(t, "--bzzapi", cluster.Nodes[0].URL, "up", tmp.Name())
-	_, matches := up.ExpectRegexp(`[a-f\d]{64}`)
+	flags := []string{"--bzzapi", cluster.Nodes[0].URL, "up", tmp.Name()}
+	hashRegexp := `[a-f\d]{64}`
+	if toEncrypt {
+		flags = []string{"--bzzapi", cluster.Nodes[0].URL, "--encrypt", "up", tmp.Name()}
+		hashRegexp = `[a-f\d]{128}`
+	}
+	up := runSwarm(t, flags...)
+	_, matches := up.ExpectRegexp(hashRegexp)
 	up.ExpectExit()
 	hash := matches[0]
 	t.Logf("file uploaded with hash %s", hash)
@@ -19,9 +19,11 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -42,9 +44,11 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/dashboard"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth/downloader"
+	"github.com/Ethereum-Reloaded/ETHR-Go/eth/filters"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth/gasprice"
 	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
 	"github.com/Ethereum-Reloaded/ETHR-Go/ethstats"
+	"github.com/Ethereum-Reloaded/ETHR-Go/graphql"
 	"github.com/Ethereum-Reloaded/ETHR-Go/les"
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
 	"github.com/Ethereum-Reloaded/ETHR-Go/metrics"
@@ -126,6 +130,10 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer (IPC path or HTTP URL, e.g. clef) providing accounts to the node",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby)",
@@ -185,6 +193,16 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: eth.DefaultConfig.LightPeers,
 	}
+	ULCServersFlag = cli.StringFlag{
+		Name:  "ulc.servers",
+		Usage: "Comma separated enode URLs of trusted LES servers, enables ultra light client mode",
+		Value: "",
+	}
+	ULCFractionFlag = cli.IntFlag{
+		Name:  "ulc.fraction",
+		Usage: "Minimum percentage of trusted LES servers that must agree on a head in ultra light client mode",
+		Value: eth.DefaultULCMinTrustedFraction,
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -449,6 +467,30 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: node.DefaultGraphQLHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: node.DefaultGraphQLPort,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphql.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -474,6 +516,10 @@ var (
 		Usage: "Network listening port",
 		Value: 30303,
 	}
+	QUICPortFlag = cli.IntFlag{
+		Name:  "quicport",
+		Usage: "QUIC network listening port (disabled if not set, must differ from the discovery port)",
+	}
 	BootnodesFlag = cli.StringFlag{
 		Name:  "bootnodes",
 		Usage: "Comma separated enode URLs for P2P discovery bootstrap (set v4+v5 instead for light servers)",
@@ -510,6 +556,10 @@ var (
 		Name:  "v5disc",
 		Usage: "Enables the experimental RLPx V5 (Topic Discovery) mechanism",
 	}
+	DNSDiscoveryFlag = cli.StringFlag{
+		Name:  "discovery.dns",
+		Usage: "Comma separated enrtree:// URLs of DNS node lists used as additional peer sources",
+	}
 	NetrestrictFlag = cli.StringFlag{
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
@@ -533,6 +583,21 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: eth.DefaultConfig.GPO.Percentile,
 	}
+	// Log filter settings
+	FilterLogCapFlag = cli.IntFlag{
+		Name:  "filter.logcap",
+		Usage: "Maximum number of logs returned by a single log query (0 = unlimited)",
+		Value: eth.DefaultConfig.Filter.LogCap,
+	}
+	FilterRangeCapFlag = cli.Uint64Flag{
+		Name:  "filter.rangecap",
+		Usage: "Maximum number of blocks a single log query may span (0 = unlimited)",
+		Value: eth.DefaultConfig.Filter.RangeCap,
+	}
+	FilterTransfersFlag = cli.BoolFlag{
+		Name:  "filter.transfers",
+		Usage: "Trace the value transfers of blocks for transfer subscriptions (slows down block processing)",
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -663,6 +728,9 @@ func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(ListenPortFlag.Name) {
 		cfg.ListenAddr = fmt.Sprintf(":%d", ctx.GlobalInt(ListenPortFlag.Name))
 	}
+	if ctx.GlobalIsSet(QUICPortFlag.Name) {
+		cfg.QUICListenAddr = fmt.Sprintf(":%d", ctx.GlobalInt(QUICPortFlag.Name))
+	}
 }
 
 // setNAT creates a port mapper from command line flags.
@@ -794,6 +862,9 @@ func MakeAddress(ks *keystore.KeyStore, account string) (accounts.Account, error
 	log.Warn("Please use explicit addresses! (can search via `geth account list`)")
 	log.Warn("-------------------------------------------------------------------")
 
+	if ks == nil {
+		return accounts.Account{}, fmt.Errorf("account index %d given, but no local keystore is used", index)
+	}
 	accs := ks.Accounts()
 	if len(accs) <= index {
 		return accounts.Account{}, fmt.Errorf("index %d higher than number of accounts %d", index, len(accs))
@@ -926,6 +997,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
 }
 
 func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
@@ -937,6 +1011,18 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	}
 }
 
+func setFilter(ctx *cli.Context, cfg *filters.Config) {
+	if ctx.GlobalIsSet(FilterLogCapFlag.Name) {
+		cfg.LogCap = ctx.GlobalInt(FilterLogCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(FilterRangeCapFlag.Name) {
+		cfg.RangeCap = ctx.GlobalUint64(FilterRangeCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(FilterTransfersFlag.Name) {
+		cfg.Transfers = ctx.GlobalBool(FilterTransfersFlag.Name)
+	}
+}
+
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
@@ -1047,9 +1133,14 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	checkExclusive(ctx, LightServFlag, LightModeFlag)
 	checkExclusive(ctx, LightServFlag, SyncModeFlag, "light")
 
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	// The key store is missing if the node uses an external signer
+	var ks *keystore.KeyStore
+	if keystores := stack.AccountManager().Backends(keystore.KeyStoreType); len(keystores) > 0 {
+		ks = keystores[0].(*keystore.KeyStore)
+	}
 	setEtherbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
+	setFilter(ctx, &cfg.Filter)
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
 
@@ -1067,9 +1158,22 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
+	if servers := ctx.GlobalString(ULCServersFlag.Name); servers != "" {
+		cfg.ULC = &eth.ULCConfig{
+			TrustedServers:     strings.Split(servers, ","),
+			MinTrustedFraction: ctx.GlobalInt(ULCFractionFlag.Name),
+		}
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
+	if ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
+		if urls := ctx.GlobalString(DNSDiscoveryFlag.Name); urls != "" {
+			cfg.DiscoveryURLs = strings.Split(urls, ",")
+		} else {
+			cfg.DiscoveryURLs = nil
+		}
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheDatabaseFlag.Name) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
@@ -1205,6 +1309,41 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterGraphQLService is a utility function to construct a new service and
+// register it against a node.
+func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts []string) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		// Try to construct the GraphQL service backed by a full node
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err == nil {
+			return graphql.New(ethServ.APIBackend, endpoint, cors, vhosts)
+		}
+		// Try to construct the GraphQL service backed by a light node
+		var lesServ *les.LightEthereum
+		if err := ctx.Service(&lesServ); err == nil {
+			return graphql.New(lesServ.ApiBackend, endpoint, cors, vhosts)
+		}
+		// Well, this should not have happened, bail out
+		return nil, errors.New("no Ethereum service")
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
+// MakeGraphQLConfig returns the listening endpoint, the allowed CORS domains and
+// the virtual hosts of the GraphQL service, as configured by the command line.
+func MakeGraphQLConfig(ctx *cli.Context) (string, []string, []string) {
+	var (
+		endpoint = net.JoinHostPort(ctx.GlobalString(GraphQLListenAddrFlag.Name), strconv.Itoa(ctx.GlobalInt(GraphQLPortFlag.Name)))
+		cors     []string
+		vhosts   = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+	)
+	if ctx.GlobalIsSet(GraphQLCORSDomainFlag.Name) {
+		cors = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	}
+	return endpoint, cors, vhosts
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
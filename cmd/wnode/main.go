@@ -90,12 +90,16 @@ var (
 	testMode       = flag.Bool("test", false, "use of predefined parameters for diagnostics (password, etc.)")
 	echoMode       = flag.Bool("echo", false, "echo mode: prints some arguments for diagnostics")
 
-	argVerbosity = flag.Int("verbosity", int(log.LvlError), "log verbosity level")
-	argTTL       = flag.Uint("ttl", 30, "time-to-live for messages in seconds")
-	argWorkTime  = flag.Uint("work", 5, "work time in seconds")
-	argMaxSize   = flag.Uint("maxsize", uint(whisper.DefaultMaxMessageSize), "max size of message")
-	argPoW       = flag.Float64("pow", whisper.DefaultMinimumPoW, "PoW for normal messages in float format (e.g. 2.7)")
-	argServerPoW = flag.Float64("mspow", whisper.DefaultMinimumPoW, "PoW requirement for Mail Server request")
+	argVerbosity   = flag.Int("verbosity", int(log.LvlError), "log verbosity level")
+	argTTL         = flag.Uint("ttl", 30, "time-to-live for messages in seconds")
+	argWorkTime    = flag.Uint("work", 5, "work time in seconds")
+	argMaxSize     = flag.Uint("maxsize", uint(whisper.DefaultMaxMessageSize), "max size of message")
+	argPoW         = flag.Float64("pow", whisper.DefaultMinimumPoW, "PoW for normal messages in float format (e.g. 2.7)")
+	argServerPoW   = flag.Float64("mspow", whisper.DefaultMinimumPoW, "PoW requirement for Mail Server request")
+	argMaxLimit    = flag.Uint("mslimit", mailserver.DefaultMaxLimit, "maximum number of messages delivered by the Mail Server per paginated request")
+	argLegacyLimit = flag.Uint("mslegacylimit", 0, "maximum number of messages delivered by the Mail Server per non-paginated request (0 = same as mslimit)")
+	argRateLimit   = flag.Duration("msratelimit", 0, "minimum interval between Mail Server requests of a peer (e.g. 10s)")
+	argRetention   = flag.Duration("msretention", 0, "age after which the Mail Server prunes archived messages (e.g. 720h)")
 
 	argIP      = flag.String("ip", "", "IP address and port of this node (e.g. 127.0.0.1:30303)")
 	argPub     = flag.String("pub", "", "public key for asymmetric encryption")
@@ -271,6 +275,12 @@ func initialize() {
 
 	if *mailServerMode {
 		shh.RegisterServer(&mailServer)
+		mailServer.Config = mailserver.Config{
+			MaxLimit:    uint32(*argMaxLimit),
+			LegacyLimit: uint32(*argLegacyLimit),
+			RateLimit:   *argRateLimit,
+			Retention:   *argRetention,
+		}
 		if err := mailServer.Init(shh, *argDBPath, msPassword, *argServerPoW); err != nil {
 			utils.Fatalf("Failed to init MailServer: %s", err)
 		}
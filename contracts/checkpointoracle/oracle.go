@@ -0,0 +1,152 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package checkpointoracle is a wrapper of the on-chain checkpoint oracle
+// contract, which registers light client checkpoints attested by a threshold
+// of trusted signers.
+package checkpointoracle
+
+//go:generate abigen --sol contract/oracle.sol --pkg contract --out contract/oracle.go
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi/bind"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/contracts/checkpointoracle/contract"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+)
+
+var errInvalidSignature = errors.New("invalid checkpoint signature")
+
+// CheckpointOracle is a Go wrapper around an on-chain checkpoint oracle contract.
+type CheckpointOracle struct {
+	address  common.Address
+	contract *contract.CheckpointOracle
+	events   *bind.BoundContract // unbound contract used to unpack vote events
+	voteID   common.Hash         // topic of the vote event
+}
+
+// NewCheckpointOracle binds checkpoint contract and returns a registrar instance.
+func NewCheckpointOracle(contractAddr common.Address, backend bind.ContractBackend) (*CheckpointOracle, error) {
+	c, err := contract.NewCheckpointOracle(contractAddr, backend)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := abi.JSON(strings.NewReader(contract.CheckpointOracleABI))
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracle{
+		address:  contractAddr,
+		contract: c,
+		events:   bind.NewBoundContract(contractAddr, parsed, nil, nil, nil),
+		voteID:   parsed.Events["NewCheckpointVote"].Id(),
+	}, nil
+}
+
+// ContractAddr returns the address of contract.
+func (oracle *CheckpointOracle) ContractAddr() common.Address {
+	return oracle.address
+}
+
+// Contract returns the underlying contract instance.
+func (oracle *CheckpointOracle) Contract() *contract.CheckpointOracle {
+	return oracle.contract
+}
+
+// LookupCheckpointEvents searches checkpoint event for specific section in the
+// given log batches.
+func (oracle *CheckpointOracle) LookupCheckpointEvents(blockLogs [][]*types.Log, section uint64, hash common.Hash) []*contract.CheckpointOracleNewCheckpointVote {
+	var votes []*contract.CheckpointOracleNewCheckpointVote
+
+	for _, logs := range blockLogs {
+		for _, log := range logs {
+			if log.Address != oracle.address || len(log.Topics) != 2 || log.Topics[0] != oracle.voteID {
+				continue
+			}
+			event := new(contract.CheckpointOracleNewCheckpointVote)
+			if err := oracle.events.UnpackLog(event, "NewCheckpointVote", *log); err != nil {
+				continue
+			}
+			event.Raw = *log
+			if event.Index == section && common.Hash(event.CheckpointHash) == hash {
+				votes = append(votes, event)
+			}
+		}
+	}
+	return votes
+}
+
+// RegisterCheckpoint registers the checkpoint with a batch of associated signatures
+// that are collected off-chain and sorted by lexicographical order.
+//
+// Notably all signatures given should be transformed to "ethereum style" which
+// transforms v from 0/1 to 27/28 according to the yellow paper.
+func (oracle *CheckpointOracle) RegisterCheckpoint(opts *bind.TransactOpts, index uint64, hash []byte, rnum *big.Int, rhash [32]byte, sigs [][]byte) (*types.Transaction, error) {
+	var (
+		r [][32]byte
+		s [][32]byte
+		v []uint8
+	)
+	for i := 0; i < len(sigs); i++ {
+		if len(sigs[i]) != 65 {
+			return nil, errInvalidSignature
+		}
+		r = append(r, common.BytesToHash(sigs[i][:32]))
+		s = append(s, common.BytesToHash(sigs[i][32:64]))
+		v = append(v, sigs[i][64])
+	}
+	return oracle.contract.SetCheckpoint(opts, rnum, rhash, common.BytesToHash(hash), index, v, r, s)
+}
+
+// SignedHash returns the EIP-191 (version 0) hash the oracle admins sign to
+// attest a checkpoint: keccak256(0x19 || 0x00 || oracle || index || hash).
+func SignedHash(oracle common.Address, index uint64, hash common.Hash) common.Hash {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, index)
+	return crypto.Keccak256Hash([]byte{0x19, 0x00}, oracle.Bytes(), buf, hash.Bytes())
+}
+
+// VoteSignature assembles a vote event into a [R || S || V] signature with V
+// being 27 or 28, as accepted by RegisterCheckpoint.
+func VoteSignature(vote *contract.CheckpointOracleNewCheckpointVote) []byte {
+	sig := make([]byte, 65)
+	copy(sig, vote.R[:])
+	copy(sig[32:], vote.S[:])
+	sig[64] = vote.V
+	return sig
+}
+
+// RecoverSigner returns the address that produced the given [R || S || V]
+// checkpoint signature, V being 27 or 28.
+func RecoverSigner(oracle common.Address, index uint64, hash common.Hash, sig []byte) (common.Address, error) {
+	if len(sig) != 65 || (sig[64] != 27 && sig[64] != 28) {
+		return common.Address{}, errInvalidSignature
+	}
+	cpy := common.CopyBytes(sig)
+	cpy[64] -= 27
+	pubkey, err := crypto.SigToPub(SignedHash(oracle, index, hash).Bytes(), cpy)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
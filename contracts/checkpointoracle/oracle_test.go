@@ -0,0 +1,278 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package checkpointoracle
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi/bind"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi/bind/backends"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/contracts/checkpointoracle/contract"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+)
+
+var (
+	oracleAddr     = common.HexToAddress("0x0000000000000000000000000000000000000123")
+	checkpointHash = common.HexToHash("0xdeadbeef")
+)
+
+// oracleTestCode is the deployment code of an oracle test contract assembled by
+// hand after contract/oracle.sol, with the same ABI, storage layout and checks.
+// The generated bindings don't carry the compiled contract.
+const oracleTestCode = "0x346100965761034538036103456101003960016000526020600020610100516101000160005b815181101561006b578060200282016020015173ffffffffffffffffffffffffffffffffffffffff16806000526000602052600160406000205583820155600101610025565b905160015550506101205160055561014051600655610160516007556102a9609c6000396102a96000f35b60006000fd3461003757366004116100375760e060020a60003504806345848dfc146100555780634d6a304c1461003d578063d459fc4614610095575b60006000fd5b60025460005260045460205260035460405260606000f35b60016000526020600020602060005260015460205260005b60015481101561008b5781810154816020026040015260010161006d565b6020026040016000f35b33600052600060205260406000205415610037576024356004354014156100375760643567ffffffffffffffff1661034052604435610360526084356103805260a4356103a05260c4356103c05261038051600401356103e0526103a051600401356103e0511415610037576103c051600401356103e05114156100375760065460055467ffffffffffffffff60016103405101160201431061029e57600254610340511061029e5760025461034051141561015857600354610340511761029e575b610360511561029e5761034051607e5261036051609e523060765260196080536000608153603e608020610100526000610300526000610320525b6103e051610300511015610037576103005160200261038051602401013560ff1661012052610300516020026103a051602401013561014052610300516020026103c0516024010135610160526000600052602060006080610100600060015af1156100375760005180600052600060205260406000205415610037576103205181111561003757610320526103605161020052610120516102205261014051610240526101605161026052610340517fce51ffa16246bcaf0899f6504f473cd0114f430f566cef71ab7e03d3dde42a416080610200a26001610300510161030052600754610300511061019357610360516004554360035561034051600255600160005260206000f35b600060005260206000f3"
+
+// signCheckpoint signs a checkpoint like an oracle admin would.
+func signCheckpoint(t *testing.T, index uint64, hash common.Hash) ([]byte, common.Address) {
+	key, _ := crypto.GenerateKey()
+	sig, err := crypto.Sign(SignedHash(oracleAddr, index, hash).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign checkpoint: %v", err)
+	}
+	sig[64] += 27
+	return sig, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func TestSignedHash(t *testing.T) {
+	// The hash must match the one the contract computes with
+	// keccak256(abi.encodePacked(byte(0x19), byte(0), this, _sectionIndex, _hash))
+	var packed []byte
+	packed = append(packed, 0x19, 0x00)
+	packed = append(packed, oracleAddr.Bytes()...)
+	packed = append(packed, 0, 0, 0, 0, 0, 0, 0, 42)
+	packed = append(packed, checkpointHash.Bytes()...)
+
+	if have, want := SignedHash(oracleAddr, 42, checkpointHash), crypto.Keccak256Hash(packed); have != want {
+		t.Fatalf("signed hash mismatch: have %x, want %x", have, want)
+	}
+}
+
+func TestRecoverSigner(t *testing.T) {
+	sig, signer := signCheckpoint(t, 42, checkpointHash)
+
+	addr, err := RecoverSigner(oracleAddr, 42, checkpointHash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if addr != signer {
+		t.Fatalf("signer mismatch: have %x, want %x", addr, signer)
+	}
+	// Signatures of other sections or hashes yield other signers
+	if addr, _ := RecoverSigner(oracleAddr, 43, checkpointHash, sig); addr == signer {
+		t.Fatalf("signature valid for another section")
+	}
+	// Signatures with an unshifted V are rejected
+	bad := common.CopyBytes(sig)
+	bad[64] -= 27
+	if _, err := RecoverSigner(oracleAddr, 42, checkpointHash, bad); err != errInvalidSignature {
+		t.Fatalf("unshifted signature error mismatch: have %v, want %v", err, errInvalidSignature)
+	}
+}
+
+// voteLog creates the log the contract emits for a checkpoint vote.
+func voteLog(t *testing.T, addr common.Address, index uint64, hash common.Hash, sig []byte) *types.Log {
+	parsed, err := abi.JSON(strings.NewReader(contract.CheckpointOracleABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := parsed.Events["NewCheckpointVote"]
+	data, err := event.Inputs.NonIndexed().Pack(hash, sig[64], common.BytesToHash(sig[:32]), common.BytesToHash(sig[32:64]))
+	if err != nil {
+		t.Fatalf("failed to pack vote: %v", err)
+	}
+	var topic common.Hash
+	binary.BigEndian.PutUint64(topic[24:], index)
+	return &types.Log{Address: addr, Topics: []common.Hash{event.Id(), topic}, Data: data}
+}
+
+func TestLookupCheckpointEvents(t *testing.T) {
+	oracle, err := NewCheckpointOracle(oracleAddr, nil)
+	if err != nil {
+		t.Fatalf("failed to bind oracle: %v", err)
+	}
+	sig1, _ := signCheckpoint(t, 42, checkpointHash)
+	sig2, _ := signCheckpoint(t, 42, checkpointHash)
+	sig3, _ := signCheckpoint(t, 41, checkpointHash)
+
+	logs := [][]*types.Log{
+		{voteLog(t, oracleAddr, 42, checkpointHash, sig1)},
+		{
+			voteLog(t, oracleAddr, 41, checkpointHash, sig3),                      // other section
+			voteLog(t, common.Address{1}, 42, checkpointHash, sig2),               // other contract
+			voteLog(t, oracleAddr, 42, common.HexToHash("0xcafebabe"), sig2),      // other checkpoint
+			{Address: oracleAddr, Topics: []common.Hash{common.HexToHash("0x1")}}, // other event
+			voteLog(t, oracleAddr, 42, checkpointHash, sig2),
+		},
+	}
+	votes := oracle.LookupCheckpointEvents(logs, 42, checkpointHash)
+	if len(votes) != 2 {
+		t.Fatalf("vote count mismatch: have %d, want 2", len(votes))
+	}
+	for i, want := range [][]byte{sig1, sig2} {
+		if have := VoteSignature(votes[i]); !bytes.Equal(have, want) {
+			t.Errorf("vote %d: signature mismatch: have %x, want %x", i, have, want)
+		}
+	}
+}
+
+// Tests the checkpoint oracle contract deployed on a simulated chain: admins
+// register checkpoints with a threshold of signatures sorted by signer, which
+// are announced in vote events.
+func TestCheckpointRegister(t *testing.T) {
+	// Create three admins sorted by address, with a threshold of two
+	var keys []*ecdsa.PrivateKey
+	alloc := make(core.GenesisAlloc)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: big.NewInt(1000000000000000000)}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(crypto.PubkeyToAddress(keys[i].PublicKey).Bytes(), crypto.PubkeyToAddress(keys[j].PublicKey).Bytes()) < 0
+	})
+	var admins []common.Address
+	for _, key := range keys {
+		admins = append(admins, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	backend := backends.NewSimulatedBackend(alloc)
+	auth := bind.NewKeyedTransactor(keys[0])
+
+	parsed, err := abi.JSON(strings.NewReader(contract.CheckpointOracleABI))
+	if err != nil {
+		t.Fatalf("failed to parse oracle ABI: %v", err)
+	}
+	addr, _, _, err := bind.DeployContract(auth, parsed, common.FromHex(oracleTestCode), backend, admins, big.NewInt(4), big.NewInt(2), big.NewInt(2))
+	if err != nil {
+		t.Fatalf("failed to deploy oracle: %v", err)
+	}
+	backend.Commit()
+
+	oracle, err := NewCheckpointOracle(addr, backend)
+	if err != nil {
+		t.Fatalf("failed to bind oracle: %v", err)
+	}
+	if have, err := oracle.Contract().GetAllAdmin(nil); err != nil || !reflect.DeepEqual(have, admins) {
+		t.Fatalf("admin list mismatch: have %x (%v), want %x", have, err, admins)
+	}
+	sign := func(index uint64, hash common.Hash, signers ...*ecdsa.PrivateKey) [][]byte {
+		var sigs [][]byte
+		for _, key := range signers {
+			sig, err := crypto.Sign(SignedHash(addr, index, hash).Bytes(), key)
+			if err != nil {
+				t.Fatalf("failed to sign checkpoint: %v", err)
+			}
+			sig[64] += 27
+			sigs = append(sigs, sig)
+		}
+		return sigs
+	}
+	register := func(sender *ecdsa.PrivateKey, index uint64, hash common.Hash, sigs [][]byte) (*types.Receipt, error) {
+		head := backend.Blockchain().CurrentHeader()
+		tx, err := oracle.RegisterCheckpoint(bind.NewKeyedTransactor(sender), index, hash.Bytes(), head.Number, head.Hash(), sigs)
+		if err != nil {
+			return nil, err
+		}
+		backend.Commit()
+		return backend.TransactionReceipt(context.Background(), tx.Hash())
+	}
+	latest := func() (uint64, common.Hash, uint64) {
+		index, hash, height, err := oracle.Contract().GetLatestCheckpoint(nil)
+		if err != nil {
+			t.Fatalf("failed to retrieve latest checkpoint: %v", err)
+		}
+		return index, hash, height.Uint64()
+	}
+	// Checkpoints of unfinished sections are ignored
+	if _, err := register(keys[0], 0, checkpointHash, sign(0, checkpointHash, keys[0], keys[1])); err != nil {
+		t.Fatalf("failed to register future checkpoint: %v", err)
+	}
+	if _, hash, _ := latest(); hash != (common.Hash{}) {
+		t.Fatalf("future checkpoint registered")
+	}
+	for backend.Blockchain().CurrentHeader().Number.Uint64() < 6 {
+		backend.Commit()
+	}
+	// Invalid registrations are rejected
+	if _, err := register(keys[0], 0, checkpointHash, sign(0, checkpointHash, keys[0])); err == nil {
+		t.Fatalf("checkpoint registered below the threshold")
+	}
+	if _, err := register(keys[0], 0, checkpointHash, sign(0, checkpointHash, keys[1], keys[0])); err == nil {
+		t.Fatalf("checkpoint registered with unsorted signatures")
+	}
+	if _, err := register(keys[0], 0, checkpointHash, sign(0, checkpointHash, keys[0], keys[0])); err == nil {
+		t.Fatalf("checkpoint registered with duplicate signatures")
+	}
+	outsider, _ := crypto.GenerateKey()
+	if _, err := register(keys[0], 0, checkpointHash, sign(0, checkpointHash, keys[0], outsider)); err == nil {
+		t.Fatalf("checkpoint registered with an outsider signature")
+	}
+	backend.SetBalance(crypto.PubkeyToAddress(outsider.PublicKey), big.NewInt(1000000000000000000))
+	if _, err := register(outsider, 0, checkpointHash, sign(0, checkpointHash, keys[0], keys[1])); err == nil {
+		t.Fatalf("checkpoint registered by an outsider")
+	}
+	// A valid registration updates the checkpoint and emits the votes
+	receipt, err := register(keys[2], 0, checkpointHash, sign(0, checkpointHash, keys[0], keys[2]))
+	if err != nil {
+		t.Fatalf("failed to register checkpoint: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("checkpoint registration failed")
+	}
+	index, hash, height := latest()
+	if index != 0 || hash != checkpointHash || height != backend.Blockchain().CurrentHeader().Number.Uint64() {
+		t.Fatalf("latest checkpoint mismatch: have #%d %x at %d", index, hash, height)
+	}
+	votes := oracle.LookupCheckpointEvents([][]*types.Log{receipt.Logs}, 0, checkpointHash)
+	if len(votes) != 2 {
+		t.Fatalf("vote count mismatch: have %d, want 2", len(votes))
+	}
+	for i, key := range []*ecdsa.PrivateKey{keys[0], keys[2]} {
+		signer, err := RecoverSigner(addr, 0, checkpointHash, VoteSignature(votes[i]))
+		if err != nil || signer != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Errorf("vote %d: signer mismatch: have %x (%v), want %x", i, signer, err, crypto.PubkeyToAddress(key.PublicKey))
+		}
+	}
+	// Stale and old checkpoints are ignored
+	other := common.HexToHash("0xcafebabe")
+	if _, err := register(keys[0], 0, other, sign(0, other, keys[0], keys[1])); err != nil {
+		t.Fatalf("failed to register stale checkpoint: %v", err)
+	}
+	if _, hash, _ := latest(); hash != checkpointHash {
+		t.Fatalf("stale checkpoint registered")
+	}
+	// The next section can be registered once it's confirmed
+	for backend.Blockchain().CurrentHeader().Number.Uint64() < 10 {
+		backend.Commit()
+	}
+	if _, err := register(keys[1], 1, other, sign(1, other, keys[0], keys[1], keys[2])); err != nil {
+		t.Fatalf("failed to register next checkpoint: %v", err)
+	}
+	if index, hash, _ := latest(); index != 1 || hash != other {
+		t.Fatalf("latest checkpoint mismatch: have #%d %x, want #1 %x", index, hash, other)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/params"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+)
+
+var (
+	testGenesis = common.HexToHash("0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3")
+
+	// testConfig schedules forks at blocks 2, 5 (twice), 10 and 20.
+	testConfig = &params.ChainConfig{
+		ChainID:        big.NewInt(1),
+		HomesteadBlock: big.NewInt(0),
+		EIP150Block:    big.NewInt(2),
+		ByzantiumBlock: big.NewInt(5),
+		ForkMasternode: big.NewInt(5),
+		EraV1Block:     big.NewInt(10),
+		EraV2Block:     big.NewInt(20),
+	}
+)
+
+// testChecksum calculates the fork checksum of the test genesis and the given
+// forks by hashing their concatenation in one go.
+func testChecksum(forks ...uint64) [4]byte {
+	blob := common.CopyBytes(testGenesis[:])
+	for _, fork := range forks {
+		var enc [8]byte
+		binary.BigEndian.PutUint64(enc[:], fork)
+		blob = append(blob, enc[:]...)
+	}
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(blob))
+	return sum
+}
+
+// Tests that fork blocks are gathered in order, deduplicated and without the
+// ones enabled at genesis.
+func TestGatherForks(t *testing.T) {
+	if forks, want := gatherForks(testConfig), []uint64{2, 5, 10, 20}; !reflect.DeepEqual(forks, want) {
+		t.Errorf("fork list mismatch: have %v, want %v", forks, want)
+	}
+}
+
+// Tests that fork IDs are calculated correctly at various chain heads.
+func TestCreation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		want ID
+	}{
+		{0, ID{Hash: testChecksum(), Next: 2}},
+		{1, ID{Hash: testChecksum(), Next: 2}},
+		{2, ID{Hash: testChecksum(2), Next: 5}},
+		{9, ID{Hash: testChecksum(2, 5), Next: 10}},
+		{10, ID{Hash: testChecksum(2, 5, 10), Next: 20}},
+		{20, ID{Hash: testChecksum(2, 5, 10, 20), Next: 0}},
+		{1000, ID{Hash: testChecksum(2, 5, 10, 20), Next: 0}},
+	}
+	for i, tt := range tests {
+		if have := newID(testConfig, testGenesis, tt.head); have != tt.want {
+			t.Errorf("test %d: fork ID mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that remote fork IDs are accepted or rejected according to the rules.
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Local is before the first fork, remote is the same, connect
+		{1, ID{Hash: testChecksum(), Next: 2}, nil},
+
+		// Local is before the first fork, remote knows of no forks, connect
+		{1, ID{Hash: testChecksum(), Next: 0}, nil},
+
+		// Local is before the first fork, remote announces an unknown fork that
+		// has not passed yet locally, connect
+		{1, ID{Hash: testChecksum(), Next: 3}, nil},
+
+		// Local is between two forks, remote announces an unknown fork at a block
+		// already passed locally, reject
+		{7, ID{Hash: testChecksum(2, 5), Next: 6}, ErrLocalIncompatibleOrStale},
+
+		// Local is between forks, remote is syncing and announces the right next
+		// fork, connect
+		{12, ID{Hash: testChecksum(2), Next: 5}, nil},
+
+		// Local is between forks, remote is stuck before a fork we've passed
+		// (not upgraded), reject
+		{12, ID{Hash: testChecksum(2), Next: 0}, ErrRemoteStale},
+
+		// Local is syncing, remote is ahead on forks we know about, connect
+		{3, ID{Hash: testChecksum(2, 5, 10), Next: 20}, nil},
+
+		// Local is syncing, remote is ahead on a fork we don't know about, reject
+		{3, ID{Hash: testChecksum(2, 5, 11), Next: 0}, ErrLocalIncompatibleOrStale},
+
+		// Remote is on a different genesis, reject
+		{3, ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}, Next: 0}, ErrLocalIncompatibleOrStale},
+
+		// Local has passed all forks, remote is the same, connect
+		{math.MaxUint64 - 1, ID{Hash: testChecksum(2, 5, 10, 20), Next: 0}, nil},
+	}
+	for i, tt := range tests {
+		filter := newFilter(testConfig, testGenesis, func() uint64 { return tt.head })
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that fork IDs are RLP encoded as a 4 byte hash and the next fork number.
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		id   ID
+		want []byte
+	}{
+		{ID{Hash: [4]byte{}, Next: 0}, common.FromHex("c6840000000080")},
+		{ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}, Next: 0xBADDCAFE}, common.FromHex("ca84deadbeef84baddcafe")},
+	}
+	for i, tt := range tests {
+		have, err := rlp.EncodeToBytes(tt.id)
+		if err != nil {
+			t.Errorf("test %d: failed to encode fork ID: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(have, tt.want) {
+			t.Errorf("test %d: RLP mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+}
@@ -46,6 +46,28 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 	}
 }
 
+// BlockTracer is a vm.Tracer that is additionally notified of every transaction
+// the state processor is about to apply, allowing it to attribute the execution
+// it traces to a particular block and transaction.
+type BlockTracer interface {
+	vm.Tracer
+	CaptureTransaction(block *types.Block, tx *types.Transaction, index int)
+}
+
+// SealingTracer is a BlockTracer which can also trace the transactions of blocks
+// assembled locally, whose hash is only known once they are sealed.
+type SealingTracer interface {
+	BlockTracer
+
+	// Fork creates a tracer for the transactions of a block being assembled,
+	// which may run concurrently with the original one.
+	Fork() SealingTracer
+
+	// Seal records the results traced for the assembled block under the hash
+	// of the sealed one.
+	Seal(block *types.Block)
+}
+
 // Process processes the state changes according to the Ethereum rules by running
 // the transaction messages using the statedb and applying any rewards to both
 // the processor (coinbase) and any included uncles.
@@ -68,6 +90,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	// Iterate over and process the individual transactions
 	for i, tx := range block.Transactions() {
 		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if tracer, ok := cfg.Tracer.(BlockTracer); ok && cfg.Debug {
+			tracer.CaptureTransaction(block, tx, i)
+		}
 		receipt, _, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
 		if err != nil {
 			return nil, nil, 0, err
@@ -0,0 +1,191 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package shamir implements Shamir's secret sharing over GF(256).
+//
+// A secret is split into parts such that any threshold of them reconstruct the
+// secret, while fewer reveal nothing about it. Every byte of the secret is
+// shared independently with a random polynomial of degree threshold-1, whose
+// constant term is the secret byte.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// MaxParts is the maximum number of parts a secret can be split into, limited
+// by the number of distinct non-zero points of the field.
+const MaxParts = 255
+
+var (
+	ErrEmptySecret      = errors.New("shamir: empty secret")
+	ErrInvalidParts     = errors.New("shamir: parts must be between threshold and 255")
+	ErrInvalidThreshold = errors.New("shamir: threshold must be at least 2")
+	ErrTooFewParts      = errors.New("shamir: at least two parts are required")
+	ErrPartLength       = errors.New("shamir: parts differ in length")
+	ErrInvalidPart      = errors.New("shamir: invalid part")
+	ErrDuplicatePart    = errors.New("shamir: duplicate part")
+)
+
+// Reader is the source of randomness for the polynomial coefficients.
+var Reader io.Reader = rand.Reader
+
+// Log and exponent tables of GF(256) with the AES reduction polynomial
+// x^8 + x^4 + x^3 + x + 1 and generator 3.
+var (
+	expTable [255]byte
+	logTable [256]byte
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		logTable[x] = byte(i)
+		// Multiply by the generator: x*3 = x*2 ^ x
+		double := x << 1
+		if x&0x80 != 0 {
+			double ^= 0x1b
+		}
+		x ^= double
+	}
+}
+
+// mul multiplies two field elements.
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+int(logTable[b]))%255]
+}
+
+// div divides a by the non-zero field element b.
+func div(a, b byte) byte {
+	if b == 0 {
+		panic("shamir: division by zero")
+	}
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])-int(logTable[b])+255)%255]
+}
+
+// evaluate returns the value of the polynomial with the given coefficients,
+// lowest degree first, at x.
+func evaluate(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// interpolate returns the value at zero of the polynomial through the given
+// points, which must have distinct x coordinates.
+func interpolate(xs, ys []byte) byte {
+	var result byte
+	for i := range xs {
+		// Lagrange basis polynomial of point i evaluated at zero
+		basis := byte(1)
+		for j := range xs {
+			if i != j {
+				basis = mul(basis, div(xs[j], xs[j]^xs[i]))
+			}
+		}
+		result ^= mul(ys[i], basis)
+	}
+	return result
+}
+
+// Split divides the secret into the given number of parts, any threshold of
+// which reconstruct it. Each part is one byte longer than the secret, the last
+// byte being the x coordinate of its points.
+func Split(secret []byte, parts, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, ErrEmptySecret
+	}
+	if threshold < 2 {
+		return nil, ErrInvalidThreshold
+	}
+	if parts < threshold || parts > MaxParts {
+		return nil, ErrInvalidParts
+	}
+	shares := make([][]byte, parts)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+	coeffs := make([]byte, threshold)
+	defer zero(coeffs)
+
+	for i, b := range secret {
+		coeffs[0] = b
+		if _, err := io.ReadFull(Reader, coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			share[i] = evaluate(coeffs, share[len(secret)])
+		}
+	}
+	return shares, nil
+}
+
+// Combine reconstructs the secret from the given parts. If fewer parts than the
+// threshold are given, the result is a random looking value unrelated to the
+// secret: callers should verify the reconstructed secret.
+func Combine(parts [][]byte) ([]byte, error) {
+	if len(parts) < 2 {
+		return nil, ErrTooFewParts
+	}
+	size := len(parts[0])
+	if size < 2 {
+		return nil, ErrEmptySecret
+	}
+	xs := make([]byte, len(parts))
+	seen := make(map[byte]bool)
+	for i, part := range parts {
+		if len(part) != size {
+			return nil, ErrPartLength
+		}
+		x := part[size-1]
+		if x == 0 {
+			return nil, ErrInvalidPart
+		}
+		if seen[x] {
+			return nil, ErrDuplicatePart
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+	secret := make([]byte, size-1)
+	ys := make([]byte, len(parts))
+	for i := range secret {
+		for j, part := range parts {
+			ys[j] = part[i]
+		}
+		secret[i] = interpolate(xs, ys)
+	}
+	zero(ys)
+	return secret, nil
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
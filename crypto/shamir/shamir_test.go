@@ -0,0 +1,124 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package shamir
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Tests the field arithmetic against known values and the field axioms.
+func TestField(t *testing.T) {
+	// Known products in the AES field
+	if p := mul(0x53, 0xca); p != 0x01 {
+		t.Errorf("0x53*0xca mismatch: have %#x, want 0x01", p)
+	}
+	if p := mul(0x57, 0x83); p != 0xc1 {
+		t.Errorf("0x57*0x83 mismatch: have %#x, want 0xc1", p)
+	}
+	for a := 0; a < 256; a++ {
+		if mul(byte(a), 1) != byte(a) || mul(byte(a), 0) != 0 {
+			t.Fatalf("identity failed for %#x", a)
+		}
+		for b := 1; b < 256; b++ {
+			if div(mul(byte(a), byte(b)), byte(b)) != byte(a) {
+				t.Fatalf("division is not the inverse of multiplication: %#x, %#x", a, b)
+			}
+		}
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("a 32 byte long private key value")
+
+	tests := []struct{ parts, threshold int }{
+		{2, 2}, {3, 2}, {5, 3}, {10, 10}, {255, 7},
+	}
+	for _, tt := range tests {
+		shares, err := Split(secret, tt.parts, tt.threshold)
+		if err != nil {
+			t.Fatalf("%d-of-%d: split failed: %v", tt.threshold, tt.parts, err)
+		}
+		if len(shares) != tt.parts {
+			t.Fatalf("%d-of-%d: part count mismatch: have %d", tt.threshold, tt.parts, len(shares))
+		}
+		// Any threshold of parts, in any order, reconstruct the secret
+		for start := 0; start+tt.threshold <= tt.parts; start++ {
+			subset := make([][]byte, 0, tt.threshold)
+			for i := start + tt.threshold - 1; i >= start; i-- {
+				subset = append(subset, shares[i])
+			}
+			combined, err := Combine(subset)
+			if err != nil {
+				t.Fatalf("%d-of-%d: combine failed: %v", tt.threshold, tt.parts, err)
+			}
+			if !bytes.Equal(combined, secret) {
+				t.Errorf("%d-of-%d: parts %d+: secret mismatch: have %x", tt.threshold, tt.parts, start, combined)
+			}
+		}
+		// Fewer parts don't
+		if tt.threshold > 2 {
+			combined, err := Combine(shares[:tt.threshold-1])
+			if err != nil {
+				t.Fatalf("%d-of-%d: combine failed: %v", tt.threshold, tt.parts, err)
+			}
+			if bytes.Equal(combined, secret) {
+				t.Errorf("%d-of-%d: secret reconstructed below threshold", tt.threshold, tt.parts)
+			}
+		}
+	}
+}
+
+func TestSplitInvalid(t *testing.T) {
+	tests := []struct {
+		secret           []byte
+		parts, threshold int
+		err              error
+	}{
+		{nil, 3, 2, ErrEmptySecret},
+		{[]byte{1}, 3, 1, ErrInvalidThreshold},
+		{[]byte{1}, 2, 3, ErrInvalidParts},
+		{[]byte{1}, 256, 3, ErrInvalidParts},
+	}
+	for i, tt := range tests {
+		if _, err := Split(tt.secret, tt.parts, tt.threshold); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+func TestCombineInvalid(t *testing.T) {
+	shares, err := Split([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		parts [][]byte
+		err   error
+	}{
+		{shares[:1], ErrTooFewParts},
+		{[][]byte{{1}, {2}}, ErrEmptySecret},
+		{[][]byte{shares[0], shares[1][1:]}, ErrPartLength},
+		{[][]byte{shares[0], shares[0]}, ErrDuplicatePart},
+		{[][]byte{shares[0], append(make([]byte, 6), 0)}, ErrInvalidPart},
+	}
+	for i, tt := range tests {
+		if _, err := Combine(tt.parts); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
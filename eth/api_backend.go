@@ -30,6 +30,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/vm"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth/downloader"
+	"github.com/Ethereum-Reloaded/ETHR-Go/eth/filters"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth/gasprice"
 	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
 	"github.com/Ethereum-Reloaded/ETHR-Go/event"
@@ -124,6 +125,13 @@ func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*typ
 	return logs, nil
 }
 
+func (b *EthAPIBackend) GetTransfers(ctx context.Context, hash common.Hash) ([]*filters.Transfer, error) {
+	if b.eth.transfers == nil {
+		return nil, nil
+	}
+	return b.eth.transfers.Transfers(hash), nil
+}
+
 func (b *EthAPIBackend) GetTd(blockHash common.Hash) *big.Int {
 	return b.eth.blockchain.GetTdByHash(blockHash)
 }
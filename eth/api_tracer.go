@@ -26,6 +26,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi"
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core"
@@ -600,12 +601,16 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	// Depending on the tracer type, format and return the output
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
-		return &ethapi.ExecutionResult{
+		result := &ethapi.ExecutionResult{
 			Gas:         gas,
 			Failed:      failed,
 			ReturnValue: fmt.Sprintf("%x", ret),
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
-		}, nil
+		}
+		if failed {
+			result.RevertReason, _ = abi.UnpackRevert(ret)
+		}
+		return result, nil
 
 	case *tracers.Tracer:
 		return tracer.GetResult()
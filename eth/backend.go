@@ -46,6 +46,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/miner"
 	"github.com/Ethereum-Reloaded/ETHR-Go/node"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/dnsdisc"
 	"github.com/Ethereum-Reloaded/ETHR-Go/params"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rpc"
@@ -55,6 +56,7 @@ type LesServer interface {
 	Start(srvr *p2p.Server)
 	Stop()
 	Protocols() []p2p.Protocol
+	APIs() []rpc.API
 	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
 }
 
@@ -82,6 +84,9 @@ type Ethereum struct {
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
+	transfers *filters.TransferTracer // Tracer recording the value transfers of imported blocks, nil if disabled
+	dnsSource *dnsdisc.Source         // DNS node lists feeding the dialer, if configured
+
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
@@ -146,6 +151,10 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
 		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
 	)
+	if config.Filter.Transfers {
+		eth.transfers = filters.NewTransferTracer()
+		vmConfig.Debug, vmConfig.Tracer = true, eth.transfers
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, eth.chainConfig, eth.engine, vmConfig)
 	if err != nil {
 		return nil, err
@@ -166,6 +175,18 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	if len(config.DiscoveryURLs) > 0 {
+		client, err := dnsdisc.NewClient(dnsdisc.Config{})
+		if err != nil {
+			return nil, err
+		}
+		if eth.dnsSource, err = client.NewSource(config.DiscoveryURLs...); err != nil {
+			return nil, err
+		}
+		for i := range eth.protocolManager.SubProtocols {
+			eth.protocolManager.SubProtocols[i].DialCandidates = eth.dnsSource
+		}
+	}
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine)
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
 
@@ -247,6 +268,11 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the APIs of the light server
+	if s.lesServer != nil {
+		apis = append(apis, s.lesServer.APIs()...)
+	}
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -272,7 +298,7 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
+			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, s.config.Filter),
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -400,6 +426,7 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 	}
 	// Start the networking layer and the light server if requested
 	s.protocolManager.Start(maxPeers)
+	s.startENRUpdate(srvr)
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
@@ -410,6 +437,9 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
 	s.bloomIndexer.Close()
+	if s.dnsSource != nil {
+		s.dnsSource.Close()
+	}
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
@@ -29,6 +29,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/consensus/ethash"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth/downloader"
+	"github.com/Ethereum-Reloaded/ETHR-Go/eth/filters"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth/gasprice"
 	"github.com/Ethereum-Reloaded/ETHR-Go/params"
 )
@@ -55,6 +56,7 @@ var DefaultConfig = Config{
 		Blocks:     20,
 		Percentile: 60,
 	},
+	Filter: filters.DefaultConfig,
 }
 
 func init() {
@@ -83,10 +85,21 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
+	// DiscoveryURLs is a list of enrtree:// URLs of DNS node lists (EIP-1459)
+	// used as additional sources of peers.
+	DiscoveryURLs []string `toml:",omitempty"`
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// CheckpointOracle is the checkpoint oracle contract light clients sync
+	// from and servers advertise the checkpoints of.
+	CheckpointOracle *params.CheckpointOracleConfig `toml:",omitempty"`
+
+	// ULC enables the ultra light client mode if set.
+	ULC *ULCConfig `toml:",omitempty"`
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
@@ -109,6 +122,9 @@ type Config struct {
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
+	// Log filter options
+	Filter filters.Config
+
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
@@ -0,0 +1,93 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/forkid"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+)
+
+// enrEntry is the ENR entry which advertises the eth protocol on the discovery
+// network, allowing peers on incompatible forks to be skipped before dialing.
+type enrEntry struct {
+	ForkID    forkid.ID // Fork identifier per EIP-2124
+	NetworkID uint64    // Network the node is participating in
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// ENRKey implements enr.Entry.
+func (e enrEntry) ENRKey() string {
+	return "eth"
+}
+
+// currentENREntry constructs an eth ENR entry based on the current state of
+// the chain.
+func currentENREntry(chain *core.BlockChain, networkID uint64) *enrEntry {
+	return &enrEntry{
+		ForkID:    forkid.NewID(chain),
+		NetworkID: networkID,
+	}
+}
+
+// startENRUpdate starts a goroutine which republishes the eth ENR entry
+// whenever a new chain head moves the node across a fork boundary.
+func (s *Ethereum) startENRUpdate(srvr *p2p.Server) {
+	headCh := make(chan core.ChainHeadEvent, 10)
+	sub := s.blockchain.SubscribeChainHeadEvent(headCh)
+
+	go func() {
+		defer sub.Unsubscribe()
+
+		last := forkid.NewID(s.blockchain)
+		for {
+			select {
+			case <-headCh:
+				entry := currentENREntry(s.blockchain, s.networkId)
+				if entry.ForkID == last {
+					continue
+				}
+				if err := srvr.SetRecordEntry(entry); err != nil {
+					log.Warn("Failed to update eth node record entry", "err", err)
+					continue
+				}
+				last = entry.ForkID
+			case <-sub.Err():
+				return
+			case <-s.shutdownChan:
+				return
+			}
+		}
+	}()
+}
+
+// nodeFilter returns a dial filter accepting only nodes which advertise the eth
+// protocol on the given network with a fork ID compatible with the local chain.
+func nodeFilter(networkID uint64, forkFilter forkid.Filter) func(*enr.Record) bool {
+	return func(r *enr.Record) bool {
+		var entry enrEntry
+		if err := r.Load(&entry); err != nil {
+			return false
+		}
+		return entry.NetworkID == networkID && forkFilter(entry.ForkID) == nil
+	}
+}
@@ -0,0 +1,410 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+)
+
+const (
+	txArriveTimeout = 500 * time.Millisecond // Time allowance before an announced transaction is explicitly requested
+	txGatherSlack   = 100 * time.Millisecond // Interval used to collate almost-expired announces with fetches
+	txFetchTimeout  = 5 * time.Second        // Maximum allotted time to return an explicitly requested transaction
+	txAnnounceLimit = 4096                   // Maximum number of unique transactions a peer may have announced
+)
+
+// MaxTxFetch is the maximum number of transactions to request from or serve to
+// a peer at once.
+const MaxTxFetch = 256
+
+var errUnrequestedTx = errors.New("unrequested transaction")
+
+// txRequesterFn is a callback type for sending a transaction retrieval request.
+type txRequesterFn func([]common.Hash) error
+
+// txExistsFn is a callback type to check whether a transaction is already known
+// to the local pool.
+type txExistsFn func(common.Hash) bool
+
+// txAddFn is a callback type to add a batch of transactions to the local pool.
+type txAddFn func([]*types.Transaction) []error
+
+// txNotify is a batch of transaction hashes announced by a peer.
+type txNotify struct {
+	origin   string        // Identifier of the peer originating the notification
+	hashes   []common.Hash // Hashes of the transactions being announced
+	time     time.Time     // Timestamp of the announcement
+	fetchTxs txRequesterFn // Fetcher function to retrieve the announced transactions
+}
+
+// txAnnounce is the notification of the availability of a single transaction
+// at a peer.
+type txAnnounce struct {
+	origin   string        // Identifier of the peer originating the notification
+	time     time.Time     // Timestamp of the announcement
+	fetchTxs txRequesterFn // Fetcher function to retrieve the transaction
+}
+
+// txFetch tracks the announcers of a transaction and its retrieval, if one is
+// in flight.
+type txFetch struct {
+	announces []*txAnnounce // Announcers not asked for the transaction yet, in arrival order
+	fetching  *txAnnounce   // Announcer the transaction is being retrieved from, nil if scheduled
+	requested time.Time     // Time the retrieval request was sent
+}
+
+// txDelivery is a batch of transactions that arrived from a peer, either as a
+// broadcast or in response to a request.
+type txDelivery struct {
+	origin    string
+	hashes    []common.Hash
+	direct    bool        // Whether the transactions are a response to a request
+	requested chan []bool // Reports which of the directly delivered transactions were requested from the origin
+}
+
+// TxFetcher is responsible for retrieving transactions announced by their hash,
+// deduplicating announcements of the same transaction from multiple peers.
+type TxFetcher struct {
+	notify  chan *txNotify
+	cleanup chan *txDelivery
+	drop    chan string
+	quit    chan struct{}
+
+	// Announce states
+	announces map[string]int           // Per peer announce counts to prevent memory exhaustion
+	fetches   map[common.Hash]*txFetch // Announced transactions, scheduled for or being fetched
+	requests  map[string][]common.Hash // Scratch space to collate requests per peer
+	fetchers  map[string]txRequesterFn // Scratch space for the request functions of peers
+
+	// Callbacks
+	hasTx  txExistsFn // Checks if a transaction is already in the local pool
+	addTxs txAddFn    // Injects a batch of transactions into the local pool
+
+	// Testing hooks
+	fetchingHook func(string, []common.Hash) // Method to call upon starting a transaction fetch
+}
+
+// NewTxFetcher creates a transaction fetcher to retrieve transactions based on
+// hash announcements.
+func NewTxFetcher(hasTx txExistsFn, addTxs txAddFn) *TxFetcher {
+	return &TxFetcher{
+		notify:    make(chan *txNotify),
+		cleanup:   make(chan *txDelivery),
+		drop:      make(chan string),
+		quit:      make(chan struct{}),
+		announces: make(map[string]int),
+		fetches:   make(map[common.Hash]*txFetch),
+		requests:  make(map[string][]common.Hash),
+		fetchers:  make(map[string]txRequesterFn),
+		hasTx:     hasTx,
+		addTxs:    addTxs,
+	}
+}
+
+// Start boots up the announcement based transaction retrieval, accepting and
+// processing hash notifications and deliveries until termination requested.
+func (f *TxFetcher) Start() {
+	go f.loop()
+}
+
+// Stop terminates the announcement based transaction retrieval, canceling all
+// pending operations.
+func (f *TxFetcher) Stop() {
+	close(f.quit)
+}
+
+// Notify announces the fetcher of the potential availability of a batch of new
+// transactions at a peer.
+func (f *TxFetcher) Notify(peer string, hashes []common.Hash, time time.Time, fetchTxs txRequesterFn) error {
+	notify := &txNotify{
+		origin:   peer,
+		hashes:   hashes,
+		time:     time,
+		fetchTxs: fetchTxs,
+	}
+	select {
+	case f.notify <- notify:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Enqueue imports a batch of transactions received from a peer, either as a
+// broadcast or in response to a request, into the local pool and marks them as
+// retrieved. Transactions delivered in response to a request are dropped unless
+// they were requested from the same peer. It returns the errors of the pool for
+// each transaction.
+func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool) []error {
+	delivery := &txDelivery{origin: peer, hashes: make([]common.Hash, len(txs)), direct: direct}
+	for i, tx := range txs {
+		delivery.hashes[i] = tx.Hash()
+	}
+	if !direct {
+		errs := f.addTxs(txs)
+		select {
+		case f.cleanup <- delivery:
+		case <-f.quit:
+		}
+		return errs
+	}
+	// Find out which of the transactions were requested and import only those
+	delivery.requested = make(chan []bool, 1)
+	select {
+	case f.cleanup <- delivery:
+	case <-f.quit:
+		return nil
+	}
+	var (
+		requested = <-delivery.requested
+		accepted  []*types.Transaction
+		indexes   []int
+		errs      = make([]error, len(txs))
+	)
+	for i, tx := range txs {
+		if !requested[i] {
+			errs[i] = errUnrequestedTx
+			continue
+		}
+		accepted = append(accepted, tx)
+		indexes = append(indexes, i)
+	}
+	if len(accepted) > 0 {
+		for i, err := range f.addTxs(accepted) {
+			errs[indexes[i]] = err
+		}
+	}
+	return errs
+}
+
+// Drop forgets all announcements of a disconnected peer, scheduling its
+// in-flight retrievals for other announcers.
+func (f *TxFetcher) Drop(peer string) error {
+	select {
+	case f.drop <- peer:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// loop is the main fetcher loop, checking and processing various notification
+// events.
+func (f *TxFetcher) loop() {
+	timer := time.NewTimer(0)
+	<-timer.C // clear out the channel
+	armed := false
+
+	// rearm starts the timer if there are transactions to track.
+	rearm := func(d time.Duration) {
+		if !armed && len(f.fetches) > 0 {
+			timer.Reset(d)
+			armed = true
+		}
+	}
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-f.quit:
+			return
+
+		case notification := <-f.notify:
+			txAnnounceInMeter.Mark(int64(len(notification.hashes)))
+			for _, hash := range notification.hashes {
+				f.announce(notification, hash)
+			}
+			rearm(txArriveTimeout)
+
+		case delivery := <-f.cleanup:
+			txDeliveryInMeter.Mark(int64(len(delivery.hashes)))
+			if !delivery.direct {
+				for _, hash := range delivery.hashes {
+					f.forget(hash)
+				}
+				break
+			}
+			// Only transactions being retrieved from the origin were requested
+			requested := make([]bool, len(delivery.hashes))
+			for i, hash := range delivery.hashes {
+				if fetch := f.fetches[hash]; fetch != nil && fetch.fetching != nil && fetch.fetching.origin == delivery.origin {
+					requested[i] = true
+					f.forget(hash)
+					continue
+				}
+				log.Trace("Dropping unrequested transaction", "peer", delivery.origin, "hash", hash)
+				txDeliveryDropMeter.Mark(1)
+			}
+			delivery.requested <- requested
+
+		case peer := <-f.drop:
+			f.dropPeer(peer)
+
+		case <-timer.C:
+			armed = false
+			f.schedule(time.Now())
+			rearm(txGatherSlack)
+		}
+	}
+}
+
+// announce records the announcement of a single transaction.
+func (f *TxFetcher) announce(notification *txNotify, hash common.Hash) {
+	if f.hasTx(hash) {
+		txAnnounceKnownMeter.Mark(1)
+		return
+	}
+	// If the peer announced too many transactions already, discard
+	count := f.announces[notification.origin] + 1
+	if count > txAnnounceLimit {
+		log.Debug("Peer exceeded outstanding transaction announces", "peer", notification.origin, "limit", txAnnounceLimit)
+		txAnnounceDOSMeter.Mark(1)
+		return
+	}
+	// Deduplicate announcements of the same peer
+	fetch := f.fetches[hash]
+	if fetch == nil {
+		fetch = new(txFetch)
+		f.fetches[hash] = fetch
+	}
+	if fetch.fetching != nil && fetch.fetching.origin == notification.origin {
+		return
+	}
+	for _, announce := range fetch.announces {
+		if announce.origin == notification.origin {
+			return
+		}
+	}
+	fetch.announces = append(fetch.announces, &txAnnounce{
+		origin:   notification.origin,
+		time:     notification.time,
+		fetchTxs: notification.fetchTxs,
+	})
+	f.announces[notification.origin] = count
+}
+
+// schedule sends retrieval requests for transactions that didn't arrive by
+// broadcast in time, and reschedules the retrievals which timed out.
+func (f *TxFetcher) schedule(now time.Time) {
+	for hash, fetch := range f.fetches {
+		// Hand timed out retrievals over to the next announcer
+		if fetch.fetching != nil {
+			if now.Sub(fetch.requested) <= txFetchTimeout {
+				continue
+			}
+			log.Trace("Transaction retrieval timed out", "peer", fetch.fetching.origin, "hash", hash)
+			txFetchTimeoutMeter.Mark(1)
+			f.release(fetch.fetching.origin)
+			fetch.fetching = nil
+			if len(fetch.announces) == 0 {
+				delete(f.fetches, hash)
+				continue
+			}
+		}
+		if len(fetch.announces) == 0 || now.Sub(fetch.announces[0].time) < txArriveTimeout-txGatherSlack {
+			continue
+		}
+		// The transaction is due, skip it if it arrived meanwhile
+		if f.hasTx(hash) {
+			f.forget(hash)
+			continue
+		}
+		// Pick a random announcer that still has request capacity
+		var picked = -1
+		for _, i := range rand.Perm(len(fetch.announces)) {
+			if len(f.requests[fetch.announces[i].origin]) < MaxTxFetch {
+				picked = i
+				break
+			}
+		}
+		if picked < 0 {
+			continue
+		}
+		announce := fetch.announces[picked]
+		fetch.announces = append(fetch.announces[:picked], fetch.announces[picked+1:]...)
+		fetch.fetching, fetch.requested = announce, now
+
+		f.requests[announce.origin] = append(f.requests[announce.origin], hash)
+		f.fetchers[announce.origin] = announce.fetchTxs
+	}
+	// Send out all transaction requests
+	for peer, hashes := range f.requests {
+		log.Trace("Fetching scheduled transactions", "peer", peer, "count", len(hashes))
+		if f.fetchingHook != nil {
+			f.fetchingHook(peer, hashes)
+		}
+		txFetchMeter.Mark(int64(len(hashes)))
+		go func(fetchTxs txRequesterFn, hashes []common.Hash) {
+			fetchTxs(hashes)
+		}(f.fetchers[peer], hashes)
+
+		delete(f.requests, peer)
+		delete(f.fetchers, peer)
+	}
+}
+
+// forget removes all traces of a transaction, which is either known now or
+// can't be retrieved.
+func (f *TxFetcher) forget(hash common.Hash) {
+	fetch := f.fetches[hash]
+	if fetch == nil {
+		return
+	}
+	for _, announce := range fetch.announces {
+		f.release(announce.origin)
+	}
+	if fetch.fetching != nil {
+		f.release(fetch.fetching.origin)
+	}
+	delete(f.fetches, hash)
+}
+
+// dropPeer removes all announcements of a peer. Transactions being retrieved
+// from it are rescheduled for their other announcers.
+func (f *TxFetcher) dropPeer(peer string) {
+	if _, ok := f.announces[peer]; !ok {
+		return
+	}
+	for hash, fetch := range f.fetches {
+		if fetch.fetching != nil && fetch.fetching.origin == peer {
+			fetch.fetching = nil
+		}
+		for i, announce := range fetch.announces {
+			if announce.origin == peer {
+				fetch.announces = append(fetch.announces[:i], fetch.announces[i+1:]...)
+				break
+			}
+		}
+		if fetch.fetching == nil && len(fetch.announces) == 0 {
+			delete(f.fetches, hash)
+		}
+	}
+	delete(f.announces, peer)
+}
+
+// release decrements the announce count of a peer.
+func (f *TxFetcher) release(peer string) {
+	if f.announces[peer]--; f.announces[peer] <= 0 {
+		delete(f.announces, peer)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+)
+
+// txFetcherTester is a test simulator for mocking out the local transaction pool.
+type txFetcherTester struct {
+	fetcher *TxFetcher
+
+	pool map[common.Hash]*types.Transaction
+	lock sync.RWMutex
+}
+
+func newTxTester() *txFetcherTester {
+	tester := &txFetcherTester{pool: make(map[common.Hash]*types.Transaction)}
+	tester.fetcher = NewTxFetcher(tester.hasTx, tester.addTxs)
+	tester.fetcher.Start()
+	return tester
+}
+
+func (f *txFetcherTester) hasTx(hash common.Hash) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.pool[hash] != nil
+}
+
+func (f *txFetcherTester) addTxs(txs []*types.Transaction) []error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, tx := range txs {
+		f.pool[tx.Hash()] = tx
+	}
+	return make([]error, len(txs))
+}
+
+// makeTxs creates a number of distinct transactions.
+func makeTxs(n int) []*types.Transaction {
+	txs := make([]*types.Transaction, n)
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	}
+	return txs
+}
+
+func txHashes(txs []*types.Transaction) []common.Hash {
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	return hashes
+}
+
+// makeTxFetcher creates a request function that delivers the requested
+// transactions from the given set, or nothing if it is nil.
+func (f *txFetcherTester) makeTxFetcher(peer string, txs map[common.Hash]*types.Transaction) txRequesterFn {
+	return func(hashes []common.Hash) error {
+		var delivery []*types.Transaction
+		for _, hash := range hashes {
+			if tx := txs[hash]; tx != nil {
+				delivery = append(delivery, tx)
+			}
+		}
+		if len(delivery) > 0 {
+			go f.fetcher.Enqueue(peer, delivery, true)
+		}
+		return nil
+	}
+}
+
+func txSet(txs []*types.Transaction) map[common.Hash]*types.Transaction {
+	set := make(map[common.Hash]*types.Transaction)
+	for _, tx := range txs {
+		set[tx.Hash()] = tx
+	}
+	return set
+}
+
+// verifyTxFetch waits for a fetch request and checks its origin and size.
+func verifyTxFetch(t *testing.T, fetching chan txFetchEvent, peer string, count int) {
+	select {
+	case ev := <-fetching:
+		if ev.peer != peer || len(ev.hashes) != count {
+			t.Fatalf("fetch mismatch: have %s/%d, want %s/%d", ev.peer, len(ev.hashes), peer, count)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("fetch timeout: peer %s", peer)
+	}
+}
+
+type txFetchEvent struct {
+	peer   string
+	hashes []common.Hash
+}
+
+// Tests that announced transactions are retrieved and added to the pool, and
+// that the same transaction announced by multiple peers is only fetched once.
+func TestTxFetcherDeduplication(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	fetching := make(chan txFetchEvent, 10)
+	tester.fetcher.fetchingHook = func(peer string, hashes []common.Hash) {
+		fetching <- txFetchEvent{peer, hashes}
+	}
+	txs := makeTxs(10)
+	set := txSet(txs)
+
+	tester.fetcher.Notify("A", txHashes(txs), time.Now().Add(-txArriveTimeout), tester.makeTxFetcher("A", set))
+	tester.fetcher.Notify("B", txHashes(txs), time.Now().Add(-txArriveTimeout), tester.makeTxFetcher("B", set))
+
+	// Requests are spread among the announcers, but each hash is requested once.
+	fetched := make(map[common.Hash]bool)
+	for len(fetched) < len(txs) {
+		select {
+		case ev := <-fetching:
+			for _, hash := range ev.hashes {
+				if fetched[hash] {
+					t.Fatalf("transaction %x fetched twice", hash)
+				}
+				fetched[hash] = true
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("transactions not fetched: have %d, want %d", len(fetched), len(txs))
+		}
+	}
+	select {
+	case ev := <-fetching:
+		t.Fatalf("duplicate fetch from %s: %d transactions", ev.peer, len(ev.hashes))
+	case <-time.After(txArriveTimeout + txGatherSlack):
+	}
+	for _, tx := range txs {
+		if !tester.hasTx(tx.Hash()) {
+			t.Errorf("transaction %x not added to pool", tx.Hash())
+		}
+	}
+}
+
+// Tests that transactions arriving by broadcast before the announce timeout
+// are not fetched.
+func TestTxFetcherBroadcastCancel(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	fetching := make(chan txFetchEvent, 10)
+	tester.fetcher.fetchingHook = func(peer string, hashes []common.Hash) {
+		fetching <- txFetchEvent{peer, hashes}
+	}
+	txs := makeTxs(5)
+	tester.fetcher.Notify("A", txHashes(txs), time.Now(), tester.makeTxFetcher("A", txSet(txs)))
+	tester.fetcher.Enqueue("B", txs, false)
+
+	select {
+	case ev := <-fetching:
+		t.Fatalf("fetched broadcast transactions from %s", ev.peer)
+	case <-time.After(txArriveTimeout + 2*txGatherSlack):
+	}
+}
+
+// Tests that a transaction is fetched from another announcer if the peer it was
+// requested from disconnects.
+func TestTxFetcherDropReschedule(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	fetching := make(chan txFetchEvent, 10)
+	tester.fetcher.fetchingHook = func(peer string, hashes []common.Hash) {
+		fetching <- txFetchEvent{peer, hashes}
+	}
+	txs := makeTxs(1)
+
+	// Announce from A first, which never delivers.
+	tester.fetcher.Notify("A", txHashes(txs), time.Now().Add(-txArriveTimeout), tester.makeTxFetcher("A", nil))
+	verifyTxFetch(t, fetching, "A", 1)
+
+	tester.fetcher.Notify("B", txHashes(txs), time.Now(), tester.makeTxFetcher("B", txSet(txs)))
+	tester.fetcher.Drop("A")
+	verifyTxFetch(t, fetching, "B", 1)
+}
+
+// Tests that transactions delivered in response to a request are only added to
+// the pool if they were requested from the delivering peer.
+func TestTxFetcherUnrequestedDelivery(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	fetching := make(chan txFetchEvent, 10)
+	tester.fetcher.fetchingHook = func(peer string, hashes []common.Hash) {
+		fetching <- txFetchEvent{peer, hashes}
+	}
+	txs := makeTxs(3)
+
+	// Request the first transaction from A, which never delivers.
+	tester.fetcher.Notify("A", txHashes(txs[:1]), time.Now().Add(-txArriveTimeout), tester.makeTxFetcher("A", nil))
+	verifyTxFetch(t, fetching, "A", 1)
+
+	// Neither the transaction requested from A nor the unannounced ones may be
+	// delivered by B.
+	for i, err := range tester.fetcher.Enqueue("B", txs, true) {
+		if err != errUnrequestedTx {
+			t.Errorf("transaction %d: error mismatch: have %v, want %v", i, err, errUnrequestedTx)
+		}
+	}
+	for i, tx := range txs {
+		if tester.hasTx(tx.Hash()) {
+			t.Errorf("unrequested transaction %d added to pool", i)
+		}
+	}
+	// The delivery of A is accepted.
+	for i, err := range tester.fetcher.Enqueue("A", txs[:1], true) {
+		if err != nil {
+			t.Errorf("transaction %d: delivery failed: %v", i, err)
+		}
+	}
+	if !tester.hasTx(txs[0].Hash()) {
+		t.Errorf("requested transaction not added to pool")
+	}
+}
+
+// Tests that peers can't announce unlimited transactions.
+func TestTxFetcherAnnounceLimit(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	hashes := make([]common.Hash, txAnnounceLimit+10)
+	for i := range hashes {
+		hashes[i] = common.BigToHash(big.NewInt(int64(i + 1)))
+	}
+	tester.fetcher.Notify("A", hashes, time.Now(), tester.makeTxFetcher("A", nil))
+	tester.fetcher.Notify("B", hashes[:1], time.Now(), tester.makeTxFetcher("B", nil))
+
+	// Drop synchronises with the fetcher loop, making the state safe to read.
+	tester.fetcher.Drop("C")
+	if count := tester.fetcher.announces["A"]; count != txAnnounceLimit {
+		t.Errorf("announce count mismatch: have %d, want %d", count, txAnnounceLimit)
+	}
+	if count := len(tester.fetcher.fetches); count != txAnnounceLimit {
+		t.Errorf("tracked transaction count mismatch: have %d, want %d", count, txAnnounceLimit)
+	}
+}
@@ -18,6 +18,7 @@ package filters
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -38,6 +39,26 @@ var (
 	deadline = 5 * time.Minute // consider a filter inactive if it has not been polled for within deadline
 )
 
+// defaultLogsPageSize is the number of logs returned per page by GetLogsPage if
+// no log cap is configured.
+const defaultLogsPageSize = 10000
+
+var (
+	errInvalidContinuation  = errors.New("invalid continuation token")
+	errTransfersUnsupported = errors.New("transfer tracking not available")
+)
+
+// Config holds the limits enforced on log queries served by the filter API.
+type Config struct {
+	LogCap    int    // Maximum number of logs returned by a single query (0 = unlimited)
+	RangeCap  uint64 // Maximum number of blocks a single query may span (0 = unlimited)
+	Transfers bool   // Whether value transfers are traced for transfer subscriptions
+}
+
+// DefaultConfig contains the default limits, which don't restrict log queries,
+// and leaves transfer tracing disabled.
+var DefaultConfig = Config{}
+
 // filter is a helper struct that holds meta information over the filter type
 // and associated subscription in the event system.
 type filter struct {
@@ -53,6 +74,7 @@ type filter struct {
 // information related to the Ethereum protocol such als blocks, transactions and logs.
 type PublicFilterAPI struct {
 	backend   Backend
+	config    Config
 	mux       *event.TypeMux
 	quit      chan struct{}
 	chainDb   ethdb.Database
@@ -62,9 +84,10 @@ type PublicFilterAPI struct {
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
+func NewPublicFilterAPI(backend Backend, lightMode bool, config Config) *PublicFilterAPI {
 	api := &PublicFilterAPI{
 		backend: backend,
+		config:  config,
 		mux:     backend.EventMux(),
 		chainDb: backend.ChainDb(),
 		events:  NewEventSystem(backend.EventMux(), backend, lightMode),
@@ -271,6 +294,52 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	return rpcSub, nil
 }
 
+// TransferCriteria selects the accounts whose value transfers are of interest.
+type TransferCriteria struct {
+	Addresses []common.Address `json:"addresses"` // Sender or recipient of the transfers, any if empty
+}
+
+// Transfers creates a subscription that fires for every native value transfer,
+// top-level or internal, made in imported blocks from or to any of the given
+// addresses. Transfers reverted by a chain reorganisation are resent with their
+// removed flag set.
+func (api *PublicFilterAPI) Transfers(ctx context.Context, crit *TransferCriteria) (*rpc.Subscription, error) {
+	if _, ok := api.backend.(TransferBackend); !ok || !api.config.Transfers {
+		return &rpc.Subscription{}, errTransfersUnsupported
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if crit == nil {
+		crit = new(TransferCriteria)
+	}
+	var (
+		rpcSub    = notifier.CreateSubscription()
+		transfers = make(chan []*Transfer)
+	)
+	transfersSub := api.events.SubscribeTransfers(crit.Addresses, transfers)
+
+	go func() {
+		for {
+			select {
+			case matched := <-transfers:
+				for _, transfer := range matched {
+					notifier.Notify(rpcSub.ID, transfer)
+				}
+			case <-rpcSub.Err():
+				transfersSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				transfersSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // FilterCriteria represents a request to create a new filter.
 // Same as ethereum.FilterQuery but with UnmarshalJSON() method.
 type FilterCriteria ethereum.FilterQuery
@@ -321,26 +390,146 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 }
 
 // GetLogs returns logs matching the given argument that are stored within the state.
+// Queries spanning more blocks or returning more logs than the configured caps
+// are rejected, such results need to be paged through with GetLogsPage.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getlogs
 func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
-	// Convert the RPC block numbers into internal representations
-	if crit.FromBlock == nil {
-		crit.FromBlock = big.NewInt(rpc.LatestBlockNumber.Int64())
+	// Resolve the block range and make sure it's within the cap
+	begin, end, err := api.resolveRange(ctx, crit)
+	if err != nil {
+		return nil, err
 	}
-	if crit.ToBlock == nil {
-		crit.ToBlock = big.NewInt(rpc.LatestBlockNumber.Int64())
+	if api.config.RangeCap > 0 && begin >= 0 && end >= begin && uint64(end-begin) >= api.config.RangeCap {
+		return nil, fmt.Errorf("query exceeds max block range %d", api.config.RangeCap)
+	}
+	// Create and run the filter to get all the logs, stopping once over the cap
+	filter := New(api.backend, begin, end, crit.Addresses, crit.Topics)
+	if api.config.LogCap > 0 {
+		filter.SetLimit(api.config.LogCap + 1)
 	}
-	// Create and run the filter to get all the logs
-	filter := New(api.backend, crit.FromBlock.Int64(), crit.ToBlock.Int64(), crit.Addresses, crit.Topics)
-
 	logs, err := filter.Logs(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if api.config.LogCap > 0 && len(logs) > api.config.LogCap {
+		return nil, fmt.Errorf("query returned more than %d results", api.config.LogCap)
+	}
 	return returnLogs(logs), err
 }
 
+// LogsPage is a page of logs returned by GetLogsPage.
+type LogsPage struct {
+	Logs         []*types.Log `json:"logs"`
+	Continuation *string      `json:"continuation"` // Token to retrieve the next page with, nil if done
+}
+
+// GetLogsPage returns a page of the logs matching the given criteria. Pages end
+// at block boundaries once the configured log cap is reached or the configured
+// block range is exhausted. If there are more logs to retrieve, the page holds a
+// continuation token to pass in along the same criteria to get the next page.
+func (api *PublicFilterAPI) GetLogsPage(ctx context.Context, crit FilterCriteria, continuation *string) (*LogsPage, error) {
+	var (
+		begin, end int64
+		err        error
+	)
+	if continuation != nil {
+		if begin, end, err = decodeContinuation(*continuation); err != nil {
+			return nil, err
+		}
+		// Tokens come from the caller, make sure they don't page past the head
+		head, err := api.headNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if end > head {
+			end = head
+		}
+	} else if begin, end, err = api.resolveRange(ctx, crit); err != nil {
+		return nil, err
+	}
+	// Restrict the page to the allowed range, resuming from there
+	last := end
+	if api.config.RangeCap > 0 && begin >= 0 && end >= begin && uint64(end-begin) >= api.config.RangeCap {
+		last = begin + int64(api.config.RangeCap) - 1
+	}
+	filter := New(api.backend, begin, last, crit.Addresses, crit.Topics)
+	if api.config.LogCap > 0 {
+		filter.SetLimit(api.config.LogCap)
+	} else {
+		filter.SetLimit(defaultLogsPageSize)
+	}
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page := &LogsPage{Logs: returnLogs(logs)}
+	if next, _, _ := filter.Resume(); int64(next) <= end {
+		token := encodeContinuation(int64(next), end)
+		page.Continuation = &token
+	}
+	return page, nil
+}
+
+// resolveRange converts the block range of the criteria into absolute block
+// numbers, so that paged queries aren't affected by the chain progressing. The
+// end of the range is capped at the current head.
+func (api *PublicFilterAPI) resolveRange(ctx context.Context, crit FilterCriteria) (int64, int64, error) {
+	begin, end := rpc.LatestBlockNumber.Int64(), rpc.LatestBlockNumber.Int64()
+	if crit.FromBlock != nil {
+		begin = crit.FromBlock.Int64()
+	}
+	if crit.ToBlock != nil {
+		end = crit.ToBlock.Int64()
+	}
+	head, err := api.headNumber(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if begin == rpc.LatestBlockNumber.Int64() {
+		begin = head
+	}
+	if end == rpc.LatestBlockNumber.Int64() || end > head {
+		end = head
+	}
+	return begin, end, nil
+}
+
+// headNumber returns the number of the current head block.
+func (api *PublicFilterAPI) headNumber(ctx context.Context) (int64, error) {
+	header, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, errors.New("latest header not found")
+	}
+	return header.Number.Int64(), nil
+}
+
+// encodeContinuation creates an opaque token identifying the remaining block
+// range of a paged log query.
+func encodeContinuation(begin, end int64) string {
+	var blob [16]byte
+	binary.BigEndian.PutUint64(blob[:8], uint64(begin))
+	binary.BigEndian.PutUint64(blob[8:], uint64(end))
+	return hexutil.Encode(blob[:])
+}
+
+// decodeContinuation extracts the remaining block range of a paged log query
+// from a continuation token.
+func decodeContinuation(token string) (int64, int64, error) {
+	blob, err := hexutil.Decode(token)
+	if err != nil || len(blob) != 16 {
+		return 0, 0, errInvalidContinuation
+	}
+	begin, end := int64(binary.BigEndian.Uint64(blob[:8])), int64(binary.BigEndian.Uint64(blob[8:]))
+	if begin < 0 || end < begin {
+		return 0, 0, errInvalidContinuation
+	}
+	return begin, end, nil
+}
+
 // UninstallFilter removes the filter with the given filter id.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_uninstallfilter
@@ -17,11 +17,14 @@
 package filters
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rpc"
 )
 
@@ -183,3 +186,78 @@ func TestUnmarshalJSONNewFilterArgs(t *testing.T) {
 		t.Fatalf("expected 0 topics, got %d topics", len(test7.Topics[2]))
 	}
 }
+
+// Tests that log queries exceeding the configured caps are rejected, and that
+// they can be paged through instead.
+func TestGetLogsPage(t *testing.T) {
+	backend, addr := newLogTestBackend(100)
+
+	api := NewPublicFilterAPI(backend, false, Config{LogCap: 10, RangeCap: 50})
+	crit := FilterCriteria{FromBlock: big.NewInt(1), Addresses: []common.Address{addr}}
+
+	// Ensure oversized queries are rejected
+	if _, err := api.GetLogs(context.Background(), crit); err == nil {
+		t.Errorf("query over range cap succeeded")
+	}
+	crit.FromBlock = big.NewInt(60)
+	if _, err := api.GetLogs(context.Background(), crit); err == nil {
+		t.Errorf("query over log cap succeeded")
+	}
+	crit.FromBlock = big.NewInt(91)
+	if logs, err := api.GetLogs(context.Background(), crit); err != nil || len(logs) != 10 {
+		t.Errorf("capped query failed: %d logs, %v", len(logs), err)
+	}
+	// Page through all logs and ensure they are all retrieved
+	crit.FromBlock = big.NewInt(1)
+
+	var (
+		logs  []*types.Log
+		token *string
+	)
+	for pages := 1; ; pages++ {
+		page, err := api.GetLogsPage(context.Background(), crit, token)
+		if err != nil {
+			t.Fatalf("page %d: failed to retrieve logs: %v", pages, err)
+		}
+		if len(page.Logs) > 10 {
+			t.Fatalf("page %d: too many logs: have %d, want <= 10", pages, len(page.Logs))
+		}
+		logs = append(logs, page.Logs...)
+		if token = page.Continuation; token == nil {
+			if pages != 10 {
+				t.Errorf("page count mismatch: have %d, want 10", pages)
+			}
+			break
+		}
+	}
+	if len(logs) != 100 {
+		t.Fatalf("log count mismatch: have %d, want 100", len(logs))
+	}
+	for i, log := range logs {
+		if log.BlockNumber != uint64(i+1) {
+			t.Errorf("log %d: block number mismatch: have %d, want %d", i, log.BlockNumber, i+1)
+		}
+	}
+	// Ensure invalid continuations are rejected
+	invalid := "0x1234"
+	if _, err := api.GetLogsPage(context.Background(), crit, &invalid); err != errInvalidContinuation {
+		t.Errorf("invalid continuation error mismatch: have %v, want %v", err, errInvalidContinuation)
+	}
+	// Ensure paging stops at the head, even if the range extends beyond it
+	crit.FromBlock, crit.ToBlock = big.NewInt(95), big.NewInt(1000)
+	page, err := api.GetLogsPage(context.Background(), crit, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve logs beyond the head: %v", err)
+	}
+	if len(page.Logs) != 6 || page.Continuation != nil {
+		t.Errorf("page beyond the head mismatch: have %d logs, continuation %v; want 6 logs, no continuation", len(page.Logs), page.Continuation != nil)
+	}
+	beyond := encodeContinuation(101, 1000)
+	page, err = api.GetLogsPage(context.Background(), crit, &beyond)
+	if err != nil {
+		t.Fatalf("failed to resume beyond the head: %v", err)
+	}
+	if len(page.Logs) != 0 || page.Continuation != nil {
+		t.Errorf("page past the head mismatch: have %d logs, continuation %v; want none", len(page.Logs), page.Continuation != nil)
+	}
+}
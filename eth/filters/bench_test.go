@@ -199,3 +199,39 @@ func BenchmarkNoBloomBits(b *testing.B) {
 	fmt.Println(" ", d, "total  ", d*time.Duration(1000000)/time.Duration(*headNum+1), "per million blocks")
 	db.Close()
 }
+
+// slowBackend is a test backend adding latency to log retrievals, mimicking
+// a light client fetching them from the network or a cold database.
+type slowBackend struct {
+	*testBackend
+	delay time.Duration
+}
+
+func (b *slowBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
+	time.Sleep(b.delay)
+	return b.testBackend.GetLogs(ctx, hash)
+}
+
+func BenchmarkFilterLogsSerial(b *testing.B)     { benchmarkFilterLogs(b, 1) }
+func BenchmarkFilterLogsConcurrent(b *testing.B) { benchmarkFilterLogs(b, logFetchers) }
+
+// benchmarkFilterLogs measures the time to filter the logs from a chain with a
+// log in every block, retrieving the logs of the given number of blocks at once.
+func benchmarkFilterLogs(b *testing.B, fetchers int) {
+	backend, addr := newLogTestBackend(1000)
+	slow := &slowBackend{backend, 100 * time.Microsecond}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter := New(slow, 0, -1, []common.Address{addr}, nil)
+		filter.fetchers = fetchers
+
+		logs, err := filter.Logs(context.Background())
+		if err != nil {
+			b.Fatalf("failed to filter logs: %v", err)
+		}
+		if len(logs) != 1000 {
+			b.Fatalf("log count mismatch: have %d, want 1000", len(logs))
+		}
+	}
+}
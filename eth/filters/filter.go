@@ -18,7 +18,9 @@ package filters
 
 import (
 	"context"
+	"errors"
 	"math/big"
+	"sync"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core"
@@ -45,6 +47,21 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// errMissingHeader is returned internally if a candidate block is not available
+// any more, ending the search silently just like a missing header while
+// iterating does.
+var errMissingHeader = errors.New("header not found")
+
+const (
+	// logFetchers is the number of candidate blocks whose logs are retrieved
+	// concurrently after bloom matching.
+	logFetchers = 8
+
+	// logBatchSize is the number of candidate blocks gathered before their logs
+	// are retrieved.
+	logBatchSize = 64
+)
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -54,6 +71,10 @@ type Filter struct {
 	addresses  []common.Address
 	topics     [][]common.Hash
 
+	limit    int // Number of logs after which to stop searching (0 = unlimited)
+	found    int // Number of logs found by the current search
+	fetchers int // Number of blocks to retrieve logs for concurrently
+
 	matcher *bloombits.Matcher
 }
 
@@ -88,10 +109,31 @@ func New(backend Backend, begin, end int64, addresses []common.Address, topics [
 		addresses: addresses,
 		topics:    topics,
 		db:        backend.ChainDb(),
+		fetchers:  logFetchers,
 		matcher:   bloombits.NewMatcher(size, filters),
 	}
 }
 
+// SetLimit caps the number of logs a search returns. The search stops at the
+// end of the block in which the limit is reached, so the last block's logs are
+// never split and the results may overshoot the limit. Resume reports where a
+// capped search needs to be continued from.
+func (f *Filter) SetLimit(limit int) {
+	f.limit = limit
+}
+
+// Resume returns the first block not yet searched and the last block of the
+// filter range, along with whether there are any blocks left to search. It's
+// only meaningful after Logs was called, which resolves the range.
+func (f *Filter) Resume() (uint64, uint64, bool) {
+	return uint64(f.begin), uint64(f.end), f.begin <= f.end
+}
+
+// full reports whether the result limit of the search was reached.
+func (f *Filter) full() bool {
+	return f.limit > 0 && f.found >= f.limit
+}
+
 // Logs searches the blockchain for matching log entries, returning all from the
 // first block that contains matches, updating the start of the filter accordingly.
 func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
@@ -105,10 +147,12 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.begin == -1 {
 		f.begin = int64(head)
 	}
-	end := uint64(f.end)
 	if f.end == -1 {
-		end = head
+		f.end = int64(head)
 	}
+	end := uint64(f.end)
+	f.found = 0
+
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
@@ -121,7 +165,7 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		} else {
 			logs, err = f.indexedLogs(ctx, indexed-1)
 		}
-		if err != nil {
+		if err != nil || f.full() {
 			return logs, err
 		}
 	}
@@ -144,12 +188,26 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 
 	f.backend.ServiceFilter(ctx, session)
 
-	// Iterate over the matches until exhausted or context closed
-	var logs []*types.Log
-
+	// Iterate over the matches until exhausted or context closed, retrieving
+	// the logs of the candidate blocks in concurrent batches
+	var (
+		logs  []*types.Log
+		batch []uint64
+		full  bool
+	)
 	for {
 		select {
 		case number, ok := <-matches:
+			if ok {
+				if batch = append(batch, number); len(batch) < logBatchSize {
+					continue
+				}
+			}
+			if logs, full, err = f.blockLogs(ctx, batch, logs); err != nil || full {
+				return logs, err
+			}
+			batch = batch[:0]
+
 			// Abort if all matches have been fulfilled
 			if !ok {
 				err := session.Error()
@@ -158,18 +216,6 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 				}
 				return logs, err
 			}
-			f.begin = int64(number) + 1
-
-			// Retrieve the suggested block and pull any truly matching logs
-			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
-			if header == nil || err != nil {
-				return logs, err
-			}
-			found, err := f.checkMatches(ctx, header)
-			if err != nil {
-				return logs, err
-			}
-			logs = append(logs, found...)
 
 		case <-ctx.Done():
 			return logs, ctx.Err()
@@ -177,27 +223,104 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	}
 }
 
-// indexedLogs returns the logs matching the filter criteria based on raw block
+// unindexedLogs returns the logs matching the filter criteria based on raw block
 // iteration and bloom matching.
 func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
-	var logs []*types.Log
-
-	for ; f.begin <= int64(end); f.begin++ {
-		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
-		if header == nil || err != nil {
-			return logs, err
+	var (
+		logs  []*types.Log
+		batch []uint64
+		full  bool
+		err   error
+	)
+	for number := uint64(f.begin); number <= end; number++ {
+		header, herr := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if header == nil || herr != nil {
+			// Retrieve the pending candidates, resuming at the missing block
+			if logs, full, err = f.blockLogs(ctx, batch, logs); err != nil || full {
+				return logs, err
+			}
+			f.begin = int64(number)
+			return logs, herr
 		}
-		if bloomFilter(header.Bloom, f.addresses, f.topics) {
-			found, err := f.checkMatches(ctx, header)
-			if err != nil {
+		if !bloomFilter(header.Bloom, f.addresses, f.topics) {
+			continue
+		}
+		if batch = append(batch, number); len(batch) == logBatchSize {
+			if logs, full, err = f.blockLogs(ctx, batch, logs); err != nil || full {
 				return logs, err
 			}
-			logs = append(logs, found...)
+			batch = batch[:0]
 		}
 	}
+	if logs, full, err = f.blockLogs(ctx, batch, logs); err != nil || full {
+		return logs, err
+	}
+	f.begin = int64(end) + 1
 	return logs, nil
 }
 
+// blockLogs retrieves the logs matching the filter criteria from a batch of
+// candidate blocks concurrently, appending them to logs in block order. The
+// progress marker of the filter is moved past the last block processed, which
+// is the one where the result limit is reached, if any.
+func (f *Filter) blockLogs(ctx context.Context, numbers []uint64, logs []*types.Log) ([]*types.Log, bool, error) {
+	if len(numbers) == 0 {
+		return logs, false, nil
+	}
+	var (
+		results = make([][]*types.Log, len(numbers))
+		errs    = make([]error, len(numbers))
+		tasks   = make(chan int, len(numbers))
+		pend    sync.WaitGroup
+	)
+	for i := range numbers {
+		tasks <- i
+	}
+	close(tasks)
+
+	fetchers := f.fetchers
+	if fetchers > len(numbers) {
+		fetchers = len(numbers)
+	}
+	for i := 0; i < fetchers; i++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+			for task := range tasks {
+				// Retrieve the suggested block and pull any truly matching logs
+				header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(numbers[task]))
+				if header == nil || err != nil {
+					if err == nil {
+						err = errMissingHeader
+					}
+					errs[task] = err
+					continue
+				}
+				results[task], errs[task] = f.checkMatches(ctx, header)
+			}
+		}()
+	}
+	pend.Wait()
+
+	// Assemble the results in order, stopping at the first failure
+	for i, number := range numbers {
+		if errs[i] != nil {
+			if errs[i] == errMissingHeader {
+				errs[i] = nil
+			}
+			return logs, false, errs[i]
+		}
+		logs = append(logs, results[i]...)
+		f.found += len(results[i])
+		f.begin = int64(number) + 1
+
+		if f.full() {
+			return logs, true, nil
+		}
+	}
+	return logs, false, nil
+}
+
 // checkMatches checks if the receipts belonging to the given header contain any log events that
 // match the filter criteria. This function is called when the bloom filter signals a potential match.
 func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs []*types.Log, err error) {
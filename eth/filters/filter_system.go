@@ -53,6 +53,9 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// TransfersSubscription queries for new or removed (chain reorg) native
+	// value transfers
+	TransfersSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -82,6 +85,8 @@ type subscription struct {
 	logs      chan []*types.Log
 	hashes    chan []common.Hash
 	headers   chan *types.Header
+	addresses []common.Address // accounts of interest for transfer subscriptions
+	transfers chan []*Transfer
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -94,6 +99,8 @@ type EventSystem struct {
 	lightMode bool
 	lastHead  *types.Header
 
+	transfersHead *types.Header // last head whose transfers were broadcast
+
 	// Subscriptions
 	txsSub        event.Subscription         // Subscription for new transaction event
 	logsSub       event.Subscription         // Subscription for new log event
@@ -175,6 +182,7 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
+			case <-sub.f.transfers:
 			}
 		}
 
@@ -242,6 +250,7 @@ func (es *EventSystem) subscribeMinedPendingLogs(crit ethereum.FilterQuery, logs
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		transfers: make(chan []*Transfer),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -259,6 +268,7 @@ func (es *EventSystem) subscribeLogs(crit ethereum.FilterQuery, logs chan []*typ
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		transfers: make(chan []*Transfer),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -276,6 +286,7 @@ func (es *EventSystem) subscribePendingLogs(crit ethereum.FilterQuery, logs chan
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		transfers: make(chan []*Transfer),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -292,6 +303,7 @@ func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscripti
 		logs:      make(chan []*types.Log),
 		hashes:    make(chan []common.Hash),
 		headers:   headers,
+		transfers: make(chan []*Transfer),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -308,6 +320,27 @@ func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscript
 		logs:      make(chan []*types.Log),
 		hashes:    hashes,
 		headers:   make(chan *types.Header),
+		transfers: make(chan []*Transfer),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeTransfers creates a subscription that writes the native value transfers
+// made in imported blocks from or to any of the given addresses, or all transfers
+// if no addresses are given. Transfers reverted by a chain reorganisation are
+// written again, marked as removed.
+func (es *EventSystem) SubscribeTransfers(addresses []common.Address, transfers chan []*Transfer) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       TransfersSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		addresses: addresses,
+		transfers: transfers,
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -369,6 +402,18 @@ func (es *EventSystem) broadcast(filters filterIndex, ev interface{}) {
 				}
 			})
 		}
+		oldh := es.transfersHead
+		es.transfersHead = e.Block.Header()
+		if oldh != nil && len(filters[TransfersSubscription]) > 0 {
+			es.traverseHeads(oldh, e.Block.Header(), func(header *types.Header, remove bool) {
+				transfers := es.blockTransfers(header, remove)
+				for _, f := range filters[TransfersSubscription] {
+					if matched := filterTransfers(transfers, f.addresses); len(matched) > 0 {
+						f.transfers <- matched
+					}
+				}
+			})
+		}
 	}
 }
 
@@ -378,7 +423,12 @@ func (es *EventSystem) lightFilterNewHead(newHeader *types.Header, callBack func
 	if oldh == nil {
 		return
 	}
-	newh := newHeader
+	es.traverseHeads(oldh, newHeader, callBack)
+}
+
+// traverseHeads invokes the callback for every header rolled back when switching
+// the chain head from oldh to newh, followed by every header newly added.
+func (es *EventSystem) traverseHeads(oldh, newh *types.Header, callBack func(*types.Header, bool)) {
 	// find common ancestor, create list of rolled back and new block hashes
 	var oldHeaders, newHeaders []*types.Header
 	for oldh.Hash() != newh.Hash() {
@@ -405,6 +455,29 @@ func (es *EventSystem) lightFilterNewHead(newHeader *types.Header, callBack func
 	}
 }
 
+// blockTransfers retrieves the value transfers of a single header, marking them
+// as removed if requested.
+func (es *EventSystem) blockTransfers(header *types.Header, remove bool) []*Transfer {
+	backend, ok := es.backend.(TransferBackend)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	transfers, err := backend.GetTransfers(ctx, header.Hash())
+	if err != nil || !remove {
+		return transfers
+	}
+	removed := make([]*Transfer, len(transfers))
+	for i, transfer := range transfers {
+		cpy := *transfer
+		cpy.Removed = true
+		removed[i] = &cpy
+	}
+	return removed
+}
+
 // filter logs of a single header in light client mode
 func (es *EventSystem) lightFilterLogs(header *types.Header, addresses []common.Address, topics [][]common.Hash, remove bool) []*types.Log {
 	if bloomFilter(header.Bloom, addresses, topics) {
@@ -230,3 +230,71 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// newLogTestBackend creates a test backend with a chain of the given length,
+// holding a log in every block.
+func newLogTestBackend(blocks int) (*testBackend, common.Address) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		backend = &testBackend{new(event.TypeMux), db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
+		addr    = common.BytesToAddress([]byte("logger"))
+	)
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, blocks, func(i int, gen *core.BlockGen) {
+		receipt := makeReceipt(addr)
+		receipt.Logs[0].BlockNumber = uint64(i + 1)
+		gen.AddUncheckedReceipt(receipt)
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	return backend, addr
+}
+
+// Tests that capped filters stop at the limit and can be resumed afterwards,
+// eventually returning all the logs in order.
+func TestFilterLimit(t *testing.T) {
+	backend, addr := newLogTestBackend(100)
+
+	var (
+		logs  []*types.Log
+		begin = int64(1)
+		calls int
+	)
+	for {
+		filter := New(backend, begin, 100, []common.Address{addr}, nil)
+		filter.SetLimit(7)
+
+		found, err := filter.Logs(context.Background())
+		if err != nil {
+			t.Fatalf("failed to filter logs: %v", err)
+		}
+		if calls++; len(found) != 7 && calls != 15 {
+			t.Fatalf("call %d: log count mismatch: have %d, want 7", calls, len(found))
+		}
+		logs = append(logs, found...)
+
+		next, end, more := filter.Resume()
+		if end != 100 {
+			t.Fatalf("call %d: range end mismatch: have %d, want 100", calls, end)
+		}
+		if !more {
+			break
+		}
+		begin = int64(next)
+	}
+	if calls != 15 {
+		t.Errorf("filter call count mismatch: have %d, want 15", calls)
+	}
+	if len(logs) != 100 {
+		t.Fatalf("log count mismatch: have %d, want 100", len(logs))
+	}
+	for i, log := range logs {
+		if log.BlockNumber != uint64(i+1) {
+			t.Errorf("log %d: block number mismatch: have %d, want %d", i, log.BlockNumber, i+1)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/vm"
+	"github.com/hashicorp/golang-lru"
+)
+
+// transferCacheLimit is the number of recent blocks whose value transfers are
+// retained by a TransferTracer.
+const transferCacheLimit = 256
+
+// Transfer is a native value transfer, either made by a transaction itself or
+// internally by a contract call, creation or self-destruct during its execution.
+type Transfer struct {
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *hexutil.Big   `json:"value"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint   `json:"transactionIndex"`
+	Removed     bool           `json:"removed"` // set if the transfer was reverted by a chain reorganisation
+}
+
+// TransferBackend is implemented by filter backends able to report the value
+// transfers made in a block. Backends without it (e.g. light clients, which do
+// not execute blocks) cannot serve transfer subscriptions.
+type TransferBackend interface {
+	GetTransfers(ctx context.Context, blockHash common.Hash) ([]*Transfer, error)
+}
+
+// pendingTransfer is a value carrying call or creation awaiting its outcome.
+type pendingTransfer struct {
+	depth    int       // Depth of the frame that issued the operation
+	index    int       // Position of the transfer within the transaction's transfers
+	transfer *Transfer // Transfer to fill in or discard once the outcome is known
+}
+
+// TransferTracer is a core.SealingTracer recording the native value transfers of
+// all processed blocks. Internal transfers are only retained if the frame that
+// made them, along with all its parents, executed successfully.
+//
+// The tracer is not safe for concurrent block processing, but its results may
+// be retrieved concurrently.
+type TransferTracer struct {
+	cache   *lru.Cache // Transfers of recently processed blocks, keyed by hash
+	sealing bool       // Whether the tracer records a block being assembled locally
+
+	block   *types.Block       // Block currently being processed
+	tx      *types.Transaction // Transaction currently being executed
+	index   int                // Index of tx within block
+	list    []*Transfer        // Transfers of block made by previous transactions
+	current []*Transfer        // Transfers made by the current transaction
+	pending []pendingTransfer  // Calls and creations awaiting their outcome
+}
+
+// NewTransferTracer creates a tracer recording the value transfers of the most
+// recently processed blocks.
+func NewTransferTracer() *TransferTracer {
+	cache, _ := lru.New(transferCacheLimit)
+	return &TransferTracer{cache: cache}
+}
+
+// Transfers returns the value transfers made by the block with the given hash,
+// or nil if there were none or the block was not processed recently.
+func (t *TransferTracer) Transfers(hash common.Hash) []*Transfer {
+	if list, ok := t.cache.Get(hash); ok {
+		return list.([]*Transfer)
+	}
+	return nil
+}
+
+// Fork implements core.SealingTracer, creating a tracer for the transactions of
+// a block being assembled locally. Its transfers are retained once the block is
+// sealed.
+func (t *TransferTracer) Fork() core.SealingTracer {
+	return &TransferTracer{cache: t.cache, sealing: true}
+}
+
+// Seal implements core.SealingTracer, retaining the transfers of an assembled
+// block under the hash of the sealed one.
+func (t *TransferTracer) Seal(block *types.Block) {
+	if len(t.list) == 0 {
+		return
+	}
+	list := make([]*Transfer, len(t.list))
+	for i, transfer := range t.list {
+		cpy := *transfer
+		cpy.BlockHash = block.Hash()
+		list[i] = &cpy
+	}
+	t.cache.Add(block.Hash(), list)
+}
+
+// CaptureTransaction implements core.BlockTracer, starting the tracing of a new
+// transaction. The hash of a block being assembled changes with every applied
+// transaction, so it only starts a new block at its first transaction.
+func (t *TransferTracer) CaptureTransaction(block *types.Block, tx *types.Transaction, index int) {
+	if t.sealing {
+		if t.block == nil || index == 0 {
+			t.block, t.list = block, nil
+		}
+	} else if t.block == nil || t.block.Hash() != block.Hash() || index == 0 {
+		t.block, t.list = block, nil
+		t.cache.Remove(block.Hash())
+	}
+	t.tx, t.index = tx, index
+	t.current, t.pending = nil, nil
+}
+
+// CaptureStart implements vm.Tracer, recording the transaction's own transfer.
+func (t *TransferTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.record(from, to, value)
+	return nil
+}
+
+// CaptureState implements vm.Tracer, recording the transfers of value carrying
+// operations and resolving the outcome of earlier ones once their issuing frame
+// resumes execution.
+func (t *TransferTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	// Failing operations are reported twice, skip the post-mortem
+	if err != nil || t.tx == nil {
+		return nil
+	}
+	// Resolve any call or creation issued by this frame: the operation's result
+	// is now on the top of the stack
+	for len(t.pending) > 0 && t.pending[len(t.pending)-1].depth >= depth {
+		p := t.pending[len(t.pending)-1]
+		t.pending = t.pending[:len(t.pending)-1]
+
+		if p.depth > depth {
+			continue // Frame aborted, its parent handles the outcome
+		}
+		if result := stack.Back(0); result.Sign() == 0 {
+			t.current = t.current[:p.index]
+		} else if p.transfer.To == (common.Address{}) {
+			p.transfer.To = common.BigToAddress(result)
+		}
+	}
+	switch op {
+	case vm.CALL:
+		if value := stack.Back(2); value.Sign() > 0 {
+			t.issue(depth, contract.Address(), common.BigToAddress(stack.Back(1)), value)
+		}
+	case vm.CREATE:
+		if value := stack.Back(0); value.Sign() > 0 {
+			t.issue(depth, contract.Address(), common.Address{}, value)
+		}
+	case vm.SELFDESTRUCT:
+		beneficiary := common.BigToAddress(stack.Back(0))
+		if beneficiary != contract.Address() {
+			t.record(contract.Address(), beneficiary, env.StateDB.GetBalance(contract.Address()))
+		}
+	}
+	return nil
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *TransferTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer, committing the transfers of the transaction
+// if it executed successfully.
+func (t *TransferTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	if t.tx == nil {
+		return nil
+	}
+	if err == nil && len(t.current) > 0 {
+		t.list = append(t.list, t.current...)
+		if !t.sealing {
+			t.cache.Add(t.block.Hash(), t.list)
+		}
+	}
+	t.tx, t.current, t.pending = nil, nil, nil
+	return nil
+}
+
+// issue records a transfer whose outcome is only known once the issuing frame
+// resumes execution.
+func (t *TransferTracer) issue(depth int, from, to common.Address, value *big.Int) {
+	t.pending = append(t.pending, pendingTransfer{depth: depth, index: len(t.current)})
+	t.record(from, to, value)
+	t.pending[len(t.pending)-1].transfer = t.current[len(t.current)-1]
+}
+
+// record appends a transfer made by the current transaction.
+func (t *TransferTracer) record(from, to common.Address, value *big.Int) {
+	if t.tx == nil || value == nil || value.Sign() == 0 {
+		return
+	}
+	t.current = append(t.current, &Transfer{
+		From:        from,
+		To:          to,
+		Value:       (*hexutil.Big)(new(big.Int).Set(value)),
+		BlockNumber: hexutil.Uint64(t.block.NumberU64()),
+		BlockHash:   t.block.Hash(),
+		TxHash:      t.tx.Hash(),
+		TxIndex:     hexutil.Uint(t.index),
+	})
+}
+
+// filterTransfers returns the transfers sent from or to any of the given
+// addresses, or all of them if no addresses are given.
+func filterTransfers(transfers []*Transfer, addresses []common.Address) []*Transfer {
+	if len(addresses) == 0 {
+		return transfers
+	}
+	var ret []*Transfer
+	for _, transfer := range transfers {
+		if includes(addresses, transfer.From) || includes(addresses, transfer.To) {
+			ret = append(ret, transfer)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,269 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/consensus/ethash"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/vm"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
+	"github.com/Ethereum-Reloaded/ETHR-Go/event"
+	"github.com/Ethereum-Reloaded/ETHR-Go/params"
+)
+
+var (
+	transferKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	transferAddr    = crypto.PubkeyToAddress(transferKey.PublicKey)
+	transferSigner  = types.HomesteadSigner{}
+	forwarderAddr   = common.HexToAddress("0x00000000000000000000000000000000000000f0")
+	revertingAddr   = common.HexToAddress("0x00000000000000000000000000000000000000f1")
+	badForwardAddr  = common.HexToAddress("0x00000000000000000000000000000000000000f2")
+	transferTarget  = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	transferGenesis = &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			transferAddr:   {Balance: big.NewInt(1000000000000000000)},
+			forwarderAddr:  {Code: forwarderCode(transferTarget), Balance: new(big.Int)},
+			revertingAddr:  {Code: common.FromHex("60006000fd"), Balance: new(big.Int)},
+			badForwardAddr: {Code: forwarderCode(revertingAddr), Balance: new(big.Int)},
+		},
+	}
+)
+
+// forwarderCode returns contract code forwarding the value it receives to the
+// given address, ignoring the outcome of the forwarding call.
+func forwarderCode(to common.Address) []byte {
+	code := common.FromHex("600060006000600034" + "73")
+	code = append(code, to.Bytes()...)
+	return append(code, common.FromHex("5af15000")...)
+}
+
+// transferTx creates a signed value transfer to the given address.
+func transferTx(nonce uint64, to common.Address, value int64, gas uint64) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(value), gas, big.NewInt(1), nil), transferSigner, transferKey)
+	return tx
+}
+
+// newTransferChain creates a blockchain recording the transfers of imported
+// blocks with the returned tracer.
+func newTransferChain(t *testing.T) (ethdb.Database, *core.BlockChain, *TransferTracer) {
+	var (
+		db     = ethdb.NewMemDatabase()
+		tracer = NewTransferTracer()
+	)
+	transferGenesis.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{Debug: true, Tracer: tracer})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	return db, chain, tracer
+}
+
+// generateTransfers generates a chain segment on top of the genesis block,
+// adding the transactions returned by txs to each block.
+func generateTransfers(n int, txs func(i int, nonce uint64) []*types.Transaction) []*types.Block {
+	db := ethdb.NewMemDatabase()
+	genesis := transferGenesis.MustCommit(db)
+
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, n, func(i int, gen *core.BlockGen) {
+		for _, tx := range txs(i, gen.TxNonce(transferAddr)) {
+			gen.AddTx(tx)
+		}
+	})
+	return blocks
+}
+
+// Tests that top-level and internal value transfers are recorded, unless they
+// are reverted.
+func TestTransferTracer(t *testing.T) {
+	_, chain, tracer := newTransferChain(t)
+	defer chain.Stop()
+
+	blocks := generateTransfers(1, func(i int, nonce uint64) []*types.Transaction {
+		return []*types.Transaction{
+			transferTx(nonce, transferTarget, 1, 21000),    // Plain transfer
+			transferTx(nonce+1, forwarderAddr, 2, 100000),  // Forwarded transfer
+			transferTx(nonce+2, badForwardAddr, 3, 100000), // Forwarding reverted internally
+			transferTx(nonce+3, revertingAddr, 4, 100000),  // Reverted transaction
+			transferTx(nonce+4, forwarderAddr, 0, 100000),  // No value moved
+		}
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	want := []struct {
+		from, to common.Address
+		value    int64
+		tx       int
+	}{
+		{transferAddr, transferTarget, 1, 0},
+		{transferAddr, forwarderAddr, 2, 1},
+		{forwarderAddr, transferTarget, 2, 1},
+		{transferAddr, badForwardAddr, 3, 2},
+	}
+	have := tracer.Transfers(blocks[0].Hash())
+	if len(have) != len(want) {
+		t.Fatalf("transfer count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i, transfer := range have {
+		if transfer.From != want[i].from || transfer.To != want[i].to || transfer.Value.ToInt().Int64() != want[i].value {
+			t.Errorf("transfer %d: have %x -> %x (%v), want %x -> %x (%d)", i, transfer.From, transfer.To, transfer.Value, want[i].from, want[i].to, want[i].value)
+		}
+		if transfer.TxHash != blocks[0].Transactions()[want[i].tx].Hash() || int(transfer.TxIndex) != want[i].tx {
+			t.Errorf("transfer %d: transaction mismatch: have %x (#%d), want #%d", i, transfer.TxHash, transfer.TxIndex, want[i].tx)
+		}
+		if transfer.BlockHash != blocks[0].Hash() || uint64(transfer.BlockNumber) != 1 {
+			t.Errorf("transfer %d: block mismatch: have %x (#%d)", i, transfer.BlockHash, transfer.BlockNumber)
+		}
+	}
+}
+
+// Tests that the transfers of a locally assembled block are only retained once
+// it is sealed, under the hash of the sealed block.
+func TestTransferTracerSealing(t *testing.T) {
+	_, chain, tracer := newTransferChain(t)
+	defer chain.Stop()
+
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	var (
+		header = &types.Header{ParentHash: chain.Genesis().Hash(), Number: big.NewInt(1), GasLimit: chain.Genesis().GasLimit(), Difficulty: big.NewInt(1), Time: big.NewInt(1)}
+		txs    = []*types.Transaction{transferTx(0, transferTarget, 1, 21000), transferTx(1, forwarderAddr, 2, 100000)}
+		gp     = new(core.GasPool).AddGas(header.GasLimit)
+		fork   = tracer.Fork()
+	)
+	for i, tx := range txs {
+		statedb.Prepare(tx.Hash(), common.Hash{}, i)
+		fork.CaptureTransaction(types.NewBlockWithHeader(header), tx, i)
+		if _, _, err := core.ApplyTransaction(params.TestChainConfig, chain, &common.Address{}, gp, statedb, header, tx, &header.GasUsed, vm.Config{Debug: true, Tracer: fork}); err != nil {
+			t.Fatalf("failed to apply transaction %d: %v", i, err)
+		}
+	}
+	if have := tracer.Transfers(header.Hash()); have != nil {
+		t.Fatalf("transfers of unsealed block retained: %d", len(have))
+	}
+	sealed := types.CopyHeader(header)
+	sealed.Nonce = types.EncodeNonce(1)
+	block := types.NewBlockWithHeader(sealed)
+	fork.Seal(block)
+
+	have := tracer.Transfers(block.Hash())
+	if len(have) != 3 {
+		t.Fatalf("transfer count mismatch: have %d, want 3", len(have))
+	}
+	for i, transfer := range have {
+		if transfer.BlockHash != block.Hash() || uint64(transfer.BlockNumber) != 1 {
+			t.Errorf("transfer %d: block mismatch: have %x (#%d)", i, transfer.BlockHash, transfer.BlockNumber)
+		}
+		if tx := int(transfer.TxIndex); transfer.TxHash != txs[tx].Hash() {
+			t.Errorf("transfer %d: transaction mismatch: have %x (#%d)", i, transfer.TxHash, tx)
+		}
+	}
+}
+
+// transferTestBackend is a filter backend serving the transfers of a tracer.
+type transferTestBackend struct {
+	*testBackend
+	tracer *TransferTracer
+}
+
+func (b *transferTestBackend) GetTransfers(ctx context.Context, hash common.Hash) ([]*Transfer, error) {
+	return b.tracer.Transfers(hash), nil
+}
+
+// Tests that transfer subscriptions receive the transfers of new blocks matching
+// their addresses, and that transfers of blocks reorganised away are resent as
+// removed.
+func TestTransferSubscription(t *testing.T) {
+	db, chain, tracer := newTransferChain(t)
+	defer chain.Stop()
+
+	var (
+		other     = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		chainFeed = new(event.Feed)
+		backend   = &transferTestBackend{&testBackend{new(event.TypeMux), db, 0, new(event.Feed), new(event.Feed), new(event.Feed), chainFeed}, tracer}
+		api       = NewPublicFilterAPI(backend, false, Config{Transfers: true})
+
+		transfers = make(chan []*Transfer)
+		sub       = api.events.SubscribeTransfers([]common.Address{transferTarget}, transfers)
+	)
+	defer sub.Unsubscribe()
+
+	// Transfer subscriptions must be enabled explicitly
+	if _, err := NewPublicFilterAPI(backend, false, DefaultConfig).Transfers(context.Background(), nil); err != errTransfersUnsupported {
+		t.Fatalf("subscription error mismatch: have %v, want %v", err, errTransfersUnsupported)
+	}
+	oldChain := generateTransfers(2, func(i int, nonce uint64) []*types.Transaction {
+		return []*types.Transaction{transferTx(nonce, forwarderAddr, int64(i+1), 100000)}
+	})
+	newChain := generateTransfers(3, func(i int, nonce uint64) []*types.Transaction {
+		return []*types.Transaction{transferTx(nonce, other, int64(i+1), 21000)}
+	})
+	chainFeed.Send(core.ChainEvent{Block: chain.Genesis()})
+	if _, err := chain.InsertChain(oldChain); err != nil {
+		t.Fatalf("failed to insert old chain: %v", err)
+	}
+	chainFeed.Send(core.ChainEvent{Block: oldChain[1]})
+	if _, err := chain.InsertChain(newChain); err != nil {
+		t.Fatalf("failed to insert new chain: %v", err)
+	}
+	chainFeed.Send(core.ChainEvent{Block: newChain[2]})
+
+	want := []struct {
+		block   common.Hash
+		value   int64
+		removed bool
+	}{
+		{oldChain[0].Hash(), 1, false},
+		{oldChain[1].Hash(), 2, false},
+		{oldChain[1].Hash(), 2, true},
+		{oldChain[0].Hash(), 1, true},
+	}
+	for i := range want {
+		select {
+		case matched := <-transfers:
+			if len(matched) != 1 {
+				t.Fatalf("notification %d: transfer count mismatch: have %d, want 1", i, len(matched))
+			}
+			transfer := matched[0]
+			if transfer.From != forwarderAddr || transfer.To != transferTarget {
+				t.Errorf("notification %d: unexpected transfer %x -> %x", i, transfer.From, transfer.To)
+			}
+			if transfer.BlockHash != want[i].block || transfer.Value.ToInt().Int64() != want[i].value || transfer.Removed != want[i].removed {
+				t.Errorf("notification %d: have block %x, value %v, removed %v; want block %x, value %d, removed %v",
+					i, transfer.BlockHash, transfer.Value, transfer.Removed, want[i].block, want[i].value, want[i].removed)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("notification %d: timeout", i)
+		}
+	}
+	select {
+	case matched := <-transfers:
+		t.Fatalf("unexpected notification: %v", matched)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/consensus"
 	"github.com/Ethereum-Reloaded/ETHR-Go/consensus/misc"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/forkid"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth/downloader"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth/fetcher"
@@ -38,6 +39,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
 	"github.com/Ethereum-Reloaded/ETHR-Go/params"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
 )
@@ -59,8 +61,18 @@ var (
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
 
+// protocolError is a violation of the eth protocol by a remote peer.
+type protocolError struct {
+	code errCode
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%v - %v", e.code, e.msg)
+}
+
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protocolError{code, fmt.Sprintf(format, v...)}
 }
 
 type ProtocolManager struct {
@@ -73,9 +85,11 @@ type ProtocolManager struct {
 	blockchain  *core.BlockChain
 	chainconfig *params.ChainConfig
 	maxPeers    int
+	forkFilter  forkid.Filter // Fork ID filter, constant across the lifetime of the node
 
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
+	txFetcher  *fetcher.TxFetcher
 	peers      *peerSet
 
 	SubProtocols []p2p.Protocol
@@ -106,6 +120,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		txpool:      txpool,
 		blockchain:  blockchain,
 		chainconfig: config,
+		forkFilter:  forkid.NewFilter(blockchain),
 		peers:       newPeerSet(),
 		newPeerCh:   make(chan *peer),
 		noMorePeers: make(chan struct{}),
@@ -153,13 +168,15 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 				}
 				return nil
 			},
+			Attributes: []enr.Entry{currentENREntry(blockchain, networkId)},
+			DialFilter: nodeFilter(networkId, manager.forkFilter),
 		})
 	}
 	if len(manager.SubProtocols) == 0 {
 		return nil, errIncompatibleConfig
 	}
 	// Construct the different synchronisation mechanisms
-	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.syncDrop)
 
 	validator := func(header *types.Header) error {
 		return engine.VerifyHeader(blockchain, header, true)
@@ -176,7 +193,12 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		atomic.StoreUint32(&manager.acceptTxs, 1) // Mark initial sync done on any fetcher import
 		return manager.blockchain.InsertChain(blocks)
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.penalizingDrop(p2p.PenaltySevere, "invalid block propagation"))
+
+	hasTx := func(hash common.Hash) bool {
+		return txpool.Get(hash) != nil
+	}
+	manager.txFetcher = fetcher.NewTxFetcher(hasTx, txpool.AddRemotes)
 
 	return manager, nil
 }
@@ -189,8 +211,9 @@ func (pm *ProtocolManager) removePeer(id string) {
 	}
 	log.Debug("Removing Ethereum peer", "peer", id)
 
-	// Unregister the peer from the downloader and Ethereum peer set
+	// Unregister the peer from the downloader, fetchers and Ethereum peer set
 	pm.downloader.UnregisterPeer(id)
+	pm.txFetcher.Drop(id)
 	if err := pm.peers.Unregister(id); err != nil {
 		log.Error("Peer removal failed", "peer", id, "err", err)
 	}
@@ -200,6 +223,33 @@ func (pm *ProtocolManager) removePeer(id string) {
 	}
 }
 
+// penalizingDrop returns a peer drop callback for the fetcher, which penalizes
+// the misbehaving peer before removing it.
+func (pm *ProtocolManager) penalizingDrop(amount uint, reason string) func(string) {
+	return func(id string) {
+		pm.penalizeAndRemove(id, amount, reason)
+	}
+}
+
+// syncDrop is the peer drop callback of the downloader. Peers which failed to
+// deliver in time are only penalized slightly, since slow peers are not
+// necessarily malicious.
+func (pm *ProtocolManager) syncDrop(id string, err error) {
+	amount := uint(p2p.PenaltyMajor)
+	if downloader.IsTimeout(err) {
+		amount = p2p.PenaltyMinor
+	}
+	pm.penalizeAndRemove(id, amount, "failed chain sync: "+err.Error())
+}
+
+// penalizeAndRemove penalizes a misbehaving peer and removes it.
+func (pm *ProtocolManager) penalizeAndRemove(id string, amount uint, reason string) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		peer.Penalize(amount, reason)
+	}
+	pm.removePeer(id)
+}
+
 func (pm *ProtocolManager) Start(maxPeers int) {
 	pm.maxPeers = maxPeers
 
@@ -213,6 +263,7 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 	go pm.minedBroadcastLoop()
 
 	// start sync handlers
+	pm.txFetcher.Start()
 	go pm.syncer()
 	go pm.txsyncLoop()
 }
@@ -229,6 +280,7 @@ func (pm *ProtocolManager) Stop() {
 
 	// Quit fetcher, txsyncLoop.
 	close(pm.quitSync)
+	pm.txFetcher.Stop()
 
 	// Disconnect existing sessions.
 	// This also closes the gate for any new registrations on the peer set.
@@ -263,7 +315,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		number  = head.Number.Uint64()
 		td      = pm.blockchain.GetTd(hash, number)
 	)
-	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash()); err != nil {
+	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash(), forkid.NewID(pm.blockchain), pm.forkFilter); err != nil {
 		p.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
@@ -308,6 +360,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	for {
 		if err := pm.handleMsg(p); err != nil {
 			p.Log().Debug("Ethereum message handling failed", "err", err)
+			if _, ok := err.(*protocolError); ok {
+				p.Penalize(p2p.PenaltyMajor, err.Error())
+			}
 			return err
 		}
 	}
@@ -671,7 +726,70 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.MarkTransaction(tx.Hash())
 		}
-		pm.txpool.AddRemotes(txs)
+		pm.enqueueTxs(p, txs, false)
+
+	case p.version >= eth65 && msg.Code == NewPooledTransactionHashesMsg:
+		// New transactions were announced, make sure we're ready to handle them
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+			break
+		}
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		// Mark the hashes as present at the remote node and schedule the unknown ones
+		for _, hash := range hashes {
+			p.MarkTransaction(hash)
+		}
+		pm.txFetcher.Notify(p.id, hashes, time.Now(), p.RequestTxs)
+
+	case p.version >= eth65 && msg.Code == GetPooledTransactionsMsg:
+		// Decode the retrieval message
+		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
+		if _, err := msgStream.List(); err != nil {
+			return err
+		}
+		// Gather transactions until the fetch or network limits is reached
+		var (
+			hash   common.Hash
+			hashes int
+			bytes  int
+			txs    []rlp.RawValue
+		)
+		for ; bytes < softResponseLimit && hashes < fetcher.MaxTxFetch; hashes++ {
+			// Retrieve the hash of the next transaction
+			if err := msgStream.Decode(&hash); err == rlp.EOL {
+				break
+			} else if err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			// Retrieve the requested transaction, skipping if unknown to us
+			tx := pm.txpool.Get(hash)
+			if tx == nil {
+				continue
+			}
+			if encoded, err := rlp.EncodeToBytes(tx); err != nil {
+				log.Error("Failed to encode transaction", "err", err)
+			} else {
+				txs = append(txs, encoded)
+				bytes += len(encoded)
+			}
+		}
+		return p.SendPooledTransactionsRLP(txs)
+
+	case p.version >= eth65 && msg.Code == PooledTransactionsMsg:
+		// Transactions we requested arrived, deliver them even if not in sync
+		var txs []*types.Transaction
+		if err := msg.Decode(&txs); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for i, tx := range txs {
+			if tx == nil {
+				return errResp(ErrDecode, "transaction %d is nil", i)
+			}
+			p.MarkTransaction(tx.Hash())
+		}
+		pm.enqueueTxs(p, txs, true)
 
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
@@ -713,15 +831,22 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 }
 
 // BroadcastTxs will propagate a batch of transactions to all peers which are not known to
-// already have the given transaction.
+// already have the given transaction. Peers supporting eth/65 only get the hashes
+// announced and retrieve the transactions they lack.
 func (pm *ProtocolManager) BroadcastTxs(txs types.Transactions) {
-	var txset = make(map[*peer]types.Transactions)
-
+	var (
+		txset   = make(map[*peer]types.Transactions)
+		annoset = make(map[*peer][]common.Hash)
+	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
 		peers := pm.peers.PeersWithoutTx(tx.Hash())
 		for _, peer := range peers {
-			txset[peer] = append(txset[peer], tx)
+			if peer.version >= eth65 {
+				annoset[peer] = append(annoset[peer], tx.Hash())
+			} else {
+				txset[peer] = append(txset[peer], tx)
+			}
 		}
 		log.Trace("Broadcast transaction", "hash", tx.Hash(), "recipients", len(peers))
 	}
@@ -729,6 +854,9 @@ func (pm *ProtocolManager) BroadcastTxs(txs types.Transactions) {
 	for peer, txs := range txset {
 		peer.AsyncSendTransactions(txs)
 	}
+	for peer, hashes := range annoset {
+		peer.AsyncSendPooledTransactionHashes(hashes)
+	}
 }
 
 // Mined broadcast loop
@@ -777,3 +905,25 @@ func (pm *ProtocolManager) NodeInfo() *NodeInfo {
 		Head:       currentBlock.Hash(),
 	}
 }
+
+// enqueueTxs delivers transactions received from a peer to the pool through the
+// transaction fetcher, penalizing the peer if any of them is invalid. Direct
+// deliveries are responses to transaction requests.
+func (pm *ProtocolManager) enqueueTxs(p *peer, txs []*types.Transaction, direct bool) {
+	for _, err := range pm.txFetcher.Enqueue(p.id, txs, direct) {
+		if invalidTx(err) {
+			p.Penalize(p2p.PenaltyMinor, err.Error())
+			break
+		}
+	}
+}
+
+// invalidTx reports whether a transaction pool error means the transaction
+// could never have been valid, as opposed to being stale or underpriced.
+func invalidTx(err error) bool {
+	switch err {
+	case core.ErrInvalidSender, core.ErrNegativeValue, core.ErrOversizedData, core.ErrIntrinsicGas:
+		return true
+	}
+	return false
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/consensus/ethash"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/forkid"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/state"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/vm"
@@ -34,6 +35,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
 	"github.com/Ethereum-Reloaded/ETHR-Go/event"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
 	"github.com/Ethereum-Reloaded/ETHR-Go/params"
 )
 
@@ -45,8 +47,8 @@ func TestProtocolCompatibility(t *testing.T) {
 		mode       downloader.SyncMode
 		compatible bool
 	}{
-		{61, downloader.FullSync, true}, {62, downloader.FullSync, true}, {63, downloader.FullSync, true},
-		{61, downloader.FastSync, false}, {62, downloader.FastSync, false}, {63, downloader.FastSync, true},
+		{61, downloader.FullSync, true}, {62, downloader.FullSync, true}, {63, downloader.FullSync, true}, {64, downloader.FullSync, true},
+		{61, downloader.FastSync, false}, {62, downloader.FastSync, false}, {63, downloader.FastSync, true}, {64, downloader.FastSync, true},
 	}
 	// Make sure anything we screw up is restored
 	backup := ProtocolVersions
@@ -66,6 +68,35 @@ func TestProtocolCompatibility(t *testing.T) {
 	}
 }
 
+// Tests that dial candidates are filtered based on the eth entry of their node
+// record.
+func TestNodeFilter(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	local := currentENREntry(pm.blockchain, pm.networkId)
+	tests := []struct {
+		entry *enrEntry
+		want  bool
+	}{
+		{local, true},
+		{&enrEntry{ForkID: local.ForkID, NetworkID: pm.networkId + 1}, false},
+		{&enrEntry{ForkID: forkid.ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}}, NetworkID: pm.networkId}, false},
+		{nil, false},
+	}
+	for i, tt := range tests {
+		var record enr.Record
+		if tt.entry != nil {
+			record.Set(tt.entry)
+		}
+		for _, proto := range pm.SubProtocols {
+			if have := proto.DialFilter(&record); have != tt.want {
+				t.Errorf("test %d, eth/%d: filter mismatch: have %v, want %v", i, proto.Version, have, tt.want)
+			}
+		}
+	}
+}
+
 // Tests that block headers can be retrieved from a remote chain based on user queries.
 func TestGetBlockHeaders62(t *testing.T) { testGetBlockHeaders(t, 62) }
 func TestGetBlockHeaders63(t *testing.T) { testGetBlockHeaders(t, 63) }
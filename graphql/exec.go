@@ -0,0 +1,588 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+)
+
+// maxQueryDepth is the maximum nesting depth of the fields selected by a query.
+const maxQueryDepth = 10
+
+// resolver is an object type of the schema, able to resolve its own fields.
+// Resolved values may be scalars (anything JSON marshallable), other resolvers,
+// or slices of either. Nil pointers and interfaces resolve to null.
+type resolver interface {
+	// typeName returns the name of the schema type the resolver implements.
+	typeName() string
+
+	// resolve returns the value of the requested field.
+	resolve(ctx context.Context, field string, args arguments) (interface{}, error)
+}
+
+// request is the body of a GraphQL request.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// response is the body of a GraphQL response.
+type response struct {
+	Data   interface{}   `json:"data"`
+	Errors []*fieldError `json:"errors,omitempty"`
+}
+
+// fieldError is an error raised while executing a query, tagged with the path
+// of the field that produced it.
+type fieldError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// execute parses and runs a query against the root resolver. Errors of the
+// request itself are reported without any data, whereas field errors null out
+// the failing field and are collected alongside the partial result.
+func execute(ctx context.Context, root resolver, req *request) *response {
+	doc, err := parseQuery(req.Query)
+	if err != nil {
+		return &response{Errors: []*fieldError{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &response{Errors: []*fieldError{{Message: err.Error()}}}
+	}
+	vars, err := op.variables(req.Variables)
+	if err != nil {
+		return &response{Errors: []*fieldError{{Message: err.Error()}}}
+	}
+	if depth := doc.depth(op.selections, make(map[string]bool), make(map[string]int)); depth > maxQueryDepth {
+		return &response{Errors: []*fieldError{{Message: fmt.Sprintf("query depth %d exceeds limit %d", depth, maxQueryDepth)}}}
+	}
+	e := &executor{doc: doc, vars: vars}
+	data := e.object(ctx, root, op.selections, nil)
+	return &response{Data: data, Errors: e.errors}
+}
+
+// operation selects the operation to execute from the document.
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("operation name required for documents with multiple operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// depth returns the nesting depth of the fields in a selection set, expanding
+// fragments. Fragments spreading themselves are not followed again, and the
+// depths of fragments are memoized so that reusing them stays cheap.
+func (doc *document) depth(sels []selection, visiting map[string]bool, memo map[string]int) int {
+	var max int
+	for _, sel := range sels {
+		var d int
+		switch sel := sel.(type) {
+		case *field:
+			d = 1 + doc.depth(sel.selections, visiting, memo)
+		case *fragmentSpread:
+			frag, ok := doc.fragments[sel.name]
+			if !ok || visiting[sel.name] {
+				continue
+			}
+			if cached, ok := memo[sel.name]; ok {
+				d = cached
+				break
+			}
+			visiting[sel.name] = true
+			d = doc.depth(frag.selections, visiting, memo)
+			delete(visiting, sel.name)
+			memo[sel.name] = d
+		case *inlineFragment:
+			d = doc.depth(sel.selections, visiting, memo)
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// variables assembles the variable values of the operation from the ones
+// supplied with the request and the declared defaults.
+func (op *operation) variables(supplied map[string]interface{}) (map[string]interface{}, error) {
+	vars := make(map[string]interface{})
+	for _, v := range op.vars {
+		if val, ok := supplied[v.name]; ok && val != nil {
+			vars[v.name] = val
+			continue
+		}
+		if v.def != nil {
+			vars[v.name] = v.def
+			continue
+		}
+		if v.required {
+			return nil, fmt.Errorf("missing value for required variable $%s", v.name)
+		}
+	}
+	return vars, nil
+}
+
+// executor walks the selection sets of an operation, resolving fields.
+type executor struct {
+	doc    *document
+	vars   map[string]interface{}
+	errors []*fieldError
+}
+
+// fail records a field error at the given path.
+func (e *executor) fail(path []interface{}, err error) {
+	e.errors = append(e.errors, &fieldError{
+		Message: err.Error(),
+		Path:    append([]interface{}{}, path...),
+	})
+}
+
+// object resolves a selection set on an object, keeping the fields in the
+// order they were requested.
+func (e *executor) object(ctx context.Context, obj resolver, sels []selection, path []interface{}) *orderedObject {
+	groups, err := e.collect(obj.typeName(), sels, nil, make(map[string]bool))
+	if err != nil {
+		e.fail(path, err)
+		return nil
+	}
+	out := &orderedObject{values: make(map[string]interface{})}
+	for _, group := range groups {
+		f := group.fields[0]
+		fpath := append(path, group.key)
+
+		out.keys = append(out.keys, group.key)
+		if f.name == "__typename" {
+			out.values[group.key] = obj.typeName()
+			continue
+		}
+		args, err := e.arguments(f.args)
+		if err != nil {
+			e.fail(fpath, err)
+			out.values[group.key] = nil
+			continue
+		}
+		val, err := obj.resolve(ctx, f.name, args)
+		if err != nil {
+			e.fail(fpath, err)
+			out.values[group.key] = nil
+			continue
+		}
+		// Merge the sub-selections of all fields sharing the response key
+		var subs []selection
+		for _, f := range group.fields {
+			subs = append(subs, f.selections...)
+		}
+		out.values[group.key] = e.value(ctx, val, f, subs, fpath)
+	}
+	return out
+}
+
+// value completes a resolved field value according to its sub-selections.
+func (e *executor) value(ctx context.Context, val interface{}, f *field, subs []selection, path []interface{}) interface{} {
+	if isNil(val) {
+		return nil
+	}
+	if obj, ok := val.(resolver); ok {
+		if len(subs) == 0 {
+			e.fail(path, fmt.Errorf("field %q of type %s must have a selection of subfields", f.name, obj.typeName()))
+			return nil
+		}
+		return e.object(ctx, obj, subs, path)
+	}
+	if _, ok := val.(json.Marshaler); !ok {
+		if rv := reflect.ValueOf(val); rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+			list := make([]interface{}, rv.Len())
+			for i := range list {
+				list[i] = e.value(ctx, rv.Index(i).Interface(), f, subs, append(path, i))
+			}
+			return list
+		}
+	}
+	if len(subs) > 0 {
+		e.fail(path, fmt.Errorf("field %q must not have a selection since it is a scalar", f.name))
+		return nil
+	}
+	return val
+}
+
+// fieldGroup is the list of fields requested under the same response key.
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+// collect flattens a selection set into the fields requested on the given type,
+// expanding fragments and evaluating @skip and @include directives.
+func (e *executor) collect(typ string, sels []selection, groups []*fieldGroup, visited map[string]bool) ([]*fieldGroup, error) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *field:
+			include, err := e.included(sel.directives)
+			if err != nil {
+				return nil, err
+			}
+			if !include {
+				continue
+			}
+			key, found := sel.key(), false
+			for _, group := range groups {
+				if group.key == key {
+					group.fields = append(group.fields, sel)
+					found = true
+					break
+				}
+			}
+			if !found {
+				groups = append(groups, &fieldGroup{key: key, fields: []*field{sel}})
+			}
+
+		case *fragmentSpread:
+			include, err := e.included(sel.directives)
+			if err != nil {
+				return nil, err
+			}
+			if !include || visited[sel.name] {
+				continue
+			}
+			visited[sel.name] = true
+
+			frag, ok := e.doc.fragments[sel.name]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %q", sel.name)
+			}
+			if frag.on != typ {
+				continue
+			}
+			if groups, err = e.collect(typ, frag.selections, groups, visited); err != nil {
+				return nil, err
+			}
+
+		case *inlineFragment:
+			include, err := e.included(sel.directives)
+			if err != nil {
+				return nil, err
+			}
+			if !include || (sel.on != "" && sel.on != typ) {
+				continue
+			}
+			if groups, err = e.collect(typ, sel.selections, groups, visited); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return groups, nil
+}
+
+// included evaluates the @skip and @include directives of a selection.
+func (e *executor) included(dirs []*directive) (bool, error) {
+	for _, dir := range dirs {
+		if dir.name != "skip" && dir.name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", dir.name)
+		}
+		args, err := e.arguments(dir.args)
+		if err != nil {
+			return false, err
+		}
+		cond, ok := args["if"].(bool)
+		if !ok {
+			return false, fmt.Errorf("directive @%s requires a boolean 'if' argument", dir.name)
+		}
+		if cond == (dir.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// arguments substitutes the variable references in the field arguments.
+func (e *executor) arguments(args map[string]interface{}) (arguments, error) {
+	out := make(arguments, len(args))
+	for name, val := range args {
+		val, err := e.substitute(val)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = val
+	}
+	return out, nil
+}
+
+func (e *executor) substitute(val interface{}) (interface{}, error) {
+	switch val := val.(type) {
+	case varRef:
+		v, ok := e.vars[string(val)]
+		if !ok {
+			if !e.declared(string(val)) {
+				return nil, fmt.Errorf("undeclared variable $%s", val)
+			}
+		}
+		return v, nil
+
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, v := range val {
+			v, err := e.substitute(v)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			v, err := e.substitute(v)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = v
+		}
+		return out, nil
+	}
+	return val, nil
+}
+
+// declared reports whether a variable was declared by any operation. Variables
+// without a supplied value or default resolve to null.
+func (e *executor) declared(name string) bool {
+	for _, op := range e.doc.operations {
+		for _, v := range op.vars {
+			if v.name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isNil reports whether val is nil or a typed nil pointer, map or slice.
+func isNil(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(val); rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// orderedObject is a JSON object preserving the order of its keys, as GraphQL
+// responses must list fields in the order they were requested.
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// arguments are the argument values of a field, with variables substituted.
+type arguments map[string]interface{}
+
+// has reports whether the argument was specified and is not null.
+func (args arguments) has(name string) bool {
+	return args[name] != nil
+}
+
+// object returns an input object argument.
+func (args arguments) object(name string) (arguments, error) {
+	switch val := args[name].(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return arguments(val), nil
+	default:
+		return nil, fmt.Errorf("argument %q: expected input object", name)
+	}
+}
+
+// list returns a list argument. Single values are coerced into a one element
+// list, as the GraphQL input coercion rules demand.
+func (args arguments) list(name string) []interface{} {
+	switch val := args[name].(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return val
+	default:
+		return []interface{}{val}
+	}
+}
+
+// long returns a Long argument, accepting both integers and hex or decimal
+// strings.
+func (args arguments) long(name string) (int64, error) {
+	n, err := parseLong(args[name])
+	if err != nil {
+		return 0, fmt.Errorf("argument %q: %v", name, err)
+	}
+	return n, nil
+}
+
+// bigInt returns a BigInt argument, accepting both integers and hex or decimal
+// strings.
+func (args arguments) bigInt(name string) (*big.Int, error) {
+	n, err := parseBigInt(args[name])
+	if err != nil {
+		return nil, fmt.Errorf("argument %q: %v", name, err)
+	}
+	return n, nil
+}
+
+// address returns an Address argument.
+func (args arguments) address(name string) (common.Address, error) {
+	addr, err := parseAddress(args[name])
+	if err != nil {
+		return common.Address{}, fmt.Errorf("argument %q: %v", name, err)
+	}
+	return addr, nil
+}
+
+// hash returns a Bytes32 argument.
+func (args arguments) hash(name string) (common.Hash, error) {
+	hash, err := parseHash(args[name])
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("argument %q: %v", name, err)
+	}
+	return hash, nil
+}
+
+// bytes returns a Bytes argument.
+func (args arguments) bytes(name string) ([]byte, error) {
+	s, ok := args[name].(string)
+	if !ok {
+		return nil, fmt.Errorf("argument %q: expected hex string", name)
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("argument %q: %v", name, err)
+	}
+	return b, nil
+}
+
+func parseLong(val interface{}) (int64, error) {
+	switch val := val.(type) {
+	case int64:
+		return val, nil
+	case float64:
+		if val != float64(int64(val)) {
+			return 0, fmt.Errorf("non-integer value %v", val)
+		}
+		return int64(val), nil
+	case json.Number:
+		return val.Int64()
+	case string:
+		if has0xPrefix(val) {
+			n, err := hexutil.DecodeUint64(val)
+			return int64(n), err
+		}
+		return strconv.ParseInt(val, 10, 64)
+	}
+	return 0, fmt.Errorf("expected integer")
+}
+
+func parseBigInt(val interface{}) (*big.Int, error) {
+	switch val := val.(type) {
+	case string:
+		if has0xPrefix(val) {
+			return hexutil.DecodeBig(val)
+		}
+		n, ok := new(big.Int).SetString(val, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", val)
+		}
+		return n, nil
+	case json.Number:
+		return parseBigInt(string(val))
+	}
+	n, err := parseLong(val)
+	if err != nil {
+		return nil, err
+	}
+	return big.NewInt(n), nil
+}
+
+func parseAddress(val interface{}) (common.Address, error) {
+	s, ok := val.(string)
+	if !ok || !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("expected hex encoded address")
+	}
+	return common.HexToAddress(s), nil
+}
+
+func parseHash(val interface{}) (common.Hash, error) {
+	s, ok := val.(string)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("expected hex encoded hash")
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(b) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("hash must be %d bytes long", common.HashLength)
+	}
+	return common.BytesToHash(b), nil
+}
+
+func has0xPrefix(s string) bool {
+	return len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+}
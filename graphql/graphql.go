@@ -0,0 +1,849 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to Ethereum node data.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/roller-project/roller"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/rawdb"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/state"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/vm"
+	"github.com/Ethereum-Reloaded/ETHR-Go/eth/filters"
+	"github.com/Ethereum-Reloaded/ETHR-Go/internal/ethapi"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rpc"
+)
+
+// maxBlockRange is the maximum number of blocks a single blocks query may span.
+const maxBlockRange = 1000
+
+var (
+	errFilterLogs      = errors.New("backend does not support log filtering")
+	errMissingArgument = errors.New("missing required argument")
+)
+
+// unknownField is returned by resolvers for fields not present in the schema.
+func unknownField(typ, field string) error {
+	return fmt.Errorf("unknown field %q on type %s", field, typ)
+}
+
+// blockNumberArg returns the optional block number argument of an account
+// lookup, defaulting to def.
+func blockNumberArg(args arguments, def rpc.BlockNumber) (rpc.BlockNumber, error) {
+	if !args.has("block") {
+		return def, nil
+	}
+	n, err := args.long("block")
+	if err != nil {
+		return 0, err
+	}
+	return rpc.BlockNumber(n), nil
+}
+
+// Account represents an Ethereum account at a particular block.
+type Account struct {
+	backend     ethapi.Backend
+	address     common.Address
+	blockNumber rpc.BlockNumber
+}
+
+func (a *Account) typeName() string { return "Account" }
+
+// getState fetches the StateDB object for an account.
+func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
+	state, _, err := a.backend.StateAndHeaderByNumber(ctx, a.blockNumber)
+	if state == nil && err == nil {
+		err = fmt.Errorf("state of block %d not available", a.blockNumber)
+	}
+	return state, err
+}
+
+func (a *Account) resolve(ctx context.Context, field string, args arguments) (interface{}, error) {
+	if field == "address" {
+		return a.address, nil
+	}
+	state, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch field {
+	case "balance":
+		return (*hexutil.Big)(state.GetBalance(a.address)), nil
+	case "transactionCount":
+		return state.GetNonce(a.address), nil
+	case "code":
+		return hexutil.Bytes(state.GetCode(a.address)), nil
+	case "storage":
+		slot, err := args.hash("slot")
+		if err != nil {
+			return nil, err
+		}
+		return state.GetState(a.address, slot), nil
+	}
+	return nil, unknownField(a.typeName(), field)
+}
+
+// Log represents an individual log message. All arguments are mandatory.
+type Log struct {
+	backend     ethapi.Backend
+	transaction *Transaction
+	log         *types.Log
+}
+
+func (l *Log) typeName() string { return "Log" }
+
+func (l *Log) resolve(ctx context.Context, field string, args arguments) (interface{}, error) {
+	switch field {
+	case "index":
+		return l.log.Index, nil
+	case "account":
+		number, err := blockNumberArg(args, rpc.BlockNumber(l.log.BlockNumber))
+		if err != nil {
+			return nil, err
+		}
+		return &Account{backend: l.backend, address: l.log.Address, blockNumber: number}, nil
+	case "topics":
+		return l.log.Topics, nil
+	case "data":
+		return hexutil.Bytes(l.log.Data), nil
+	case "transaction":
+		return l.transaction, nil
+	}
+	return nil, unknownField(l.typeName(), field)
+}
+
+// Transaction represents an Ethereum transaction. The backend and hash are
+// mandatory, all other fields are lazily populated.
+type Transaction struct {
+	backend ethapi.Backend
+	hash    common.Hash
+	tx      *types.Transaction
+	block   *Block
+	index   uint64
+}
+
+func (t *Transaction) typeName() string { return "Transaction" }
+
+// resolveTx retrieves the transaction from the chain database, or the pool if
+// it's still pending. A nil transaction is returned if it's not found at all.
+func (t *Transaction) resolveTx(ctx context.Context) (*types.Transaction, error) {
+	if t.tx == nil {
+		tx, blockHash, _, index := rawdb.ReadTransaction(t.backend.ChainDb(), t.hash)
+		if tx != nil {
+			block, err := t.backend.GetBlock(ctx, blockHash)
+			if err != nil {
+				return nil, err
+			}
+			t.tx = tx
+			t.block = &Block{backend: t.backend, block: block}
+			t.index = index
+		} else {
+			t.tx = t.backend.GetPoolTransaction(t.hash)
+		}
+	}
+	return t.tx, nil
+}
+
+// getReceipt returns the receipt of the transaction, or nil if it's pending.
+func (t *Transaction) getReceipt(ctx context.Context) (*types.Receipt, error) {
+	if _, err := t.resolveTx(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.block.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t.index >= uint64(len(receipts)) {
+		return nil, fmt.Errorf("receipt of transaction %x not found", t.hash)
+	}
+	return receipts[t.index], nil
+}
+
+// blockNumber returns the number of the block to query accounts at by default,
+// which is the pending block for transactions not yet mined.
+func (t *Transaction) blockNumber() rpc.BlockNumber {
+	if t.block == nil {
+		return rpc.PendingBlockNumber
+	}
+	return rpc.BlockNumber(t.block.block.NumberU64())
+}
+
+func (t *Transaction) resolve(ctx context.Context, field string, args arguments) (interface{}, error) {
+	if field == "hash" {
+		return t.hash, nil
+	}
+	tx, err := t.resolveTx(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	switch field {
+	case "nonce":
+		return tx.Nonce(), nil
+	case "index":
+		if t.block == nil {
+			return nil, nil
+		}
+		return t.index, nil
+	case "from":
+		number, err := blockNumberArg(args, t.blockNumber())
+		if err != nil {
+			return nil, err
+		}
+		signer := types.MakeSigner(t.backend.ChainConfig(), t.backend.CurrentBlock().Number())
+		if t.block != nil {
+			signer = types.MakeSigner(t.backend.ChainConfig(), t.block.block.Number())
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		return &Account{backend: t.backend, address: from, blockNumber: number}, nil
+	case "to":
+		if tx.To() == nil {
+			return nil, nil
+		}
+		number, err := blockNumberArg(args, t.blockNumber())
+		if err != nil {
+			return nil, err
+		}
+		return &Account{backend: t.backend, address: *tx.To(), blockNumber: number}, nil
+	case "value":
+		return (*hexutil.Big)(tx.Value()), nil
+	case "gasPrice":
+		return (*hexutil.Big)(tx.GasPrice()), nil
+	case "gas":
+		return tx.Gas(), nil
+	case "inputData":
+		return hexutil.Bytes(tx.Data()), nil
+	case "block":
+		if t.block == nil {
+			return nil, nil
+		}
+		return t.block, nil
+	}
+	// All remaining fields are derived from the receipt
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	switch field {
+	case "status":
+		if len(receipt.PostState) != 0 {
+			return nil, nil
+		}
+		return receipt.Status, nil
+	case "gasUsed":
+		return receipt.GasUsed, nil
+	case "cumulativeGasUsed":
+		return receipt.CumulativeGasUsed, nil
+	case "createdContract":
+		if receipt.ContractAddress == (common.Address{}) {
+			return nil, nil
+		}
+		number, err := blockNumberArg(args, t.blockNumber())
+		if err != nil {
+			return nil, err
+		}
+		return &Account{backend: t.backend, address: receipt.ContractAddress, blockNumber: number}, nil
+	case "logs":
+		logs := make([]*Log, 0, len(receipt.Logs))
+		for _, log := range receipt.Logs {
+			logs = append(logs, &Log{backend: t.backend, transaction: t, log: log})
+		}
+		return logs, nil
+	}
+	return nil, unknownField(t.typeName(), field)
+}
+
+// Block represents an Ethereum block. Ommers are represented by blocks without
+// a body.
+type Block struct {
+	backend  ethapi.Backend
+	block    *types.Block
+	receipts types.Receipts
+}
+
+func (b *Block) typeName() string { return "Block" }
+
+// resolveReceipts returns the receipts of the block's transactions.
+func (b *Block) resolveReceipts(ctx context.Context) (types.Receipts, error) {
+	if b.receipts == nil {
+		receipts, err := b.backend.GetReceipts(ctx, b.block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		b.receipts = receipts
+	}
+	return b.receipts, nil
+}
+
+// transaction wraps the index-th transaction of the block.
+func (b *Block) transaction(index int) *Transaction {
+	tx := b.block.Transactions()[index]
+	return &Transaction{backend: b.backend, hash: tx.Hash(), tx: tx, block: b, index: uint64(index)}
+}
+
+func (b *Block) resolve(ctx context.Context, field string, args arguments) (interface{}, error) {
+	header := b.block.Header()
+
+	switch field {
+	case "number":
+		return header.Number.Uint64(), nil
+	case "hash":
+		return b.block.Hash(), nil
+	case "parent":
+		if header.Number.Sign() == 0 {
+			return nil, nil
+		}
+		parent, err := b.backend.GetBlock(ctx, header.ParentHash)
+		if err != nil || parent == nil {
+			return nil, err
+		}
+		return &Block{backend: b.backend, block: parent}, nil
+	case "nonce":
+		return hexutil.Bytes(header.Nonce[:]), nil
+	case "transactionsRoot":
+		return header.TxHash, nil
+	case "transactionCount":
+		return len(b.block.Transactions()), nil
+	case "stateRoot":
+		return header.Root, nil
+	case "receiptsRoot":
+		return header.ReceiptHash, nil
+	case "miner":
+		number, err := blockNumberArg(args, rpc.BlockNumber(header.Number.Int64()))
+		if err != nil {
+			return nil, err
+		}
+		return &Account{backend: b.backend, address: header.Coinbase, blockNumber: number}, nil
+	case "extraData":
+		return hexutil.Bytes(header.Extra), nil
+	case "gasLimit":
+		return header.GasLimit, nil
+	case "gasUsed":
+		return header.GasUsed, nil
+	case "timestamp":
+		return (*hexutil.Big)(header.Time), nil
+	case "logsBloom":
+		return hexutil.Bytes(header.Bloom.Bytes()), nil
+	case "mixHash":
+		return header.MixDigest, nil
+	case "difficulty":
+		return (*hexutil.Big)(header.Difficulty), nil
+	case "totalDifficulty":
+		td := b.backend.GetTd(b.block.Hash())
+		if td == nil {
+			return nil, fmt.Errorf("total difficulty of block %x not found", b.block.Hash())
+		}
+		return (*hexutil.Big)(td), nil
+	case "ommerCount":
+		return len(b.block.Uncles()), nil
+	case "ommers":
+		ommers := make([]*Block, 0, len(b.block.Uncles()))
+		for _, uncle := range b.block.Uncles() {
+			ommers = append(ommers, &Block{backend: b.backend, block: types.NewBlockWithHeader(uncle)})
+		}
+		return ommers, nil
+	case "ommerAt":
+		index, err := args.long("index")
+		if err != nil {
+			return nil, err
+		}
+		uncles := b.block.Uncles()
+		if index < 0 || index >= int64(len(uncles)) {
+			return nil, nil
+		}
+		return &Block{backend: b.backend, block: types.NewBlockWithHeader(uncles[index])}, nil
+	case "ommerHash":
+		return header.UncleHash, nil
+	case "transactions":
+		txs := make([]*Transaction, len(b.block.Transactions()))
+		for i := range txs {
+			txs[i] = b.transaction(i)
+		}
+		return txs, nil
+	case "transactionAt":
+		index, err := args.long("index")
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(b.block.Transactions())) {
+			return nil, nil
+		}
+		return b.transaction(int(index)), nil
+	case "logs":
+		return b.logs(ctx, args)
+	case "account":
+		address, err := args.address("address")
+		if err != nil {
+			return nil, err
+		}
+		return &Account{backend: b.backend, address: address, blockNumber: rpc.BlockNumber(header.Number.Int64())}, nil
+	case "call":
+		return call(ctx, b.backend, args, rpc.BlockNumber(header.Number.Int64()))
+	case "estimateGas":
+		return estimateGas(ctx, b.backend, args, rpc.BlockNumber(header.Number.Int64()))
+	}
+	return nil, unknownField(b.typeName(), field)
+}
+
+// logs returns the logs of the block matching the filter criteria.
+func (b *Block) logs(ctx context.Context, args arguments) (interface{}, error) {
+	filter, err := args.object("filter")
+	if err != nil {
+		return nil, err
+	}
+	addresses, topics, err := filterCriteria(filter)
+	if err != nil {
+		return nil, err
+	}
+	logs := []*Log{}
+	if !bloomFilter(b.block.Bloom(), addresses, topics) {
+		return logs, nil
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, receipt := range receipts {
+		for _, log := range filterLogs(receipt.Logs, addresses, topics) {
+			logs = append(logs, &Log{backend: b.backend, transaction: b.transaction(i), log: log})
+		}
+	}
+	return logs, nil
+}
+
+// CallResult is the result of executing a message call.
+type CallResult struct {
+	data    hexutil.Bytes
+	gasUsed uint64
+	status  uint64
+}
+
+func (c *CallResult) typeName() string { return "CallResult" }
+
+func (c *CallResult) resolve(ctx context.Context, field string, args arguments) (interface{}, error) {
+	switch field {
+	case "data":
+		return c.data, nil
+	case "gasUsed":
+		return c.gasUsed, nil
+	case "status":
+		return c.status, nil
+	}
+	return nil, unknownField(c.typeName(), field)
+}
+
+// callArgs converts the CallData input object into call arguments.
+func callArgs(args arguments) (ethapi.CallArgs, error) {
+	var result ethapi.CallArgs
+
+	data, err := args.object("data")
+	if err != nil {
+		return result, err
+	}
+	if data == nil {
+		return result, fmt.Errorf("argument \"data\": %v", errMissingArgument)
+	}
+	if data.has("from") {
+		if result.From, err = data.address("from"); err != nil {
+			return result, err
+		}
+	}
+	if data.has("to") {
+		to, err := data.address("to")
+		if err != nil {
+			return result, err
+		}
+		result.To = &to
+	}
+	if data.has("gas") {
+		gas, err := data.long("gas")
+		if err != nil {
+			return result, err
+		}
+		result.Gas = hexutil.Uint64(gas)
+	}
+	if data.has("gasPrice") {
+		price, err := data.bigInt("gasPrice")
+		if err != nil {
+			return result, err
+		}
+		result.GasPrice = hexutil.Big(*price)
+	}
+	if data.has("value") {
+		value, err := data.bigInt("value")
+		if err != nil {
+			return result, err
+		}
+		result.Value = hexutil.Big(*value)
+	}
+	if data.has("data") {
+		if result.Data, err = data.bytes("data"); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// call executes a message call against the state of the given block.
+func call(ctx context.Context, backend ethapi.Backend, args arguments, number rpc.BlockNumber) (*CallResult, error) {
+	msg, err := callArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	result, gas, failed, err := ethapi.DoCall(ctx, backend, msg, number, vm.Config{}, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	status := uint64(1)
+	if failed {
+		status = 0
+	}
+	return &CallResult{data: result, gasUsed: gas, status: status}, nil
+}
+
+// estimateGas estimates the gas needed to execute a message against the state
+// of the given block.
+func estimateGas(ctx context.Context, backend ethapi.Backend, args arguments, number rpc.BlockNumber) (interface{}, error) {
+	msg, err := callArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	gas, err := ethapi.DoEstimateGas(ctx, backend, msg, number)
+	if err != nil {
+		return nil, err
+	}
+	return uint64(gas), nil
+}
+
+// Pending represents the pending state of the chain.
+type Pending struct {
+	backend ethapi.Backend
+}
+
+func (p *Pending) typeName() string { return "Pending" }
+
+func (p *Pending) resolve(ctx context.Context, field string, args arguments) (interface{}, error) {
+	switch field {
+	case "transactionCount":
+		txs, err := p.backend.GetPoolTransactions()
+		if err != nil {
+			return nil, err
+		}
+		return len(txs), nil
+	case "transactions":
+		txs, err := p.backend.GetPoolTransactions()
+		if err != nil {
+			return nil, err
+		}
+		ret := make([]*Transaction, len(txs))
+		for i, tx := range txs {
+			ret[i] = &Transaction{backend: p.backend, hash: tx.Hash(), tx: tx}
+		}
+		return ret, nil
+	case "account":
+		address, err := args.address("address")
+		if err != nil {
+			return nil, err
+		}
+		return &Account{backend: p.backend, address: address, blockNumber: rpc.PendingBlockNumber}, nil
+	case "call":
+		return call(ctx, p.backend, args, rpc.PendingBlockNumber)
+	case "estimateGas":
+		return estimateGas(ctx, p.backend, args, rpc.PendingBlockNumber)
+	}
+	return nil, unknownField(p.typeName(), field)
+}
+
+// SyncState represents the progress of a chain synchronisation.
+type SyncState struct {
+	progress ethereum.SyncProgress
+}
+
+func (s *SyncState) typeName() string { return "SyncState" }
+
+func (s *SyncState) resolve(ctx context.Context, field string, args arguments) (interface{}, error) {
+	switch field {
+	case "startingBlock":
+		return s.progress.StartingBlock, nil
+	case "currentBlock":
+		return s.progress.CurrentBlock, nil
+	case "highestBlock":
+		return s.progress.HighestBlock, nil
+	case "pulledStates":
+		return s.progress.PulledStates, nil
+	case "knownStates":
+		return s.progress.KnownStates, nil
+	}
+	return nil, unknownField(s.typeName(), field)
+}
+
+// Resolver is the top-level object in the GraphQL hierarchy.
+type Resolver struct {
+	backend ethapi.Backend
+}
+
+func (r *Resolver) typeName() string { return "Query" }
+
+func (r *Resolver) resolve(ctx context.Context, field string, args arguments) (interface{}, error) {
+	switch field {
+	case "block":
+		return r.block(ctx, args)
+	case "blocks":
+		return r.blocks(ctx, args)
+	case "pending":
+		return &Pending{backend: r.backend}, nil
+	case "transaction":
+		hash, err := args.hash("hash")
+		if err != nil {
+			return nil, err
+		}
+		tx := &Transaction{backend: r.backend, hash: hash}
+		if t, err := tx.resolveTx(ctx); err != nil || t == nil {
+			return nil, err
+		}
+		return tx, nil
+	case "logs":
+		return r.logs(ctx, args)
+	case "gasPrice":
+		price, err := r.backend.SuggestPrice(ctx)
+		return (*hexutil.Big)(price), err
+	case "protocolVersion":
+		return r.backend.ProtocolVersion(), nil
+	case "syncing":
+		progress := r.backend.Downloader().Progress()
+		if progress.CurrentBlock >= progress.HighestBlock {
+			return nil, nil
+		}
+		return &SyncState{progress: progress}, nil
+	}
+	return nil, unknownField(r.typeName(), field)
+}
+
+// block retrieves a block by number or hash, defaulting to the latest one.
+func (r *Resolver) block(ctx context.Context, args arguments) (interface{}, error) {
+	var (
+		block *types.Block
+		err   error
+	)
+	switch {
+	case args.has("number") && args.has("hash"):
+		return nil, errors.New("only one of number or hash may be specified")
+	case args.has("hash"):
+		hash, herr := args.hash("hash")
+		if herr != nil {
+			return nil, herr
+		}
+		block, err = r.backend.GetBlock(ctx, hash)
+	case args.has("number"):
+		number, nerr := args.long("number")
+		if nerr != nil {
+			return nil, nerr
+		}
+		block, err = r.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	default:
+		block, err = r.backend.BlockByNumber(ctx, rpc.LatestBlockNumber)
+	}
+	if err != nil || block == nil {
+		return nil, err
+	}
+	return &Block{backend: r.backend, block: block}, nil
+}
+
+// blocks retrieves a range of blocks, ending at the latest one by default. The
+// range is capped at the latest block and may span at most maxBlockRange blocks.
+func (r *Resolver) blocks(ctx context.Context, args arguments) (interface{}, error) {
+	if !args.has("from") {
+		return nil, fmt.Errorf("argument \"from\": %v", errMissingArgument)
+	}
+	from, err := args.long("from")
+	if err != nil {
+		return nil, err
+	}
+	head := r.backend.CurrentBlock().Number().Int64()
+	to := head
+	if args.has("to") {
+		if to, err = args.long("to"); err != nil {
+			return nil, err
+		}
+		if to > head {
+			to = head
+		}
+	}
+	if from < 0 || to < from {
+		return []*Block{}, nil
+	}
+	if to-from >= maxBlockRange {
+		return nil, fmt.Errorf("block range exceeds %d blocks", maxBlockRange)
+	}
+	ret := []*Block{}
+	for i := from; i <= to; i++ {
+		block, err := r.backend.BlockByNumber(ctx, rpc.BlockNumber(i))
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			break
+		}
+		ret = append(ret, &Block{backend: r.backend, block: block})
+	}
+	return ret, nil
+}
+
+// logs retrieves the logs matching the filter criteria from a range of blocks,
+// using the bloombits index of the backend.
+func (r *Resolver) logs(ctx context.Context, args arguments) (interface{}, error) {
+	backend, ok := r.backend.(filters.Backend)
+	if !ok {
+		return nil, errFilterLogs
+	}
+	filter, err := args.object("filter")
+	if err != nil {
+		return nil, err
+	}
+	// Convert the range, an unspecified end being the latest block
+	begin, end := rpc.LatestBlockNumber.Int64(), rpc.LatestBlockNumber.Int64()
+	if filter.has("fromBlock") {
+		if begin, err = filter.long("fromBlock"); err != nil {
+			return nil, err
+		}
+	}
+	if filter.has("toBlock") {
+		if end, err = filter.long("toBlock"); err != nil {
+			return nil, err
+		}
+	}
+	addresses, topics, err := filterCriteria(filter)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := filters.New(backend, begin, end, addresses, topics).Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Log, 0, len(logs))
+	for _, log := range logs {
+		ret = append(ret, &Log{
+			backend:     r.backend,
+			transaction: &Transaction{backend: r.backend, hash: log.TxHash},
+			log:         log,
+		})
+	}
+	return ret, nil
+}
+
+// filterCriteria extracts the addresses and topics of a log filter.
+func filterCriteria(filter arguments) ([]common.Address, [][]common.Hash, error) {
+	var addresses []common.Address
+	for _, val := range filter.list("addresses") {
+		addr, err := parseAddress(val)
+		if err != nil {
+			return nil, nil, fmt.Errorf("argument \"addresses\": %v", err)
+		}
+		addresses = append(addresses, addr)
+	}
+	var topics [][]common.Hash
+	for _, val := range filter.list("topics") {
+		var sub []common.Hash
+		for _, val := range (arguments{"topic": val}).list("topic") {
+			topic, err := parseHash(val)
+			if err != nil {
+				return nil, nil, fmt.Errorf("argument \"topics\": %v", err)
+			}
+			sub = append(sub, topic)
+		}
+		topics = append(topics, sub)
+	}
+	return addresses, topics, nil
+}
+
+// filterLogs returns the logs matching the given addresses and topics.
+func filterLogs(logs []*types.Log, addresses []common.Address, topics [][]common.Hash) []*types.Log {
+	var ret []*types.Log
+Logs:
+	for _, log := range logs {
+		if len(addresses) > 0 && !includes(addresses, log.Address) {
+			continue
+		}
+		if len(topics) > len(log.Topics) {
+			continue
+		}
+		for i, sub := range topics {
+			match := len(sub) == 0 // empty rule set == wildcard
+			for _, topic := range sub {
+				if log.Topics[i] == topic {
+					match = true
+					break
+				}
+			}
+			if !match {
+				continue Logs
+			}
+		}
+		ret = append(ret, log)
+	}
+	return ret
+}
+
+// bloomFilter reports whether a bloom may contain logs matching the criteria.
+func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if types.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, sub := range topics {
+		included := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if types.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+func includes(addresses []common.Address, a common.Address) bool {
+	for _, addr := range addresses {
+		if addr == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/consensus/ethash"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
+	"github.com/Ethereum-Reloaded/ETHR-Go/internal/ethapi"
+	"github.com/Ethereum-Reloaded/ETHR-Go/params"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rpc"
+)
+
+// testObject is a resolver serving fixed field values.
+type testObject struct {
+	name   string
+	fields map[string]interface{}
+}
+
+func (o *testObject) typeName() string { return o.name }
+
+func (o *testObject) resolve(ctx context.Context, field string, args arguments) (interface{}, error) {
+	if field == "echo" {
+		return args["value"], nil
+	}
+	val, ok := o.fields[field]
+	if !ok {
+		return nil, unknownField(o.name, field)
+	}
+	if err, ok := val.(error); ok {
+		return nil, err
+	}
+	return val, nil
+}
+
+func newTestRoot() resolver {
+	child := &testObject{name: "Child", fields: map[string]interface{}{"id": 1, "label": "one"}}
+	other := &testObject{name: "Child", fields: map[string]interface{}{"id": 2, "label": "two"}}
+	return &testObject{name: "Query", fields: map[string]interface{}{
+		"child":    child,
+		"children": []*testObject{child, other},
+		"missing":  (*testObject)(nil),
+		"broken":   fmt.Errorf("boom"),
+		"scalar":   "value",
+	}}
+}
+
+// Tests that queries are parsed and executed, producing the expected output.
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		query string
+		vars  map[string]interface{}
+		want  string
+	}{
+		// Plain fields, aliases and typenames
+		{query: `{ scalar }`, want: `{"data":{"scalar":"value"}}`},
+		{query: `query { a: scalar, b: scalar }`, want: `{"data":{"a":"value","b":"value"}}`},
+		{query: `{ child { __typename id } }`, want: `{"data":{"child":{"__typename":"Child","id":1}}}`},
+		{query: `{ children { label } missing { id } }`, want: `{"data":{"children":[{"label":"one"},{"label":"two"}],"missing":null}}`},
+
+		// Fragments and directives
+		{query: `{ child { ...F } } fragment F on Child { id label }`, want: `{"data":{"child":{"id":1,"label":"one"}}}`},
+		{query: `{ child { ... on Child { id } ... on Other { label } } }`, want: `{"data":{"child":{"id":1}}}`},
+		{query: `{ child { id @skip(if: true) label @include(if: true) } }`, want: `{"data":{"child":{"label":"one"}}}`},
+
+		// Arguments and variables
+		{query: `{ echo(value: [1, "two", {three: 3.5}, FOUR]) }`, want: `{"data":{"echo":[1,"two",{"three":3.5},"FOUR"]}}`},
+		{query: `query Q($v: Long = 7) { echo(value: $v) }`, want: `{"data":{"echo":7}}`},
+		{query: `query Q($v: Long = 7) { echo(value: $v) }`, vars: map[string]interface{}{"v": "0x10"}, want: `{"data":{"echo":"0x10"}}`},
+
+		// Field and request errors
+		{query: `{ scalar broken }`, want: `{"data":{"scalar":"value","broken":null},"errors":[{"message":"boom","path":["broken"]}]}`},
+		{query: `{ child }`, want: `{"data":{"child":null},"errors":[{"message":"field \"child\" of type Child must have a selection of subfields","path":["child"]}]}`},
+		{query: `{ child { nope } }`, want: `{"data":{"child":{"nope":null}},"errors":[{"message":"unknown field \"nope\" on type Child","path":["child","nope"]}]}`},
+		{query: `query Q($v: Long!) { echo(value: $v) }`, want: `{"data":null,"errors":[{"message":"missing value for required variable $v"}]}`},
+		{query: `mutation { scalar }`, want: `{"data":null,"errors":[{"message":"mutation operations are not supported"}]}`},
+		{query: `{ scalar `, want: `{"data":null,"errors":[{"message":"syntax error: unexpected end of query"}]}`},
+
+		// Query limits
+		{query: `{ child { ...F } } fragment F on Child { child { child { child { child { child { child { child { child { child { id } } } } } } } } } }`, want: `{"data":null,"errors":[{"message":"query depth 11 exceeds limit 10"}]}`},
+		{query: `{ child { ...F } } fragment F on Child { id ...F }`, want: `{"data":{"child":{"id":1}}}`},
+	}
+	for i, tt := range tests {
+		resp := execute(context.Background(), newTestRoot(), &request{Query: tt.query, Variables: tt.vars})
+		out, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatalf("test %d: failed to marshal response: %v", i, err)
+		}
+		if string(out) != tt.want {
+			t.Errorf("test %d: response mismatch:\nhave %s\nwant %s", i, out, tt.want)
+		}
+	}
+}
+
+// testBackend is an ethapi.Backend serving a fixed chain. Only the methods
+// required by the tests are implemented.
+type testBackend struct {
+	ethapi.Backend
+	blocks []*types.Block
+}
+
+func (b *testBackend) CurrentBlock() *types.Block { return b.blocks[len(b.blocks)-1] }
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber {
+		return b.CurrentBlock(), nil
+	}
+	if int(number) >= len(b.blocks) {
+		return nil, nil
+	}
+	return b.blocks[number], nil
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	for _, block := range b.blocks {
+		if block.Hash() == hash {
+			return block, nil
+		}
+	}
+	return nil, nil
+}
+
+func newTestBackend(n int) *testBackend {
+	var (
+		db      = ethdb.NewMemDatabase()
+		genesis = new(core.Genesis).MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, n, nil)
+	return &testBackend{blocks: append([]*types.Block{genesis}, blocks...)}
+}
+
+// Tests that chain data is served over HTTP, and that the schema is available.
+func TestHandler(t *testing.T) {
+	backend := newTestBackend(3)
+	server := httptest.NewServer(newHandler(backend))
+	defer server.Close()
+
+	// Query a block along with its parent
+	query := `{"query": "query Q($n: Long) { block(number: $n) { number hash parent { number hash } } latest: block { number } }", "variables": {"n": 2}}`
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(query))
+	if err != nil {
+		t.Fatalf("failed to post query: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	want := fmt.Sprintf(`{"data":{"block":{"number":2,"hash":"%s","parent":{"number":1,"hash":"%s"}},"latest":{"number":3}}}`,
+		backend.blocks[2].Hash().Hex(), backend.blocks[1].Hash().Hex())
+	if string(body) != want {
+		t.Errorf("response mismatch:\nhave %s\nwant %s", body, want)
+	}
+	// Query a range of blocks via GET
+	resp, err = http.Get(server.URL + "?query=" + "%7B%20blocks(from%3A%201%2C%20to%3A%2010)%20%7B%20number%20%7D%20%7D")
+	if err != nil {
+		t.Fatalf("failed to get query: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ = ioutil.ReadAll(resp.Body)
+	if want := `{"data":{"blocks":[{"number":1},{"number":2},{"number":3}]}}`; string(body) != want {
+		t.Errorf("response mismatch:\nhave %s\nwant %s", body, want)
+	}
+	// Ranges spanning too many blocks are rejected
+	if _, err := (&Resolver{backend: newTestBackend(maxBlockRange)}).blocks(context.Background(), arguments{"from": 0}); err == nil {
+		t.Errorf("oversized block range accepted")
+	}
+	// Retrieve the schema
+	resp, err = http.Get(server.URL + "/schema")
+	if err != nil {
+		t.Fatalf("failed to get schema: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ = ioutil.ReadAll(resp.Body)
+	if string(body) != schema {
+		t.Errorf("schema mismatch")
+	}
+}
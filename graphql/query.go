@@ -0,0 +1,647 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed GraphQL query document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a single query operation within a document. Mutations and
+// subscriptions are rejected by the parser.
+type operation struct {
+	name       string
+	vars       []*variable
+	selections []selection
+}
+
+// variable is the declaration of a query variable with its default value.
+type variable struct {
+	name     string
+	def      interface{}
+	required bool
+}
+
+// fragment is a named, reusable selection set bound to a type.
+type fragment struct {
+	name       string
+	on         string
+	selections []selection
+}
+
+// selection is one of *field, *fragmentSpread or *inlineFragment.
+type selection interface{}
+
+// field is a selected field, with its arguments and sub-selections.
+type field struct {
+	alias      string
+	name       string
+	args       map[string]interface{}
+	directives []*directive
+	selections []selection
+}
+
+// key returns the name the field is reported under in the response.
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// fragmentSpread includes the selections of a named fragment.
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+// inlineFragment includes selections, optionally restricted to a type.
+type inlineFragment struct {
+	on         string
+	directives []*directive
+	selections []selection
+}
+
+// directive is an @name(args...) annotation on a selection.
+type directive struct {
+	name string
+	args map[string]interface{}
+}
+
+// varRef is a reference to a query variable inside an argument value.
+type varRef string
+
+// enumValue is an unquoted enum literal inside an argument value.
+type enumValue string
+
+// Token kinds produced by the lexer.
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// lexer splits a GraphQL query into tokens, skipping whitespace, commas and
+// comments, which are all insignificant.
+type lexer struct {
+	input string
+	pos   int
+}
+
+func (l *lexer) next() (token, error) {
+	// Skip over any ignored characters
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+			continue
+		}
+		if c == '#' {
+			for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.input) {
+		return token{kind: tokEOF, pos: l.pos}, nil
+	}
+	start := l.pos
+	c := l.input[l.pos]
+
+	switch {
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		l.pos++
+		return token{kind: tokPunct, text: string(c), pos: start}, nil
+
+	case c == '.':
+		if !strings.HasPrefix(l.input[l.pos:], "...") {
+			return token{}, fmt.Errorf("syntax error at %d: unexpected '.'", start)
+		}
+		l.pos += 3
+		return token{kind: tokPunct, text: "...", pos: start}, nil
+
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.input) && (l.input[l.pos] == '_' || isLetter(l.input[l.pos]) || isDigit(l.input[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokName, text: l.input[start:l.pos], pos: start}, nil
+
+	case c == '-' || isDigit(c):
+		kind := tokInt
+		l.pos++
+		for l.pos < len(l.input) {
+			c := l.input[l.pos]
+			if c == '.' || c == 'e' || c == 'E' {
+				kind = tokFloat
+			} else if !isDigit(c) && !((c == '+' || c == '-') && kind == tokFloat) {
+				break
+			}
+			l.pos++
+		}
+		return token{kind: kind, text: l.input[start:l.pos], pos: start}, nil
+
+	case c == '"':
+		return l.lexString()
+	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.pos:])
+	return token{}, fmt.Errorf("syntax error at %d: unexpected character %q", start, r)
+}
+
+// lexString reads a quoted string literal, resolving its escape sequences.
+func (l *lexer) lexString() (token, error) {
+	start := l.pos
+	l.pos++
+
+	var out strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return token{kind: tokString, text: out.String(), pos: start}, nil
+		case '\n', '\r':
+			return token{}, fmt.Errorf("syntax error at %d: unterminated string", start)
+		case '\\':
+			if l.pos+1 >= len(l.input) {
+				return token{}, fmt.Errorf("syntax error at %d: unterminated string", start)
+			}
+			l.pos++
+			switch esc := l.input[l.pos]; esc {
+			case '"', '\\', '/':
+				out.WriteByte(esc)
+			case 'b':
+				out.WriteByte('\b')
+			case 'f':
+				out.WriteByte('\f')
+			case 'n':
+				out.WriteByte('\n')
+			case 'r':
+				out.WriteByte('\r')
+			case 't':
+				out.WriteByte('\t')
+			case 'u':
+				if l.pos+4 >= len(l.input) {
+					return token{}, fmt.Errorf("syntax error at %d: invalid unicode escape", l.pos)
+				}
+				code, err := strconv.ParseUint(l.input[l.pos+1:l.pos+5], 16, 16)
+				if err != nil {
+					return token{}, fmt.Errorf("syntax error at %d: invalid unicode escape", l.pos)
+				}
+				out.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				return token{}, fmt.Errorf("syntax error at %d: invalid escape '\\%c'", l.pos, esc)
+			}
+			l.pos++
+		default:
+			out.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("syntax error at %d: unterminated string", start)
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// parser is a recursive descent parser for the executable subset of GraphQL.
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// parseQuery parses a GraphQL query document.
+func parseQuery(query string) (*document, error) {
+	p := &parser{lex: &lexer{input: query}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		if p.peek(tokName, "fragment") {
+			frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[frag.name]; ok {
+				return nil, fmt.Errorf("duplicate fragment %q", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+			continue
+		}
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		doc.operations = append(doc.operations, op)
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("no operations in query document")
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() (err error) {
+	p.tok, err = p.lex.next()
+	return err
+}
+
+// peek reports whether the current token is of the given kind and text.
+func (p *parser) peek(kind int, text string) bool {
+	return p.tok.kind == kind && p.tok.text == text
+}
+
+// expect consumes a punctuator, failing if the current token differs.
+func (p *parser) expect(punct string) error {
+	if !p.peek(tokPunct, punct) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+// name consumes a name token, returning its text.
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.unexpected()
+	}
+	name := p.tok.text
+	return name, p.advance()
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return fmt.Errorf("syntax error: unexpected end of query")
+	}
+	return fmt.Errorf("syntax error at %d: unexpected %q", p.tok.pos, p.tok.text)
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	op := new(operation)
+	if p.tok.kind == tokName {
+		switch p.tok.text {
+		case "query":
+		case "mutation", "subscription":
+			return nil, fmt.Errorf("%s operations are not supported", p.tok.text)
+		default:
+			return nil, p.unexpected()
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokName {
+			op.name = p.tok.text
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek(tokPunct, "(") {
+			vars, err := p.parseVariables()
+			if err != nil {
+				return nil, err
+			}
+			op.vars = vars
+		}
+		if _, err := p.parseDirectives(); err != nil {
+			return nil, err
+		}
+	}
+	sels, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = sels
+	return op, nil
+}
+
+func (p *parser) parseVariables() ([]*variable, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var vars []*variable
+	for !p.peek(tokPunct, ")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		required, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		v := &variable{name: name, required: required}
+		if p.peek(tokPunct, "=") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if v.def, err = p.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+		vars = append(vars, v)
+	}
+	return vars, p.advance()
+}
+
+// parseType skips over a variable type, reporting whether it is non-null.
+func (p *parser) parseType() (bool, error) {
+	if p.peek(tokPunct, "[") {
+		if err := p.advance(); err != nil {
+			return false, err
+		}
+		if _, err := p.parseType(); err != nil {
+			return false, err
+		}
+		if err := p.expect("]"); err != nil {
+			return false, err
+		}
+	} else if _, err := p.name(); err != nil {
+		return false, err
+	}
+	if p.peek(tokPunct, "!") {
+		return true, p.advance()
+	}
+	return false, nil
+}
+
+func (p *parser) parseFragment() (*fragment, error) {
+	if err := p.advance(); err != nil { // 'fragment'
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peek(tokName, "on") {
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	on, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	sels, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &fragment{name: name, on: on, selections: sels}, nil
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []selection
+	for !p.peek(tokPunct, "}") {
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, fmt.Errorf("syntax error at %d: empty selection set", p.tok.pos)
+	}
+	return sels, p.advance()
+}
+
+func (p *parser) parseSelection() (selection, error) {
+	if !p.peek(tokPunct, "...") {
+		return p.parseField()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	// Named fragment spread, unless it's an inline fragment type condition
+	if p.tok.kind == tokName && p.tok.text != "on" {
+		name := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		dirs, err := p.parseDirectives()
+		if err != nil {
+			return nil, err
+		}
+		return &fragmentSpread{name: name, directives: dirs}, nil
+	}
+	frag := new(inlineFragment)
+	if p.peek(tokName, "on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		on, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		frag.on = on
+	}
+	var err error
+	if frag.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if frag.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+func (p *parser) parseField() (*field, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f := &field{name: name}
+	if p.peek(tokPunct, ":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		f.alias = name
+	}
+	if p.peek(tokPunct, "(") {
+		if f.args, err = p.parseArguments(); err != nil {
+			return nil, err
+		}
+	}
+	if f.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peek(tokPunct, "{") {
+		if f.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) parseArguments() (map[string]interface{}, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	args := make(map[string]interface{})
+	for !p.peek(tokPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if _, ok := args[name]; ok {
+			return nil, fmt.Errorf("duplicate argument %q", name)
+		}
+		if args[name], err = p.parseValue(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.advance()
+}
+
+func (p *parser) parseDirectives() ([]*directive, error) {
+	var dirs []*directive
+	for p.peek(tokPunct, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		dir := &directive{name: name}
+		if p.peek(tokPunct, "(") {
+			if dir.args, err = p.parseArguments(); err != nil {
+				return nil, err
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// parseValue parses an argument value. Constant values (variable defaults) may
+// not reference other variables.
+func (p *parser) parseValue(constant bool) (interface{}, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokPunct:
+		switch tok.text {
+		case "$":
+			if constant {
+				return nil, p.unexpected()
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			return varRef(name), err
+
+		case "[":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			list := []interface{}{}
+			for !p.peek(tokPunct, "]") {
+				val, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, val)
+			}
+			return list, p.advance()
+
+		case "{":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			obj := make(map[string]interface{})
+			for !p.peek(tokPunct, "}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				if obj[name], err = p.parseValue(constant); err != nil {
+					return nil, err
+				}
+			}
+			return obj, p.advance()
+		}
+	case tokInt:
+		n, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", tok.text)
+		}
+		return n, p.advance()
+
+	case tokFloat:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", tok.text)
+		}
+		return f, p.advance()
+
+	case tokString:
+		return tok.text, p.advance()
+
+	case tokName:
+		var val interface{}
+		switch tok.text {
+		case "true":
+			val = true
+		case "false":
+			val = false
+		case "null":
+			val = nil
+		default:
+			val = enumValue(tok.text)
+		}
+		return val, p.advance()
+	}
+	return nil, p.unexpected()
+}
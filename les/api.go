@@ -0,0 +1,148 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/light"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/params"
+)
+
+var (
+	errNoCheckpoint         = errors.New("no local checkpoint available")
+	errNotActivated         = errors.New("checkpoint oracle is not configured")
+	errCheckpointNotIndexed = errors.New("checkpoint section is not processed yet")
+	errCapacityTooLow       = errors.New("capacity is below the free client capacity")
+)
+
+// CheckpointInfo is a checkpoint as reported over RPC.
+type CheckpointInfo struct {
+	SectionIndex  hexutil.Uint64 `json:"sectionIndex"`
+	SectionHead   common.Hash    `json:"sectionHead"`
+	CHTRoot       common.Hash    `json:"chtRoot"`
+	BloomTrieRoot common.Hash    `json:"bloomTrieRoot"`
+	Hash          common.Hash    `json:"hash"` // Hash registered in the oracle contract
+}
+
+func newCheckpointInfo(cp *light.TrustedCheckpoint) *CheckpointInfo {
+	return &CheckpointInfo{
+		SectionIndex:  hexutil.Uint64(cp.SectionIdx),
+		SectionHead:   cp.SectionHead,
+		CHTRoot:       cp.CHTRoot,
+		BloomTrieRoot: cp.BloomTrieRoot,
+		Hash:          cp.Hash(),
+	}
+}
+
+// ClientInfo is the state of a light client in the client pool as reported
+// over RPC.
+type ClientInfo struct {
+	ID          discover.NodeID `json:"id"`
+	Connected   bool            `json:"connected"`
+	Priority    bool            `json:"priority"`
+	Capacity    uint64          `json:"capacity"` // Minimum recharge rate while the client has priority
+	BufLimit    uint64          `json:"bufLimit"`
+	MinRecharge uint64          `json:"minRecharge"`
+	Balance     uint64          `json:"balance"`
+	ServedCost  uint64          `json:"servedCost"`
+	Requests    uint64          `json:"requests"`
+}
+
+// PrivateLightServerAPI provides an API to access the checkpoints generated by
+// a light server, which the checkpoint oracle admins sign, and to manage the
+// priority clients of the server.
+type PrivateLightServerAPI struct {
+	server *LesServer
+}
+
+// NewPrivateLightServerAPI creates a new light server API.
+func NewPrivateLightServerAPI(server *LesServer) *PrivateLightServerAPI {
+	return &PrivateLightServerAPI{server: server}
+}
+
+// LatestCheckpoint returns the checkpoint of the most recently processed
+// section.
+func (api *PrivateLightServerAPI) LatestCheckpoint() (*CheckpointInfo, error) {
+	sections := api.server.checkpointSections()
+	if sections == 0 {
+		return nil, errNoCheckpoint
+	}
+	return newCheckpointInfo(api.server.localCheckpoint(sections - 1)), nil
+}
+
+// GetCheckpoint returns the checkpoint of the given section.
+func (api *PrivateLightServerAPI) GetCheckpoint(index uint64) (*CheckpointInfo, error) {
+	if index >= api.server.checkpointSections() {
+		return nil, errCheckpointNotIndexed
+	}
+	return newCheckpointInfo(api.server.localCheckpoint(index)), nil
+}
+
+// GetCheckpointContractConfig returns the configuration of the checkpoint
+// oracle contract.
+func (api *PrivateLightServerAPI) GetCheckpointContractConfig() (*params.CheckpointOracleConfig, error) {
+	oracle := api.server.protocolManager.oracle
+	if oracle == nil {
+		return nil, errNotActivated
+	}
+	return oracle.config, nil
+}
+
+// ClientInfo returns the state of the given light client.
+func (api *PrivateLightServerAPI) ClientInfo(id discover.NodeID) *ClientInfo {
+	pool := api.server.clientPool
+	info, connected := pool.info(id)
+	params := pool.params(&info)
+	return &ClientInfo{
+		ID:          id,
+		Connected:   connected,
+		Priority:    info.priority(),
+		Capacity:    info.Capacity,
+		BufLimit:    params.BufLimit,
+		MinRecharge: params.MinRecharge,
+		Balance:     info.Balance,
+		ServedCost:  info.Served,
+		Requests:    info.Requests,
+	}
+}
+
+// AddBalance adds the given amount of request cost to the balance of a light
+// client. Clients with a positive balance are priority clients, which may evict
+// free clients when the server is full.
+func (api *PrivateLightServerAPI) AddBalance(id discover.NodeID, amount uint64) *ClientInfo {
+	api.server.clientPool.addBalance(id, amount)
+	return api.ClientInfo(id)
+}
+
+// SetClientParams assigns the capacity (minimum recharge rate) of a light client
+// it gets while it has priority, 0 resets it to the default. The buffer limit is
+// scaled accordingly. The capacities assigned to all clients may not exceed the
+// total capacity of the server. Connected clients receive the new parameters
+// when they reconnect.
+func (api *PrivateLightServerAPI) SetClientParams(id discover.NodeID, capacity uint64) (*ClientInfo, error) {
+	if capacity != 0 && capacity < api.server.defParams.MinRecharge {
+		return nil, errCapacityTooLow
+	}
+	if err := api.server.clientPool.setCapacity(id, capacity); err != nil {
+		return nil, err
+	}
+	return api.ClientInfo(id), nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi/bind"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/contracts/checkpointoracle"
+	"github.com/Ethereum-Reloaded/ETHR-Go/contracts/checkpointoracle/contract"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/rawdb"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/vm"
+	"github.com/Ethereum-Reloaded/ETHR-Go/internal/ethapi"
+	"github.com/Ethereum-Reloaded/ETHR-Go/light"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/params"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rpc"
+	ethereum "github.com/roller-project/roller"
+)
+
+const oracleCallTimeout = 5 * time.Second // Time allowance for reading the oracle contract
+
+// checkpointReader reads the latest checkpoint registered in the oracle contract.
+// It is implemented by the generated contract binding.
+type checkpointReader interface {
+	GetLatestCheckpoint(opts *bind.CallOpts) (uint64, [32]byte, *big.Int, error)
+}
+
+// checkpointOracle is the les side of the checkpoint oracle contract. Servers
+// advertise the latest checkpoint registered in the contract along with the
+// signatures attesting it; clients verify that the checkpoint is signed by
+// enough trusted signers before syncing from it.
+type checkpointOracle struct {
+	config *params.CheckpointOracleConfig
+	oracle *checkpointoracle.CheckpointOracle
+	reader checkpointReader // Contract reader, nil on clients
+
+	lock     sync.Mutex
+	lastHash common.Hash              // Hash of the last advertised checkpoint
+	last     *light.TrustedCheckpoint // Last advertised checkpoint, nil if none
+	lastSigs [][]byte                 // Signatures of the last advertised checkpoint
+}
+
+// newCheckpointOracle creates the checkpoint oracle of the given config, which
+// may be nil to disable it. Servers need a caller to read the contract with.
+func newCheckpointOracle(config *params.CheckpointOracleConfig, caller bind.ContractCaller) *checkpointOracle {
+	if config == nil {
+		return nil
+	}
+	if config.Threshold == 0 {
+		log.Error("Invalid checkpoint oracle threshold, oracle disabled", "address", config.Address)
+		return nil
+	}
+	oracle, err := checkpointoracle.NewCheckpointOracle(config.Address, nil)
+	if err != nil {
+		log.Error("Failed to bind checkpoint oracle", "err", err)
+		return nil
+	}
+	o := &checkpointOracle{config: config, oracle: oracle}
+	if caller != nil {
+		reader, err := contract.NewCheckpointOracleCaller(config.Address, caller)
+		if err != nil {
+			log.Error("Failed to bind checkpoint oracle", "err", err)
+			return nil
+		}
+		o.reader = reader
+	}
+	log.Info("Configured checkpoint oracle", "address", config.Address, "signers", len(config.Signers), "threshold", config.Threshold)
+	return o
+}
+
+// verify reports whether the checkpoint is attested by at least the threshold
+// number of distinct trusted signers. A zero threshold accepts nothing.
+func (o *checkpointOracle) verify(cp *light.TrustedCheckpoint, sigs [][]byte) bool {
+	if o.config.Threshold == 0 {
+		return false
+	}
+	hash := cp.Hash()
+	signers := make(map[common.Address]bool)
+	for _, sig := range sigs {
+		signer, err := checkpointoracle.RecoverSigner(o.config.Address, cp.SectionIdx, hash, sig)
+		if err != nil {
+			continue
+		}
+		for _, trusted := range o.config.Signers {
+			if signer == trusted {
+				signers[signer] = true
+			}
+		}
+	}
+	return uint64(len(signers)) >= o.config.Threshold
+}
+
+// oracleCheckpoint returns the latest checkpoint registered in the oracle
+// contract along with the signatures attesting it, as last read by
+// updateOracleCheckpoint. Nil is returned if there's no oracle, no registered
+// checkpoint, or the local chain doesn't have the trie roots matching it.
+func (s *LesServer) oracleCheckpoint() (*light.TrustedCheckpoint, [][]byte) {
+	o := s.protocolManager.oracle
+	if o == nil {
+		return nil, nil
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	return o.last, o.lastSigs
+}
+
+// oracleLoop refreshes the advertised checkpoint whenever the chain head
+// changes, so that handshakes don't need to read the oracle contract.
+func (s *LesServer) oracleLoop() {
+	pm := s.protocolManager
+	if pm.oracle == nil || pm.oracle.reader == nil {
+		return
+	}
+	pm.wg.Add(1)
+	headCh := make(chan core.ChainHeadEvent, 10)
+	headSub := pm.blockchain.SubscribeChainHeadEvent(headCh)
+	go func() {
+		defer pm.wg.Done()
+		defer headSub.Unsubscribe()
+
+		s.updateOracleCheckpoint()
+		for {
+			select {
+			case <-headCh:
+				s.updateOracleCheckpoint()
+			case <-pm.quitSync:
+				return
+			}
+		}
+	}()
+}
+
+// updateOracleCheckpoint reads the latest checkpoint registered in the oracle
+// contract and collects the signatures attesting it for advertisement.
+func (s *LesServer) updateOracleCheckpoint() {
+	o := s.protocolManager.oracle
+	ctx, cancel := context.WithTimeout(context.Background(), oracleCallTimeout)
+	defer cancel()
+
+	index, hash, height, err := o.reader.GetLatestCheckpoint(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		log.Debug("Failed to read checkpoint oracle", "err", err)
+		return
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if height.Sign() == 0 || (o.last != nil && o.lastHash == hash) {
+		return
+	}
+	cp := s.localCheckpoint(index)
+	if cp.Hash() != hash {
+		log.Debug("Registered checkpoint unavailable locally", "section", index, "hash", common.Hash(hash))
+		return
+	}
+	// Collect the signatures from the votes of the registering transaction
+	header := s.protocolManager.blockchain.GetHeaderByNumber(height.Uint64())
+	if header == nil {
+		return
+	}
+	var logs []*types.Log
+	for _, receipt := range rawdb.ReadReceipts(s.protocolManager.chainDb, header.Hash(), header.Number.Uint64()) {
+		logs = append(logs, receipt.Logs...)
+	}
+	var sigs [][]byte
+	for _, vote := range o.oracle.LookupCheckpointEvents([][]*types.Log{logs}, index, hash) {
+		sigs = append(sigs, checkpointoracle.VoteSignature(vote))
+	}
+	log.Info("Advertising registered checkpoint", "section", index, "hash", common.Hash(hash), "signatures", len(sigs))
+	o.lastHash, o.last, o.lastSigs = hash, cp, sigs
+}
+
+// localCheckpoint assembles the checkpoint of the given LES/2 section from the
+// locally generated CHT and BloomTrie.
+func (s *LesServer) localCheckpoint(index uint64) *light.TrustedCheckpoint {
+	// The CHT indexer still uses the LES/1 section size
+	sectionHead := s.chtIndexer.SectionHead((index+1)*(light.CHTFrequencyClient/light.CHTFrequencyServer) - 1)
+	return &light.TrustedCheckpoint{
+		SectionIdx:    index,
+		SectionHead:   sectionHead,
+		CHTRoot:       light.GetChtV2Root(s.protocolManager.chainDb, index, sectionHead),
+		BloomTrieRoot: light.GetBloomTrieRoot(s.protocolManager.chainDb, index, sectionHead),
+	}
+}
+
+// checkpointSections returns the number of LES/2 sections that have both their
+// CHT and BloomTrie generated, making them available as checkpoints.
+func (s *LesServer) checkpointSections() uint64 {
+	chtSections, _, _ := s.chtIndexer.Sections()
+	chtSections /= light.CHTFrequencyClient / light.CHTFrequencyServer
+
+	bloomTrieSections, _, _ := s.bloomTrieIndexer.Sections()
+	if bloomTrieSections < chtSections {
+		return bloomTrieSections
+	}
+	return chtSections
+}
+
+// chainCaller implements bind.ContractCaller on the latest state of the local
+// chain of a full node.
+type chainCaller struct {
+	backend ethapi.Backend
+}
+
+func (c *chainCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	state, _, err := c.backend.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	return state.GetCode(contract), nil
+}
+
+func (c *chainCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	args := ethapi.CallArgs{From: call.From, To: call.To, Data: call.Data}
+	res, _, _, err := ethapi.DoCall(ctx, c.backend, args, rpc.LatestBlockNumber, vm.Config{}, oracleCallTimeout)
+	return res, err
+}
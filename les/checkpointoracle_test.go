@@ -0,0 +1,232 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/abi/bind"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/contracts/checkpointoracle"
+	"github.com/Ethereum-Reloaded/ETHR-Go/contracts/checkpointoracle/contract"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/rawdb"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/eth"
+	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
+	"github.com/Ethereum-Reloaded/ETHR-Go/light"
+	"github.com/Ethereum-Reloaded/ETHR-Go/params"
+)
+
+var testOracleAddr = common.HexToAddress("0x0000000000000000000000000000000000000123")
+
+// testCheckpointReader is a checkpoint oracle contract stand-in.
+type testCheckpointReader struct {
+	index  uint64
+	hash   common.Hash
+	height uint64
+	calls  int32 // Number of contract reads
+}
+
+func (r *testCheckpointReader) GetLatestCheckpoint(opts *bind.CallOpts) (uint64, [32]byte, *big.Int, error) {
+	atomic.AddInt32(&r.calls, 1)
+	return r.index, r.hash, new(big.Int).SetUint64(r.height), nil
+}
+
+// newTestOracleConfig creates an oracle config with the given number of signers.
+func newTestOracleConfig(signers int, threshold uint64) (*params.CheckpointOracleConfig, []*ecdsa.PrivateKey) {
+	config := &params.CheckpointOracleConfig{Address: testOracleAddr, Threshold: threshold}
+	keys := make([]*ecdsa.PrivateKey, signers)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		config.Signers = append(config.Signers, crypto.PubkeyToAddress(keys[i].PublicKey))
+	}
+	return config, keys
+}
+
+// signTestCheckpoint signs a checkpoint with the given key.
+func signTestCheckpoint(t *testing.T, cp *light.TrustedCheckpoint, key *ecdsa.PrivateKey) []byte {
+	sig, err := crypto.Sign(checkpointoracle.SignedHash(testOracleAddr, cp.SectionIdx, cp.Hash()).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign checkpoint: %v", err)
+	}
+	sig[64] += 27
+	return sig
+}
+
+// checkpointVoteLog creates the log the oracle contract emits for a vote.
+func checkpointVoteLog(t *testing.T, cp *light.TrustedCheckpoint, sig []byte) *types.Log {
+	parsed, err := abi.JSON(strings.NewReader(contract.CheckpointOracleABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := parsed.Events["NewCheckpointVote"]
+	data, err := event.Inputs.NonIndexed().Pack(cp.Hash(), sig[64], common.BytesToHash(sig[:32]), common.BytesToHash(sig[32:64]))
+	if err != nil {
+		t.Fatalf("failed to pack vote: %v", err)
+	}
+	var index common.Hash
+	binary.BigEndian.PutUint64(index[24:], cp.SectionIdx)
+	return &types.Log{Address: testOracleAddr, Topics: []common.Hash{event.Id(), index}, Data: data}
+}
+
+func TestCheckpointOracleVerify(t *testing.T) {
+	config, keys := newTestOracleConfig(3, 2)
+	oracle := newCheckpointOracle(config, nil)
+	cp := &light.TrustedCheckpoint{SectionIdx: 1, SectionHead: common.HexToHash("0x01"), CHTRoot: common.HexToHash("0x02"), BloomTrieRoot: common.HexToHash("0x03")}
+
+	untrusted, _ := crypto.GenerateKey()
+	sig0, sig1 := signTestCheckpoint(t, cp, keys[0]), signTestCheckpoint(t, cp, keys[1])
+
+	tests := []struct {
+		sigs [][]byte
+		want bool
+	}{
+		{[][]byte{sig0, sig1}, true},
+		{[][]byte{sig0}, false},
+		{[][]byte{sig0, sig0}, false}, // signers are counted once
+		{[][]byte{sig0, signTestCheckpoint(t, cp, untrusted)}, false},
+		{[][]byte{sig0, {0x01, 0x02}}, false},
+	}
+	for i, tt := range tests {
+		if have := oracle.verify(cp, tt.sigs); have != tt.want {
+			t.Errorf("test %d: verification mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Signatures of another checkpoint are invalid
+	other := *cp
+	other.CHTRoot = common.HexToHash("0x04")
+	if oracle.verify(&other, [][]byte{sig0, sig1}) {
+		t.Errorf("signatures valid for another checkpoint")
+	}
+	// Oracles requiring no signatures are rejected
+	config.Threshold = 0
+	if newCheckpointOracle(config, nil) != nil {
+		t.Errorf("oracle with zero threshold configured")
+	}
+	if oracle.verify(cp, [][]byte{sig0, sig1}) {
+		t.Errorf("checkpoint verified with zero threshold")
+	}
+}
+
+// Tests that servers advertise the checkpoint registered in the oracle in the
+// handshake, and that clients start syncing from it.
+func TestCheckpointOracleSync(t *testing.T) {
+	config, keys := newTestOracleConfig(3, 2)
+
+	// Assemble a server with a registered checkpoint and the votes for it
+	db := ethdb.NewMemDatabase()
+	pm := newTestProtocolManagerMust(t, false, 4, testChainGen, nil, nil, db)
+	pm.server.chtIndexer = light.NewChtIndexer(db, false)
+	pm.server.bloomTrieIndexer = light.NewBloomTrieIndexer(db, false)
+
+	head := common.HexToHash("0x01")
+	pm.server.chtIndexer.AddKnownSectionHead(light.CHTFrequencyClient/light.CHTFrequencyServer-1, head)
+	light.StoreChtRoot(db, light.CHTFrequencyClient/light.CHTFrequencyServer-1, head, common.HexToHash("0x02"))
+	light.StoreBloomTrieRoot(db, 0, head, common.HexToHash("0x03"))
+
+	cp := pm.server.localCheckpoint(0)
+	if cp.SectionHead != head || cp.CHTRoot != common.HexToHash("0x02") || cp.BloomTrieRoot != common.HexToHash("0x03") {
+		t.Fatalf("local checkpoint mismatch: %+v", cp)
+	}
+	sigs := [][]byte{signTestCheckpoint(t, cp, keys[0]), signTestCheckpoint(t, cp, keys[2])}
+	header := pm.blockchain.GetHeaderByNumber(2)
+	rawdb.WriteReceipts(db, header.Hash(), 2, types.Receipts{
+		{Logs: []*types.Log{checkpointVoteLog(t, cp, sigs[0]), checkpointVoteLog(t, cp, sigs[1])}},
+	})
+	pm.oracle = newCheckpointOracle(config, nil)
+	reader := &testCheckpointReader{index: 0, hash: cp.Hash(), height: 2}
+	pm.oracle.reader = reader
+
+	// The advertised checkpoint is read in the background, and refreshed on
+	// chain head events
+	pm.server.oracleLoop()
+	waitOracleCalls := func(calls int32) {
+		deadline := time.Now().Add(time.Second)
+		for atomic.LoadInt32(&reader.calls) < calls {
+			if time.Now().After(deadline) {
+				t.Fatalf("checkpoint oracle not read")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitOracleCalls(1)
+	if have, _ := pm.server.oracleCheckpoint(); have == nil || have.Hash() != cp.Hash() {
+		t.Fatalf("registered checkpoint not read")
+	}
+	pm.blockchain.(*core.BlockChain).PostChainEvents([]interface{}{core.ChainHeadEvent{Block: pm.blockchain.(*core.BlockChain).CurrentBlock()}}, nil)
+	waitOracleCalls(2)
+
+	// Connect a client to it
+	peers := newPeerSet()
+	dist := newRequestDistributor(peers, make(chan struct{}))
+	rm := newRetrieveManager(peers, dist, nil)
+	ldb := ethdb.NewMemDatabase()
+	odr := NewLesOdr(ldb, light.NewChtIndexer(ldb, true), light.NewBloomTrieIndexer(ldb, true), eth.NewBloomIndexer(ldb, light.BloomTrieFrequency), rm)
+	lpm := newTestProtocolManagerMust(t, true, 0, nil, peers, odr, ldb)
+	lpm.oracle = newCheckpointOracle(config, nil)
+
+	_, err1, lpeer, err2 := newTestPeerPair("peer", lpv2, pm, lpm)
+	select {
+	case <-time.After(time.Millisecond * 100):
+	case err := <-err1:
+		t.Fatalf("peer 1 handshake error: %v", err)
+	case err := <-err2:
+		t.Fatalf("peer 2 handshake error: %v", err)
+	}
+	if lpeer.checkpoint == nil {
+		t.Fatalf("checkpoint not advertised")
+	}
+	if lpeer.checkpoint.Hash() != cp.Hash() || len(lpeer.checkpointSigs) != len(sigs) {
+		t.Fatalf("advertised checkpoint mismatch: have %x/%d sigs, want %x/%d sigs", lpeer.checkpoint.Hash(), len(lpeer.checkpointSigs), cp.Hash(), len(sigs))
+	}
+	if calls := atomic.LoadInt32(&reader.calls); calls != 2 {
+		t.Fatalf("checkpoint oracle read during the handshake: %d reads, want 2", calls)
+	}
+	// The client syncs from the checkpoint after the handshake
+	deadline := time.Now().Add(time.Second)
+	for {
+		if sections, _, _ := odr.ChtIndexer().Sections(); sections > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("attested checkpoint not used")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sections, _, head := odr.ChtIndexer().Sections(); sections != 1 || head != cp.SectionHead {
+		t.Fatalf("CHT section mismatch: have %d/%x, want 1/%x", sections, head, cp.SectionHead)
+	}
+	if root := light.GetChtRoot(ldb, 0, cp.SectionHead); root != cp.CHTRoot {
+		t.Fatalf("CHT root mismatch: have %x, want %x", root, cp.CHTRoot)
+	}
+	if root := light.GetBloomTrieRoot(ldb, 0, cp.SectionHead); root != cp.BloomTrieRoot {
+		t.Fatalf("BloomTrie root mismatch: have %x, want %x", root, cp.BloomTrieRoot)
+	}
+	// Known checkpoints are not added again
+	if lpm.useCheckpoint(lpeer) {
+		t.Fatalf("known checkpoint used again")
+	}
+}
@@ -0,0 +1,409 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/mclock"
+	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
+	"github.com/Ethereum-Reloaded/ETHR-Go/les/flowcontrol"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+const (
+	clientRecordExpiry = 30 * 24 * time.Hour // Inactivity after which the records of free clients are dropped
+	clientExpireCycle  = time.Hour           // Interval of dropping expired client records from the database
+)
+
+var (
+	clientInfoPrefix    = []byte("clientPool/")          // Database key prefix of the persisted client records
+	clientCapacitiesKey = []byte("clientPoolCapacities") // Database key of the assigned client capacities
+
+	errCapacityExceeded = errors.New("total capacity of the server exceeded")
+)
+
+// clientInfo is the record of a light client. Clients with a positive balance
+// are priority clients, the cost of the requests served to them is deducted
+// from their balance. The records of all clients are persisted, so that the
+// served requests are accounted across reconnects, but the ones of free
+// clients expire after clientRecordExpiry of inactivity.
+type clientInfo struct {
+	Capacity uint64 // Minimum recharge rate of the client while it has priority, 0 for the default
+	Balance  uint64 // Remaining request cost paid for by the client
+	Served   uint64 // Total cost of the requests served to the client
+	Requests uint64 // Number of requests served to the client
+	LastSeen uint64 // Unix time the client was last connected or served
+
+	dirty bool // Whether the record has changed since it was last stored
+}
+
+// priority returns whether the client is currently a priority client.
+func (c *clientInfo) priority() bool {
+	return c.Balance > 0
+}
+
+// persistent returns whether the client record is kept regardless of activity.
+func (c *clientInfo) persistent() bool {
+	return c.priority() || c.Capacity != 0
+}
+
+// expired returns whether the record of a free client has been inactive for
+// longer than clientRecordExpiry.
+func (c *clientInfo) expired(now time.Time) bool {
+	return !c.persistent() && now.Sub(time.Unix(int64(c.LastSeen), 0)) > clientRecordExpiry
+}
+
+// seen updates the activity time of the client.
+func (c *clientInfo) seen(now time.Time) {
+	c.LastSeen = uint64(now.Unix())
+	c.dirty = true
+}
+
+// clientCapacity is a capacity assigned to a client, as persisted.
+type clientCapacity struct {
+	ID       discover.NodeID
+	Capacity uint64
+}
+
+// clientEntry is a connected light client.
+type clientEntry struct {
+	id         discover.NodeID
+	info       *clientInfo
+	trusted    bool
+	connected  mclock.AbsTime
+	disconnect func() // Callback dropping the connection when the client is evicted
+}
+
+// clientPool keeps track of the connected light clients and the priority client
+// registry. Free clients are served on a first come first served basis up to
+// the peer limit, priority clients evict free ones when there is no room left.
+type clientPool struct {
+	db        ethdb.Database
+	defParams *flowcontrol.ServerParams
+	maxPeers  int
+	now       func() time.Time // wall clock, replaceable for testing
+	expired   time.Time        // last time expired records were dropped from the database
+
+	clients    map[discover.NodeID]*clientInfo  // Cache of the loaded client records
+	connected  map[discover.NodeID]*clientEntry // Currently connected clients
+	capacities map[discover.NodeID]uint64       // Capacities assigned to clients
+	lock       sync.Mutex
+}
+
+// newClientPool creates a new client pool. The client records are persisted in
+// the given database, which may be nil in tests.
+func newClientPool(db ethdb.Database, defParams *flowcontrol.ServerParams, maxPeers int) *clientPool {
+	pool := &clientPool{
+		db:         db,
+		defParams:  defParams,
+		maxPeers:   maxPeers,
+		now:        time.Now,
+		clients:    make(map[discover.NodeID]*clientInfo),
+		connected:  make(map[discover.NodeID]*clientEntry),
+		capacities: make(map[discover.NodeID]uint64),
+	}
+	if db != nil {
+		if enc, err := db.Get(clientCapacitiesKey); err == nil {
+			var list []clientCapacity
+			if err := rlp.DecodeBytes(enc, &list); err != nil {
+				log.Error("Failed to decode light client capacities", "err", err)
+			}
+			for _, c := range list {
+				pool.capacities[c.ID] = c.Capacity
+			}
+		}
+	}
+	return pool
+}
+
+// connect registers a new client connection and returns the flow control
+// parameters assigned to it. If the pool is full, priority clients evict the
+// free client that has been connected the longest, otherwise the connection
+// is refused. Trusted clients are always accepted but never evict others.
+func (pool *clientPool) connect(id discover.NodeID, trusted bool, disconnect func()) (*flowcontrol.ServerParams, bool) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if _, ok := pool.connected[id]; ok {
+		return nil, false
+	}
+	info := pool.get(id)
+	if len(pool.connected) >= pool.maxPeers && !trusted {
+		if !info.priority() {
+			return nil, false
+		}
+		var evict *clientEntry
+		for _, e := range pool.connected {
+			if e.trusted || e.info.priority() {
+				continue
+			}
+			if evict == nil || e.connected < evict.connected {
+				evict = e
+			}
+		}
+		if evict == nil {
+			return nil, false
+		}
+		log.Debug("Evicting free light client", "id", evict.id, "priority", id)
+		delete(pool.connected, evict.id)
+		pool.store(evict.id, evict.info)
+		evict.disconnect()
+	}
+	info.seen(pool.now())
+	pool.connected[id] = &clientEntry{
+		id:         id,
+		info:       info,
+		trusted:    trusted,
+		connected:  mclock.Now(),
+		disconnect: disconnect,
+	}
+	return pool.params(info), true
+}
+
+// disconnect removes a client connection and stores its record.
+func (pool *clientPool) disconnect(id discover.NodeID) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	delete(pool.connected, id)
+	if info, ok := pool.clients[id]; ok {
+		pool.store(id, info)
+	}
+}
+
+// requestServed accounts the cost of a request served to the given client.
+func (pool *clientPool) requestServed(id discover.NodeID, cost uint64) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	info := pool.get(id)
+	info.Served += cost
+	info.Requests++
+	if info.Balance > cost {
+		info.Balance -= cost
+	} else {
+		info.Balance = 0
+	}
+	info.seen(pool.now())
+}
+
+// addBalance adds the given amount to the balance of a client, making it a
+// priority client. The new balance is returned.
+func (pool *clientPool) addBalance(id discover.NodeID, amount uint64) uint64 {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	info := pool.get(id)
+	info.Balance += amount
+	if info.Balance < amount {
+		info.Balance = ^uint64(0) // Saturate on overflow
+	}
+	info.dirty = true
+	pool.store(id, info)
+	return info.Balance
+}
+
+// setCapacity assigns the capacity (minimum recharge rate) a client gets while
+// it has priority, 0 resets it to the default. The capacities assigned to all
+// clients may not exceed the total capacity of the server, which serves its
+// peer limit of free clients. Connected clients get the new parameters when
+// they reconnect, as they are only exchanged in the handshake.
+func (pool *clientPool) setCapacity(id discover.NodeID, capacity uint64) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	assigned := capacity
+	for other, c := range pool.capacities {
+		if other != id {
+			assigned += c
+		}
+	}
+	if assigned > pool.defParams.MinRecharge*uint64(pool.maxPeers) {
+		return errCapacityExceeded
+	}
+	if capacity == 0 {
+		delete(pool.capacities, id)
+	} else {
+		pool.capacities[id] = capacity
+	}
+	pool.storeCapacities()
+
+	info := pool.get(id)
+	info.Capacity = capacity
+	info.dirty = true
+	pool.store(id, info)
+	return nil
+}
+
+// info returns a copy of the record of a client and whether it is connected.
+// Records of unknown clients are not cached.
+func (pool *clientPool) info(id discover.NodeID) (clientInfo, bool) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	_, connected := pool.connected[id]
+	if info, ok := pool.clients[id]; ok {
+		return *info, connected
+	}
+	return *pool.load(id), connected
+}
+
+// stop stores the records of all clients.
+func (pool *clientPool) stop() {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for id, info := range pool.clients {
+		pool.store(id, info)
+	}
+}
+
+// params returns the flow control parameters of a client. Priority clients with
+// an assigned capacity get a buffer limit scaled by the same ratio.
+func (pool *clientPool) params(info *clientInfo) *flowcontrol.ServerParams {
+	if !info.priority() || info.Capacity == 0 {
+		return pool.defParams
+	}
+	return &flowcontrol.ServerParams{
+		BufLimit:    pool.defParams.BufLimit / pool.defParams.MinRecharge * info.Capacity,
+		MinRecharge: info.Capacity,
+	}
+}
+
+// get returns the record of a client, loading it from the database if needed.
+// The caller must hold the pool lock.
+func (pool *clientPool) get(id discover.NodeID) *clientInfo {
+	if info, ok := pool.clients[id]; ok {
+		return info
+	}
+	info := pool.load(id)
+	pool.clients[id] = info
+	return info
+}
+
+// load reads the record of a client from the database, or returns an empty
+// record if there is none or it expired.
+func (pool *clientPool) load(id discover.NodeID) *clientInfo {
+	info := new(clientInfo)
+	if pool.db != nil {
+		if enc, err := pool.db.Get(clientInfoKey(id)); err == nil {
+			if err := rlp.DecodeBytes(enc, info); err != nil {
+				log.Error("Failed to decode light client record", "id", id, "err", err)
+				info = new(clientInfo)
+			}
+		}
+	}
+	if info.expired(pool.now()) {
+		info = new(clientInfo)
+	}
+	return info
+}
+
+// store writes the record of a client into the database if it changed, and
+// drops disconnected free clients from the cache. The caller must hold the
+// pool lock.
+func (pool *clientPool) store(id discover.NodeID, info *clientInfo) {
+	if info.dirty && pool.db != nil {
+		enc, err := rlp.EncodeToBytes(info)
+		if err != nil {
+			log.Error("Failed to encode light client record", "id", id, "err", err)
+			return
+		}
+		if err := pool.db.Put(clientInfoKey(id), enc); err != nil {
+			log.Error("Failed to store light client record", "id", id, "err", err)
+			return
+		}
+		info.dirty = false
+	}
+	if _, ok := pool.connected[id]; !ok && !info.persistent() {
+		delete(pool.clients, id)
+	}
+	if pool.now().Sub(pool.expired) >= clientExpireCycle {
+		pool.expireRecords()
+	}
+}
+
+// expireRecords deletes the expired records of free clients from the database,
+// if it supports iteration. Records which aren't deleted this way are still
+// treated as expired when loaded. The caller must hold the pool lock.
+func (pool *clientPool) expireRecords() {
+	now := pool.now()
+	pool.expired = now
+
+	db, ok := pool.db.(interface {
+		NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+	})
+	if !ok {
+		return
+	}
+	it := db.NewIteratorWithPrefix(clientInfoPrefix)
+	defer it.Release()
+
+	var expired [][]byte
+	for it.Next() {
+		var id discover.NodeID
+		copy(id[:], it.Key()[len(clientInfoPrefix):])
+		if _, ok := pool.clients[id]; ok {
+			continue
+		}
+		var info clientInfo
+		if err := rlp.DecodeBytes(it.Value(), &info); err != nil || info.expired(now) {
+			expired = append(expired, common.CopyBytes(it.Key()))
+		}
+	}
+	for _, key := range expired {
+		if err := pool.db.Delete(key); err != nil {
+			log.Error("Failed to delete expired light client record", "err", err)
+			return
+		}
+	}
+	if len(expired) > 0 {
+		log.Debug("Dropped expired light client records", "count", len(expired))
+	}
+}
+
+// clientInfoKey returns the database key of the record of a client.
+func clientInfoKey(id discover.NodeID) []byte {
+	return append(common.CopyBytes(clientInfoPrefix), id[:]...)
+}
+
+// storeCapacities writes the capacities assigned to clients into the database.
+// The caller must hold the pool lock.
+func (pool *clientPool) storeCapacities() {
+	if pool.db == nil {
+		return
+	}
+	list := make([]clientCapacity, 0, len(pool.capacities))
+	for id, capacity := range pool.capacities {
+		list = append(list, clientCapacity{ID: id, Capacity: capacity})
+	}
+	enc, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		log.Error("Failed to encode light client capacities", "err", err)
+		return
+	}
+	if err := pool.db.Put(clientCapacitiesKey, enc); err != nil {
+		log.Error("Failed to store light client capacities", "err", err)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
+	"github.com/Ethereum-Reloaded/ETHR-Go/les/flowcontrol"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+)
+
+var testClientParams = &flowcontrol.ServerParams{BufLimit: 1000, MinRecharge: 10}
+
+func testClientID(i byte) discover.NodeID {
+	var id discover.NodeID
+	id[0] = i
+	return id
+}
+
+// Tests that priority clients evict the longest connected free client when the
+// pool is full, and that free clients are refused.
+func TestClientPoolEviction(t *testing.T) {
+	pool := newClientPool(nil, testClientParams, 2)
+
+	evicted := make(map[discover.NodeID]bool)
+	connect := func(id discover.NodeID, trusted bool) bool {
+		_, ok := pool.connect(id, trusted, func() { evicted[id] = true })
+		return ok
+	}
+	if !connect(testClientID(1), false) || !connect(testClientID(2), false) {
+		t.Fatalf("free clients refused below the limit")
+	}
+	if connect(testClientID(3), false) {
+		t.Fatalf("free client accepted above the limit")
+	}
+	// Replace a free client with a newer one, then add a trusted client
+	time.Sleep(time.Millisecond)
+	pool.disconnect(testClientID(2))
+	if !connect(testClientID(3), false) {
+		t.Fatalf("free client refused after a disconnect")
+	}
+	if !connect(testClientID(4), true) {
+		t.Fatalf("trusted client refused")
+	}
+
+	pool.addBalance(testClientID(5), 100)
+	pool.addBalance(testClientID(6), 100)
+	pool.addBalance(testClientID(7), 100)
+
+	if !connect(testClientID(5), false) {
+		t.Fatalf("priority client refused")
+	}
+	if !evicted[testClientID(1)] || len(evicted) != 1 {
+		t.Fatalf("evicted clients mismatch: have %v, want client 1", evicted)
+	}
+	if !connect(testClientID(6), false) {
+		t.Fatalf("priority client refused")
+	}
+	if !evicted[testClientID(3)] || len(evicted) != 2 {
+		t.Fatalf("evicted clients mismatch: have %v, want clients 1 and 3", evicted)
+	}
+	// Neither priority nor trusted clients are evicted
+	if connect(testClientID(7), false) {
+		t.Fatalf("priority client accepted without free clients to evict")
+	}
+	if len(evicted) != 2 {
+		t.Fatalf("evicted clients mismatch: have %v, want clients 1 and 3", evicted)
+	}
+}
+
+// Tests that served requests are deducted from the balance, priority clients get
+// their assigned capacity and the client records are persisted.
+func TestClientPoolAccounting(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	pool := newClientPool(db, testClientParams, 10)
+	id := testClientID(1)
+
+	if err := pool.setCapacity(id, 50); err != nil {
+		t.Fatalf("failed to set capacity: %v", err)
+	}
+	pool.addBalance(id, 100)
+
+	params, ok := pool.connect(id, false, func() {})
+	if !ok {
+		t.Fatalf("client refused")
+	}
+	if params.MinRecharge != 50 || params.BufLimit != 5000 {
+		t.Fatalf("priority params mismatch: have %+v, want {5000 50}", params)
+	}
+	pool.requestServed(id, 60)
+	pool.requestServed(id, 60)
+	pool.disconnect(id)
+
+	// Reload the records from the database
+	pool = newClientPool(db, testClientParams, 10)
+	info, connected := pool.info(id)
+	if connected {
+		t.Fatalf("client reported connected")
+	}
+	if info.Balance != 0 || info.Served != 120 || info.Requests != 2 || info.Capacity != 50 {
+		t.Fatalf("client record mismatch: have %+v", info)
+	}
+	// Clients out of balance are served as free clients
+	if params, _ = pool.connect(id, false, func() {}); params != testClientParams {
+		t.Fatalf("free params mismatch: have %+v, want %+v", params, testClientParams)
+	}
+}
+
+// Tests that the records of all clients are persisted across reconnects, that
+// the ones of inactive free clients expire, and that querying unknown clients
+// doesn't cache their records.
+func TestClientPoolRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clientpool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Unix(1000000, 0)
+	newPool := func() *clientPool {
+		pool := newClientPool(db, testClientParams, 10)
+		pool.now = func() time.Time { return now }
+		return pool
+	}
+	pool := newPool()
+
+	// Free clients keep their accounting across reconnects, but aren't cached
+	free := testClientID(1)
+	pool.connect(free, false, func() {})
+	pool.requestServed(free, 10)
+	pool.disconnect(free)
+	if _, ok := pool.clients[free]; ok {
+		t.Fatalf("free client record cached after disconnect")
+	}
+	pool.connect(free, false, func() {})
+	pool.requestServed(free, 10)
+	pool.disconnect(free)
+	if info, _ := pool.info(free); info.Served != 20 || info.Requests != 2 {
+		t.Fatalf("free client accounting lost on reconnect: have %+v", info)
+	}
+	// Priority clients are persisted, also after their balance runs out
+	paid := testClientID(2)
+	pool.addBalance(paid, 10)
+	if ok, _ := db.Has(clientInfoKey(paid)); !ok {
+		t.Fatalf("priority client record not persisted")
+	}
+	pool.connect(paid, false, func() {})
+	pool.requestServed(paid, 20)
+	pool.disconnect(paid)
+	if info, _ := newPool().info(paid); info.Balance != 0 || info.Served != 20 {
+		t.Fatalf("record of a client out of balance mismatch: have %+v", info)
+	}
+	// Queried clients are not cached
+	pool.info(testClientID(3))
+	if _, ok := pool.clients[testClientID(3)]; ok {
+		t.Fatalf("queried client record cached")
+	}
+	// Records of clients with an assigned capacity don't expire, the ones of
+	// inactive free clients are dropped
+	if err := pool.setCapacity(testClientID(4), 10); err != nil {
+		t.Fatalf("failed to set capacity: %v", err)
+	}
+	now = now.Add(clientRecordExpiry + time.Second)
+	if info, _ := pool.info(free); info.Requests != 0 {
+		t.Fatalf("expired record of a free client loaded: have %+v", info)
+	}
+	pool = newPool()
+	pool.expireRecords()
+	for _, id := range []discover.NodeID{free, paid} {
+		if ok, _ := db.Has(clientInfoKey(id)); ok {
+			t.Errorf("expired record of client %x not dropped", id[:1])
+		}
+	}
+	if info, _ := newPool().info(testClientID(4)); info.Capacity != 10 {
+		t.Fatalf("record of a client with assigned capacity dropped: have %+v", info)
+	}
+}
+
+// Tests that the capacities assigned to clients can't exceed the total capacity
+// of the server, also after a restart.
+func TestClientPoolCapacityLimit(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	pool := newClientPool(db, testClientParams, 3) // Total capacity of 30
+
+	if err := pool.setCapacity(testClientID(1), 20); err != nil {
+		t.Fatalf("failed to set capacity: %v", err)
+	}
+	if err := pool.setCapacity(testClientID(2), 20); err != errCapacityExceeded {
+		t.Fatalf("error mismatch: have %v, want %v", err, errCapacityExceeded)
+	}
+	// Reassigning a capacity only counts the new value
+	if err := pool.setCapacity(testClientID(1), 30); err != nil {
+		t.Fatalf("failed to raise capacity: %v", err)
+	}
+	pool = newClientPool(db, testClientParams, 3)
+	if err := pool.setCapacity(testClientID(2), 10); err != errCapacityExceeded {
+		t.Fatalf("error mismatch after restart: have %v, want %v", err, errCapacityExceeded)
+	}
+	if err := pool.setCapacity(testClientID(1), 0); err != nil {
+		t.Fatalf("failed to reset capacity: %v", err)
+	}
+	if err := pool.setCapacity(testClientID(2), 10); err != nil {
+		t.Fatalf("failed to set capacity after a reset: %v", err)
+	}
+}
@@ -398,9 +398,13 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 	)
 	bestTd := f.maxConfirmedTd
 	bestSyncing := false
+	ulc := f.pm.ulc.active()
 
 	for p, fp := range f.peers {
 		for hash, n := range fp.nodeByHash {
+			if ulc && f.pm.ulc.trustedTd(hash, n.number) == nil {
+				continue // ultra light clients only fetch the heads of the trusted servers
+			}
 			if !f.checkKnownNode(p, n) && !n.requested && (bestTd == nil || n.td.Cmp(bestTd) >= 0) {
 				amount := f.requestAmount(p, n)
 				if bestTd == nil || n.td.Cmp(bestTd) > 0 || amount < bestAmount {
@@ -415,7 +419,14 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 	if bestTd == f.maxConfirmedTd {
 		return nil, 0
 	}
-
+	if ulc {
+		// Instead of syncing, ultra light clients jump to the trusted head and
+		// retrieve the skipped headers on demand
+		if bestSyncing || bestAmount > MaxHeaderFetch {
+			bestAmount = 1
+		}
+		bestSyncing = false
+	}
 	f.syncing = bestSyncing
 
 	var rq *distReq
@@ -504,12 +515,35 @@ func (f *lightFetcher) processResponse(req fetchRequest, resp fetchResponse) boo
 	for i, header := range resp.headers {
 		headers[int(req.amount)-1-i] = header
 	}
-	if _, err := f.chain.InsertHeaderChain(headers, 1); err != nil {
-		if err == consensus.ErrFutureBlock {
-			return true
+	var trustedTd *big.Int
+	if f.pm.ulc.active() {
+		trustedTd = f.pm.ulc.trustedTd(req.hash, headers[len(headers)-1].Number.Uint64())
+	}
+	insert, checkFreq := headers, 1
+	if trustedTd != nil {
+		// The head was announced by the quorum of trusted servers and the
+		// headers link to it, no proof of work verification is needed
+		checkFreq = 0
+		if first := headers[0]; !f.chain.HasHeader(first.ParentHash, first.Number.Uint64()-1) {
+			// The ancestors are unknown, insert the oldest header directly with
+			// the total difficulty derived from the trusted head. The skipped
+			// ancestors are retrieved on demand through ODR.
+			td := new(big.Int).Set(trustedTd)
+			for _, header := range headers[1:] {
+				td.Sub(td, header.Difficulty)
+			}
+			f.chain.InsertTrustedHeader(first, td)
+			insert = headers[1:]
+		}
+	}
+	if len(insert) > 0 {
+		if _, err := f.chain.InsertHeaderChain(insert, checkFreq); err != nil {
+			if err == consensus.ErrFutureBlock {
+				return true
+			}
+			log.Debug("Failed to insert header chain", "err", err)
+			return false
 		}
-		log.Debug("Failed to insert header chain", "err", err)
-		return false
 	}
 	tds := make([]*big.Int, len(headers))
 	for i, header := range headers {
@@ -566,6 +600,10 @@ func (f *lightFetcher) checkAnnouncedHeaders(fp *fetcherPeerInfo, headers []*typ
 			td = f.chain.GetTd(hash, number)
 			header = f.chain.GetHeader(hash, number)
 			if header == nil || td == nil {
+				if f.pm.ulc != nil {
+					// ultra light clients skip the ancestors of trusted heads
+					return true
+				}
 				log.Error("Missing parent of validated header", "hash", hash, "number", number)
 				return false
 			}
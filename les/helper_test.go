@@ -193,6 +193,7 @@ func newTestProtocolManager(lightSync bool, blocks int, generator func(int, *cor
 
 		srv.fcManager = flowcontrol.NewClientManager(50, 10, 1000000000)
 		srv.fcCostStats = newCostStats(nil)
+		srv.clientPool = newClientPool(db, srv.defParams, 1000)
 	}
 	pm.Start(1000)
 	return pm, nil
@@ -256,13 +257,18 @@ func newTestPeer(t *testing.T, name string, version int, pm *ProtocolManager, sh
 }
 
 func newTestPeerPair(name string, version int, pm, pm2 *ProtocolManager) (*peer, <-chan error, *peer, <-chan error) {
-	// Create a message pipe to communicate through
-	app, net := p2p.MsgPipe()
-
-	// Generate a random id and create the peer
+	// Generate a random id and create the peers
 	var id discover.NodeID
 	rand.Read(id[:])
 
+	return newTestPeerPairWithID(name, version, pm, pm2, id)
+}
+
+// newTestPeerPairWithID connects two protocol managers with peers of the given id.
+func newTestPeerPairWithID(name string, version int, pm, pm2 *ProtocolManager, id discover.NodeID) (*peer, <-chan error, *peer, <-chan error) {
+	// Create a message pipe to communicate through
+	app, net := p2p.MsgPipe()
+
 	peer := pm.newPeer(version, NetworkId, p2p.NewPeer(id, name, nil), net)
 	peer2 := pm2.newPeer(version, NetworkId, p2p.NewPeer(id, name, nil), app)
 
@@ -38,6 +38,7 @@ var (
 	errInvalidMessageType  = errors.New("invalid message type")
 	errInvalidEntryCount   = errors.New("invalid number of response entries")
 	errHeaderUnavailable   = errors.New("header unavailable")
+	errHeaderHashMismatch  = errors.New("header hash mismatch")
 	errTxHashMismatch      = errors.New("transaction hash mismatch")
 	errUncleHashMismatch   = errors.New("uncle hash mismatch")
 	errReceiptHashMismatch = errors.New("receipt hash mismatch")
@@ -58,6 +59,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 	switch r := req.(type) {
 	case *light.BlockRequest:
 		return (*BlockRequest)(r)
+	case *light.HeaderRequest:
+		return (*HeaderRequest)(r)
 	case *light.ReceiptsRequest:
 		return (*ReceiptsRequest)(r)
 	case *light.TrieRequest:
@@ -129,6 +132,56 @@ func (r *BlockRequest) Validate(db ethdb.Database, msg *Msg) error {
 	return nil
 }
 
+// HeaderRequest is the ODR request type for block headers skipped by ultra
+// light clients
+type HeaderRequest light.HeaderRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *HeaderRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetBlockHeadersMsg, int(r.Amount))
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *HeaderRequest) CanSend(peer *peer) bool {
+	peer.lock.RLock()
+	defer peer.lock.RUnlock()
+
+	return peer.headInfo.Number >= r.Number
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *HeaderRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting skipped headers", "hash", r.Hash, "amount", r.Amount)
+	return peer.RequestHeadersByHash(reqID, r.GetCost(peer), r.Hash, int(r.Amount), 0, true)
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *HeaderRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating skipped headers", "hash", r.Hash, "amount", r.Amount)
+
+	// Ensure we have a correct message with a chain of headers ending in the
+	// requested one
+	if msg.MsgType != MsgBlockHeaders {
+		return errInvalidMessageType
+	}
+	headers := msg.Obj.([]*types.Header)
+	if len(headers) == 0 || uint64(len(headers)) > r.Amount {
+		return errInvalidEntryCount
+	}
+	hash, number := r.Hash, r.Number
+	for _, header := range headers {
+		if header.Hash() != hash || header.Number.Uint64() != number {
+			return errHeaderHashMismatch
+		}
+		hash, number = header.ParentHash, number-1
+	}
+	r.Headers = headers
+	return nil
+}
+
 // ReceiptsRequest is the ODR request type for block receipts by block hash
 type ReceiptsRequest light.ReceiptsRequest
 
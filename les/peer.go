@@ -75,7 +75,11 @@ type peer struct {
 	fcClient       *flowcontrol.ClientNode // nil if the peer is server only
 	fcServer       *flowcontrol.ServerNode // nil if the peer is client only
 	fcServerParams *flowcontrol.ServerParams
+	fcClientParams *flowcontrol.ServerParams // Parameters assigned to the client by the client pool
 	fcCosts        requestCostTable
+
+	checkpoint     *light.TrustedCheckpoint // Latest oracle checkpoint advertised by the server
+	checkpointSigs [][]byte                 // Signatures attesting the advertised checkpoint
 }
 
 func newPeer(version int, network uint64, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -409,13 +413,19 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		send = send.add("serveChainSince", uint64(0))
 		send = send.add("serveStateSince", uint64(0))
 		send = send.add("txRelay", nil)
-		send = send.add("flowControl/BL", server.defParams.BufLimit)
-		send = send.add("flowControl/MRR", server.defParams.MinRecharge)
+		send = send.add("flowControl/BL", p.fcClientParams.BufLimit)
+		send = send.add("flowControl/MRR", p.fcClientParams.MinRecharge)
 		list := server.fcCostStats.getCurrentList()
 		send = send.add("flowControl/MRC", list)
 		p.fcCosts = list.decode()
+		if cp, sigs := server.oracleCheckpoint(); cp != nil {
+			send = send.add("checkpoint", cp)
+			send = send.add("checkpoint/sigs", sigs)
+		}
 	} else {
-		p.requestAnnounceType = announceTypeSimple // set to default until "very light" client mode is implemented
+		if p.requestAnnounceType == announceTypeNone {
+			p.requestAnnounceType = announceTypeSimple // ultra light clients request signed announcements from trusted servers
+		}
 		send = send.add("announceType", p.requestAnnounceType)
 	}
 	recvList, err := p.sendReceiveHandshake(send)
@@ -464,7 +474,7 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		if recv.get("announceType", &p.announceType) != nil {
 			p.announceType = announceTypeSimple
 		}
-		p.fcClient = flowcontrol.NewClientNode(server.fcManager, server.defParams)
+		p.fcClient = flowcontrol.NewClientNode(server.fcManager, p.fcClientParams)
 	} else {
 		if recv.get("serveChainSince", nil) != nil {
 			return errResp(ErrUselessPeer, "peer cannot serve chain")
@@ -489,6 +499,12 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		p.fcServerParams = params
 		p.fcServer = flowcontrol.NewServerNode(params)
 		p.fcCosts = MRC.decode()
+
+		// The oracle checkpoint is optional, it's verified before use
+		var cp light.TrustedCheckpoint
+		if recv.get("checkpoint", &cp) == nil && recv.get("checkpoint/sigs", &p.checkpointSigs) == nil {
+			p.checkpoint = &cp
+		}
 	}
 
 	p.headInfo = &announceData{Td: rTd, Hash: rHash, Number: rNum}
@@ -162,6 +162,14 @@ func (rm *retrieveManager) sendReq(reqID uint64, req *distReq, val validatorFunc
 	return r
 }
 
+// requested tells if a certain reqID belongs to a waiting request
+func (rm *retrieveManager) requested(reqID uint64) bool {
+	rm.lock.RLock()
+	_, ok := rm.sentReqs[reqID]
+	rm.lock.RUnlock()
+	return ok
+}
+
 // deliver is called by the LES protocol manager to deliver reply messages to waiting requests
 func (rm *retrieveManager) deliver(peer distPeer, msg *Msg) error {
 	rm.lock.RLock()
@@ -35,6 +35,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discv5"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rpc"
 )
 
 type LesServer struct {
@@ -43,6 +44,7 @@ type LesServer struct {
 	fcManager       *flowcontrol.ClientManager // nil if our node is client only
 	fcCostStats     *requestCostStats
 	defParams       *flowcontrol.ServerParams
+	clientPool      *clientPool
 	lesTopics       []discv5.Topic
 	privateKey      *ecdsa.PrivateKey
 	quitSync        chan struct{}
@@ -93,6 +95,7 @@ func NewLesServer(eth *eth.Ethereum, config *eth.Config) (*LesServer, error) {
 
 	srv.chtIndexer.Start(eth.BlockChain())
 	pm.server = srv
+	pm.oracle = newCheckpointOracle(config.CheckpointOracle, &chainCaller{eth.APIBackend})
 
 	srv.defParams = &flowcontrol.ServerParams{
 		BufLimit:    300000000,
@@ -100,6 +103,7 @@ func NewLesServer(eth *eth.Ethereum, config *eth.Config) (*LesServer, error) {
 	}
 	srv.fcManager = flowcontrol.NewClientManager(uint64(config.LightServ), 10, 1000000000)
 	srv.fcCostStats = newCostStats(eth.ChainDb())
+	srv.clientPool = newClientPool(eth.ChainDb(), srv.defParams, config.LightPeers)
 	return srv, nil
 }
 
@@ -107,6 +111,18 @@ func (s *LesServer) Protocols() []p2p.Protocol {
 	return s.protocolManager.SubProtocols
 }
 
+// APIs returns the RPC APIs of the LES server.
+func (s *LesServer) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPrivateLightServerAPI(s),
+			Public:    false,
+		},
+	}
+}
+
 // Start starts the LES server
 func (s *LesServer) Start(srvr *p2p.Server) {
 	s.protocolManager.Start(s.config.LightPeers)
@@ -124,6 +140,7 @@ func (s *LesServer) Start(srvr *p2p.Server) {
 	}
 	s.privateKey = srvr.PrivateKey
 	s.protocolManager.blockLoop()
+	s.oracleLoop()
 }
 
 func (s *LesServer) SetBloomBitsIndexer(bloomIndexer *core.ChainIndexer) {
@@ -136,6 +153,7 @@ func (s *LesServer) Stop() {
 	// bloom trie indexer is closed by parent bloombits indexer
 	s.fcCostStats.store()
 	s.fcManager.Stop()
+	s.clientPool.stop()
 	go func() {
 		<-s.protocolManager.noMorePeers
 	}()
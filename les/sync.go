@@ -23,6 +23,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/rawdb"
 	"github.com/Ethereum-Reloaded/ETHR-Go/eth/downloader"
 	"github.com/Ethereum-Reloaded/ETHR-Go/light"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
 )
 
 // syncer is responsible for periodically synchronising with the network, both
@@ -72,8 +73,30 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		return
 	}
 
+	// Start syncing from the oracle checkpoint if it's newer than the known ones
+	pm.useCheckpoint(peer)
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 	pm.blockchain.(*light.LightChain).SyncCht(ctx)
 	pm.downloader.Synchronise(peer.id, peer.Head(), peer.Td(), downloader.LightSync)
 }
+
+// useCheckpoint adds the oracle checkpoint advertised by the peer to the trusted
+// checkpoints if it's attested by enough trusted signers and is newer than the
+// known checkpoints. It reports whether the checkpoint was added.
+func (pm *ProtocolManager) useCheckpoint(peer *peer) bool {
+	cp := peer.checkpoint
+	if cp == nil || pm.oracle == nil {
+		return false
+	}
+	if sections, _, _ := pm.odr.ChtIndexer().Sections(); cp.SectionIdx < sections {
+		return false
+	}
+	if !pm.oracle.verify(cp, peer.checkpointSigs) {
+		log.Debug("Ignoring unattested checkpoint", "peer", peer.id, "section", cp.SectionIdx)
+		return false
+	}
+	pm.blockchain.(*light.LightChain).AddTrustedCheckpoint(cp)
+	return true
+}
@@ -0,0 +1,222 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/eth"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+)
+
+const (
+	// ulcVoteHistory is the number of block heights the signed announcements
+	// of the trusted servers are remembered for.
+	ulcVoteHistory = maxNodeCount
+
+	// ulcFallbackHeights is the number of consecutive heights the trusted
+	// servers must disagree at before falling back to light sync.
+	ulcFallbackHeights = 3
+
+	// ulcRecoverHeights is the number of consecutive heights the trusted
+	// servers must agree at again before leaving the fallback mode.
+	ulcRecoverHeights = 3
+)
+
+// ulcVote is the set of trusted servers that announced a head.
+type ulcVote struct {
+	td      *big.Int
+	signers map[discover.NodeID]struct{}
+}
+
+// ulcOutcome is the outcome of the voting at a block height.
+type ulcOutcome int
+
+const (
+	ulcPending   ulcOutcome = iota // No head reached the quorum yet, but one still can
+	ulcAgreed                      // A head reached the quorum
+	ulcDisagreed                   // No head can reach the quorum any more
+)
+
+// ulc implements the ultra light client mode. It tallies the head announcements
+// signed by the trusted servers, heads announced by at least the configured
+// fraction of them are accepted without verifying the proof of work. If the
+// trusted servers keep disagreeing so much that no head can reach the quorum
+// at several consecutive heights, the client falls back to normal light sync
+// until they agree again.
+type ulc struct {
+	trusted map[discover.NodeID]struct{}
+	quorum  int // Number of trusted servers that must agree on a head
+
+	votes    map[uint64]map[common.Hash]*ulcVote // Announced heads by height
+	fallback bool                                // Whether the quorum keeps disagreeing
+	lock     sync.RWMutex
+}
+
+// newULC creates the ultra light client state from the given config, or returns
+// nil if the mode is not enabled.
+func newULC(config *eth.ULCConfig) *ulc {
+	if config == nil || len(config.TrustedServers) == 0 {
+		return nil
+	}
+	u := &ulc{
+		trusted: make(map[discover.NodeID]struct{}),
+		votes:   make(map[uint64]map[common.Hash]*ulcVote),
+	}
+	for _, url := range config.TrustedServers {
+		node, err := discover.ParseNode(url)
+		if err != nil {
+			log.Error("Invalid trusted ULC server", "url", url, "err", err)
+			continue
+		}
+		u.trusted[node.ID] = struct{}{}
+	}
+	if len(u.trusted) == 0 {
+		log.Error("No valid trusted ULC servers, ultra light client mode disabled")
+		return nil
+	}
+	fraction := config.MinTrustedFraction
+	if fraction <= 0 || fraction > 100 {
+		log.Warn("Invalid minimum trusted fraction for ULC, using default", "fraction", fraction, "default", eth.DefaultULCMinTrustedFraction)
+		fraction = eth.DefaultULCMinTrustedFraction
+	}
+	u.quorum = (len(u.trusted)*fraction + 99) / 100
+	log.Info("Ultra light client mode enabled", "servers", len(u.trusted), "quorum", u.quorum)
+	return u
+}
+
+// isTrusted returns whether the given server is a trusted one.
+func (u *ulc) isTrusted(id discover.NodeID) bool {
+	_, ok := u.trusted[id]
+	return ok
+}
+
+// active returns whether the ultra light client mode is in effect, that is it
+// is enabled and the trusted servers have not kept disagreeing.
+func (u *ulc) active() bool {
+	if u == nil {
+		return false
+	}
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+
+	return !u.fallback
+}
+
+// vote records a head announced by a trusted server, replacing its earlier
+// announcement at the same height. If the quorum became unreachable at several
+// consecutive heights the client switches to normal light sync, and back once
+// the quorum agrees again at several consecutive heights.
+func (u *ulc) vote(id discover.NodeID, head *announceData) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if !u.isTrusted(id) {
+		return
+	}
+	heads := u.votes[head.Number]
+	if heads == nil {
+		heads = make(map[common.Hash]*ulcVote)
+		u.votes[head.Number] = heads
+
+		// Forget the heights too old to matter
+		for number := range u.votes {
+			if number+ulcVoteHistory < head.Number {
+				delete(u.votes, number)
+			}
+		}
+	}
+	for hash, v := range heads {
+		if hash != head.Hash {
+			delete(v.signers, id)
+		}
+	}
+	v := heads[head.Hash]
+	if v == nil {
+		v = &ulcVote{td: head.Td, signers: make(map[discover.NodeID]struct{})}
+		heads[head.Hash] = v
+	}
+	v.signers[id] = struct{}{}
+
+	// Switch modes if the latest decided heights all went the other way
+	switch {
+	case !u.fallback && u.recentOutcomes(ulcFallbackHeights, ulcDisagreed):
+		log.Warn("Trusted servers disagree, falling back to light sync", "number", head.Number, "quorum", u.quorum)
+		u.fallback = true
+	case u.fallback && u.recentOutcomes(ulcRecoverHeights, ulcAgreed):
+		log.Info("Trusted servers agree again, resuming ultra light client mode", "number", head.Number, "quorum", u.quorum)
+		u.fallback = false
+	}
+}
+
+// outcome returns the voting outcome at the given height.
+func (u *ulc) outcome(number uint64) ulcOutcome {
+	var voted, best int
+	for _, v := range u.votes[number] {
+		voted += len(v.signers)
+		if len(v.signers) > best {
+			best = len(v.signers)
+		}
+	}
+	switch {
+	case best >= u.quorum:
+		return ulcAgreed
+	case best+len(u.trusted)-voted < u.quorum:
+		return ulcDisagreed
+	}
+	return ulcPending
+}
+
+// recentOutcomes returns whether the voting at the latest count decided heights
+// ended with the given outcome.
+func (u *ulc) recentOutcomes(count int, outcome ulcOutcome) bool {
+	numbers := make([]uint64, 0, len(u.votes))
+	for number := range u.votes {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] > numbers[j] })
+
+	for _, number := range numbers {
+		switch u.outcome(number) {
+		case ulcPending:
+			continue
+		case outcome:
+			if count--; count == 0 {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// trustedTd returns the total difficulty of the given head if it was announced
+// by the quorum of trusted servers, or nil otherwise.
+func (u *ulc) trustedTd(hash common.Hash, number uint64) *big.Int {
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+
+	if v := u.votes[number][hash]; v != nil && len(v.signers) >= u.quorum {
+		return v.td
+	}
+	return nil
+}
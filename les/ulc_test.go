@@ -0,0 +1,192 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/consensus/ethash"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/eth"
+	"github.com/Ethereum-Reloaded/ETHR-Go/ethdb"
+	"github.com/Ethereum-Reloaded/ETHR-Go/light"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/params"
+)
+
+// newTestULC creates an ultra light client trusting the given servers.
+func newTestULC(t *testing.T, fraction int, servers ...discover.NodeID) *ulc {
+	config := &eth.ULCConfig{MinTrustedFraction: fraction}
+	for _, id := range servers {
+		config.TrustedServers = append(config.TrustedServers, discover.NewNode(id, net.IP{127, 0, 0, 1}, 30303, 30303).String())
+	}
+	u := newULC(config)
+	if u == nil {
+		t.Fatalf("ultra light client mode not enabled")
+	}
+	return u
+}
+
+func TestULCQuorum(t *testing.T) {
+	servers := []discover.NodeID{testClientID(1), testClientID(2), testClientID(3)}
+	u := newTestULC(t, 60, servers...)
+	if u.quorum != 2 {
+		t.Fatalf("quorum mismatch: have %d, want 2", u.quorum)
+	}
+	head := &announceData{Hash: common.HexToHash("0x01"), Number: 10, Td: big.NewInt(100)}
+
+	// Untrusted servers don't count, a single trusted one is not enough
+	u.vote(testClientID(4), head)
+	u.vote(servers[0], head)
+	if td := u.trustedTd(head.Hash, head.Number); td != nil {
+		t.Fatalf("head trusted below the quorum")
+	}
+	// A second trusted server makes the head trusted
+	u.vote(servers[1], head)
+	if td := u.trustedTd(head.Hash, head.Number); td == nil || td.Cmp(head.Td) != 0 {
+		t.Fatalf("trusted td mismatch: have %v, want %v", td, head.Td)
+	}
+	// Changing an announcement at the same height withdraws the vote
+	other := &announceData{Hash: common.HexToHash("0x02"), Number: 10, Td: big.NewInt(100)}
+	u.vote(servers[1], other)
+	if td := u.trustedTd(head.Hash, head.Number); td != nil {
+		t.Fatalf("head trusted after a withdrawn vote")
+	}
+	if !u.active() {
+		t.Fatalf("ultra light client mode inactive while the quorum is reachable")
+	}
+	// The third server disagreeing with both makes the quorum unreachable, but
+	// a single disagreement doesn't end the ultra light client mode
+	u.vote(servers[2], &announceData{Hash: common.HexToHash("0x03"), Number: 10, Td: big.NewInt(100)})
+	if !u.active() {
+		t.Fatalf("ultra light client mode inactive after a single disagreement")
+	}
+	// Sustained disagreement falls back to light sync
+	for number := uint64(11); number < 10+ulcFallbackHeights; number++ {
+		for i, id := range servers {
+			u.vote(id, &announceData{Hash: common.BigToHash(big.NewInt(int64(i + 1))), Number: number, Td: big.NewInt(100)})
+		}
+	}
+	if u.active() {
+		t.Fatalf("ultra light client mode active after the quorum kept disagreeing")
+	}
+	// The quorum agreeing again at enough consecutive heights recovers
+	for number := uint64(10 + ulcFallbackHeights); number < 10+ulcFallbackHeights+ulcRecoverHeights; number++ {
+		if u.active() {
+			t.Fatalf("ultra light client mode recovered early at #%d", number)
+		}
+		for _, id := range servers[:2] {
+			u.vote(id, &announceData{Hash: common.BigToHash(big.NewInt(int64(number))), Number: number, Td: big.NewInt(100)})
+		}
+	}
+	if !u.active() {
+		t.Fatalf("ultra light client mode inactive after the quorum agreed again")
+	}
+}
+
+// Tests that ultra light clients jump to the head announced by the trusted
+// servers, and retrieve the skipped ancestors on demand.
+func TestULCSync(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	id := discover.PubkeyID(&key.PublicKey)
+
+	// Assemble a server that signs its announcements
+	db := ethdb.NewMemDatabase()
+	pm := newTestProtocolManagerMust(t, false, 4, testChainGen, nil, nil, db)
+	pm.server.privateKey = key
+	pm.blockLoop()
+
+	// Assemble an ultra light client trusting it
+	peers := newPeerSet()
+	dist := newRequestDistributor(peers, make(chan struct{}))
+	rm := newRetrieveManager(peers, dist, nil)
+	ldb := ethdb.NewMemDatabase()
+	odr := NewLesOdr(ldb, light.NewChtIndexer(ldb, true), light.NewBloomTrieIndexer(ldb, true), eth.NewBloomIndexer(ldb, light.BloomTrieFrequency), rm)
+	lpm := newTestProtocolManagerMust(t, true, 0, nil, peers, odr, ldb)
+	lpm.ulc = newTestULC(t, 100, id)
+
+	_, err1, lpeer, err2 := newTestPeerPairWithID("peer", lpv2, pm, lpm, id)
+	select {
+	case <-time.After(time.Millisecond * 100):
+	case err := <-err1:
+		t.Fatalf("peer 1 handshake error: %v", err)
+	case err := <-err2:
+		t.Fatalf("peer 2 handshake error: %v", err)
+	}
+	// Wait for the client to register the peer after the handshake
+	deadline := time.Now().Add(time.Second)
+	for lpm.peers.Peer(lpeer.id) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("server peer not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if lpeer.requestAnnounceType != announceTypeSigned {
+		t.Fatalf("signed announcements not requested")
+	}
+	// Extend the server chain, the client should follow the signed announcement
+	blockchain := pm.blockchain.(*core.BlockChain)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, blockchain.CurrentBlock(), ethash.NewFaker(), db, 1, nil)
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to extend server chain: %v", err)
+	}
+	head := blocks[0].Header()
+	deadline = time.Now().Add(time.Second)
+	for lpm.blockchain.CurrentHeader().Hash() != head.Hash() {
+		if time.Now().After(deadline) {
+			t.Fatalf("trusted head not followed: have #%d, want #%d", lpm.blockchain.CurrentHeader().Number, head.Number)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if td, want := lpm.blockchain.GetTd(head.Hash(), head.Number.Uint64()), blockchain.GetTd(head.Hash(), head.Number.Uint64()); td.Cmp(want) != 0 {
+		t.Fatalf("head td mismatch: have %v, want %v", td, want)
+	}
+	if lpm.peers.Peer(lpeer.id) == nil {
+		t.Fatalf("trusted server dropped after the jump")
+	}
+	// The ancestors were skipped, and are retrieved through ODR when needed
+	lc := lpm.blockchain.(*light.LightChain)
+	for number := head.Number.Uint64() - 1; number > 0; number-- {
+		if lc.GetHeaderByNumber(number) != nil {
+			t.Fatalf("ancestor #%d of the trusted head downloaded", number)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	header, err := lc.GetHeaderByNumberOdr(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to retrieve skipped header: %v", err)
+	}
+	if want := blockchain.GetHeaderByNumber(1); header.Hash() != want.Hash() {
+		t.Fatalf("skipped header mismatch: have %x, want %x", header.Hash(), want.Hash())
+	}
+	for number := head.Number.Uint64() - 1; number > 0; number-- {
+		hash := blockchain.GetHeaderByNumber(number).Hash()
+		if header := lc.GetHeaderByNumber(number); header == nil || header.Hash() != hash {
+			t.Fatalf("skipped ancestor #%d not filled in", number)
+		}
+		if td, want := lc.GetTd(hash, number), blockchain.GetTd(hash, number); td == nil || td.Cmp(want) != 0 {
+			t.Fatalf("skipped ancestor #%d td mismatch: have %v, want %v", number, td, want)
+		}
+	}
+}
@@ -99,7 +99,7 @@ func NewLightChain(odr OdrBackend, config *params.ChainConfig, engine consensus.
 		return nil, core.ErrNoGenesis
 	}
 	if cp, ok := trustedCheckpoints[bc.genesisBlock.Hash()]; ok {
-		bc.addTrustedCheckpoint(cp)
+		bc.AddTrustedCheckpoint(&cp)
 	}
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
@@ -115,20 +115,25 @@ func NewLightChain(odr OdrBackend, config *params.ChainConfig, engine consensus.
 	return bc, nil
 }
 
-// addTrustedCheckpoint adds a trusted checkpoint to the blockchain
-func (self *LightChain) addTrustedCheckpoint(cp trustedCheckpoint) {
+// AddTrustedCheckpoint adds a trusted checkpoint to the blockchain, allowing
+// the header chain to be synced from the checkpoint onwards.
+func (self *LightChain) AddTrustedCheckpoint(cp *TrustedCheckpoint) {
 	if self.odr.ChtIndexer() != nil {
-		StoreChtRoot(self.chainDb, cp.sectionIdx, cp.sectionHead, cp.chtRoot)
-		self.odr.ChtIndexer().AddKnownSectionHead(cp.sectionIdx, cp.sectionHead)
+		StoreChtRoot(self.chainDb, cp.SectionIdx, cp.SectionHead, cp.CHTRoot)
+		self.odr.ChtIndexer().AddKnownSectionHead(cp.SectionIdx, cp.SectionHead)
 	}
 	if self.odr.BloomTrieIndexer() != nil {
-		StoreBloomTrieRoot(self.chainDb, cp.sectionIdx, cp.sectionHead, cp.bloomTrieRoot)
-		self.odr.BloomTrieIndexer().AddKnownSectionHead(cp.sectionIdx, cp.sectionHead)
+		StoreBloomTrieRoot(self.chainDb, cp.SectionIdx, cp.SectionHead, cp.BloomTrieRoot)
+		self.odr.BloomTrieIndexer().AddKnownSectionHead(cp.SectionIdx, cp.SectionHead)
 	}
 	if self.odr.BloomIndexer() != nil {
-		self.odr.BloomIndexer().AddKnownSectionHead(cp.sectionIdx, cp.sectionHead)
+		self.odr.BloomIndexer().AddKnownSectionHead(cp.SectionIdx, cp.SectionHead)
 	}
-	log.Info("Added trusted checkpoint", "chain", cp.name, "block", (cp.sectionIdx+1)*CHTFrequencyClient-1, "hash", cp.sectionHead)
+	name := cp.name
+	if name == "" {
+		name = "oracle"
+	}
+	log.Info("Added trusted checkpoint", "chain", name, "block", (cp.SectionIdx+1)*CHTFrequencyClient-1, "hash", cp.SectionHead)
 }
 
 func (self *LightChain) getProcInterrupt() bool {
@@ -391,6 +396,46 @@ func (self *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int)
 	return i, err
 }
 
+// InsertTrustedHeader writes a header together with its total difficulty
+// without verifying it against its ancestors, and makes it the new head if its
+// total difficulty is higher than the current one. It is used by ultra light
+// clients following the heads signed by trusted servers, the skipped ancestors
+// are retrieved on demand. The caller is responsible for the header's validity.
+func (self *LightChain) InsertTrustedHeader(header *types.Header, td *big.Int) {
+	self.chainmu.Lock()
+	defer self.chainmu.Unlock()
+
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+		events []interface{}
+	)
+	self.mu.Lock()
+	if err := self.hc.WriteTd(hash, number, td); err != nil {
+		log.Crit("Failed to write header total difficulty", "err", err)
+	}
+	rawdb.WriteHeader(self.chainDb, header)
+
+	head := self.hc.CurrentHeader()
+	if td.Cmp(self.hc.GetTd(head.Hash(), head.Number.Uint64())) > 0 {
+		// Delete any canonical number assignments above the new head
+		for i := number + 1; ; i++ {
+			if rawdb.ReadCanonicalHash(self.chainDb, i) == (common.Hash{}) {
+				break
+			}
+			rawdb.DeleteCanonicalHash(self.chainDb, i)
+		}
+		rawdb.WriteCanonicalHash(self.chainDb, hash, number)
+		self.hc.SetCurrentHeader(header)
+
+		log.Debug("Inserted trusted header", "number", number, "hash", hash)
+		events = append(events, core.ChainEvent{Block: types.NewBlockWithHeader(header), Hash: hash})
+	}
+	self.mu.Unlock()
+
+	self.postChainEvents(events)
+}
+
 // CurrentHeader retrieves the current head header of the canonical chain. The
 // header is retrieved from the HeaderChain's internal cache.
 func (self *LightChain) CurrentHeader() *types.Header {
@@ -116,6 +116,23 @@ func (req *BlockRequest) StoreResult(db ethdb.Database) {
 	rawdb.WriteBodyRLP(db, req.Hash, req.Number, req.Rlp)
 }
 
+// HeaderRequest is the ODR request type for retrieving a batch of block headers
+// backwards from a block hash
+type HeaderRequest struct {
+	OdrRequest
+	Hash    common.Hash
+	Number  uint64
+	Amount  uint64
+	Headers []*types.Header
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *HeaderRequest) StoreResult(db ethdb.Database) {
+	for _, header := range req.Headers {
+		rawdb.WriteHeader(db, header)
+	}
+}
+
 // ReceiptsRequest is the ODR request type for retrieving block bodies
 type ReceiptsRequest struct {
 	OdrRequest
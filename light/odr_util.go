@@ -19,6 +19,7 @@ package light
 import (
 	"bytes"
 	"context"
+	"math/big"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core"
@@ -30,6 +31,10 @@ import (
 
 var sha3_nil = crypto.Keccak256Hash(nil)
 
+// maxSkippedHeaderFetch is the number of skipped headers retrieved in a single
+// request.
+const maxSkippedHeaderFetch = 192
+
 func GetHeaderByNumber(ctx context.Context, odr OdrBackend, number uint64) (*types.Header, error) {
 	db := odr.Database()
 	hash := rawdb.ReadCanonicalHash(db, number)
@@ -60,7 +65,7 @@ func GetHeaderByNumber(ctx context.Context, odr OdrBackend, number uint64) (*typ
 		}
 	}
 	if number >= chtCount*CHTFrequencyClient {
-		return nil, ErrNoTrustedCht
+		return getSkippedHeader(ctx, odr, number)
 	}
 	r := &ChtRequest{ChtRoot: GetChtRoot(db, chtCount-1, sectionHead), ChtNum: chtCount - 1, BlockNum: number}
 	if err := odr.Retrieve(ctx, r); err != nil {
@@ -69,6 +74,49 @@ func GetHeaderByNumber(ctx context.Context, odr OdrBackend, number uint64) (*typ
 	return r.Header, nil
 }
 
+// getSkippedHeader retrieves a canonical header above the last trusted CHT which
+// is missing because an ultra light client jumped to a trusted head. The missing
+// headers are retrieved backwards from the closest canonical header above it,
+// and written together with their total difficulties and canonical hashes.
+func getSkippedHeader(ctx context.Context, odr OdrBackend, number uint64) (*types.Header, error) {
+	db := odr.Database()
+	headNumber := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db))
+	if headNumber == nil || number >= *headNumber {
+		return nil, ErrNoTrustedCht
+	}
+	next := number + 1
+	for next < *headNumber && rawdb.ReadCanonicalHash(db, next) == (common.Hash{}) {
+		next++
+	}
+	header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, next), next)
+	if header == nil {
+		return nil, ErrNoHeader
+	}
+	td := rawdb.ReadTd(db, header.Hash(), next)
+	if td == nil {
+		return nil, ErrNoHeader
+	}
+	for header.Number.Uint64() > number {
+		amount := header.Number.Uint64() - number
+		if amount > maxSkippedHeaderFetch {
+			amount = maxSkippedHeaderFetch
+		}
+		r := &HeaderRequest{Hash: header.ParentHash, Number: header.Number.Uint64() - 1, Amount: amount}
+		if err := odr.Retrieve(ctx, r); err != nil {
+			return nil, err
+		}
+		for _, parent := range r.Headers {
+			td = new(big.Int).Sub(td, header.Difficulty)
+			hash, num := parent.Hash(), parent.Number.Uint64()
+
+			rawdb.WriteTd(db, hash, num, td)
+			rawdb.WriteCanonicalHash(db, hash, num)
+			header = parent
+		}
+	}
+	return header, nil
+}
+
 func GetCanonicalHash(ctx context.Context, odr OdrBackend, number uint64) (common.Hash, error) {
 	hash := rawdb.ReadCanonicalHash(odr.Database(), number)
 	if (hash != common.Hash{}) {
@@ -78,6 +78,7 @@ type Work struct {
 	header   *types.Header
 	txs      []*types.Transaction
 	receipts []*types.Receipt
+	tracer   core.SealingTracer // traces the transactions of the new block, nil if not tracing
 
 	createdAt time.Time
 }
@@ -321,6 +322,9 @@ func (self *worker) wait() {
 				log.Error("Failed writing block to chain", "err", err)
 				continue
 			}
+			if work.tracer != nil {
+				work.tracer.Seal(block)
+			}
 			// Broadcast the block and announce chain insertion event
 			self.mux.Post(core.NewMinedBlockEvent{Block: block})
 			var (
@@ -368,6 +372,12 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 		header:    header,
 		createdAt: time.Now(),
 	}
+	// trace the new block like the imported ones if the chain does so
+	if config := self.chain.GetVMConfig(); config.Debug {
+		if tracer, ok := config.Tracer.(core.SealingTracer); ok {
+			work.tracer = tracer.Fork()
+		}
+	}
 
 	// when 08 is processed ancestors contain 07 (quick block)
 	for _, ancestor := range self.chain.GetBlocksFromHash(parent.Hash(), 7) {
@@ -606,7 +616,12 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 func (env *Work) commitTransaction(tx *types.Transaction, bc *core.BlockChain, coinbase common.Address, gp *core.GasPool) (error, []*types.Log) {
 	snap := env.state.Snapshot()
 
-	receipt, _, err := core.ApplyTransaction(env.config, bc, &coinbase, gp, env.state, env.header, tx, &env.header.GasUsed, vm.Config{})
+	var vmConfig vm.Config
+	if env.tracer != nil {
+		env.tracer.CaptureTransaction(types.NewBlockWithHeader(env.header), tx, env.tcount)
+		vmConfig = vm.Config{Debug: true, Tracer: env.tracer}
+	}
+	receipt, _, err := core.ApplyTransaction(env.config, bc, &coinbase, gp, env.state, env.header, tx, &env.header.GasUsed, vmConfig)
 	if err != nil {
 		env.state.RevertToSnapshot(snap)
 		return err, nil
@@ -33,6 +33,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/les"
 	"github.com/Ethereum-Reloaded/ETHR-Go/node"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/nat"
 	"github.com/Ethereum-Reloaded/ETHR-Go/params"
 	whisper "github.com/Ethereum-Reloaded/ETHR-Go/whisper/whisperv6"
@@ -71,6 +72,15 @@ type NodeConfig struct {
 	// It has the form "nodename:secret@host:port"
 	EthereumNetStats string
 
+	// UltraLightServers is the list of trusted LES servers. If set, the node runs
+	// in ultra light client mode, following the head announcements signed by a
+	// quorum of these servers instead of downloading and verifying every header.
+	UltraLightServers *Enodes
+
+	// UltraLightMinTrustedFraction is the minimum percentage of trusted servers
+	// that must agree on a head in ultra light client mode.
+	UltraLightMinTrustedFraction int
+
 	// WhisperEnabled specifies whether the node should run the Whisper protocol.
 	WhisperEnabled bool
 
@@ -131,6 +141,16 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 			MaxPeers:         config.MaxPeers,
 		},
 	}
+	// Ultra light clients always keep connected to the trusted servers
+	if config.UltraLightServers != nil {
+		for _, n := range config.UltraLightServers.nodes {
+			server, err := discover.ParseNode(n.String())
+			if err != nil {
+				return nil, fmt.Errorf("invalid ultra light server: %v", err)
+			}
+			nodeConf.P2P.StaticNodes = append(nodeConf.P2P.StaticNodes, server)
+		}
+	}
 	rawStack, err := node.New(nodeConf)
 	if err != nil {
 		return nil, err
@@ -160,6 +180,12 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 		ethConf.SyncMode = downloader.LightSync
 		ethConf.NetworkId = uint64(config.EthereumNetworkID)
 		ethConf.DatabaseCache = config.EthereumDatabaseCache
+		if config.UltraLightServers != nil && config.UltraLightServers.Size() > 0 {
+			ethConf.ULC = &eth.ULCConfig{MinTrustedFraction: config.UltraLightMinTrustedFraction}
+			for _, n := range config.UltraLightServers.nodes {
+				ethConf.ULC.TrustedServers = append(ethConf.ULC.TrustedServers, n.String())
+			}
+		}
 		if err := rawStack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 			return les.New(ctx, &ethConf)
 		}); err != nil {
@@ -19,6 +19,7 @@ package node
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -75,6 +76,96 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// defaultBanDuration is the duration of bans requested without an explicit one.
+const defaultBanDuration = 24 * time.Hour
+
+// BanInfo represents a banned node ID or IP address.
+type BanInfo struct {
+	ID     string    `json:"id,omitempty"`
+	IP     string    `json:"ip,omitempty"`
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// BanPeer prevents a remote node from connecting, disconnecting it if it is
+// connected. The target is either an enode URL, a hex node ID or an IP address.
+// The ban lasts for the given number of seconds, or a day if omitted.
+func (api *PrivateAdminAPI) BanPeer(target string, seconds *uint64) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, ip, err := parseBanTarget(target)
+	if err != nil {
+		return false, err
+	}
+	duration := defaultBanDuration
+	if seconds != nil {
+		duration = time.Duration(*seconds) * time.Second
+	}
+	if err := server.Ban(id, ip, duration, "banned by admin"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnbanPeer lifts the ban of a node ID or IP address, reporting whether it was
+// banned.
+func (api *PrivateAdminAPI) UnbanPeer(target string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, ip, err := parseBanTarget(target)
+	if err != nil {
+		return false, err
+	}
+	return server.Unban(id, ip), nil
+}
+
+// ListBans retrieves the node IDs and IP addresses currently banned.
+func (api *PrivateAdminAPI) ListBans() ([]*BanInfo, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	bans := server.Bans()
+	infos := make([]*BanInfo, 0, len(bans))
+	for _, ban := range bans {
+		info := &BanInfo{Until: ban.Until, Reason: ban.Reason}
+		if ban.ID != (discover.NodeID{}) {
+			info.ID = ban.ID.String()
+		}
+		if ban.IP != nil {
+			info.IP = ban.IP.String()
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// parseBanTarget interprets an enode URL, hex node ID or IP address.
+func parseBanTarget(target string) (discover.NodeID, net.IP, error) {
+	if ip := net.ParseIP(target); ip != nil {
+		return discover.NodeID{}, ip, nil
+	}
+	if strings.HasPrefix(target, "enode://") {
+		node, err := discover.ParseNode(target)
+		if err != nil {
+			return discover.NodeID{}, nil, fmt.Errorf("invalid enode: %v", err)
+		}
+		return node.ID, nil, nil
+	}
+	id, err := discover.HexID(target)
+	if err != nil {
+		return discover.NodeID{}, nil, fmt.Errorf("invalid ban target %q: not an enode URL, node ID or IP address", target)
+	}
+	return id, nil, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
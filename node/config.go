@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/accounts"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/external"
 	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/keystore"
 	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/usbwallet"
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
@@ -85,6 +86,11 @@ type Config struct {
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
+	// ExternalSigner is the endpoint (IPC path or HTTP URL) of an external signer,
+	// such as clef, whose accounts are made available to the node. If set, the
+	// node uses neither its key store nor hardware wallets.
+	ExternalSigner string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -423,6 +429,16 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	if err := os.MkdirAll(keydir, 0700); err != nil {
 		return nil, "", err
 	}
+	// An external signer holds all keys outside of the node, so no local key
+	// store or hardware wallets are used alongside it.
+	if conf.ExternalSigner != "" {
+		extapi, err := external.NewExternalBackend(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to connect to external signer: %v", err)
+		}
+		log.Info("Using external signer", "url", conf.ExternalSigner)
+		return accounts.NewManager(extapi), ephemeral, nil
+	}
 	// Assemble the account manager and supported backends
 	backends := []accounts.Backend{
 		keystore.NewKeyStore(keydir, scryptN, scryptP),
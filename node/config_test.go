@@ -21,9 +21,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/external"
+	"github.com/Ethereum-Reloaded/ETHR-Go/accounts/keystore"
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p"
 )
@@ -148,3 +151,26 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that a node using an external signer doesn't start any local account
+// backends.
+func TestExternalSignerOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	am, _, err := makeAccountManager(&Config{DataDir: dir, NoUSB: true, ExternalSigner: "http://127.0.0.1:8550"})
+	if err != nil {
+		t.Fatalf("failed to create account manager: %v", err)
+	}
+	defer am.Close()
+
+	if backends := am.Backends(keystore.KeyStoreType); len(backends) != 0 {
+		t.Errorf("keystore started alongside the external signer")
+	}
+	if backends := am.Backends(reflect.TypeOf(&external.ExternalBackend{})); len(backends) != 1 {
+		t.Errorf("external backend count mismatch: have %d, want 1", len(backends))
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/netutil"
 )
 
@@ -72,11 +73,15 @@ type dialstate struct {
 	maxDynDials int
 	ntab        discoverTable
 	netrestrict *netutil.Netlist
+	filter      func(*enr.Record) bool // predicate on the records of dynamic dial candidates
+	sources     []NodeSource           // additional sources of dynamic dial candidates
 
 	lookupRunning bool
 	dialing       map[discover.NodeID]connFlag
 	lookupBuf     []*discover.Node // current discovery lookup results
+	unknownBuf    []*discover.Node // candidates whose record couldn't be retrieved
 	randomNodes   []*discover.Node // filled from Table
+	randomRecords []*enr.Record    // filled from additional node sources
 	static        map[discover.NodeID]*dialTask
 	hist          *dialHistory
 
@@ -84,12 +89,20 @@ type dialstate struct {
 	bootnodes []*discover.Node // default dials when there are no peers
 }
 
+// NodeSource provides the node records of dial candidates found outside of the
+// discovery table, e.g. through DNS. Implementations must not block and must be
+// comparable, as sources shared by several protocols are only used once.
+type NodeSource interface {
+	ReadRandomRecords([]*enr.Record) int
+}
+
 type discoverTable interface {
 	Self() *discover.Node
 	Close()
 	Resolve(target discover.NodeID) *discover.Node
 	Lookup(target discover.NodeID) []*discover.Node
 	ReadRandomNodes([]*discover.Node) int
+	RequestENR(*discover.Node) (*enr.Record, error)
 }
 
 // the dial history remembers recent dials.
@@ -112,6 +125,9 @@ type dialTask struct {
 	dest         *discover.Node
 	lastResolved time.Time
 	resolveDelay time.Duration
+	filter       func(*enr.Record) bool
+	record       *enr.Record // known node record of the destination, if any
+	norecord     bool        // set if the record couldn't be retrieved for filtering
 }
 
 // discoverTask runs discovery table operations.
@@ -127,16 +143,18 @@ type waitExpireTask struct {
 	time.Duration
 }
 
-func newDialState(static []*discover.Node, bootnodes []*discover.Node, ntab discoverTable, maxdyn int, netrestrict *netutil.Netlist) *dialstate {
+func newDialState(static []*discover.Node, bootnodes []*discover.Node, ntab discoverTable, maxdyn int, netrestrict *netutil.Netlist, filter func(*enr.Record) bool) *dialstate {
 	s := &dialstate{
-		maxDynDials: maxdyn,
-		ntab:        ntab,
-		netrestrict: netrestrict,
-		static:      make(map[discover.NodeID]*dialTask),
-		dialing:     make(map[discover.NodeID]connFlag),
-		bootnodes:   make([]*discover.Node, len(bootnodes)),
-		randomNodes: make([]*discover.Node, maxdyn/2),
-		hist:        new(dialHistory),
+		maxDynDials:   maxdyn,
+		ntab:          ntab,
+		netrestrict:   netrestrict,
+		filter:        filter,
+		static:        make(map[discover.NodeID]*dialTask),
+		dialing:       make(map[discover.NodeID]connFlag),
+		bootnodes:     make([]*discover.Node, len(bootnodes)),
+		randomNodes:   make([]*discover.Node, maxdyn/2),
+		randomRecords: make([]*enr.Record, maxdyn/2+1),
+		hist:          new(dialHistory),
 	}
 	copy(s.bootnodes, bootnodes)
 	for _, n := range static {
@@ -151,6 +169,16 @@ func (s *dialstate) addStatic(n *discover.Node) {
 	s.static[n.ID] = &dialTask{flags: staticDialedConn, dest: n}
 }
 
+// addSource adds a source of dynamic dial candidates besides the discovery table.
+func (s *dialstate) addSource(src NodeSource) {
+	for _, have := range s.sources {
+		if have == src {
+			return
+		}
+	}
+	s.sources = append(s.sources, src)
+}
+
 func (s *dialstate) removeStatic(n *discover.Node) {
 	// This removes a task so future attempts to connect will not be made.
 	delete(s.static, n.ID)
@@ -165,13 +193,13 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 	}
 
 	var newtasks []task
-	addDial := func(flag connFlag, n *discover.Node) bool {
+	addDial := func(flag connFlag, n *discover.Node, r *enr.Record) bool {
 		if err := s.checkDial(n, peers); err != nil {
 			log.Trace("Skipping dial candidate", "id", n.ID, "addr", &net.TCPAddr{IP: n.IP, Port: int(n.TCP)}, "err", err)
 			return false
 		}
 		s.dialing[n.ID] = flag
-		newtasks = append(newtasks, &dialTask{flags: flag, dest: n})
+		newtasks = append(newtasks, &dialTask{flags: flag, dest: n, filter: s.filter, record: r})
 		return true
 	}
 
@@ -211,7 +239,7 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 		s.bootnodes = append(s.bootnodes[:0], s.bootnodes[1:]...)
 		s.bootnodes = append(s.bootnodes, bootnode)
 
-		if addDial(dynDialedConn, bootnode) {
+		if addDial(dynDialedConn, bootnode, nil) {
 			needDynDials--
 		}
 	}
@@ -221,20 +249,53 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 	if randomCandidates > 0 {
 		n := s.ntab.ReadRandomNodes(s.randomNodes)
 		for i := 0; i < randomCandidates && i < n; i++ {
-			if addDial(dynDialedConn, s.randomNodes[i]) {
+			if addDial(dynDialedConn, s.randomNodes[i], nil) {
 				needDynDials--
 			}
 		}
 	}
+	// Use records from the additional node sources for another share
+	// of the dynamic dials.
+	if len(s.sources) > 0 && needDynDials > 0 {
+		share := randomCandidates / len(s.sources)
+		if share == 0 {
+			share = 1
+		}
+		for _, src := range s.sources {
+			n := src.ReadRandomRecords(s.randomRecords[:share])
+			for i := 0; i < n && needDynDials > 0; i++ {
+				node, err := discover.NodeFromRecord(s.randomRecords[i])
+				if err != nil {
+					log.Trace("Skipping dial candidate record", "err", err)
+					continue
+				}
+				if addDial(dynDialedConn, node, s.randomRecords[i]) {
+					needDynDials--
+				}
+			}
+		}
+	}
 	// Create dynamic dials from random lookup results, removing tried
 	// items from the result buffer.
 	i := 0
 	for ; i < len(s.lookupBuf) && needDynDials > 0; i++ {
-		if addDial(dynDialedConn, s.lookupBuf[i]) {
+		if addDial(dynDialedConn, s.lookupBuf[i], nil) {
 			needDynDials--
 		}
 	}
 	s.lookupBuf = s.lookupBuf[:copy(s.lookupBuf, s.lookupBuf[i:])]
+	// Dial candidates whose record couldn't be retrieved only when there are
+	// no other candidates left. They can't be filtered, so they are tried last.
+	for len(s.lookupBuf) == 0 && len(s.unknownBuf) > 0 && needDynDials > 0 {
+		n := s.unknownBuf[0]
+		s.unknownBuf = s.unknownBuf[1:]
+		if _, dialing := s.dialing[n.ID]; dialing || peers[n.ID] != nil {
+			continue
+		}
+		s.dialing[n.ID] = dynDialedConn
+		newtasks = append(newtasks, &dialTask{flags: dynDialedConn, dest: n})
+		needDynDials--
+	}
 	// Launch a discovery lookup if more candidates are needed.
 	if len(s.lookupBuf) < needDynDials && !s.lookupRunning {
 		s.lookupRunning = true
@@ -282,18 +343,42 @@ func (s *dialstate) taskDone(t task, now time.Time) {
 	case *dialTask:
 		s.hist.add(t.dest.ID, now.Add(dialHistoryExpiration))
 		delete(s.dialing, t.dest.ID)
+		if t.norecord {
+			s.addUnknown(t.dest)
+		}
 	case *discoverTask:
 		s.lookupRunning = false
 		s.lookupBuf = append(s.lookupBuf, t.results...)
 	}
 }
 
+// addUnknown keeps a candidate whose record couldn't be retrieved for later,
+// dropping the oldest ones beyond the number of dynamic dial slots.
+func (s *dialstate) addUnknown(n *discover.Node) {
+	for _, have := range s.unknownBuf {
+		if have.ID == n.ID {
+			return
+		}
+	}
+	s.unknownBuf = append(s.unknownBuf, n)
+	if len(s.unknownBuf) > s.maxDynDials {
+		s.unknownBuf = s.unknownBuf[len(s.unknownBuf)-s.maxDynDials:]
+	}
+}
+
 func (t *dialTask) Do(srv *Server) {
 	if t.dest.Incomplete() {
 		if !t.resolve(srv) {
 			return
 		}
 	}
+	if t.filter != nil && t.flags&dynDialedConn != 0 && !t.checkRecord(srv) {
+		return
+	}
+	if srv.rep.banned(t.dest.ID, t.dest.IP) {
+		log.Trace("Skipping dial of banned node", "id", t.dest.ID, "ip", t.dest.IP)
+		return
+	}
 	err := t.dial(srv, t.dest)
 	if err != nil {
 		log.Trace("Dial error", "task", t, "err", err)
@@ -340,12 +425,47 @@ func (t *dialTask) resolve(srv *Server) bool {
 	return true
 }
 
+// checkRecord retrieves the node record of the destination, unless it is known
+// already, and reports whether it passes the dial filter. Nodes whose record
+// can't be retrieved are not dialed now, but marked for a later fallback dial.
+func (t *dialTask) checkRecord(srv *Server) bool {
+	record := t.record
+	if record == nil && srv.ntab != nil {
+		var err error
+		if record, err = srv.ntab.RequestENR(t.dest); err != nil {
+			log.Trace("Can't retrieve record of dial candidate", "id", t.dest.ID, "err", err)
+			record = nil
+		}
+	}
+	if record == nil {
+		log.Trace("Deferring dial candidate without record", "id", t.dest.ID)
+		t.norecord = true
+		return false
+	}
+	t.record = record
+	if !t.filter(record) {
+		log.Trace("Skipping dial candidate", "id", t.dest.ID, "err", "record rejected by filter")
+		return false
+	}
+	return true
+}
+
 type dialError struct {
 	error
 }
 
-// dial performs the actual connection attempt.
+// dial performs the actual connection attempt. If the server listens for QUIC,
+// nodes announcing a QUIC port are dialed over QUIC first.
 func (t *dialTask) dial(srv *Server, dest *discover.Node) error {
+	if srv.quicListener != nil {
+		if addr := t.quicAddr(srv, dest); addr != nil {
+			fd, err := srv.quicListener.dial(addr)
+			if err == nil {
+				return srv.SetupConn(newMeteredConn(fd, false), t.flags, dest)
+			}
+			log.Trace("QUIC dial error", "id", dest.ID, "addr", addr, "err", err)
+		}
+	}
 	fd, err := srv.Dialer.Dial(dest)
 	if err != nil {
 		return &dialError{err}
@@ -354,6 +474,19 @@ func (t *dialTask) dial(srv *Server, dest *discover.Node) error {
 	return srv.SetupConn(mfd, t.flags, dest)
 }
 
+// quicAddr returns the QUIC endpoint announced in the node record of dest, or
+// nil if there is none or the record can't be retrieved.
+func (t *dialTask) quicAddr(srv *Server, dest *discover.Node) *net.UDPAddr {
+	if t.record == nil && srv.ntab != nil {
+		t.record, _ = srv.ntab.RequestENR(dest)
+	}
+	var port enr.QUIC
+	if t.record == nil || t.record.Load(&port) != nil {
+		return nil
+	}
+	return &net.UDPAddr{IP: dest.IP, Port: int(port)}
+}
+
 func (t *dialTask) String() string {
 	return fmt.Sprintf("%v %x %v:%d", t.flags, t.dest.ID[:8], t.dest.IP, t.dest.TCP)
 }
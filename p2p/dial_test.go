@@ -18,12 +18,15 @@ package p2p
 
 import (
 	"encoding/binary"
+	"errors"
 	"net"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/netutil"
 	"github.com/davecgh/go-spew/spew"
 )
@@ -83,11 +86,14 @@ func (t fakeTable) Close()                                   {}
 func (t fakeTable) Lookup(discover.NodeID) []*discover.Node  { return nil }
 func (t fakeTable) Resolve(discover.NodeID) *discover.Node   { return nil }
 func (t fakeTable) ReadRandomNodes(buf []*discover.Node) int { return copy(buf, t) }
+func (t fakeTable) RequestENR(*discover.Node) (*enr.Record, error) {
+	return nil, errors.New("no record")
+}
 
 // This test checks that dynamic dials are launched from discovery results.
 func TestDialStateDynDial(t *testing.T) {
 	runDialTest(t, dialtest{
-		init: newDialState(nil, nil, fakeTable{}, 5, nil),
+		init: newDialState(nil, nil, fakeTable{}, 5, nil, nil),
 		rounds: []round{
 			// A discovery query is launched.
 			{
@@ -234,7 +240,7 @@ func TestDialStateDynDialBootnode(t *testing.T) {
 		{ID: uintID(8)},
 	}
 	runDialTest(t, dialtest{
-		init: newDialState(nil, bootnodes, table, 5, nil),
+		init: newDialState(nil, bootnodes, table, 5, nil, nil),
 		rounds: []round{
 			// 2 dynamic dials attempted, bootnodes pending fallback interval
 			{
@@ -322,7 +328,7 @@ func TestDialStateDynDialFromTable(t *testing.T) {
 	}
 
 	runDialTest(t, dialtest{
-		init: newDialState(nil, nil, table, 10, nil),
+		init: newDialState(nil, nil, table, 10, nil, nil),
 		rounds: []round{
 			// 5 out of 8 of the nodes returned by ReadRandomNodes are dialed.
 			{
@@ -420,7 +426,7 @@ func TestDialStateNetRestrict(t *testing.T) {
 	restrict.Add("127.0.2.0/24")
 
 	runDialTest(t, dialtest{
-		init: newDialState(nil, nil, table, 10, restrict),
+		init: newDialState(nil, nil, table, 10, restrict, nil),
 		rounds: []round{
 			{
 				new: []task{
@@ -443,7 +449,7 @@ func TestDialStateStaticDial(t *testing.T) {
 	}
 
 	runDialTest(t, dialtest{
-		init: newDialState(wantStatic, nil, fakeTable{}, 0, nil),
+		init: newDialState(wantStatic, nil, fakeTable{}, 0, nil, nil),
 		rounds: []round{
 			// Static dials are launched for the nodes that
 			// aren't yet connected.
@@ -547,7 +553,7 @@ func TestDialStaticAfterReset(t *testing.T) {
 		},
 	}
 	dTest := dialtest{
-		init:   newDialState(wantStatic, nil, fakeTable{}, 0, nil),
+		init:   newDialState(wantStatic, nil, fakeTable{}, 0, nil, nil),
 		rounds: rounds,
 	}
 	runDialTest(t, dTest)
@@ -568,7 +574,7 @@ func TestDialStateCache(t *testing.T) {
 	}
 
 	runDialTest(t, dialtest{
-		init: newDialState(wantStatic, nil, fakeTable{}, 0, nil),
+		init: newDialState(wantStatic, nil, fakeTable{}, 0, nil, nil),
 		rounds: []round{
 			// Static dials are launched for the nodes that
 			// aren't yet connected.
@@ -630,7 +636,7 @@ func TestDialStateCache(t *testing.T) {
 func TestDialResolve(t *testing.T) {
 	resolved := discover.NewNode(uintID(1), net.IP{127, 0, 55, 234}, 3333, 4444)
 	table := &resolveMock{answer: resolved}
-	state := newDialState(nil, nil, table, 0, nil)
+	state := newDialState(nil, nil, table, 0, nil, nil)
 
 	// Check that the task is generated with an incomplete ID.
 	dest := discover.NewNode(uintID(1), nil, 0, 0)
@@ -655,6 +661,108 @@ func TestDialResolve(t *testing.T) {
 	}
 }
 
+// This test checks that dynamic dials are only attempted if the record of the
+// destination passes the dial filter, while static dials are left alone.
+// Candidates whose record can't be retrieved are only dialed as a fallback.
+func TestDialFilter(t *testing.T) {
+	record := func(value uint) *enr.Record {
+		r := new(enr.Record)
+		r.Set(enr.WithEntry("test", value))
+		return r
+	}
+	table := &recordMock{
+		fakeTable: fakeTable{
+			discover.NewNode(uintID(1), net.IP{127, 0, 0, 1}, 30303, 30303),
+			discover.NewNode(uintID(2), net.IP{127, 0, 0, 2}, 30303, 30303),
+			discover.NewNode(uintID(3), net.IP{127, 0, 0, 3}, 30303, 30303),
+		},
+		records: map[discover.NodeID]*enr.Record{
+			uintID(1): record(1), // accepted
+			uintID(2): record(2), // rejected
+			// node 3 has no record, deferred
+		},
+	}
+	static := discover.NewNode(uintID(4), net.IP{127, 0, 0, 4}, 30303, 30303)
+	filter := func(r *enr.Record) bool {
+		var value uint
+		return r.Load(enr.WithEntry("test", &value)) == nil && value == 1
+	}
+	state := newDialState([]*discover.Node{static}, nil, table, 6, nil, filter)
+
+	dialer := new(recordingDialer)
+	srv := &Server{ntab: table, Config: Config{Dialer: dialer}}
+	runDials := func(now time.Time) map[discover.NodeID]bool {
+		dialer.dialed = nil
+		for _, task := range state.newTasks(0, nil, now) {
+			if _, ok := task.(*dialTask); ok {
+				task.Do(srv)
+				state.taskDone(task, now)
+			}
+		}
+		dialed := make(map[discover.NodeID]bool)
+		for _, id := range dialer.dialed {
+			dialed[id] = true
+		}
+		return dialed
+	}
+	// Candidates without record aren't dialed while others are available.
+	want := map[discover.NodeID]bool{uintID(1): true, uintID(4): true}
+	if dialed := runDials(time.Time{}); !reflect.DeepEqual(dialed, want) {
+		t.Fatalf("dialed nodes mismatch: got %v, want %v", dialed, want)
+	}
+	// Once all other candidates have been tried, they are dialed last.
+	want = map[discover.NodeID]bool{uintID(3): true}
+	if dialed := runDials(time.Time{}.Add(time.Second)); !reflect.DeepEqual(dialed, want) {
+		t.Fatalf("fallback dials mismatch: got %v, want %v", dialed, want)
+	}
+}
+
+// This test checks that candidates from additional node sources are dialed
+// and checked against the dial filter using their known records.
+func TestDialSources(t *testing.T) {
+	var records []*enr.Record
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		r := new(enr.Record)
+		r.Set(enr.IP(net.IP{127, 0, 0, byte(i + 1)}))
+		r.Set(enr.TCP(30303))
+		r.Set(enr.WithEntry("test", uint(i)))
+		if err := enr.SignV4(r, key); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	filter := func(r *enr.Record) bool {
+		var value uint
+		return r.Load(enr.WithEntry("test", &value)) == nil && value != 1
+	}
+	state := newDialState(nil, nil, fakeTable{}, 6, nil, filter)
+	src := &recordSource{records}
+	state.addSource(src)
+	state.addSource(src) // duplicates are ignored
+
+	dialer := new(recordingDialer)
+	srv := &Server{ntab: fakeTable{}, Config: Config{Dialer: dialer}}
+	var dials int
+	for _, task := range state.newTasks(0, nil, time.Time{}) {
+		if dt, ok := task.(*dialTask); ok {
+			if dt.record == nil {
+				t.Errorf("dial task %v has no record", dt)
+			}
+			dials++
+			task.Do(srv)
+		}
+	}
+	if dials != len(records) {
+		t.Fatalf("wrong number of dial tasks: got %d, want %d", dials, len(records))
+	}
+	// Nodes are dialed without requesting their record, unless the filter
+	// rejects the known one.
+	if len(dialer.dialed) != len(records)-1 {
+		t.Fatalf("wrong number of dials: got %d, want %d", len(dialer.dialed), len(records)-1)
+	}
+}
+
 // compares task lists but doesn't care about the order.
 func sametasks(a, b []task) bool {
 	if len(a) != len(b) {
@@ -694,3 +802,36 @@ func (t *resolveMock) Close()                                   {}
 func (t *resolveMock) Bootstrap([]*discover.Node)               {}
 func (t *resolveMock) Lookup(discover.NodeID) []*discover.Node  { return nil }
 func (t *resolveMock) ReadRandomNodes(buf []*discover.Node) int { return 0 }
+func (t *resolveMock) RequestENR(*discover.Node) (*enr.Record, error) {
+	return nil, errors.New("no record")
+}
+
+// implements discoverTable for TestDialFilter
+type recordMock struct {
+	fakeTable
+	records map[discover.NodeID]*enr.Record
+}
+
+func (t *recordMock) RequestENR(n *discover.Node) (*enr.Record, error) {
+	if r, ok := t.records[n.ID]; ok {
+		return r, nil
+	}
+	return nil, errors.New("no record")
+}
+
+// recordSource is a node source serving a fixed set of records.
+type recordSource struct {
+	records []*enr.Record
+}
+
+func (s *recordSource) ReadRandomRecords(buf []*enr.Record) int { return copy(buf, s.records) }
+
+// recordingDialer records the nodes it is asked to dial, failing every attempt.
+type recordingDialer struct {
+	dialed []discover.NodeID
+}
+
+func (d *recordingDialer) Dial(n *discover.Node) (net.Conn, error) {
+	d.dialed = append(d.dialed, n.ID)
+	return nil, errors.New("dial disabled")
+}
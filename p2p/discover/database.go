@@ -23,10 +23,12 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/binary"
+	"net"
 	"os"
 	"sync"
 	"time"
 
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
@@ -61,6 +63,10 @@ var (
 	nodeDBDiscoverPing      = nodeDBDiscoverRoot + ":lastping"
 	nodeDBDiscoverPong      = nodeDBDiscoverRoot + ":lastpong"
 	nodeDBDiscoverFindFails = nodeDBDiscoverRoot + ":findfail"
+
+	nodeDBBanPrefix   = []byte("ban:")                    // Identifier to prefix ban entries with
+	nodeDBBanIDPrefix = append(nodeDBBanPrefix, "id:"...) // Bans of node IDs
+	nodeDBBanIPPrefix = append(nodeDBBanPrefix, "ip:"...) // Bans of IP addresses
 )
 
 // newNodeDB creates a new node database for storing and retrieving infos about
@@ -303,6 +309,65 @@ func (db *nodeDB) updateFindFails(id NodeID, fails int) error {
 	return db.storeInt64(makeKey(id, nodeDBDiscoverFindFails), int64(fails))
 }
 
+// banRLP is the database encoding of a ban.
+type banRLP struct {
+	ID     NodeID
+	IP     net.IP
+	Until  uint64
+	Reason string
+}
+
+// banKey generates the leveldb key-blob of a ban. Node ID bans take precedence
+// over IP bans.
+func banKey(id NodeID, ip net.IP) []byte {
+	if id != (NodeID{}) {
+		return append(common.CopyBytes(nodeDBBanIDPrefix), id[:]...)
+	}
+	return append(common.CopyBytes(nodeDBBanIPPrefix), ip.To16()...)
+}
+
+// storeBan inserts - potentially overwriting - a ban into the database.
+func (db *nodeDB) storeBan(ban Ban) error {
+	blob, err := rlp.EncodeToBytes(&banRLP{ban.ID, ban.IP, uint64(ban.Until.Unix()), ban.Reason})
+	if err != nil {
+		return err
+	}
+	return db.lvl.Put(banKey(ban.ID, ban.IP), blob, nil)
+}
+
+// deleteBan removes the ban of a node ID or IP address from the database.
+func (db *nodeDB) deleteBan(id NodeID, ip net.IP) error {
+	return db.lvl.Delete(banKey(id, ip), nil)
+}
+
+// bans retrieves all bans still in effect, deleting the expired ones.
+func (db *nodeDB) bans() []Ban {
+	it := db.lvl.NewIterator(util.BytesPrefix(nodeDBBanPrefix), nil)
+	defer it.Release()
+
+	var (
+		now  = time.Now()
+		bans []Ban
+	)
+	for it.Next() {
+		var enc banRLP
+		if err := rlp.DecodeBytes(it.Value(), &enc); err != nil {
+			log.Error("Failed to decode ban RLP", "err", err)
+			continue
+		}
+		ban := Ban{ID: enc.ID, Until: time.Unix(int64(enc.Until), 0), Reason: enc.Reason}
+		if len(enc.IP) > 0 {
+			ban.IP = enc.IP
+		}
+		if !ban.Until.After(now) {
+			db.lvl.Delete(it.Key(), nil)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	return bans
+}
+
 // querySeeds retrieves random nodes to be used as potential seed nodes
 // for bootstrapping.
 func (db *nodeDB) querySeeds(n int, maxAge time.Duration) []*Node {
@@ -378,3 +378,43 @@ func TestNodeDBSelfExpiration(t *testing.T) {
 		t.Errorf("self not evacuated")
 	}
 }
+
+func TestNodeDBBans(t *testing.T) {
+	db, _ := newNodeDB("", Version, NodeID{})
+	defer db.close()
+
+	var (
+		id      = MustHexID("0x1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439")
+		idBan   = Ban{ID: id, Until: time.Now().Add(time.Hour).Truncate(time.Second), Reason: "invalid block"}
+		ipBan   = Ban{IP: net.ParseIP("1.2.3.4"), Until: time.Now().Add(time.Hour).Truncate(time.Second)}
+		expired = Ban{IP: net.ParseIP("5.6.7.8"), Until: time.Now().Add(-time.Hour)}
+	)
+	for _, ban := range []Ban{idBan, ipBan, expired} {
+		if err := db.storeBan(ban); err != nil {
+			t.Fatalf("failed to store ban %v: %v", ban, err)
+		}
+	}
+	bans := db.bans()
+	if len(bans) != 2 {
+		t.Fatalf("ban count mismatch: have %d, want 2", len(bans))
+	}
+	for _, ban := range bans {
+		want := ipBan
+		if ban.ID == id {
+			want = idBan
+		}
+		if !ban.IP.Equal(want.IP) || !ban.Until.Equal(want.Until) || ban.Reason != want.Reason {
+			t.Errorf("ban mismatch: have %+v, want %+v", ban, want)
+		}
+	}
+	// Check that deleting works and expired bans were removed.
+	if err := db.deleteBan(NodeID{}, ipBan.IP); err != nil {
+		t.Fatalf("failed to delete ban: %v", err)
+	}
+	if bans := db.bans(); len(bans) != 1 || bans[0].ID != id {
+		t.Errorf("bans after deletion mismatch: %+v", bans)
+	}
+	if _, err := db.lvl.Get(banKey(NodeID{}, expired.IP), nil); err == nil {
+		t.Errorf("expired ban not removed")
+	}
+}
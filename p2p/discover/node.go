@@ -34,6 +34,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto/secp256k1"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
 )
 
 const NodeIDBits = 512
@@ -71,6 +72,33 @@ func NewNode(id NodeID, ip net.IP, udpPort, tcpPort uint16) *Node {
 	}
 }
 
+// NodeFromRecord creates a node from a signed v4 node record. The record must
+// contain an IP address and TCP port.
+func NodeFromRecord(r *enr.Record) (*Node, error) {
+	var (
+		pubkey enr.Secp256k1
+		ip     enr.IP
+		tcp    enr.TCP
+		udp    enr.UDP
+	)
+	if !r.Signed() {
+		return nil, errors.New("record is not signed")
+	}
+	if err := r.Load(&pubkey); err != nil {
+		return nil, err
+	}
+	if err := r.Load(&ip); err != nil {
+		return nil, err
+	}
+	if err := r.Load(&tcp); err != nil {
+		return nil, err
+	}
+	if err := r.Load(&udp); err != nil && !enr.IsNotFound(err) {
+		return nil, err
+	}
+	return NewNode(PubkeyID((*ecdsa.PublicKey)(&pubkey)), net.IP(ip), uint16(udp), uint16(tcp)), nil
+}
+
 func (n *Node) addr() *net.UDPAddr {
 	return &net.UDPAddr{IP: n.IP, Port: int(n.UDP)}
 }
@@ -36,6 +36,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/netutil"
 )
 
@@ -101,6 +102,8 @@ type transport interface {
 	ping(NodeID, *net.UDPAddr) error
 	waitping(NodeID) error
 	findnode(toid NodeID, addr *net.UDPAddr, target NodeID) ([]*Node, error)
+	requestENR(toid NodeID, addr *net.UDPAddr) (*enr.Record, error)
+	setRecordEntry(enr.Entry) error
 	close()
 }
 
@@ -270,6 +273,41 @@ func (tab *Table) Resolve(targetID NodeID) *Node {
 	return nil
 }
 
+// RequestENR retrieves the current record of the given node. The record is
+// verified to be signed by the node.
+func (tab *Table) RequestENR(n *Node) (*enr.Record, error) {
+	return tab.net.requestENR(n.ID, n.addr())
+}
+
+// SetRecordEntry adds or updates an entry of the local node record served to
+// other nodes.
+func (tab *Table) SetRecordEntry(entry enr.Entry) error {
+	return tab.net.setRecordEntry(entry)
+}
+
+// Ban excludes a node ID or IP address from the network until a given time.
+type Ban struct {
+	ID     NodeID    // Banned node, zero for IP address bans
+	IP     net.IP    // Banned IP address, or the address banned along with the node
+	Until  time.Time // Time the ban expires
+	Reason string    // Human readable reason of the ban
+}
+
+// StoreBan persists a ban in the node database.
+func (tab *Table) StoreBan(ban Ban) error {
+	return tab.db.storeBan(ban)
+}
+
+// DeleteBan removes the ban of a node ID or IP address from the node database.
+func (tab *Table) DeleteBan(id NodeID, ip net.IP) error {
+	return tab.db.deleteBan(id, ip)
+}
+
+// Bans returns all bans in effect stored in the node database.
+func (tab *Table) Bans() []Ban {
+	return tab.db.bans()
+}
+
 // Lookup performs a network search for nodes close
 // to the given target. It approaches the target by querying
 // nodes that are closer to it on each iteration.
@@ -23,10 +23,12 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/nat"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/netutil"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
@@ -44,6 +46,7 @@ var (
 	errTimeout          = errors.New("RPC timeout")
 	errClockWarp        = errors.New("reply deadline too far in the future")
 	errClosed           = errors.New("socket closed")
+	errRecordMismatch   = errors.New("record does not match node ID")
 )
 
 // Timeouts
@@ -62,6 +65,8 @@ const (
 	pongPacket
 	findnodePacket
 	neighborsPacket
+	enrRequestPacket
+	enrResponsePacket
 )
 
 // RPC request structures
@@ -103,6 +108,21 @@ type (
 		Rest []rlp.RawValue `rlp:"tail"`
 	}
 
+	// enrRequest queries for the remote node's record.
+	enrRequest struct {
+		Expiration uint64
+		// Ignore additional fields (for forward compatibility).
+		Rest []rlp.RawValue `rlp:"tail"`
+	}
+
+	// enrResponse is the reply to enrRequest.
+	enrResponse struct {
+		ReplyTok []byte // Hash of the enrRequest packet.
+		Record   enr.Record
+		// Ignore additional fields (for forward compatibility).
+		Rest []rlp.RawValue `rlp:"tail"`
+	}
+
 	rpcNode struct {
 		IP  net.IP // len 4 for IPv4 or 16 for IPv6
 		UDP uint16 // for discovery protocol
@@ -162,6 +182,8 @@ type udp struct {
 	netrestrict *netutil.Netlist
 	priv        *ecdsa.PrivateKey
 	ourEndpoint rpcEndpoint
+	ourRecord   *enr.Record // signed record of the local node, served on request
+	recordMu    sync.Mutex  // protects ourRecord
 
 	addpending chan *pending
 	gotreply   chan reply
@@ -226,6 +248,7 @@ type Config struct {
 	NetRestrict  *netutil.Netlist  // network whitelist
 	Bootnodes    []*Node           // list of bootstrap nodes
 	Unhandled    chan<- ReadPacket // unhandled packets are sent on this channel
+	Entries      []enr.Entry       // additional entries of the local node record
 }
 
 // ListenUDP returns a new table that listens for UDP packets on laddr.
@@ -253,6 +276,12 @@ func newUDP(c conn, cfg Config) (*Table, *udp, error) {
 	}
 	// TODO: separate TCP port
 	udp.ourEndpoint = makeEndpoint(realaddr, uint16(realaddr.Port))
+	record, err := makeRecord(cfg.PrivateKey, udp.ourEndpoint, cfg.Entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	udp.ourRecord = record
+
 	tab, err := newTable(udp, PubkeyID(&cfg.PrivateKey.PublicKey), realaddr, cfg.NodeDBPath, cfg.Bootnodes)
 	if err != nil {
 		return nil, nil, err
@@ -264,6 +293,47 @@ func newUDP(c conn, cfg Config) (*Table, *udp, error) {
 	return udp.Table, udp, nil
 }
 
+// makeRecord creates the signed record of the local node, announcing its
+// endpoint along with the given entries.
+func makeRecord(priv *ecdsa.PrivateKey, endpoint rpcEndpoint, entries []enr.Entry) (*enr.Record, error) {
+	record := new(enr.Record)
+	if !endpoint.IP.IsUnspecified() {
+		record.Set(enr.IP(endpoint.IP))
+	}
+	record.Set(enr.UDP(endpoint.UDP))
+	record.Set(enr.TCP(endpoint.TCP))
+	for _, entry := range entries {
+		record.Set(entry)
+	}
+	if err := enr.SignV4(record, priv); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// setRecordEntry adds or updates an entry of the local node record, signing
+// the record again with an incremented sequence number.
+func (t *udp) setRecordEntry(entry enr.Entry) error {
+	t.recordMu.Lock()
+	defer t.recordMu.Unlock()
+
+	record := *t.ourRecord
+	record.Set(entry)
+	if err := enr.SignV4(&record, t.priv); err != nil {
+		return err
+	}
+	t.ourRecord = &record
+	return nil
+}
+
+// record returns the current record of the local node.
+func (t *udp) record() *enr.Record {
+	t.recordMu.Lock()
+	defer t.recordMu.Unlock()
+
+	return t.ourRecord
+}
+
 func (t *udp) close() {
 	close(t.closing)
 	t.conn.Close()
@@ -319,6 +389,39 @@ func (t *udp) findnode(toid NodeID, toaddr *net.UDPAddr, target NodeID) ([]*Node
 	return nodes, err
 }
 
+// requestENR sends an enrRequest to the given node and waits for its record.
+// The record is verified to be signed by the node.
+func (t *udp) requestENR(toid NodeID, toaddr *net.UDPAddr) (*enr.Record, error) {
+	req := &enrRequest{
+		Expiration: uint64(time.Now().Add(expiration).Unix()),
+	}
+	packet, hash, err := encodePacket(t.priv, enrRequestPacket, req)
+	if err != nil {
+		return nil, err
+	}
+	var record *enr.Record
+	errc := t.pending(toid, enrResponsePacket, func(r interface{}) bool {
+		reply := r.(*enrResponse)
+		if !bytes.Equal(reply.ReplyTok, hash) {
+			return false
+		}
+		record = &reply.Record
+		return true
+	})
+	t.write(toaddr, req.name(), packet)
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	var pubkey enr.Secp256k1
+	if err := record.Load(&pubkey); err != nil {
+		return nil, err
+	}
+	if PubkeyID((*ecdsa.PublicKey)(&pubkey)) != toid {
+		return nil, errRecordMismatch
+	}
+	return record, nil
+}
+
 // pending adds a reply callback to the pending reply queue.
 // see the documentation of type pending for a detailed explanation.
 func (t *udp) pending(id NodeID, ptype byte, callback func(interface{}) bool) <-chan error {
@@ -570,6 +673,10 @@ func decodePacket(buf []byte) (packet, NodeID, []byte, error) {
 		req = new(findnode)
 	case neighborsPacket:
 		req = new(neighbors)
+	case enrRequestPacket:
+		req = new(enrRequest)
+	case enrResponsePacket:
+		req = new(enrResponse)
 	default:
 		return nil, fromID, hash, fmt.Errorf("unknown type: %d", ptype)
 	}
@@ -661,6 +768,32 @@ func (req *neighbors) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byt
 
 func (req *neighbors) name() string { return "NEIGHBORS/v4" }
 
+func (req *enrRequest) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
+	if expired(req.Expiration) {
+		return errExpired
+	}
+	if !t.db.hasBond(fromID) {
+		// No bond exists, we don't process the packet, just like findnode.
+		return errUnknownNode
+	}
+	t.send(from, enrResponsePacket, &enrResponse{
+		ReplyTok: mac,
+		Record:   *t.record(),
+	})
+	return nil
+}
+
+func (req *enrRequest) name() string { return "ENRREQUEST/v4" }
+
+func (req *enrResponse) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
+	if !t.handleReply(fromID, enrResponsePacket, req) {
+		return errUnsolicitedReply
+	}
+	return nil
+}
+
+func (req *enrResponse) name() string { return "ENRRESPONSE/v4" }
+
 func expired(ts uint64) bool {
 	return time.Unix(int64(ts), 0).Before(time.Now())
 }
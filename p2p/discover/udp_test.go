@@ -35,6 +35,7 @@ import (
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
 	"github.com/davecgh/go-spew/spew"
 )
@@ -321,6 +322,106 @@ func TestUDP_findnodeMultiReply(t *testing.T) {
 	}
 }
 
+func TestUDP_ENRRequest(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	// Requests from unknown nodes are ignored.
+	test.packetIn(errExpired, enrRequestPacket, &enrRequest{})
+	test.packetIn(errUnknownNode, enrRequestPacket, &enrRequest{Expiration: futureExp})
+
+	// Once bonded, the local record is served.
+	test.table.db.updateBondTime(PubkeyID(&test.remotekey.PublicKey), time.Now())
+	test.packetIn(nil, enrRequestPacket, &enrRequest{Expiration: futureExp})
+	hash := test.sent[len(test.sent)-1][:macSize]
+
+	test.waitPacketOut(func(p *enrResponse) {
+		if !bytes.Equal(p.ReplyTok, hash) {
+			t.Errorf("wrong reply token: got %x, want %x", p.ReplyTok, hash)
+		}
+		var pubkey enr.Secp256k1
+		if err := p.Record.Load(&pubkey); err != nil {
+			t.Fatalf("can't load public key from record: %v", err)
+		}
+		if id := PubkeyID((*ecdsa.PublicKey)(&pubkey)); id != test.table.self.ID {
+			t.Errorf("wrong record owner: got %x, want %x", id[:8], test.table.self.ID[:8])
+		}
+		if p.Record.Seq() != test.udp.ourRecord.Seq() {
+			t.Errorf("wrong record sequence number: got %d, want %d", p.Record.Seq(), test.udp.ourRecord.Seq())
+		}
+	})
+}
+
+func TestUDP_setRecordEntry(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	seq := test.udp.record().Seq()
+	if err := test.udp.setRecordEntry(enr.WithEntry("foo", uint(1))); err != nil {
+		t.Fatalf("can't set record entry: %v", err)
+	}
+	record := test.udp.record()
+	if record.Seq() <= seq {
+		t.Errorf("record sequence number not incremented: got %d, had %d", record.Seq(), seq)
+	}
+	var foo uint
+	if err := record.Load(enr.WithEntry("foo", &foo)); err != nil || foo != 1 {
+		t.Errorf("entry not set: value %d, err %v", foo, err)
+	}
+	enc, err := rlp.EncodeToBytes(record)
+	if err != nil {
+		t.Fatalf("can't encode record: %v", err)
+	}
+	if err := rlp.DecodeBytes(enc, new(enr.Record)); err != nil {
+		t.Errorf("updated record doesn't verify: %v", err)
+	}
+}
+
+func TestUDP_requestENR(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	rid := PubkeyID(&test.remotekey.PublicKey)
+	for i, key := range []*ecdsa.PrivateKey{test.remotekey, newkey()} {
+		// Queue a pending record request
+		type result struct {
+			record *enr.Record
+			err    error
+		}
+		resultc := make(chan result, 1)
+		go func() {
+			record, err := test.udp.requestENR(rid, test.remoteaddr)
+			resultc <- result{record, err}
+		}()
+		hash, _ := test.waitPacketOut(func(p *enrRequest) {})
+
+		// Reply with a record signed by the given key
+		var record enr.Record
+		record.Set(enr.WithEntry("eth", uint(i)))
+		if err := enr.SignV4(&record, key); err != nil {
+			t.Fatalf("test %d: failed to sign record: %v", i, err)
+		}
+		test.packetIn(nil, enrResponsePacket, &enrResponse{ReplyTok: hash, Record: record})
+
+		select {
+		case res := <-resultc:
+			if key == test.remotekey {
+				if res.err != nil {
+					t.Fatalf("test %d: record request failed: %v", i, res.err)
+				}
+				var entry uint
+				if err := res.record.Load(enr.WithEntry("eth", &entry)); err != nil || entry != uint(i) {
+					t.Errorf("test %d: entry mismatch: got %d (err %v), want %d", i, entry, err, i)
+				}
+			} else if res.err != errRecordMismatch {
+				t.Errorf("test %d: error mismatch: got %v, want %v", i, res.err, errRecordMismatch)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("test %d: record request did not return within 5 seconds", i)
+		}
+	}
+}
+
 func TestUDP_successfulPing(t *testing.T) {
 	test := newUDPTest(t)
 	added := make(chan *Node, 1)
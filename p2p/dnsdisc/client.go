@@ -0,0 +1,320 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package dnsdisc implements node discovery via DNS (EIP-1459). Node lists are
+// published as a signed merkle tree of node records in DNS TXT records, which
+// are resolved and authenticated by the client.
+package dnsdisc
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
+	"github.com/hashicorp/golang-lru"
+)
+
+// Client discovers nodes by querying DNS servers.
+type Client struct {
+	cfg     Config
+	entries *lru.Cache
+}
+
+// Config holds configuration options for the client.
+type Config struct {
+	Timeout         time.Duration // timeout used for DNS lookups (default 5s)
+	RecheckInterval time.Duration // time between tree root update checks (default 30min)
+	CacheLimit      int           // maximum number of cached tree entries (default 1000)
+	Resolver        Resolver      // the DNS resolver to use (defaults to system DNS)
+	Logger          log.Logger    // destination of client log messages (defaults to root logger)
+}
+
+// Resolver is a DNS resolver that can query TXT records.
+type Resolver interface {
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+func (cfg Config) withDefaults() Config {
+	const (
+		defaultTimeout = 5 * time.Second
+		defaultRecheck = 30 * time.Minute
+		defaultCache   = 1000
+	)
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RecheckInterval == 0 {
+		cfg.RecheckInterval = defaultRecheck
+	}
+	if cfg.CacheLimit == 0 {
+		cfg.CacheLimit = defaultCache
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = new(net.Resolver)
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Root()
+	}
+	return cfg
+}
+
+// NewClient creates a client.
+func NewClient(cfg Config) (*Client, error) {
+	cfg = cfg.withDefaults()
+	cache, err := lru.New(cfg.CacheLimit)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{cfg: cfg, entries: cache}, nil
+}
+
+// SyncTree downloads the entire node tree at the given URL.
+func (c *Client) SyncTree(url string) (*Tree, error) {
+	loc, err := parseLink(url)
+	if err != nil {
+		return nil, err
+	}
+	return c.syncTree(context.Background(), loc)
+}
+
+// syncTree downloads the tree at the given location, authenticating its root
+// against the public key of the location.
+func (c *Client) syncTree(ctx context.Context, loc *linkEntry) (*Tree, error) {
+	root, err := c.resolveRoot(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tree{root: &root, entries: make(map[string]entry)}
+	if err := c.syncSubtree(ctx, loc.domain, root.eroot, t.entries, false); err != nil {
+		return nil, err
+	}
+	if err := c.syncSubtree(ctx, loc.domain, root.lroot, t.entries, true); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// syncSubtree downloads the subtree rooted at the given hash. Link trees may
+// only contain links, node trees only node records.
+func (c *Client) syncSubtree(ctx context.Context, domain, hash string, entries map[string]entry, link bool) error {
+	e, err := c.resolveEntry(ctx, domain, hash)
+	if err != nil {
+		return err
+	}
+	entries[hash] = e
+
+	switch e := e.(type) {
+	case *branchEntry:
+		for _, child := range e.children {
+			if err := c.syncSubtree(ctx, domain, child, entries, link); err != nil {
+				return err
+			}
+		}
+	case *enrEntry:
+		if link {
+			return nameError{hash + "." + domain, errENRInLinkTree}
+		}
+	case *linkEntry:
+		if !link {
+			return nameError{hash + "." + domain, errLinkInENRTree}
+		}
+	}
+	return nil
+}
+
+// resolveRoot retrieves a root entry via DNS and verifies its signature.
+func (c *Client) resolveRoot(ctx context.Context, loc *linkEntry) (rootEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	txts, err := c.cfg.Resolver.LookupTXT(ctx, loc.domain)
+	c.cfg.Logger.Trace("Updating DNS discovery root", "tree", loc.domain, "err", err)
+	if err != nil {
+		return rootEntry{}, err
+	}
+	for _, txt := range txts {
+		if strings.HasPrefix(txt, rootPrefix) {
+			root, err := parseRoot(txt)
+			if err != nil {
+				return rootEntry{}, nameError{loc.domain, err}
+			}
+			if !root.verifySignature(loc.pubkey) {
+				return rootEntry{}, nameError{loc.domain, entryError{"root", errInvalidSig}}
+			}
+			return root, nil
+		}
+	}
+	return rootEntry{}, nameError{loc.domain, errNoRoot}
+}
+
+// resolveEntry retrieves an entry from the cache or fetches it from the network
+// if it isn't cached.
+func (c *Client) resolveEntry(ctx context.Context, domain, hash string) (entry, error) {
+	if e, ok := c.entries.Get(hash); ok {
+		return e.(entry), nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	wantHash, err := b32format.DecodeString(hash)
+	if err != nil {
+		return nil, errInvalidChild
+	}
+	name := hash + "." + domain
+	txts, err := c.cfg.Resolver.LookupTXT(ctx, name)
+	c.cfg.Logger.Trace("DNS discovery lookup", "name", name, "err", err)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		e, err := parseEntry(txt)
+		if err == errUnknownEntry {
+			continue
+		}
+		if !bytes.HasPrefix(crypto.Keccak256([]byte(txt)), wantHash) {
+			err = nameError{name, errHashMismatch}
+		} else if err != nil {
+			err = nameError{name, err}
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.entries.Add(hash, e)
+		return e, nil
+	}
+	return nil, nameError{name, errNoEntry}
+}
+
+// Source keeps the node records of one or more trees in sync in the background,
+// serving them as dial candidates. Trees linked from the given ones are synced
+// as well.
+type Source struct {
+	client *Client
+	roots  []*linkEntry
+
+	mu      sync.Mutex
+	records map[string][]*enr.Record // node records by tree URL
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSource creates a source syncing the trees at the given URLs.
+func (c *Client) NewSource(urls ...string) (*Source, error) {
+	s := &Source{client: c, records: make(map[string][]*enr.Record)}
+	for _, url := range urls {
+		loc, err := parseLink(url)
+		if err != nil {
+			return nil, err
+		}
+		s.roots = append(s.roots, loc)
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.wg.Add(1)
+	go s.loop()
+	return s, nil
+}
+
+// Close stops the background sync.
+func (s *Source) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// ReadRandomRecords fills the given slice with random node records from all
+// synced trees, returning the number of records written.
+func (s *Source) ReadRandomRecords(buf []*enr.Record) int {
+	s.mu.Lock()
+	var all []*enr.Record
+	for _, records := range s.records {
+		all = append(all, records...)
+	}
+	s.mu.Unlock()
+
+	n := 0
+	for _, i := range rand.Perm(len(all)) {
+		if n == len(buf) {
+			break
+		}
+		buf[n] = all[i]
+		n++
+	}
+	return n
+}
+
+// loop periodically syncs all trees until the source is closed.
+func (s *Source) loop() {
+	defer s.wg.Done()
+
+	for {
+		s.syncAll()
+
+		select {
+		case <-time.After(s.client.cfg.RecheckInterval):
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// syncAll syncs the root trees and all trees reachable through their links,
+// keeping the previous records of trees which fail to sync.
+func (s *Source) syncAll() {
+	var (
+		queue   = append([]*linkEntry{}, s.roots...)
+		visited = make(map[string]bool)
+		records = make(map[string][]*enr.Record)
+	)
+	for len(queue) > 0 {
+		loc := queue[0]
+		queue = queue[1:]
+
+		url := loc.url()
+		if visited[url] {
+			continue
+		}
+		visited[url] = true
+
+		tree, err := s.client.syncTree(s.ctx, loc)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			s.client.cfg.Logger.Debug("Failed to sync DNS discovery tree", "tree", url, "err", err)
+			s.mu.Lock()
+			records[url] = s.records[url]
+			s.mu.Unlock()
+			continue
+		}
+		records[url] = tree.Records()
+		for _, link := range tree.Links() {
+			if le, err := parseLink(link); err == nil {
+				queue = append(queue, le)
+			}
+		}
+	}
+	s.mu.Lock()
+	s.records = records
+	s.mu.Unlock()
+}
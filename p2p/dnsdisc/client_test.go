@@ -0,0 +1,177 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
+)
+
+const signingKeySeed = "signing key"
+
+// Tests that a published tree is downloaded in full and its records are
+// authenticated.
+func TestClientSyncTree(t *testing.T) {
+	var (
+		records   = testRecords(t, 30)
+		tree, url = makeTestTree(t, "n", records, nil)
+		resolver  = newMapResolver(tree.ToTXT("n"))
+	)
+	c, _ := NewClient(Config{Resolver: resolver})
+	synced, err := c.SyncTree(url)
+	if err != nil {
+		t.Fatal("sync error:", err)
+	}
+	if !reflect.DeepEqual(sortByAddr(synced.Records()), sortByAddr(records)) {
+		t.Errorf("wrong records in synced tree")
+	}
+	if synced.Seq() != tree.Seq() || synced.Signature() != tree.Signature() {
+		t.Errorf("synced root mismatch: seq %d, sig %s", synced.Seq(), synced.Signature())
+	}
+}
+
+// Tests that trees with bad signatures, tampered entries or misplaced entries
+// are rejected.
+func TestClientSyncTreeBadData(t *testing.T) {
+	var (
+		records   = testRecords(t, 3)
+		tree, url = makeTestTree(t, "n", records, nil)
+		otherKey  = testKey("other key")
+		wrongURL  = (&linkEntry{"n", &otherKey.PublicKey}).url()
+	)
+	// A root signed by a different key.
+	c, _ := NewClient(Config{Resolver: newMapResolver(tree.ToTXT("n"))})
+	if _, err := c.SyncTree(wrongURL); err == nil || !strings.Contains(err.Error(), errInvalidSig.Error()) {
+		t.Errorf("expected signature error, got %v", err)
+	}
+
+	// An entry which doesn't match its hash.
+	txt := tree.ToTXT("n")
+	for name, value := range txt {
+		if strings.HasPrefix(value, enrPrefix) {
+			txt[name] = (&enrEntry{testRecords(t, 4)[3]}).String()
+			break
+		}
+	}
+	c, _ = NewClient(Config{Resolver: newMapResolver(txt)})
+	if _, err := c.SyncTree(url); err == nil || !strings.Contains(err.Error(), errHashMismatch.Error()) {
+		t.Errorf("expected hash mismatch error, got %v", err)
+	}
+
+	// A link placed into the node record subtree.
+	link := &linkEntry{"m", &otherKey.PublicKey}
+	bad := &Tree{entries: map[string]entry{subdomain(link): link}}
+	bad.root = &rootEntry{seq: 1, eroot: subdomain(link), lroot: subdomain(link)}
+	url, _ = bad.Sign(testKey(signingKeySeed), "n")
+	c, _ = NewClient(Config{Resolver: newMapResolver(bad.ToTXT("n"))})
+	if _, err := c.SyncTree(url); err == nil || !strings.Contains(err.Error(), errLinkInENRTree.Error()) {
+		t.Errorf("expected misplaced link error, got %v", err)
+	}
+}
+
+// Tests that a source serves the records of its tree and all linked trees.
+func TestSourceFollowsLinks(t *testing.T) {
+	var (
+		records       = testRecords(t, 10)
+		leaf, leafURL = makeTestTree(t, "leaf", records[5:], nil)
+		root, rootURL = makeTestTree(t, "root", records[:5], []string{leafURL})
+		txt           = make(map[string]string)
+	)
+	for name, value := range leaf.ToTXT("leaf") {
+		txt[name] = value
+	}
+	for name, value := range root.ToTXT("root") {
+		txt[name] = value
+	}
+	c, _ := NewClient(Config{Resolver: newMapResolver(txt)})
+	src, err := c.NewSource(rootURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	buf := make([]*enr.Record, 20)
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if n := src.ReadRandomRecords(buf); n == len(records) {
+			if !reflect.DeepEqual(sortByAddr(buf[:n]), sortByAddr(records)) {
+				t.Fatal("wrong records served by source")
+			}
+			if n := src.ReadRandomRecords(buf[:3]); n != 3 {
+				t.Fatalf("wrong record count for short buffer: %d", n)
+			}
+			return
+		}
+	}
+	t.Fatal("source did not sync all trees in time")
+}
+
+// mapResolver is an in-memory resolver serving TXT records from a map.
+type mapResolver map[string]string
+
+func newMapResolver(txt map[string]string) mapResolver {
+	return mapResolver(txt)
+}
+
+func (mr mapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if record, ok := mr[name]; ok {
+		return []string{record}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name}
+}
+
+// makeTestTree creates a tree of the given records and links, signed by the
+// test signing key.
+func makeTestTree(t *testing.T, domain string, records []*enr.Record, links []string) (*Tree, string) {
+	tree, err := MakeTree(1, records, links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := tree.Sign(testKey(signingKeySeed), domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, url
+}
+
+// testRecords creates n deterministic signed node records.
+func testRecords(t *testing.T, n int) []*enr.Record {
+	records := make([]*enr.Record, n)
+	for i := range records {
+		var r enr.Record
+		r.Set(enr.IP(net.IP{127, 0, 0, byte(i)}))
+		r.Set(enr.TCP(30303))
+		r.Set(enr.UDP(30303))
+		if err := enr.SignV4(&r, testKey(fmt.Sprintf("node %d", i))); err != nil {
+			t.Fatal(err)
+		}
+		records[i] = &r
+	}
+	return records
+}
+
+func testKey(seed string) *ecdsa.PrivateKey {
+	return crypto.ToECDSAUnsafe(crypto.Keccak256([]byte(seed)))
+}
@@ -0,0 +1,169 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"crypto/ecdsa"
+	"reflect"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+)
+
+func TestParseRoot(t *testing.T) {
+	tree, _ := MakeTree(3, nil, nil)
+	tree.Sign(testKey(signingKeySeed), "n")
+	valid := *tree.root
+
+	tests := []struct {
+		input string
+		e     rootEntry
+		err   error
+	}{
+		{
+			input: "enrtree-root:v1 e=" + valid.eroot + " seq=3 sig=" + b64format.EncodeToString(valid.sig),
+			err:   entryError{"root", errSyntax},
+		},
+		{
+			input: "enrtree-root:v1 e=" + valid.eroot + " l=" + valid.lroot + " seq=3 sig=" + b64format.EncodeToString(valid.sig[:64]),
+			err:   entryError{"root", errInvalidSig},
+		},
+		{
+			input: "enrtree-root:v1 e=1 l=" + valid.lroot + " seq=3 sig=" + b64format.EncodeToString(valid.sig),
+			err:   entryError{"root", errInvalidChild},
+		},
+		{
+			input: valid.String(),
+			e:     valid,
+		},
+	}
+	for i, test := range tests {
+		e, err := parseRoot(test.input)
+		if !reflect.DeepEqual(e, test.e) {
+			t.Errorf("test %d: wrong entry %+v, want %+v", i, e, test.e)
+		}
+		if err != test.err {
+			t.Errorf("test %d: wrong error %q, want %q", i, err, test.err)
+		}
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	testkey := testKey(signingKeySeed)
+	tests := []struct {
+		input string
+		e     entry
+		err   error
+	}{
+		// Subtrees:
+		{
+			input: "enrtree-branch:1,2",
+			err:   entryError{"branch", errInvalidChild},
+		},
+		{
+			input: "enrtree-branch:AAAAAAAAAA",
+			err:   entryError{"branch", errInvalidChild},
+		},
+		{
+			input: "enrtree-branch:",
+			e:     &branchEntry{},
+		},
+		{
+			input: "enrtree-branch:AAAAAAAAAAAAAAAAAAAAAAAAAA",
+			e:     &branchEntry{[]string{"AAAAAAAAAAAAAAAAAAAAAAAAAA"}},
+		},
+		{
+			input: "enrtree-branch:AAAAAAAAAAAAAAAAAAAAAAAAAA,BBBBBBBBBBBBBBBBBBBBBBBBBB",
+			e:     &branchEntry{[]string{"AAAAAAAAAAAAAAAAAAAAAAAAAA", "BBBBBBBBBBBBBBBBBBBBBBBBBB"}},
+		},
+		// Links
+		{
+			input: "enrtree://" + pubkeyString(&testkey.PublicKey) + "@nodes.example.org",
+			e:     &linkEntry{"nodes.example.org", &testkey.PublicKey},
+		},
+		{
+			input: "enrtree://nodes.example.org",
+			err:   entryError{"link", errNoPubkey},
+		},
+		{
+			input: "enrtree://AP62DT7WOTEQZGQZOU474PP3KMEGVTTE7A7NPRXKX3DUD57@nodes.example.org",
+			err:   entryError{"link", errBadPubkey},
+		},
+		// ENRs
+		{
+			input: "enr:*invalid*",
+			err:   entryError{"enr", errInvalidENR},
+		},
+		// Invalid:
+		{input: "", err: errUnknownEntry},
+		{input: "foo", err: errUnknownEntry},
+		{input: "enrtree", err: errUnknownEntry},
+		{input: "enrtree-x=", err: errUnknownEntry},
+	}
+	for i, test := range tests {
+		e, err := parseEntry(test.input)
+		if !reflect.DeepEqual(e, test.e) {
+			t.Errorf("test %d: wrong entry %v, want %v", i, e, test.e)
+		}
+		if err != test.err {
+			t.Errorf("test %d: wrong error %q, want %q", i, err, test.err)
+		}
+	}
+}
+
+func TestMakeTree(t *testing.T) {
+	records := testRecords(t, 50)
+	tree, err := MakeTree(2, records, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt := tree.ToTXT("")
+	if len(txt) < len(records)+1 {
+		t.Fatal("too few TXT records in output")
+	}
+	if !reflect.DeepEqual(sortByAddr(tree.Records()), sortByAddr(records)) {
+		t.Fatal("tree records don't match input")
+	}
+}
+
+func TestTreeSignature(t *testing.T) {
+	key := testKey(signingKeySeed)
+	tree, err := MakeTree(1, testRecords(t, 3), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := tree.Sign(key, "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domain, pubkey, err := ParseURL(url); err != nil || domain != "n" || !reflect.DeepEqual(pubkey, &key.PublicKey) {
+		t.Fatalf("wrong URL %q (err %v)", url, err)
+	}
+	// The signature can be moved to a tree with the same content, but not to
+	// one signed by a different key.
+	other, _ := MakeTree(1, tree.Records(), nil)
+	if err := other.SetSignature(&key.PublicKey, tree.Signature()); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if err := other.SetSignature(&testKey("other").PublicKey, tree.Signature()); err != errInvalidSig {
+		t.Fatalf("wrong error for invalid signature: %v", err)
+	}
+}
+
+func pubkeyString(key *ecdsa.PublicKey) string {
+	return b32format.EncodeToString(crypto.CompressPubkey(key))
+}
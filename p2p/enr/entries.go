@@ -67,6 +67,11 @@ type UDP uint16
 
 func (v UDP) ENRKey() string { return "udp" }
 
+// QUIC is the "quic" key, which holds the UDP port of the node's QUIC listener.
+type QUIC uint16
+
+func (v QUIC) ENRKey() string { return "quic" }
+
 // ID is the "id" key, which holds the name of the identity scheme.
 type ID string
 
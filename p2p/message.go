@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -310,3 +311,92 @@ func (ev *msgEventer) Close() error {
 	}
 	return nil
 }
+
+// MsgStats contains the number and total payload size of the messages sent and
+// received with a single message code.
+type MsgStats struct {
+	InPackets  uint64 `json:"inPackets"`
+	InBytes    uint64 `json:"inBytes"`
+	OutPackets uint64 `json:"outPackets"`
+	OutBytes   uint64 `json:"outBytes"`
+}
+
+// MsgCounter wraps a MsgReadWriter and counts the messages passing through it
+// by message code.
+type MsgCounter struct {
+	MsgReadWriter
+
+	lock  sync.Mutex
+	stats map[uint64]*MsgStats
+}
+
+// NewMsgCounter returns a MsgCounter counting the messages read from and
+// written to rw.
+func NewMsgCounter(rw MsgReadWriter) *MsgCounter {
+	return &MsgCounter{
+		MsgReadWriter: rw,
+		stats:         make(map[uint64]*MsgStats),
+	}
+}
+
+// ReadMsg reads a message from the underlying MsgReadWriter and counts it.
+func (c *MsgCounter) ReadMsg() (Msg, error) {
+	msg, err := c.MsgReadWriter.ReadMsg()
+	if err != nil {
+		return msg, err
+	}
+	c.lock.Lock()
+	stats := c.get(msg.Code)
+	stats.InPackets++
+	stats.InBytes += uint64(msg.Size)
+	c.lock.Unlock()
+	return msg, nil
+}
+
+// WriteMsg writes a message to the underlying MsgReadWriter and counts it if
+// the write succeeds.
+func (c *MsgCounter) WriteMsg(msg Msg) error {
+	if err := c.MsgReadWriter.WriteMsg(msg); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	stats := c.get(msg.Code)
+	stats.OutPackets++
+	stats.OutBytes += uint64(msg.Size)
+	c.lock.Unlock()
+	return nil
+}
+
+// get returns the counters of a message code, creating them if needed. The
+// caller must hold c.lock.
+func (c *MsgCounter) get(code uint64) *MsgStats {
+	stats := c.stats[code]
+	if stats == nil {
+		stats = new(MsgStats)
+		c.stats[code] = stats
+	}
+	return stats
+}
+
+// Stats returns the counters of a single message code.
+func (c *MsgCounter) Stats(code uint64) MsgStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if stats := c.stats[code]; stats != nil {
+		return *stats
+	}
+	return MsgStats{}
+}
+
+// AllStats returns the counters of all message codes seen so far.
+func (c *MsgCounter) AllStats() map[uint64]MsgStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	all := make(map[uint64]MsgStats, len(c.stats))
+	for code, stats := range c.stats {
+		all[code] = *stats
+	}
+	return all
+}
@@ -150,3 +150,34 @@ func unhex(str string) []byte {
 	}
 	return b
 }
+
+func TestMsgCounter(t *testing.T) {
+	rw1, rw2 := MsgPipe()
+	defer rw1.Close()
+	counter := NewMsgCounter(rw1)
+
+	go func() {
+		Send(rw2, 1, []uint{1})
+		Send(rw2, 1, []uint{2, 3})
+		ExpectMsg(rw2, 2, []uint{4})
+	}()
+	for i := 0; i < 2; i++ {
+		msg, err := counter.ReadMsg()
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		msg.Discard()
+	}
+	if err := Send(counter, 2, []uint{4}); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if stats := counter.Stats(1); stats.InPackets != 2 || stats.InBytes != 5 || stats.OutPackets != 0 {
+		t.Errorf("code 1 stats mismatch: %+v", stats)
+	}
+	if stats := counter.Stats(2); stats.OutPackets != 1 || stats.OutBytes != 2 || stats.InPackets != 0 {
+		t.Errorf("code 2 stats mismatch: %+v", stats)
+	}
+	if all := counter.AllStats(); len(all) != 2 {
+		t.Errorf("counted %d message codes, want 2", len(all))
+	}
+}
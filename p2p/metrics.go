@@ -19,7 +19,9 @@
 package p2p
 
 import (
+	"fmt"
 	"net"
+	"sync"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/metrics"
 )
@@ -38,8 +40,8 @@ type meteredConn struct {
 }
 
 // newMeteredConn creates a new metered connection, also bumping the ingress or
-// egress connection meter. If the metrics system is disabled, this function
-// returns the original object.
+// egress connection meter. If the metrics system is disabled or the connection
+// isn't a TCP connection, this function returns the original object.
 func newMeteredConn(conn net.Conn, ingress bool) net.Conn {
 	// Short circuit if metrics are disabled
 	if !metrics.Enabled {
@@ -51,7 +53,11 @@ func newMeteredConn(conn net.Conn, ingress bool) net.Conn {
 	} else {
 		egressConnectMeter.Mark(1)
 	}
-	return &meteredConn{conn.(*net.TCPConn)}
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok {
+		return conn
+	}
+	return &meteredConn{tcp}
 }
 
 // Read delegates a network read to the underlying connection, bumping the ingress
@@ -69,3 +75,76 @@ func (c *meteredConn) Write(b []byte) (n int, err error) {
 	egressTrafficMeter.Mark(int64(n))
 	return
 }
+
+// msgMeters are the meters of a message code in both directions.
+type msgMeters struct {
+	inPackets, inTraffic   metrics.Meter
+	outPackets, outTraffic metrics.Meter
+}
+
+var (
+	protoMeters     = make(map[string][]*msgMeters) // Message meters by protocol metrics prefix
+	protoMetersLock sync.Mutex
+)
+
+// getProtoMeters returns the meters of the message codes of a protocol,
+// registering them on first use.
+func getProtoMeters(prefix string, length uint64) []*msgMeters {
+	protoMetersLock.Lock()
+	defer protoMetersLock.Unlock()
+
+	meters := protoMeters[prefix]
+	for code := uint64(len(meters)); code < length; code++ {
+		base := fmt.Sprintf("%s/%d", prefix, code)
+		meters = append(meters, &msgMeters{
+			inPackets:  metrics.GetOrRegisterMeter(base+"/in/packets", nil),
+			inTraffic:  metrics.GetOrRegisterMeter(base+"/in/traffic", nil),
+			outPackets: metrics.GetOrRegisterMeter(base+"/out/packets", nil),
+			outTraffic: metrics.GetOrRegisterMeter(base+"/out/traffic", nil),
+		})
+	}
+	protoMeters[prefix] = meters
+	return meters
+}
+
+// meteredMsgReadWriter is a wrapper around a protocol's MsgReadWriter that meters
+// the packets and bytes transferred per message code.
+type meteredMsgReadWriter struct {
+	MsgReadWriter              // Protocol message stream to meter
+	meters        []*msgMeters // Meters of the message codes of the protocol
+}
+
+// newMeteredMsgReadWriter wraps the message stream of a protocol with metering
+// support, setting up the meters of its message codes. If the metrics system is
+// disabled, this function returns the original object.
+func newMeteredMsgReadWriter(rw MsgReadWriter, proto string, version uint, length uint64) MsgReadWriter {
+	if !metrics.Enabled {
+		return rw
+	}
+	prefix := fmt.Sprintf("p2p/msg/%s/%d", proto, version)
+	return &meteredMsgReadWriter{MsgReadWriter: rw, meters: getProtoMeters(prefix, length)}
+}
+
+// ReadMsg delegates a message read to the underlying stream, bumping the ingress
+// meters of the message code along the way.
+func (rw *meteredMsgReadWriter) ReadMsg() (Msg, error) {
+	msg, err := rw.MsgReadWriter.ReadMsg()
+	if err == nil && msg.Code < uint64(len(rw.meters)) {
+		m := rw.meters[msg.Code]
+		m.inPackets.Mark(1)
+		m.inTraffic.Mark(int64(msg.Size))
+	}
+	return msg, err
+}
+
+// WriteMsg delegates a message write to the underlying stream, bumping the
+// egress meters of the message code along the way.
+func (rw *meteredMsgReadWriter) WriteMsg(msg Msg) error {
+	err := rw.MsgReadWriter.WriteMsg(msg)
+	if err == nil && msg.Code < uint64(len(rw.meters)) {
+		m := rw.meters[msg.Code]
+		m.outPackets.Mark(1)
+		m.outTraffic.Mark(int64(msg.Size))
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/metrics"
+)
+
+// Tests that messages are metered per code on the meters set up with the stream.
+func TestMeteredMsgReadWriter(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	rw1, rw2 := MsgPipe()
+	defer rw1.Close()
+
+	in := newMeteredMsgReadWriter(rw1, "test", 1, 2).(*meteredMsgReadWriter)
+	out := newMeteredMsgReadWriter(rw2, "test", 1, 2).(*meteredMsgReadWriter)
+	if len(in.meters) != 2 || in.meters[1] != out.meters[1] {
+		t.Fatalf("message meters not shared by the protocol streams")
+	}
+	go func() {
+		Send(out, 1, []uint{1, 2, 3})
+		Send(out, 5, []uint{1}) // unknown code, not metered
+	}()
+	for i := 0; i < 2; i++ {
+		msg, err := in.ReadMsg()
+		if err != nil {
+			t.Fatalf("failed to read message: %v", err)
+		}
+		msg.Discard()
+	}
+	m := in.meters[1]
+	if m.inPackets.Count() != 1 || m.outPackets.Count() != 1 {
+		t.Errorf("packet count mismatch: have %d in, %d out, want 1", m.inPackets.Count(), m.outPackets.Count())
+	}
+	if m.inTraffic.Count() == 0 || m.inTraffic.Count() != m.outTraffic.Count() {
+		t.Errorf("traffic mismatch: have %d in, %d out", m.inTraffic.Count(), m.outTraffic.Count())
+	}
+	if in.meters[0].inPackets.Count() != 0 {
+		t.Errorf("unused message code metered")
+	}
+}
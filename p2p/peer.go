@@ -107,6 +107,9 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// rep tracks penalties of the peer, nil if not connected through a Server
+	rep *reputation
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -158,6 +161,21 @@ func (p *Peer) String() string {
 	return fmt.Sprintf("Peer %x %v", p.rw.id[:8], p.RemoteAddr())
 }
 
+// Penalize adds a penalty for misbehaviour to the peer's score. Penalties decay
+// over time, but a peer whose score accumulates to PenaltySevere is banned and
+// disconnected.
+func (p *Peer) Penalize(amount uint, reason string) {
+	if p.rep.penalize(p.ID(), p.remoteIP(), amount, reason) {
+		p.log.Debug("Disconnecting banned peer", "reason", reason)
+		p.Disconnect(DiscUselessPeer)
+	}
+}
+
+// remoteIP returns the IP address of the peer, or nil if it isn't known.
+func (p *Peer) remoteIP() net.IP {
+	return addrIP(p.RemoteAddr())
+}
+
 // Inbound returns true if the peer is an inbound connection
 func (p *Peer) Inbound() bool {
 	return p.rw.flags&inboundConn != 0
@@ -322,7 +340,9 @@ outer:
 					offset -= old.Length
 				}
 				// Assign the new match
-				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw}
+				prw := &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw}
+				prw.counter = NewMsgCounter(prw)
+				result[cap.Name] = prw
 				offset += proto.Length
 
 				continue outer
@@ -339,7 +359,7 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		proto.closed = p.closed
 		proto.wstart = writeStart
 		proto.werr = writeErr
-		var rw MsgReadWriter = proto
+		rw := newMeteredMsgReadWriter(proto.counter, proto.Name, proto.Version, proto.Length)
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name)
 		}
@@ -377,6 +397,8 @@ type protoRW struct {
 	werr   chan<- error    // for write results
 	offset uint64
 	w      MsgWriter
+
+	counter *MsgCounter // counts the messages of the protocol, wraps the protoRW itself
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
@@ -423,6 +445,9 @@ type PeerInfo struct {
 		Static        bool   `json:"static"`
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+
+	// Messages and bytes transferred per sub-protocol and message code
+	Traffic map[string]map[uint64]MsgStats `json:"traffic"`
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -438,6 +463,7 @@ func (p *Peer) Info() *PeerInfo {
 		Name:      p.Name(),
 		Caps:      caps,
 		Protocols: make(map[string]interface{}),
+		Traffic:   make(map[string]map[uint64]MsgStats),
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
@@ -456,6 +482,7 @@ func (p *Peer) Info() *PeerInfo {
 			}
 		}
 		info.Protocols[proto.Name] = protoInfo
+		info.Traffic[proto.Name] = proto.counter.AllStats()
 	}
 	return info
 }
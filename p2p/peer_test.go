@@ -98,6 +98,48 @@ func TestPeerProtoReadMsg(t *testing.T) {
 	}
 }
 
+func TestPeerTraffic(t *testing.T) {
+	proto := Protocol{
+		Name:   "a",
+		Length: 5,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			if err := ExpectMsg(rw, 2, []uint{1}); err != nil {
+				t.Error(err)
+			}
+			if err := ExpectMsg(rw, 2, []uint{2}); err != nil {
+				t.Error(err)
+			}
+			if err := SendItems(rw, 3, uint(3)); err != nil {
+				t.Error(err)
+			}
+			want := map[uint64]MsgStats{
+				2: {InPackets: 2, InBytes: 4},
+				3: {OutPackets: 1, OutBytes: 2},
+			}
+			if traffic := peer.Info().Traffic["a"]; !reflect.DeepEqual(traffic, want) {
+				t.Errorf("traffic mismatch:\nhave %+v\nwant %+v", traffic, want)
+			}
+			return nil
+		},
+	}
+	closer, rw, _, errc := testPeer([]Protocol{proto})
+	defer closer()
+
+	Send(rw, baseProtocolLength+2, []uint{1})
+	Send(rw, baseProtocolLength+2, []uint{2})
+	if err := ExpectMsg(rw, baseProtocolLength+3, []uint{3}); err != nil {
+		t.Error(err)
+	}
+	select {
+	case err := <-errc:
+		if err != errProtocolReturned {
+			t.Errorf("peer returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("receive timeout")
+	}
+}
+
 func TestPeerProtoEncodeMsg(t *testing.T) {
 	proto := Protocol{
 		Name:   "a",
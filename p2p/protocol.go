@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
 )
 
 // Protocol represents a P2P subprotocol implementation.
@@ -52,6 +53,19 @@ type Protocol struct {
 	// about a certain peer in the network. If an info retrieval function is set,
 	// but returns nil, it is assumed that the protocol handshake is still running.
 	PeerInfo func(id discover.NodeID) interface{}
+
+	// Attributes contains protocol specific information for the node record.
+	Attributes []enr.Entry
+
+	// DialFilter is an optional predicate on the node records of dial candidates.
+	// Nodes found through discovery are only dialed if the filter of at least one
+	// protocol accepts their record. Nodes whose record can't be retrieved are
+	// only dialed when no other candidates are left.
+	DialFilter func(*enr.Record) bool
+
+	// DialCandidates is an optional source of dial candidates besides the
+	// discovery table, such as a DNS node list.
+	DialCandidates NodeSource
 }
 
 func (p Protocol) cap() Cap {
@@ -0,0 +1,455 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+	"github.com/quic-go/quic-go"
+)
+
+// The QUIC transport runs the RLPx encryption handshake, the protocol handshake
+// and the base protocol on a bidirectional control stream. Every sub-protocol
+// gets a unidirectional stream in each direction, which is opened on its first
+// message, so that the protocols are flow controlled independently and a large
+// message of one protocol doesn't hold up the others.
+//
+// TLS only protects the connection against eavesdroppers, the certificates are
+// ephemeral and not verified. The peers authenticate each other through the
+// encryption handshake, whose session secrets are bound to the TLS session.
+
+const (
+	quicALPN      = "devp2p"
+	quicKeysLabel = "EXPORTER-devp2p-quic"
+
+	// quicNoReason is the application error code used to close connections
+	// without a disconnect reason. Lower codes carry a DiscReason.
+	quicNoReason = quic.ApplicationErrorCode(0x100)
+)
+
+var errQUICClosed = errors.New("QUIC connection closed")
+
+// transportConn is implemented by connections whose listener or dialer comes
+// with its own transport instead of RLPx.
+type transportConn interface {
+	net.Conn
+	newTransport(protocols []Protocol) transport
+}
+
+// quicEndpoint accepts and dials QUIC connections on a single UDP socket, so
+// that outbound connections originate from the announced listening port.
+// It implements net.Listener.
+type quicEndpoint struct {
+	conn      *net.UDPConn
+	tr        *quic.Transport
+	ln        *quic.Listener
+	config    *quic.Config
+	serverTLS *tls.Config
+	clientTLS *tls.Config
+}
+
+func listenQUIC(addr string) (*quicEndpoint, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := newQUICCertificate()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	e := &quicEndpoint{
+		conn: conn,
+		tr:   &quic.Transport{Conn: conn},
+		config: &quic.Config{
+			HandshakeIdleTimeout: handshakeTimeout,
+			MaxIdleTimeout:       frameReadTimeout,
+			KeepAlivePeriod:      pingInterval,
+		},
+		serverTLS: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{quicALPN},
+			MinVersion:   tls.VersionTLS13,
+		},
+		clientTLS: &tls.Config{
+			InsecureSkipVerify: true,
+			NextProtos:         []string{quicALPN},
+			MinVersion:         tls.VersionTLS13,
+		},
+	}
+	if e.ln, err = e.tr.Listen(e.serverTLS, e.config); err != nil {
+		e.tr.Close()
+		conn.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// newQUICCertificate creates the ephemeral self-signed certificate presented by
+// the QUIC listener.
+func newQUICCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(10 * 365 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// Accept waits for the next inbound connection.
+func (e *quicEndpoint) Accept() (net.Conn, error) {
+	c, err := e.ln.Accept(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &quicConn{Conn: c}, nil
+}
+
+// Close stops accepting inbound connections. Established connections and
+// dialing are unaffected until stop is called.
+func (e *quicEndpoint) Close() error {
+	return e.ln.Close()
+}
+
+// Addr returns the listening address.
+func (e *quicEndpoint) Addr() net.Addr {
+	return e.ln.Addr()
+}
+
+// stop closes all connections and the UDP socket.
+func (e *quicEndpoint) stop() {
+	e.tr.Close()
+	e.conn.Close()
+}
+
+// dial connects to the QUIC listener at addr.
+func (e *quicEndpoint) dial(addr *net.UDPAddr) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDialTimeout)
+	defer cancel()
+	c, err := e.tr.Dial(ctx, addr, e.clientTLS, e.config)
+	if err != nil {
+		return nil, err
+	}
+	return &quicConn{Conn: c}, nil
+}
+
+// quicConn is a QUIC connection used as a net.Conn. Reads, writes and deadlines
+// apply to the control stream, which is set up by the encryption handshake.
+type quicConn struct {
+	*quic.Conn
+	ctrl *quic.Stream
+}
+
+func (c *quicConn) Read(b []byte) (int, error)         { return c.ctrl.Read(b) }
+func (c *quicConn) Write(b []byte) (int, error)        { return c.ctrl.Write(b) }
+func (c *quicConn) SetDeadline(t time.Time) error      { return c.ctrl.SetDeadline(t) }
+func (c *quicConn) SetReadDeadline(t time.Time) error  { return c.ctrl.SetReadDeadline(t) }
+func (c *quicConn) SetWriteDeadline(t time.Time) error { return c.ctrl.SetWriteDeadline(t) }
+
+func (c *quicConn) Close() error {
+	return c.CloseWithError(quicNoReason, "")
+}
+
+func (c *quicConn) newTransport(protocols []Protocol) transport {
+	return newQUICTransport(c, protocols)
+}
+
+// quicTransport is the transport of QUIC connections.
+type quicTransport struct {
+	conn      *quicConn
+	protocols []Protocol
+
+	wmu sync.Mutex
+	rw  *rlpxFrameRW // frames the control stream
+
+	smu     sync.Mutex
+	protos  map[string]*protoRW    // matched protocols, valid after the protocol handshake
+	streams map[string]*quicStream // outbound streams by protocol name
+
+	in        chan quicMsg
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// quicStream is the outbound stream of a protocol.
+type quicStream struct {
+	mu sync.Mutex
+	*quic.SendStream
+}
+
+type quicMsg struct {
+	msg Msg
+	err error
+}
+
+func newQUICTransport(conn *quicConn, protocols []Protocol) transport {
+	return &quicTransport{
+		conn:      conn,
+		protocols: protocols,
+		streams:   make(map[string]*quicStream),
+		in:        make(chan quicMsg),
+		closing:   make(chan struct{}),
+	}
+}
+
+func (t *quicTransport) doEncHandshake(prv *ecdsa.PrivateKey, dial *discover.Node) (discover.NodeID, error) {
+	ctx, cancel := context.WithTimeout(t.conn.Context(), handshakeTimeout)
+	defer cancel()
+
+	var (
+		sec secrets
+		err error
+	)
+	if dial == nil {
+		t.conn.ctrl, err = t.conn.AcceptStream(ctx)
+	} else {
+		t.conn.ctrl, err = t.conn.OpenStreamSync(ctx)
+	}
+	if err != nil {
+		return discover.NodeID{}, err
+	}
+	t.conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if dial == nil {
+		sec, err = receiverEncHandshake(t.conn, prv, nil)
+	} else {
+		sec, err = initiatorEncHandshake(t.conn, prv, dial.ID, nil)
+	}
+	if err != nil {
+		return discover.NodeID{}, quicError(err)
+	}
+	// Bind the frame secrets to the TLS session. Both ends only derive the
+	// same secrets if the QUIC connection isn't relayed by a man in the
+	// middle, otherwise the protocol handshake fails.
+	state := t.conn.ConnectionState().TLS
+	keys, err := state.ExportKeyingMaterial(quicKeysLabel, nil, 32)
+	if err != nil {
+		return discover.NodeID{}, err
+	}
+	sec.AES = crypto.Keccak256(sec.AES, keys)
+	sec.MAC = crypto.Keccak256(sec.MAC, keys)
+
+	t.wmu.Lock()
+	t.rw = newRLPXFrameRW(t.conn, sec)
+	t.wmu.Unlock()
+	return sec.RemoteID, nil
+}
+
+func (t *quicTransport) doProtoHandshake(our *protoHandshake) (their *protoHandshake, err error) {
+	// Same as in rlpx, prefer the read error over the write error.
+	werr := make(chan error, 1)
+	go func() { werr <- Send(t.rw, handshakeMsg, our) }()
+	if their, err = readProtocolHandshake(t.rw, our); err != nil {
+		<-werr // make sure the write terminates too
+		return nil, quicError(err)
+	}
+	if err := <-werr; err != nil {
+		return nil, fmt.Errorf("write error: %v", err)
+	}
+	t.smu.Lock()
+	t.protos = matchProtocols(t.protocols, their.Caps, nil)
+	t.smu.Unlock()
+
+	go t.readControl()
+	go t.acceptStreams()
+	return their, nil
+}
+
+func (t *quicTransport) ReadMsg() (Msg, error) {
+	select {
+	case m := <-t.in:
+		return m.msg, m.err
+	case <-t.closing:
+		return Msg{}, errQUICClosed
+	}
+}
+
+func (t *quicTransport) WriteMsg(msg Msg) error {
+	if msg.Code < baseProtocolLength {
+		t.wmu.Lock()
+		defer t.wmu.Unlock()
+		t.conn.SetWriteDeadline(time.Now().Add(frameWriteTimeout))
+		return t.rw.WriteMsg(msg)
+	}
+	s, err := t.stream(msg.Code)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.SetWriteDeadline(time.Now().Add(frameWriteTimeout))
+	return writeQUICFrame(s, msg)
+}
+
+// stream returns the outbound stream of the protocol that msg code belongs to,
+// opening it if needed.
+func (t *quicTransport) stream(code uint64) (*quicStream, error) {
+	t.smu.Lock()
+	defer t.smu.Unlock()
+
+	for name, proto := range t.protos {
+		if code < proto.offset || code >= proto.offset+proto.Length {
+			continue
+		}
+		if s := t.streams[name]; s != nil {
+			return s, nil
+		}
+		ss, err := t.conn.OpenUniStream()
+		if err != nil {
+			return nil, err
+		}
+		s := &quicStream{SendStream: ss}
+		t.streams[name] = s
+		return s, nil
+	}
+	return nil, fmt.Errorf("no protocol for message code %d", code)
+}
+
+func (t *quicTransport) close(err error) {
+	t.closeOnce.Do(func() { close(t.closing) })
+
+	// Tell the remote end why we're disconnecting if possible. The reason is
+	// sent as the error code of the connection close, as any stream data still
+	// in flight is discarded.
+	if r, ok := err.(DiscReason); ok && r != DiscNetworkError {
+		t.conn.CloseWithError(quic.ApplicationErrorCode(r), r.String())
+		return
+	}
+	t.conn.Close()
+}
+
+// readControl delivers the messages of the control stream.
+func (t *quicTransport) readControl() {
+	for {
+		t.conn.SetReadDeadline(time.Now().Add(frameReadTimeout))
+		msg, err := t.rw.ReadMsg()
+		if !t.deliver(msg, err) {
+			return
+		}
+	}
+}
+
+// acceptStreams reads the protocol streams opened by the remote end.
+func (t *quicTransport) acceptStreams() {
+	for {
+		s, err := t.conn.AcceptUniStream(t.conn.Context())
+		if err != nil {
+			t.deliver(Msg{}, err)
+			return
+		}
+		go t.readStream(s)
+	}
+}
+
+// readStream delivers the messages of a protocol stream.
+func (t *quicTransport) readStream(s *quic.ReceiveStream) {
+	for {
+		msg, err := readQUICFrame(s)
+		if err == nil && msg.Code < baseProtocolLength {
+			err = fmt.Errorf("base protocol message %d on protocol stream", msg.Code)
+		}
+		if !t.deliver(msg, err) {
+			s.CancelRead(0)
+			return
+		}
+	}
+}
+
+// deliver hands a message or read error to ReadMsg. It returns false if the
+// reader should stop.
+func (t *quicTransport) deliver(msg Msg, err error) bool {
+	select {
+	case t.in <- quicMsg{msg, quicError(err)}:
+		return err == nil
+	case <-t.closing:
+		return false
+	}
+}
+
+// writeQUICFrame writes msg to a protocol stream. Frames consist of the 24 bit
+// size of the frame content, followed by the RLP encoded message code and the
+// payload.
+func writeQUICFrame(w io.Writer, msg Msg) error {
+	ptype, _ := rlp.EncodeToBytes(msg.Code)
+	fsize := uint32(len(ptype)) + msg.Size
+	if fsize > maxUint24 {
+		return errors.New("message size overflows uint24")
+	}
+	head := make([]byte, 3, 3+len(ptype))
+	putInt24(fsize, head)
+	if _, err := w.Write(append(head, ptype...)); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, msg.Payload)
+	return err
+}
+
+// readQUICFrame reads a frame written by writeQUICFrame.
+func readQUICFrame(r io.Reader) (msg Msg, err error) {
+	head := make([]byte, 3)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return msg, err
+	}
+	frame := make([]byte, readInt24(head))
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return msg, err
+	}
+	content := bytes.NewReader(frame)
+	if err := rlp.Decode(content, &msg.Code); err != nil {
+		return msg, err
+	}
+	msg.Size = uint32(content.Len())
+	msg.Payload = content
+	return msg, nil
+}
+
+// quicError converts the close error of a connection closed by the remote end
+// with a disconnect reason into the reason.
+func quicError(err error) error {
+	var aerr *quic.ApplicationError
+	if errors.As(err, &aerr) && aerr.Remote && aerr.ErrorCode < quicNoReason {
+		return DiscReason(aerr.ErrorCode)
+	}
+	return err
+}
@@ -0,0 +1,300 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
+)
+
+var quicTestProtocols = []Protocol{
+	{Name: "a", Version: 1, Length: 5},
+	{Name: "b", Version: 2, Length: 3},
+}
+
+// quicPipe connects two QUIC endpoints on localhost, returning the dialed and
+// the accepted connection.
+func quicPipe(t *testing.T) (*quicEndpoint, *quicConn, *quicEndpoint, *quicConn) {
+	e0, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd0, err := e0.dial(e1.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd1, err := e1.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e0, fd0.(*quicConn), e1, fd1.(*quicConn)
+}
+
+// quicHandshake runs both handshakes on a pair of QUIC transports, where t0
+// dials the node with the given ID.
+func quicHandshake(t0, t1 transport, prv0, prv1 *ecdsa.PrivateKey, dialID discover.NodeID) (err0, err1 error) {
+	var (
+		hs0 = &protoHandshake{Version: baseProtocolVersion, ID: discover.PubkeyID(&prv0.PublicKey), Caps: []Cap{{"a", 1}, {"b", 2}}}
+		hs1 = &protoHandshake{Version: baseProtocolVersion, ID: discover.PubkeyID(&prv1.PublicKey), Caps: []Cap{{"a", 1}, {"b", 2}}}
+		wg  sync.WaitGroup
+	)
+	run := func(tr transport, prv *ecdsa.PrivateKey, dial *discover.Node, hs *protoHandshake, errp *error) {
+		defer wg.Done()
+		if _, *errp = tr.doEncHandshake(prv, dial); *errp != nil {
+			tr.close(*errp)
+			return
+		}
+		if _, *errp = tr.doProtoHandshake(hs); *errp != nil {
+			tr.close(*errp)
+		}
+	}
+	wg.Add(2)
+	go run(t0, prv0, &discover.Node{ID: dialID}, hs0, &err0)
+	go run(t1, prv1, nil, hs1, &err1)
+	wg.Wait()
+	return err0, err1
+}
+
+func TestQUICTransport(t *testing.T) {
+	e0, fd0, e1, fd1 := quicPipe(t)
+	defer e0.stop()
+	defer e1.stop()
+
+	var (
+		prv0, _ = crypto.GenerateKey()
+		prv1, _ = crypto.GenerateKey()
+		t0      = fd0.newTransport(quicTestProtocols)
+		t1      = fd1.newTransport(quicTestProtocols)
+	)
+	if err0, err1 := quicHandshake(t0, t1, prv0, prv1, discover.PubkeyID(&prv1.PublicKey)); err0 != nil || err1 != nil {
+		t.Fatalf("handshake failed: dial side %v, listen side %v", err0, err1)
+	}
+
+	// Protocol "a" starts at code 16, protocol "b" at 21.
+	sent := map[uint64]string{17: "a", 21: "b", pongMsg: ""}
+	for code, data := range sent {
+		if err := Send(t0, code, []string{data}); err != nil {
+			t.Fatalf("can't send message %d: %v", code, err)
+		}
+	}
+	for n := len(sent); n > 0; n-- {
+		msg, err := t1.ReadMsg()
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		want, ok := sent[msg.Code]
+		if !ok {
+			t.Fatalf("unexpected message code %d", msg.Code)
+		}
+		var data []string
+		if err := msg.Decode(&data); err != nil || len(data) != 1 || data[0] != want {
+			t.Errorf("message %d: got %q (err %v), want %q", msg.Code, data, err, want)
+		}
+		delete(sent, msg.Code)
+	}
+	if n := len(t0.(*quicTransport).streams); n != 2 {
+		t.Errorf("dial side opened %d protocol streams, want 2", n)
+	}
+	if err := Send(t0, 24, []string{"c"}); err == nil {
+		t.Error("sent message without matching protocol")
+	}
+
+	t0.close(DiscQuitting)
+	if _, err := t1.ReadMsg(); err != DiscQuitting {
+		t.Errorf("got error %v after disconnect, want %v", err, DiscQuitting)
+	}
+	t1.close(DiscNetworkError)
+}
+
+func TestQUICTransportWrongIdentity(t *testing.T) {
+	e0, fd0, e1, fd1 := quicPipe(t)
+	defer e0.stop()
+	defer e1.stop()
+
+	var (
+		prv0, _  = crypto.GenerateKey()
+		prv1, _  = crypto.GenerateKey()
+		other, _ = crypto.GenerateKey()
+		t0       = fd0.newTransport(quicTestProtocols)
+		t1       = fd1.newTransport(quicTestProtocols)
+	)
+	// The dialer expects the key of another node.
+	err0, err1 := quicHandshake(t0, t1, prv0, prv1, discover.PubkeyID(&other.PublicKey))
+	if err0 == nil || err1 == nil {
+		t.Fatalf("handshake succeeded with wrong identity: dial side %v, listen side %v", err0, err1)
+	}
+}
+
+// This test checks that a relay forwarding the control stream between two
+// QUIC connections can't complete the handshake, even though it doesn't
+// tamper with the encryption handshake.
+func TestQUICTransportRelayed(t *testing.T) {
+	e0, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e0.stop()
+	e1, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e1.stop()
+	relay, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer relay.stop()
+
+	// Run the relay, which accepts the connection of the dialer and forwards
+	// its control stream to the listener.
+	go func() {
+		in, err := relay.Accept()
+		if err != nil {
+			return
+		}
+		out, err := relay.dial(e1.Addr().(*net.UDPAddr))
+		if err != nil {
+			return
+		}
+		ctx := context.Background()
+		sin, err := in.(*quicConn).AcceptStream(ctx)
+		if err != nil {
+			return
+		}
+		sout, err := out.(*quicConn).OpenStreamSync(ctx)
+		if err != nil {
+			return
+		}
+		go func() {
+			io.Copy(sout, sin)
+			out.Close()
+		}()
+		io.Copy(sin, sout)
+		in.Close()
+	}()
+
+	fd0, err := e0.dial(relay.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd1, err := e1.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		prv0, _ = crypto.GenerateKey()
+		prv1, _ = crypto.GenerateKey()
+		t0      = fd0.(*quicConn).newTransport(quicTestProtocols)
+		t1      = fd1.(*quicConn).newTransport(quicTestProtocols)
+	)
+	err0, err1 := quicHandshake(t0, t1, prv0, prv1, discover.PubkeyID(&prv1.PublicKey))
+	if err0 == nil || err1 == nil {
+		t.Fatalf("handshake succeeded through relay: dial side %v, listen side %v", err0, err1)
+	}
+}
+
+func TestServerQUIC(t *testing.T) {
+	results := make(chan string, 4)
+	echo := func(name string) Protocol {
+		return Protocol{
+			Name:    name,
+			Version: 1,
+			Length:  1,
+			Run: func(p *Peer, rw MsgReadWriter) error {
+				if !p.Inbound() {
+					if err := Send(rw, 0, []string{name}); err != nil {
+						return err
+					}
+				}
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				var data []string
+				if err := msg.Decode(&data); err != nil {
+					return err
+				}
+				if _, ok := p.RemoteAddr().(*net.UDPAddr); !ok {
+					t.Errorf("peer has remote address %v, want UDP address", p.RemoteAddr())
+				}
+				if p.Inbound() {
+					if err := Send(rw, 0, data); err != nil {
+						return err
+					}
+				}
+				results <- data[0]
+				<-p.closed
+				return nil
+			},
+		}
+	}
+	newServer := func() *Server {
+		srv := &Server{Config: Config{
+			Name:           "test",
+			PrivateKey:     newkey(),
+			MaxPeers:       10,
+			NoDial:         true,
+			NoDiscovery:    true,
+			QUICListenAddr: "127.0.0.1:0",
+			Protocols:      []Protocol{echo("a"), echo("b")},
+		}}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("could not start server: %v", err)
+		}
+		return srv
+	}
+	srv0, srv1 := newServer(), newServer()
+	defer srv0.Stop()
+	defer srv1.Stop()
+
+	// Dial the second server through a dial task, which picks the QUIC
+	// endpoint from the node record.
+	addr := srv1.quicListener.Addr().(*net.UDPAddr)
+	record := new(enr.Record)
+	record.Set(enr.QUIC(addr.Port))
+	dest := &discover.Node{ID: discover.PubkeyID(&srv1.PrivateKey.PublicKey), IP: addr.IP}
+	task := &dialTask{flags: dynDialedConn, dest: dest, record: record}
+	if err := task.dial(srv0, dest); err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+
+	got := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		select {
+		case name := <-results:
+			got[name]++
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for protocol messages, got %v", got)
+		}
+	}
+	if got["a"] != 2 || got["b"] != 2 {
+		t.Errorf("protocols received %v, want two messages each", got)
+	}
+}
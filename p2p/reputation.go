@@ -0,0 +1,279 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/mclock"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/netutil"
+)
+
+// Penalties that protocols can assign to misbehaving peers through
+// Peer.Penalize. A peer is banned once its accumulated score reaches
+// PenaltySevere.
+const (
+	PenaltyMinor  = 10  // e.g. a useless or invalid transaction
+	PenaltyMajor  = 50  // e.g. a malformed protocol message
+	PenaltySevere = 100 // e.g. an invalid block, bans immediately
+)
+
+const (
+	banThreshold   = PenaltySevere
+	scoreHalfLife  = 30 * time.Minute // time for a penalty score to decay by half
+	scoreMin       = 1                // scores decayed below this are forgotten
+	defaultBanTime = 24 * time.Hour   // duration of automatic bans
+)
+
+// banStore persists bans across restarts. It is implemented by the discovery
+// table, which keeps bans in the node database.
+type banStore interface {
+	StoreBan(discover.Ban) error
+	DeleteBan(discover.NodeID, net.IP) error
+	Bans() []discover.Ban
+}
+
+// score is the decaying penalty score of a node.
+type score struct {
+	value   float64
+	updated mclock.AbsTime
+}
+
+// reputation tracks penalty scores of nodes and bans the ones that misbehave
+// too often. A nil reputation doesn't ban anything.
+type reputation struct {
+	now   func() mclock.AbsTime // monotonic clock, replaceable for testing
+	store banStore              // may be nil
+
+	mu      sync.Mutex
+	scores  map[discover.NodeID]*score
+	expired mclock.AbsTime // last time decayed scores were removed
+	idBans  map[discover.NodeID]discover.Ban
+	ipBans  map[string]discover.Ban
+}
+
+func newReputation(store banStore) *reputation {
+	r := &reputation{
+		now:    mclock.Now,
+		store:  store,
+		scores: make(map[discover.NodeID]*score),
+		idBans: make(map[discover.NodeID]discover.Ban),
+		ipBans: make(map[string]discover.Ban),
+	}
+	if store != nil {
+		for _, ban := range store.Bans() {
+			r.addBan(ban)
+		}
+	}
+	return r
+}
+
+// penalize adds a penalty to the score of a node. If the score reaches the
+// ban threshold, the node and its (public) IP address are banned and true is
+// returned.
+func (r *reputation) penalize(id discover.NodeID, ip net.IP, amount uint, reason string) bool {
+	if r == nil || amount == 0 {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.expireScores(now)
+
+	s := r.scores[id]
+	if s == nil {
+		s = &score{updated: now}
+		r.scores[id] = s
+	}
+	s.value = decay(s.value, time.Duration(now-s.updated)) + float64(amount)
+	s.updated = now
+	if s.value < banThreshold {
+		return false
+	}
+	delete(r.scores, id)
+
+	// Don't ban local addresses, they are likely shared by many nodes. The
+	// node ban remembers the address, so that unbanning the node lifts both.
+	until := time.Now().Add(defaultBanTime)
+	if ip != nil && !netutil.IsLAN(ip) {
+		r.ban(discover.Ban{IP: ip, Until: until, Reason: reason})
+	} else {
+		ip = nil
+	}
+	r.ban(discover.Ban{ID: id, IP: ip, Until: until, Reason: reason})
+	log.Debug("Banned misbehaving node", "id", id, "ip", ip, "reason", reason)
+	return true
+}
+
+// expireScores forgets the scores which decayed below scoreMin, at most once
+// per half-life. The caller must hold r.mu.
+func (r *reputation) expireScores(now mclock.AbsTime) {
+	if time.Duration(now-r.expired) < scoreHalfLife {
+		return
+	}
+	r.expired = now
+	for id, s := range r.scores {
+		if decay(s.value, time.Duration(now-s.updated)) < scoreMin {
+			delete(r.scores, id)
+		}
+	}
+}
+
+// decay returns the value of a score after the given time has passed.
+func decay(value float64, elapsed time.Duration) float64 {
+	return value * math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+}
+
+// score returns the current penalty score of a node.
+func (r *reputation) score(id discover.NodeID) float64 {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.scores[id]
+	if s == nil {
+		return 0
+	}
+	return decay(s.value, time.Duration(r.now()-s.updated))
+}
+
+// ban adds a ban and persists it. The caller must hold r.mu.
+func (r *reputation) ban(ban discover.Ban) {
+	r.addBan(ban)
+	if r.store != nil {
+		if err := r.store.StoreBan(ban); err != nil {
+			log.Warn("Failed to store ban", "id", ban.ID, "ip", ban.IP, "err", err)
+		}
+	}
+}
+
+func (r *reputation) addBan(ban discover.Ban) {
+	if ban.ID != (discover.NodeID{}) {
+		r.idBans[ban.ID] = ban
+	} else if ban.IP != nil {
+		r.ipBans[ban.IP.String()] = ban
+	}
+}
+
+// setBan bans a node ID or IP address until the given time.
+func (r *reputation) setBan(ban discover.Ban) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ban.ID != (discover.NodeID{}) {
+		delete(r.scores, ban.ID)
+	}
+	r.ban(ban)
+}
+
+// unban lifts the ban of a node ID or IP address. Unbanning a node also lifts
+// the ban of the address it was banned along with. It reports whether a ban
+// was in effect.
+func (r *reputation) unban(id discover.NodeID, ip net.IP) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id == (discover.NodeID{}) {
+		return r.removeBan(discover.NodeID{}, ip)
+	}
+	ban, found := r.idBans[id]
+	if !found {
+		return false
+	}
+	r.removeBan(id, nil)
+	if ban.IP != nil {
+		// Leave the address banned if it was banned separately.
+		if ipBan, ok := r.ipBans[ban.IP.String()]; ok && ipBan.Until.Equal(ban.Until) && ipBan.Reason == ban.Reason {
+			r.removeBan(discover.NodeID{}, ban.IP)
+		}
+	}
+	return true
+}
+
+// removeBan deletes the ban of a node ID or IP address, reporting whether it
+// existed. The caller must hold r.mu.
+func (r *reputation) removeBan(id discover.NodeID, ip net.IP) bool {
+	var found bool
+	if id != (discover.NodeID{}) {
+		_, found = r.idBans[id]
+		delete(r.idBans, id)
+	} else if ip != nil {
+		_, found = r.ipBans[ip.String()]
+		delete(r.ipBans, ip.String())
+	}
+	if found && r.store != nil {
+		if err := r.store.DeleteBan(id, ip); err != nil {
+			log.Warn("Failed to delete ban", "id", id, "ip", ip, "err", err)
+		}
+	}
+	return found
+}
+
+// banned reports whether the given node ID or IP address is banned. Either of
+// them may be zero.
+func (r *reputation) banned(id discover.NodeID, ip net.IP) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if ban, ok := r.idBans[id]; ok {
+		if ban.Until.After(now) {
+			return true
+		}
+		delete(r.idBans, id)
+	}
+	if ip != nil {
+		if ban, ok := r.ipBans[ip.String()]; ok {
+			if ban.Until.After(now) {
+				return true
+			}
+			delete(r.ipBans, ip.String())
+		}
+	}
+	return false
+}
+
+// bans returns all bans in effect.
+func (r *reputation) bans() []discover.Ban {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	bans := make([]discover.Ban, 0, len(r.idBans)+len(r.ipBans))
+	for _, ban := range r.idBans {
+		if ban.Until.After(now) {
+			bans = append(bans, ban)
+		}
+	}
+	for _, ban := range r.ipBans {
+		if ban.Until.After(now) {
+			bans = append(bans, ban)
+		}
+	}
+	return bans
+}
@@ -0,0 +1,204 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/mclock"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+)
+
+// memBanStore is an in-memory banStore.
+type memBanStore struct {
+	bans map[string]discover.Ban
+}
+
+func (s *memBanStore) key(id discover.NodeID, ip net.IP) string {
+	if id != (discover.NodeID{}) {
+		return id.String()
+	}
+	return ip.String()
+}
+
+func (s *memBanStore) StoreBan(ban discover.Ban) error {
+	s.bans[s.key(ban.ID, ban.IP)] = ban
+	return nil
+}
+
+func (s *memBanStore) DeleteBan(id discover.NodeID, ip net.IP) error {
+	delete(s.bans, s.key(id, ip))
+	return nil
+}
+
+func (s *memBanStore) Bans() []discover.Ban {
+	var bans []discover.Ban
+	for _, ban := range s.bans {
+		bans = append(bans, ban)
+	}
+	return bans
+}
+
+func TestReputationDecay(t *testing.T) {
+	var (
+		now = mclock.AbsTime(0)
+		rep = newReputation(nil)
+		id  = randomID()
+		ip  = net.IP{8, 8, 8, 8}
+	)
+	rep.now = func() mclock.AbsTime { return now }
+
+	if rep.penalize(id, ip, PenaltyMajor, "test") {
+		t.Fatal("banned after a single major penalty")
+	}
+	now += mclock.AbsTime(scoreHalfLife)
+	if score := rep.score(id); score != PenaltyMajor/2 {
+		t.Fatalf("score not decayed: have %v, want %v", score, PenaltyMajor/2)
+	}
+	// The decayed score is below the threshold after another major penalty.
+	if rep.penalize(id, ip, PenaltyMajor, "test") {
+		t.Fatal("banned although the score decayed")
+	}
+	if rep.banned(id, nil) {
+		t.Fatal("node banned below threshold")
+	}
+	// The next one crosses it.
+	if !rep.penalize(id, ip, PenaltyMajor, "test") {
+		t.Fatal("not banned above threshold")
+	}
+	if !rep.banned(id, nil) {
+		t.Error("node not banned")
+	}
+	if !rep.banned(discover.NodeID{}, ip) {
+		t.Error("IP not banned")
+	}
+}
+
+func TestReputationExpiry(t *testing.T) {
+	var (
+		now = mclock.AbsTime(0)
+		rep = newReputation(nil)
+		old = randomID()
+		ip  = net.IP{8, 8, 8, 8}
+	)
+	rep.now = func() mclock.AbsTime { return now }
+
+	rep.penalize(old, ip, PenaltyMinor, "test")
+	now += mclock.AbsTime(10 * scoreHalfLife)
+
+	// Penalizing another node forgets the decayed score.
+	rep.penalize(randomID(), ip, PenaltyMinor, "test")
+	if _, ok := rep.scores[old]; ok {
+		t.Error("decayed score not removed")
+	}
+	if len(rep.scores) != 1 {
+		t.Errorf("score count mismatch: have %d, want 1", len(rep.scores))
+	}
+}
+
+func TestReputationUnbanLiftsIPBan(t *testing.T) {
+	var (
+		store = &memBanStore{bans: make(map[string]discover.Ban)}
+		rep   = newReputation(store)
+		id    = randomID()
+		ip    = net.IP{8, 8, 8, 8}
+		other = net.IP{8, 8, 4, 4}
+	)
+	if !rep.penalize(id, ip, PenaltySevere, "test") {
+		t.Fatal("not banned after severe penalty")
+	}
+	rep.setBan(discover.Ban{IP: other, Until: time.Now().Add(time.Hour), Reason: "admin"})
+
+	// Unbanning the node lifts the automatic ban of its address, also after
+	// the bans were reloaded from the store.
+	rep = newReputation(store)
+	if !rep.unban(id, nil) {
+		t.Fatal("unban of banned node returned false")
+	}
+	if rep.banned(id, nil) {
+		t.Error("node still banned")
+	}
+	if rep.banned(discover.NodeID{}, ip) {
+		t.Error("address of unbanned node still banned")
+	}
+	if !rep.banned(discover.NodeID{}, other) {
+		t.Error("unrelated address ban lifted")
+	}
+	if len(store.bans) != 1 {
+		t.Errorf("stored ban count mismatch: have %d, want 1", len(store.bans))
+	}
+}
+
+func TestReputationLANNotBanned(t *testing.T) {
+	rep := newReputation(nil)
+	ip := net.IP{192, 168, 0, 1}
+	if !rep.penalize(randomID(), ip, PenaltySevere, "test") {
+		t.Fatal("not banned after severe penalty")
+	}
+	if rep.banned(discover.NodeID{}, ip) {
+		t.Error("LAN address banned")
+	}
+}
+
+func TestReputationPersistence(t *testing.T) {
+	var (
+		store = &memBanStore{bans: make(map[string]discover.Ban)}
+		rep   = newReputation(store)
+		id    = randomID()
+		ip    = net.IP{1, 2, 3, 4}
+	)
+	rep.setBan(discover.Ban{ID: id, Until: time.Now().Add(time.Hour)})
+	rep.setBan(discover.Ban{IP: ip, Until: time.Now().Add(time.Hour)})
+	rep.setBan(discover.Ban{IP: net.IP{5, 6, 7, 8}, Until: time.Now().Add(-time.Hour)})
+
+	// A new instance loads the bans from the store.
+	rep = newReputation(store)
+	if !rep.banned(id, nil) || !rep.banned(discover.NodeID{}, ip) {
+		t.Fatal("bans not loaded from store")
+	}
+	if bans := rep.bans(); len(bans) != 2 {
+		t.Errorf("ban count mismatch: have %d, want 2", len(bans))
+	}
+	if !rep.unban(id, nil) {
+		t.Fatal("unban of banned node returned false")
+	}
+	if rep.unban(id, nil) {
+		t.Error("unban of unbanned node returned true")
+	}
+	if rep.banned(id, nil) {
+		t.Error("node still banned")
+	}
+	if len(store.bans) != 2 {
+		t.Errorf("stored ban count mismatch: have %d, want 2", len(store.bans))
+	}
+}
+
+func TestServerRejectsBanned(t *testing.T) {
+	srv := &Server{Config: Config{MaxPeers: 10}, rep: newReputation(nil)}
+
+	c := &conn{id: randomID(), flags: inboundConn}
+	srv.rep.setBan(discover.Ban{ID: c.id, Until: time.Now().Add(time.Hour)})
+	if err := srv.encHandshakeChecks(nil, 0, c); err != DiscUselessPeer {
+		t.Errorf("banned node: got %v, want %v", err, DiscUselessPeer)
+	}
+	c.flags |= trustedConn
+	if err := srv.encHandshakeChecks(nil, 0, c); err != nil {
+		t.Errorf("banned trusted node: got %v, want nil", err)
+	}
+}
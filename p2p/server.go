@@ -31,6 +31,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discv5"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/enr"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/nat"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/netutil"
 )
@@ -123,6 +124,16 @@ type Config struct {
 	// the server is started.
 	ListenAddr string
 
+	// If QUICListenAddr is set to a non-nil address, the server also
+	// accepts connections over QUIC on the given UDP address and
+	// announces the port in its node record. Peers announcing a QUIC
+	// port are then dialed over QUIC as well. The address must not
+	// be used by discovery.
+	//
+	// The QUICListenAddr field is updated with the actual address when
+	// the server is started.
+	QUICListenAddr string `toml:",omitempty"`
+
 	// If set to a non-nil value, the given NAT port mapper
 	// is used to make the listening port available to the
 	// Internet.
@@ -158,9 +169,11 @@ type Server struct {
 
 	ntab         discoverTable
 	listener     net.Listener
+	quicListener *quicEndpoint
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
 	DiscV5       *discv5.Network
+	rep          *reputation
 
 	// These are for Peers, PeerCount (and nothing else).
 	peerOp     chan peerOpFunc
@@ -316,6 +329,23 @@ func (srv *Server) Self() *discover.Node {
 	return srv.makeSelf(srv.listener, srv.ntab)
 }
 
+// SetRecordEntry adds or updates an entry of the local node record published
+// on the discovery network. It does nothing if discovery is not running.
+func (srv *Server) SetRecordEntry(entry enr.Entry) error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if tab, ok := srv.ntab.(recordSetter); ok {
+		return tab.SetRecordEntry(entry)
+	}
+	return nil
+}
+
+// recordSetter is implemented by discovery tables publishing a node record.
+type recordSetter interface {
+	SetRecordEntry(enr.Entry) error
+}
+
 func (srv *Server) makeSelf(listener net.Listener, ntab discoverTable) *discover.Node {
 	// If the server's not running, return an empty node.
 	// If the node is running but discovery is off, manually assemble the node infos.
@@ -336,6 +366,43 @@ func (srv *Server) makeSelf(listener net.Listener, ntab discoverTable) *discover
 	return ntab.Self()
 }
 
+// Ban prevents the given node ID or IP address from connecting until the ban
+// expires, disconnecting any matching peers. Exactly one of id and ip should be
+// set. Bans are persisted in the node database if discovery is enabled.
+func (srv *Server) Ban(id discover.NodeID, ip net.IP, duration time.Duration, reason string) error {
+	if srv.rep == nil {
+		return errServerStopped
+	}
+	if id == (discover.NodeID{}) && ip == nil {
+		return errors.New("neither node ID nor IP address given")
+	}
+	srv.rep.setBan(discover.Ban{ID: id, IP: ip, Until: time.Now().Add(duration), Reason: reason})
+	for _, p := range srv.Peers() {
+		if p.ID() == id || (ip != nil && p.remoteIP().Equal(ip)) {
+			p.Disconnect(DiscUselessPeer)
+		}
+	}
+	return nil
+}
+
+// Unban lifts the ban of a node ID or IP address. Unbanning a node also lifts
+// the automatic ban of the address it misbehaved from. It reports whether
+// there was a ban in effect.
+func (srv *Server) Unban(id discover.NodeID, ip net.IP) bool {
+	if srv.rep == nil {
+		return false
+	}
+	return srv.rep.unban(id, ip)
+}
+
+// Bans returns the node IDs and IP addresses currently banned.
+func (srv *Server) Bans() []discover.Ban {
+	if srv.rep == nil {
+		return nil
+	}
+	return srv.rep.bans()
+}
+
 // Stop terminates the server and all active peer connections.
 // It blocks until all active connections have been closed.
 func (srv *Server) Stop() {
@@ -349,8 +416,14 @@ func (srv *Server) Stop() {
 		// this unblocks listener Accept
 		srv.listener.Close()
 	}
+	if srv.quicListener != nil {
+		srv.quicListener.Close()
+	}
 	close(srv.quit)
 	srv.loopWG.Wait()
+	if srv.quicListener != nil {
+		srv.quicListener.stop()
+	}
 }
 
 // sharedUDPConn implements a shared connection. Write sends messages to the underlying connection while read returns
@@ -456,6 +529,9 @@ func (srv *Server) Start() (err error) {
 			Bootnodes:    srv.BootstrapNodes,
 			Unhandled:    unhandled,
 		}
+		for _, p := range srv.Protocols {
+			cfg.Entries = append(cfg.Entries, p.Attributes...)
+		}
 		ntab, err := discover.ListenUDP(conn, cfg)
 		if err != nil {
 			return err
@@ -482,8 +558,20 @@ func (srv *Server) Start() (err error) {
 		srv.DiscV5 = ntab
 	}
 
+	// peer reputation, bans are kept in the node database if there is one
+	if store, ok := srv.ntab.(banStore); ok {
+		srv.rep = newReputation(store)
+	} else {
+		srv.rep = newReputation(nil)
+	}
+
 	dynPeers := srv.maxDialedConns()
-	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict, srv.dialFilter())
+	for _, p := range srv.Protocols {
+		if p.DialCandidates != nil {
+			dialer.addSource(p.DialCandidates)
+		}
+	}
 
 	// handshake
 	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
@@ -496,7 +584,12 @@ func (srv *Server) Start() (err error) {
 			return err
 		}
 	}
-	if srv.NoDial && srv.ListenAddr == "" {
+	if srv.QUICListenAddr != "" {
+		if err := srv.startQUICListening(); err != nil {
+			return err
+		}
+	}
+	if srv.NoDial && srv.ListenAddr == "" && srv.QUICListenAddr == "" {
 		srv.log.Warn("P2P server will be useless, neither dialing nor listening")
 	}
 
@@ -506,6 +599,29 @@ func (srv *Server) Start() (err error) {
 	return nil
 }
 
+// dialFilter combines the dial filters of all protocols into a single predicate,
+// accepting a node record if any of them accepts it. It returns nil if none of
+// the protocols filters dial candidates.
+func (srv *Server) dialFilter() func(*enr.Record) bool {
+	var filters []func(*enr.Record) bool
+	for _, p := range srv.Protocols {
+		if p.DialFilter != nil {
+			filters = append(filters, p.DialFilter)
+		}
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return func(r *enr.Record) bool {
+		for _, filter := range filters {
+			if filter(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 func (srv *Server) startListening() error {
 	// Launch the TCP listener.
 	listener, err := net.Listen("tcp", srv.ListenAddr)
@@ -515,8 +631,9 @@ func (srv *Server) startListening() error {
 	laddr := listener.Addr().(*net.TCPAddr)
 	srv.ListenAddr = laddr.String()
 	srv.listener = listener
+	srv.log.Info("RLPx listener up", "self", srv.makeSelf(listener, srv.ntab))
 	srv.loopWG.Add(1)
-	go srv.listenLoop()
+	go srv.listenLoop(listener)
 	// Map the TCP listening port if NAT is configured.
 	if !laddr.IP.IsLoopback() && srv.NAT != nil {
 		srv.loopWG.Add(1)
@@ -528,6 +645,37 @@ func (srv *Server) startListening() error {
 	return nil
 }
 
+func (srv *Server) startQUICListening() error {
+	// Launch the QUIC listener.
+	listener, err := listenQUIC(srv.QUICListenAddr)
+	if err != nil {
+		return err
+	}
+	laddr := listener.Addr().(*net.UDPAddr)
+	// Announce the port, so that other nodes dial us over QUIC.
+	if tab, ok := srv.ntab.(recordSetter); ok {
+		if err := tab.SetRecordEntry(enr.QUIC(laddr.Port)); err != nil {
+			listener.Close()
+			listener.stop()
+			return err
+		}
+	}
+	srv.QUICListenAddr = laddr.String()
+	srv.quicListener = listener
+	srv.log.Info("QUIC listener up", "addr", laddr)
+	srv.loopWG.Add(1)
+	go srv.listenLoop(listener)
+	// Map the UDP listening port if NAT is configured.
+	if !laddr.IP.IsLoopback() && srv.NAT != nil {
+		srv.loopWG.Add(1)
+		go func() {
+			nat.Map(srv.NAT, srv.quit, "udp", laddr.Port, laddr.Port, "ethereum quic")
+			srv.loopWG.Done()
+		}()
+	}
+	return nil
+}
+
 type dialer interface {
 	newTasks(running int, peers map[discover.NodeID]*Peer, now time.Time) []task
 	taskDone(task, time.Time)
@@ -636,6 +784,7 @@ running:
 			if err == nil {
 				// The handshakes are done and it passed all checks.
 				p := newPeer(c, srv.Protocols)
+				p.rep = srv.rep
 				// If message events are enabled, pass the peerFeed
 				// to the peer
 				if srv.EnableMsgEvents {
@@ -711,6 +860,8 @@ func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, inboundCo
 		return DiscAlreadyConnected
 	case c.id == srv.Self().ID:
 		return DiscSelf
+	case !c.is(trustedConn) && srv.rep.banned(c.id, nil):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -735,11 +886,10 @@ type tempError interface {
 	Temporary() bool
 }
 
-// listenLoop runs in its own goroutine and accepts
-// inbound connections.
-func (srv *Server) listenLoop() {
+// listenLoop runs in its own goroutine for each listener and
+// accepts inbound connections.
+func (srv *Server) listenLoop(listener net.Listener) {
 	defer srv.loopWG.Done()
-	srv.log.Info("RLPx listener up", "self", srv.makeSelf(srv.listener, srv.ntab))
 
 	tokens := defaultMaxPendingPeers
 	if srv.MaxPendingPeers > 0 {
@@ -759,7 +909,7 @@ func (srv *Server) listenLoop() {
 			err error
 		)
 		for {
-			fd, err = srv.listener.Accept()
+			fd, err = listener.Accept()
 			if tempErr, ok := err.(tempError); ok && tempErr.Temporary() {
 				srv.log.Debug("Temporary read error", "err", err)
 				continue
@@ -771,8 +921,9 @@ func (srv *Server) listenLoop() {
 		}
 
 		// Reject connections that do not match NetRestrict.
+		ip := addrIP(fd.RemoteAddr())
 		if srv.NetRestrict != nil {
-			if tcp, ok := fd.RemoteAddr().(*net.TCPAddr); ok && !srv.NetRestrict.Contains(tcp.IP) {
+			if ip != nil && !srv.NetRestrict.Contains(ip) {
 				srv.log.Debug("Rejected conn (not whitelisted in NetRestrict)", "addr", fd.RemoteAddr())
 				fd.Close()
 				slots <- struct{}{}
@@ -780,6 +931,14 @@ func (srv *Server) listenLoop() {
 			}
 		}
 
+		// Reject connections from banned addresses.
+		if ip != nil && srv.rep.banned(discover.NodeID{}, ip) {
+			srv.log.Debug("Rejected conn (banned address)", "addr", fd.RemoteAddr())
+			fd.Close()
+			slots <- struct{}{}
+			continue
+		}
+
 		fd = newMeteredConn(fd, true)
 		srv.log.Trace("Accepted connection", "addr", fd.RemoteAddr())
 		go func() {
@@ -797,7 +956,7 @@ func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 	if self == nil {
 		return errors.New("shutdown")
 	}
-	c := &conn{fd: fd, transport: srv.newTransport(fd), flags: flags, cont: make(chan error)}
+	c := &conn{fd: fd, transport: srv.transportFor(fd), flags: flags, cont: make(chan error)}
 	err := srv.setupConn(c, flags, dialDest)
 	if err != nil {
 		c.close(err)
@@ -806,6 +965,15 @@ func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 	return err
 }
 
+// transportFor selects the transport of a connection. Connections of listeners
+// and dialers with their own transport use it, all others use newTransport.
+func (srv *Server) transportFor(fd net.Conn) transport {
+	if tc, ok := fd.(transportConn); ok {
+		return tc.newTransport(srv.Protocols)
+	}
+	return srv.newTransport(fd)
+}
+
 func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *discover.Node) error {
 	// Prevent leftover pending conns from entering the handshake.
 	srv.lock.Lock()
@@ -853,6 +1021,18 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *discover.Node) e
 	return nil
 }
 
+// addrIP returns the IP address of a TCP or UDP address, or nil for other kinds
+// of addresses.
+func addrIP(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	}
+	return nil
+}
+
 func truncateName(s string) string {
 	if len(s) > 20 {
 		return s[:20] + "..."
@@ -188,6 +188,14 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// CheckpointOracleConfig is the location of the checkpoint oracle contract and
+// the set of signers whose attestations light clients trust.
+type CheckpointOracleConfig struct {
+	Address   common.Address   `json:"address"`   // Address of the oracle contract
+	Signers   []common.Address `json:"signers"`   // Trusted checkpoint signers
+	Threshold uint64           `json:"threshold"` // Number of signatures required for a checkpoint
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 
@@ -490,5 +498,5 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainID == nil {
 		chainID = new(big.Int)
 	}
-	return Rules{ChainID: new(big.Int).Set(chainID), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num), IsConstantinople: c.IsConstantinople(num), IsForkMasternode: c.IsForkMasternode(num), IsForkSmartContract: c.IsForkSmartContract(num) IsEraV1: c.IsEraV1(num) IsEraV2: c.IsEraV2(num) IsEraV3: c.IsEraV3(num) IsEraV4: c.IsEraV4(num) IsEraV5: c.IsEraV5(num) IsEraV6: c.IsEraV6(num) IsEraV7: c.IsEraV7(num) IsEraV8: c.IsEraV8(num) IsEraV9: c.IsEraV9(num)}
+	return Rules{ChainID: new(big.Int).Set(chainID), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num), IsConstantinople: c.IsConstantinople(num), IsForkMasternode: c.IsForkMasternode(num), IsForkSmartContract: c.IsForkSmartContract(num), IsEraV1: c.IsEraV1(num), IsEraV2: c.IsEraV2(num), IsEraV3: c.IsEraV3(num), IsEraV4: c.IsEraV4(num), IsEraV5: c.IsEraV5(num), IsEraV6: c.IsEraV6(num), IsEraV7: c.IsEraV7(num), IsEraV8: c.IsEraV8(num), IsEraV9: c.IsEraV9(num)}
 }
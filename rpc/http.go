@@ -165,8 +165,7 @@ func (t *httpReadWriteNopCloser) Close() error {
 // Deprecated: Server implements http.Handler
 func NewHTTPServer(cors []string, vhosts []string, user string, password string, srv *Server) *http.Server {
 	// Wrap the CORS-handler within a host-handler
-	handler := newCorsHandler(srv, cors)
-	handler = newVHostHandler(vhosts, handler)
+	handler := NewHTTPHandlerStack(srv, cors, vhosts)
 
 	if user != "" && password != "" {
 		log.Info("HTTP endpoint is secured by basic authentication.")
@@ -224,7 +223,14 @@ func validateRequest(r *http.Request) (int, error) {
 	return 0, nil
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+// NewHTTPHandlerStack wraps an http.Handler with the CORS and virtual host
+// filtering used by the HTTP RPC endpoint.
+func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string) http.Handler {
+	handler := newCorsHandler(srv, cors)
+	return newVHostHandler(vhosts, handler)
+}
+
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
 		return srv
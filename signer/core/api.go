@@ -34,6 +34,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/internal/ethapi"
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+	"github.com/Ethereum-Reloaded/ETHR-Go/signer/eip712"
 )
 
 // ExternalAPI defines the external API through which signing requests are made.
@@ -46,6 +47,8 @@ type ExternalAPI interface {
 	SignTransaction(ctx context.Context, args SendTxArgs, methodSelector *string) (*ethapi.SignTransactionResult, error)
 	// Sign - request to sign the given data (plus prefix)
 	Sign(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error)
+	// SignTypedData - request to sign the given EIP-712 typed structured data
+	SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data eip712.TypedData) (hexutil.Bytes, error)
 	// EcRecover - request to perform ecrecover
 	EcRecover(ctx context.Context, data, sig hexutil.Bytes) (common.Address, error)
 	// Export - request to export an account
@@ -155,11 +158,13 @@ type (
 		NewPassword string `json:"new_password"`
 	}
 	SignDataRequest struct {
-		Address common.MixedcaseAddress `json:"address"`
-		Rawdata hexutil.Bytes           `json:"raw_data"`
-		Message string                  `json:"message"`
-		Hash    hexutil.Bytes           `json:"hash"`
-		Meta    Metadata                `json:"meta"`
+		Address   common.MixedcaseAddress `json:"address"`
+		Rawdata   hexutil.Bytes           `json:"raw_data"`
+		Message   string                  `json:"message"`
+		Hash      hexutil.Bytes           `json:"hash"`
+		TypedData *eip712.TypedData       `json:"typed_data,omitempty"`
+		Messages  []*eip712.Field         `json:"messages,omitempty"`
+		Meta      Metadata                `json:"meta"`
 	}
 	SignDataResponse struct {
 		Approved bool `json:"approved"`
@@ -389,8 +394,40 @@ func (api *SignerAPI) Sign(ctx context.Context, addr common.MixedcaseAddress, da
 	// We make the request prior to looking up if we actually have the account, to prevent
 	// account-enumeration via the API
 	req := &SignDataRequest{Address: addr, Rawdata: data, Message: msg, Hash: sighash, Meta: MetadataFromContext(ctx)}
-	res, err := api.UI.ApproveSignData(req)
+	return api.signData(req)
+}
 
+// SignTypedData calculates an Ethereum ECDSA signature for the given EIP-712
+// typed structured data:
+// keccack256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+//
+// The decoded fields of the domain and the message are shown to the UI for
+// approval. The V value of the signature will be 27 or 28.
+func (api *SignerAPI) SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data eip712.TypedData) (hexutil.Bytes, error) {
+	rawdata, err := data.SigningData()
+	if err != nil {
+		return nil, err
+	}
+	fields, err := data.Format()
+	if err != nil {
+		return nil, err
+	}
+	req := &SignDataRequest{
+		Address:   addr,
+		Rawdata:   rawdata,
+		Message:   eip712.Pprint(fields),
+		Hash:      crypto.Keccak256(rawdata),
+		TypedData: &data,
+		Messages:  fields,
+		Meta:      MetadataFromContext(ctx),
+	}
+	return api.signData(req)
+}
+
+// signData requests approval for signing the hash of the given request, and
+// signs it with the requested account.
+func (api *SignerAPI) signData(req *SignDataRequest) (hexutil.Bytes, error) {
+	res, err := api.UI.ApproveSignData(req)
 	if err != nil {
 		return nil, err
 	}
@@ -398,13 +435,13 @@ func (api *SignerAPI) Sign(ctx context.Context, addr common.MixedcaseAddress, da
 		return nil, ErrRequestDenied
 	}
 	// Look up the wallet containing the requested signer
-	account := accounts.Account{Address: addr.Address()}
+	account := accounts.Account{Address: req.Address.Address()}
 	wallet, err := api.am.Find(account)
 	if err != nil {
 		return nil, err
 	}
 	// Assemble sign the data with the wallet
-	signature, err := wallet.SignHashWithPassphrase(account, res.Password, sighash)
+	signature, err := wallet.SignHashWithPassphrase(account, res.Password, req.Hash)
 	if err != nil {
 		api.UI.ShowError(err.Error())
 		return nil, err
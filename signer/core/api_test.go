@@ -19,6 +19,7 @@ package core
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -32,8 +33,10 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/internal/ethapi"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+	"github.com/Ethereum-Reloaded/ETHR-Go/signer/eip712"
 )
 
 //Used for testing
@@ -254,6 +257,54 @@ func TestSignData(t *testing.T) {
 		t.Errorf("Expected 65 byte signature (got %d bytes)", len(h))
 	}
 }
+func TestSignTypedData(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0].Address)
+
+	var data eip712.TypedData
+	if err := json.Unmarshal([]byte(`{
+		"types": {
+			"EIP712Domain": [{"name": "name", "type": "string"}, {"name": "chainId", "type": "uint256"}],
+			"Permit": [{"name": "spender", "type": "address"}, {"name": "amounts", "type": "uint256[]"}]
+		},
+		"primaryType": "Permit",
+		"domain": {"name": "Token", "chainId": "0x1"},
+		"message": {"spender": "0x0000000000000000000000000000000000001337", "amounts": [1, "2"]}
+	}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	control <- "Y"
+	control <- "apassword"
+	sig, err := api.SignTypedData(context.Background(), a, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sig) != 65 {
+		t.Fatalf("Expected 65 byte signature (got %d bytes)", len(sig))
+	}
+	// Check that the signature recovers to the signer of the typed data hash
+	hash, _ := data.Hash()
+	sig[64] -= 27
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := crypto.PubkeyToAddress(*pub); addr != a.Address() {
+		t.Errorf("Signer mismatch: have %x, want %x", addr, a.Address())
+	}
+	// Invalid typed data is rejected before asking for approval
+	data.Message["extra"] = true
+	if _, err := api.SignTypedData(context.Background(), a, data); err == nil {
+		t.Errorf("Expected error for invalid typed data")
+	}
+}
+
 func mkTestTx(from common.MixedcaseAddress) SendTxArgs {
 	to := common.NewMixedcaseAddress(common.HexToAddress("0x1337"))
 	gas := hexutil.Uint64(21000)
@@ -0,0 +1,640 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package eip712 implements hashing of typed structured data as specified by
+// EIP-712.
+//
+// https://github.com/ethereum/EIPs/blob/master/EIPS/eip-712.md
+package eip712
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/math"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+)
+
+// DomainType is the name of the struct type describing the signing domain.
+const DomainType = "EIP712Domain"
+
+var (
+	errMissingDomain  = errors.New("types do not define " + DomainType)
+	errMissingPrimary = errors.New("primary type not defined")
+
+	typeNameRegexp  = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*$`)
+	arrayTypeRegexp = regexp.MustCompile(`^(.+)\[([0-9]*)\]$`)
+)
+
+// Type is a single named and typed member of a struct type.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Types maps struct type names to their members.
+type Types map[string][]Type
+
+// TypedDataDomain is the domain separating the signatures of an application
+// from the ones of others. Only the fields listed in the EIP712Domain type
+// take part in the domain hash.
+type TypedDataDomain struct {
+	Name              string                `json:"name"`
+	Version           string                `json:"version"`
+	ChainId           *math.HexOrDecimal256 `json:"chainId"`
+	VerifyingContract string                `json:"verifyingContract"`
+	Salt              string                `json:"salt"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting the chain id both as JSON
+// number and as decimal or hex string.
+func (domain *TypedDataDomain) UnmarshalJSON(input []byte) error {
+	type plainDomain TypedDataDomain
+	var dec struct {
+		plainDomain
+		ChainId json.RawMessage `json:"chainId"`
+	}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	*domain = TypedDataDomain(dec.plainDomain)
+	if len(dec.ChainId) == 0 || string(dec.ChainId) == "null" {
+		return nil
+	}
+	chainId := strings.Trim(string(dec.ChainId), `"`)
+	n, err := parseInt(chainId)
+	if err != nil || n.Sign() < 0 {
+		return fmt.Errorf("invalid chain id %s", dec.ChainId)
+	}
+	domain.ChainId = (*math.HexOrDecimal256)(n)
+	return nil
+}
+
+// TypedData is a typed structured message to sign.
+type TypedData struct {
+	Types       Types                  `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      TypedDataDomain        `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the numbers of the
+// message as json.Number so that large integers are not rounded.
+func (typedData *TypedData) UnmarshalJSON(input []byte) error {
+	type plainTypedData TypedData
+	var dec struct {
+		plainTypedData
+		Message json.RawMessage `json:"message"`
+	}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	*typedData = TypedData(dec.plainTypedData)
+	if len(dec.Message) == 0 || string(dec.Message) == "null" {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(dec.Message))
+	decoder.UseNumber()
+	return decoder.Decode(&typedData.Message)
+}
+
+// Field is a decoded member of a typed message, meant to be shown to the user
+// before signing. The value of struct members is a list of further fields, the
+// value of arrays a list of their elements.
+type Field struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// Validate checks that all the referenced types are defined and that the
+// primary and domain types exist.
+func (typedData *TypedData) Validate() error {
+	if _, ok := typedData.Types[DomainType]; !ok {
+		return errMissingDomain
+	}
+	if _, ok := typedData.Types[typedData.PrimaryType]; !ok {
+		return errMissingPrimary
+	}
+	for name, members := range typedData.Types {
+		if !typeNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid type name %q", name)
+		}
+		seen := make(map[string]bool)
+		for _, member := range members {
+			if member.Name == "" || seen[member.Name] {
+				return fmt.Errorf("type %s: empty or duplicate member name %q", name, member.Name)
+			}
+			seen[member.Name] = true
+
+			elem := elementType(member.Type)
+			if _, ok := typedData.Types[elem]; !ok && !isAtomicType(elem) && elem != "string" && elem != "bytes" {
+				return fmt.Errorf("type %s: unknown type %q of member %s", name, member.Type, member.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Hash returns the hash to sign for the typed data:
+//   keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+func (typedData *TypedData) Hash() (common.Hash, error) {
+	data, err := typedData.SigningData()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(data), nil
+}
+
+// SigningData returns the raw data whose hash is signed for the typed data.
+func (typedData *TypedData) SigningData() ([]byte, error) {
+	if err := typedData.Validate(); err != nil {
+		return nil, err
+	}
+	domainSeparator, err := typedData.HashStruct(DomainType, typedData.Domain.Map())
+	if err != nil {
+		return nil, fmt.Errorf("domain: %v", err)
+	}
+	structHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{0x19, 0x01}, domainSeparator[:]...), structHash[:]...), nil
+}
+
+// HashStruct returns the hash of the given struct value of the named type:
+//   keccak256(typeHash ‖ encodeData(value))
+func (typedData *TypedData) HashStruct(primaryType string, data map[string]interface{}) (common.Hash, error) {
+	enc, err := typedData.EncodeData(primaryType, data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(enc), nil
+}
+
+// TypeHash returns the hash of the encoded type.
+func (typedData *TypedData) TypeHash(primaryType string) common.Hash {
+	return crypto.Keccak256Hash([]byte(typedData.EncodeType(primaryType)))
+}
+
+// EncodeType returns the signature of the named type followed by the ones of
+// the struct types it references, sorted by name, e.g.
+//   Mail(Person from,Person to,string contents)Person(string name,address wallet)
+func (typedData *TypedData) EncodeType(primaryType string) string {
+	deps := typedData.Dependencies(primaryType, nil)
+	if len(deps) > 0 {
+		sort.Strings(deps[1:])
+	}
+	var buffer bytes.Buffer
+	for _, dep := range deps {
+		buffer.WriteString(dep)
+		buffer.WriteString("(")
+		for i, member := range typedData.Types[dep] {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(member.Type)
+			buffer.WriteString(" ")
+			buffer.WriteString(member.Name)
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.String()
+}
+
+// Dependencies returns the named type followed by all the struct types it
+// references directly or indirectly, skipping the ones already found.
+func (typedData *TypedData) Dependencies(primaryType string, found []string) []string {
+	primaryType = elementType(primaryType)
+	for _, name := range found {
+		if name == primaryType {
+			return found
+		}
+	}
+	if _, ok := typedData.Types[primaryType]; !ok {
+		return found
+	}
+	found = append(found, primaryType)
+	for _, member := range typedData.Types[primaryType] {
+		found = typedData.Dependencies(member.Type, found)
+	}
+	return found
+}
+
+// EncodeData returns the encoding of the given struct value of the named type,
+// that is the type hash followed by the 32 byte encoding of each member.
+func (typedData *TypedData) EncodeData(primaryType string, data map[string]interface{}) ([]byte, error) {
+	members := typedData.Types[primaryType]
+	if err := checkFields(primaryType, members, data); err != nil {
+		return nil, err
+	}
+	typeHash := typedData.TypeHash(primaryType)
+
+	buffer := bytes.NewBuffer(typeHash[:])
+	for _, member := range members {
+		value, ok := data[member.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing field %s", primaryType, member.Name)
+		}
+		enc, err := typedData.encodeValue(member.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", primaryType, member.Name, err)
+		}
+		buffer.Write(enc)
+	}
+	return buffer.Bytes(), nil
+}
+
+// encodeValue returns the 32 byte encoding of a single value of the given type.
+func (typedData *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	// Arrays are encoded as the hash of their concatenated elements
+	if match := arrayTypeRegexp.FindStringSubmatch(typ); match != nil {
+		elems, err := arrayValue(match[2], value)
+		if err != nil {
+			return nil, err
+		}
+		var buffer bytes.Buffer
+		for i, elem := range elems {
+			enc, err := typedData.encodeValue(match[1], elem)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			buffer.Write(enc)
+		}
+		return crypto.Keccak256(buffer.Bytes()), nil
+	}
+	// Structs are encoded as their hash
+	if _, ok := typedData.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid struct value %v", value)
+		}
+		hash, err := typedData.HashStruct(typ, data)
+		if err != nil {
+			return nil, err
+		}
+		return hash[:], nil
+	}
+	// Dynamic types are encoded as the hash of their contents
+	switch typ {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string value %v", value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+	case "bytes":
+		blob, err := bytesValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(blob), nil
+	}
+	return encodeAtomic(typ, value)
+}
+
+// encodeAtomic returns the 32 byte encoding of a value of a fixed size type.
+func encodeAtomic(typ string, value interface{}) ([]byte, error) {
+	switch {
+	case typ == "address":
+		str, ok := value.(string)
+		if !ok || !common.IsHexAddress(str) {
+			return nil, fmt.Errorf("invalid address value %v", value)
+		}
+		return common.LeftPadBytes(common.HexToAddress(str).Bytes(), 32), nil
+
+	case typ == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool value %v", value)
+		}
+		if b {
+			return math.PaddedBigBytes(common.Big1, 32), nil
+		}
+		return make([]byte, 32), nil
+
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := strconv.Atoi(typ[len("bytes"):])
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("unknown type %s", typ)
+		}
+		blob, err := bytesValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(blob) != size {
+			return nil, fmt.Errorf("invalid %s value length %d", typ, len(blob))
+		}
+		return common.RightPadBytes(blob, 32), nil
+
+	case strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint"):
+		signed := strings.HasPrefix(typ, "int")
+		bits, err := intBits(typ)
+		if err != nil {
+			return nil, err
+		}
+		n, err := intValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if signed {
+			limit := new(big.Int).Lsh(common.Big1, uint(bits-1))
+			if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, fmt.Errorf("value %v out of %s range", n, typ)
+			}
+			return math.PaddedBigBytes(math.U256(n), 32), nil
+		}
+		if n.Sign() < 0 || n.BitLen() > bits {
+			return nil, fmt.Errorf("value %v out of %s range", n, typ)
+		}
+		return math.PaddedBigBytes(n, 32), nil
+	}
+	return nil, fmt.Errorf("unknown type %s", typ)
+}
+
+// Map returns the domain as a struct value for hashing.
+func (domain *TypedDataDomain) Map() map[string]interface{} {
+	data := make(map[string]interface{})
+	if domain.Name != "" {
+		data["name"] = domain.Name
+	}
+	if domain.Version != "" {
+		data["version"] = domain.Version
+	}
+	if domain.ChainId != nil {
+		data["chainId"] = (*big.Int)(domain.ChainId)
+	}
+	if domain.VerifyingContract != "" {
+		data["verifyingContract"] = domain.VerifyingContract
+	}
+	if domain.Salt != "" {
+		data["salt"] = domain.Salt
+	}
+	return data
+}
+
+// Format decodes the message into the list of its fields for displaying.
+func (typedData *TypedData) Format() ([]*Field, error) {
+	if err := typedData.Validate(); err != nil {
+		return nil, err
+	}
+	domain, err := typedData.formatStruct(DomainType, typedData.Domain.Map())
+	if err != nil {
+		return nil, err
+	}
+	message, err := typedData.formatStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return nil, err
+	}
+	return []*Field{
+		{Name: "domain", Type: DomainType, Value: domain},
+		{Name: "message", Type: typedData.PrimaryType, Value: message},
+	}, nil
+}
+
+// formatStruct decodes a struct value of the named type.
+func (typedData *TypedData) formatStruct(primaryType string, data map[string]interface{}) ([]*Field, error) {
+	members := typedData.Types[primaryType]
+	if err := checkFields(primaryType, members, data); err != nil {
+		return nil, err
+	}
+	var fields []*Field
+	for _, member := range members {
+		value, ok := data[member.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing field %s", primaryType, member.Name)
+		}
+		formatted, err := typedData.formatValue(member.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", primaryType, member.Name, err)
+		}
+		fields = append(fields, &Field{Name: member.Name, Type: member.Type, Value: formatted})
+	}
+	return fields, nil
+}
+
+// formatValue decodes a single value of the given type.
+func (typedData *TypedData) formatValue(typ string, value interface{}) (interface{}, error) {
+	if match := arrayTypeRegexp.FindStringSubmatch(typ); match != nil {
+		elems, err := arrayValue(match[2], value)
+		if err != nil {
+			return nil, err
+		}
+		fields := make([]*Field, len(elems))
+		for i, elem := range elems {
+			formatted, err := typedData.formatValue(match[1], elem)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			fields[i] = &Field{Name: strconv.Itoa(i), Type: match[1], Value: formatted}
+		}
+		return fields, nil
+	}
+	if _, ok := typedData.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid struct value %v", value)
+		}
+		return typedData.formatStruct(typ, data)
+	}
+	switch {
+	case typ == "string":
+		if _, err := typedData.encodeValue(typ, value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case strings.HasPrefix(typ, "bytes"):
+		if _, err := typedData.encodeValue(typ, value); err != nil {
+			return nil, err
+		}
+		blob, _ := bytesValue(value)
+		return hexutil.Encode(blob), nil
+	case strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint"):
+		if _, err := encodeAtomic(typ, value); err != nil {
+			return nil, err
+		}
+		n, _ := intValue(value)
+		return n.String(), nil
+	case typ == "address":
+		if _, err := encodeAtomic(typ, value); err != nil {
+			return nil, err
+		}
+		return common.HexToAddress(value.(string)).Hex(), nil
+	}
+	if _, err := encodeAtomic(typ, value); err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("%v", value), nil
+}
+
+// Pprint returns an indented textual rendering of the given fields.
+func Pprint(fields []*Field) string {
+	var buffer bytes.Buffer
+	pprint(&buffer, fields, 0)
+	return buffer.String()
+}
+
+func pprint(buffer *bytes.Buffer, fields []*Field, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, field := range fields {
+		if nested, ok := field.Value.([]*Field); ok {
+			fmt.Fprintf(buffer, "%s%s [%s]:\n", indent, field.Name, field.Type)
+			pprint(buffer, nested, depth+1)
+			continue
+		}
+		fmt.Fprintf(buffer, "%s%s [%s]: %v\n", indent, field.Name, field.Type, field.Value)
+	}
+}
+
+// checkFields returns an error if the struct value has fields not declared by
+// its type.
+func checkFields(primaryType string, members []Type, data map[string]interface{}) error {
+	for name := range data {
+		found := false
+		for _, member := range members {
+			if member.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: unknown field %s", primaryType, name)
+		}
+	}
+	return nil
+}
+
+// elementType strips the array suffixes of a type.
+func elementType(typ string) string {
+	for {
+		match := arrayTypeRegexp.FindStringSubmatch(typ)
+		if match == nil {
+			return typ
+		}
+		typ = match[1]
+	}
+}
+
+// isAtomicType returns whether the given type is a valid fixed size type.
+func isAtomicType(typ string) bool {
+	switch {
+	case typ == "address" || typ == "bool":
+		return true
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := strconv.Atoi(typ[len("bytes"):])
+		return err == nil && size >= 1 && size <= 32
+	case strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint"):
+		_, err := intBits(typ)
+		return err == nil
+	}
+	return false
+}
+
+// intBits returns the bit size of an integer type.
+func intBits(typ string) (int, error) {
+	size := strings.TrimPrefix(strings.TrimPrefix(typ, "u"), "int")
+	if size == "" {
+		return 256, nil
+	}
+	bits, err := strconv.Atoi(size)
+	if err != nil || bits < 8 || bits > 256 || bits%8 != 0 {
+		return 0, fmt.Errorf("unknown type %s", typ)
+	}
+	return bits, nil
+}
+
+// arrayValue returns the elements of an array value, checking the length of
+// fixed size arrays.
+func arrayValue(length string, value interface{}) ([]interface{}, error) {
+	elems, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid array value %v", value)
+	}
+	if length != "" {
+		if n, err := strconv.Atoi(length); err != nil || n != len(elems) {
+			return nil, fmt.Errorf("array length %d, want %s", len(elems), length)
+		}
+	}
+	return elems, nil
+}
+
+// bytesValue decodes a byte array value given as hex string.
+func bytesValue(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case hexutil.Bytes:
+		return v, nil
+	case string:
+		blob, err := hexutil.Decode(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bytes value %q: %v", v, err)
+		}
+		return blob, nil
+	}
+	return nil, fmt.Errorf("invalid bytes value %v", value)
+}
+
+// intValue decodes an integer value given as JSON number or as decimal or hex
+// string.
+func intValue(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, nil
+	case *math.HexOrDecimal256:
+		return (*big.Int)(v), nil
+	case float64:
+		// Integers beyond 2^53 may have been rounded when decoded into a float
+		if v >= 1<<53 || v <= -(1<<53) {
+			return nil, fmt.Errorf("integer value %v too large for a JSON number, use a string", v)
+		}
+		n, accuracy := new(big.Float).SetFloat64(v).Int(nil)
+		if accuracy != big.Exact {
+			return nil, fmt.Errorf("invalid integer value %v", v)
+		}
+		return n, nil
+	case json.Number:
+		return parseInt(string(v))
+	case string:
+		return parseInt(v)
+	}
+	return nil, fmt.Errorf("invalid integer value %v", value)
+}
+
+// parseInt parses a possibly negative decimal or hex integer.
+func parseInt(s string) (*big.Int, error) {
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+	n, ok := math.ParseBig256(s)
+	if !ok || s == "" {
+		return nil, fmt.Errorf("invalid integer value %q", s)
+	}
+	if negative {
+		n.Neg(n)
+	}
+	return n, nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eip712
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+)
+
+// mailJSON is the example message of the EIP.
+const mailJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func decodeTypedData(t *testing.T, input string) *TypedData {
+	var typedData TypedData
+	if err := json.Unmarshal([]byte(input), &typedData); err != nil {
+		t.Fatalf("failed to decode typed data: %v", err)
+	}
+	return &typedData
+}
+
+func TestMailExample(t *testing.T) {
+	typedData := decodeTypedData(t, mailJSON)
+
+	if enc, want := typedData.EncodeType("Mail"), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; enc != want {
+		t.Errorf("encoded type mismatch: have %s, want %s", enc, want)
+	}
+	if hash, want := typedData.TypeHash("Mail"), common.HexToHash("0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"); hash != want {
+		t.Errorf("type hash mismatch: have %x, want %x", hash, want)
+	}
+	domainSeparator, err := typedData.HashStruct(DomainType, typedData.Domain.Map())
+	if err != nil {
+		t.Fatalf("failed to hash domain: %v", err)
+	}
+	if want := common.HexToHash("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"); domainSeparator != want {
+		t.Errorf("domain separator mismatch: have %x, want %x", domainSeparator, want)
+	}
+	structHash, err := typedData.HashStruct("Mail", typedData.Message)
+	if err != nil {
+		t.Fatalf("failed to hash message: %v", err)
+	}
+	if want := common.HexToHash("0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"); structHash != want {
+		t.Errorf("struct hash mismatch: have %x, want %x", structHash, want)
+	}
+	hash, err := typedData.Hash()
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	if want := common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"); hash != want {
+		t.Errorf("signing hash mismatch: have %x, want %x", hash, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	typedData := decodeTypedData(t, mailJSON)
+
+	fields, err := typedData.Format()
+	if err != nil {
+		t.Fatalf("failed to format typed data: %v", err)
+	}
+	out := Pprint(fields)
+	for _, want := range []string{
+		"name [string]: Ether Mail",
+		"chainId [uint256]: 1",
+		"from [Person]:",
+		"    wallet [address]: 0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826",
+		"contents [string]: Hello, Bob!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("formatted output misses %q:\n%s", want, out)
+		}
+	}
+}
+
+// Tests that arrays of atomic, dynamic and struct types are hashed, and that
+// invalid values are rejected.
+func TestEncodeData(t *testing.T) {
+	types := `"types": {
+		"EIP712Domain": [{"name": "name", "type": "string"}],
+		"Item": [{"name": "id", "type": "uint8"}, {"name": "tags", "type": "string[]"}],
+		"Order": [
+			{"name": "items", "type": "Item[]"},
+			{"name": "amounts", "type": "int16[2]"},
+			{"name": "salt", "type": "bytes4"},
+			{"name": "data", "type": "bytes"},
+			{"name": "final", "type": "bool"}
+		]
+	},
+	"primaryType": "Order",
+	"domain": {"name": "Shop"},`
+
+	tests := []struct {
+		message string
+		err     string
+	}{
+		{`{"items": [{"id": 1, "tags": ["a", "b"]}, {"id": "0xff", "tags": []}], "amounts": [-1, "300"], "salt": "0x01020304", "data": "0x", "final": true}`, ""},
+		{`{"items": [], "amounts": [1, 2], "salt": "0x01020304", "data": "0x"}`, "missing field final"},
+		{`{"items": [], "amounts": [1, 2], "salt": "0x01020304", "data": "0x", "final": true, "extra": 1}`, "unknown field extra"},
+		{`{"items": [{"id": 256, "tags": []}], "amounts": [1, 2], "salt": "0x01020304", "data": "0x", "final": true}`, "out of uint8 range"},
+		{`{"items": [], "amounts": [1], "salt": "0x01020304", "data": "0x", "final": true}`, "array length 1, want 2"},
+		{`{"items": [], "amounts": [1, 2], "salt": "0x0102", "data": "0x", "final": true}`, "invalid bytes4 value length 2"},
+		{`{"items": [], "amounts": [1.5, 2], "salt": "0x01020304", "data": "0x", "final": true}`, "invalid integer value"},
+	}
+	for i, tt := range tests {
+		typedData := decodeTypedData(t, "{"+types+`"message": `+tt.message+"}")
+		_, err := typedData.Hash()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		types string
+		err   string
+	}{
+		{`{"Mail": [{"name": "contents", "type": "string"}]}`, "types do not define EIP712Domain"},
+		{`{"EIP712Domain": [], "Other": []}`, "primary type not defined"},
+		{`{"EIP712Domain": [], "Mail": [{"name": "to", "type": "Person"}]}`, `unknown type "Person"`},
+		{`{"EIP712Domain": [], "Mail": [{"name": "to", "type": "uint7"}]}`, `unknown type "uint7"`},
+		{`{"EIP712Domain": [], "Mail": [{"name": "to", "type": "string"}, {"name": "to", "type": "string"}]}`, "duplicate member name"},
+	}
+	for i, tt := range tests {
+		typedData := decodeTypedData(t, `{"types": `+tt.types+`, "primaryType": "Mail"}`)
+		if err := typedData.Validate(); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that integers in the message are decoded exactly, and that integers
+// which may have been rounded in a float are rejected.
+func TestLargeIntegers(t *testing.T) {
+	typedData := decodeTypedData(t, `{
+		"types": {
+			"EIP712Domain": [{"name": "name", "type": "string"}],
+			"Transfer": [{"name": "amount", "type": "uint256"}]
+		},
+		"primaryType": "Transfer",
+		"domain": {"name": "Bank"},
+		"message": {"amount": 12345678901234567890123}
+	}`)
+	n, err := intValue(typedData.Message["amount"])
+	if err != nil {
+		t.Fatalf("failed to decode amount: %v", err)
+	}
+	if want, _ := new(big.Int).SetString("12345678901234567890123", 10); n.Cmp(want) != 0 {
+		t.Errorf("amount mismatch: have %v, want %v", n, want)
+	}
+	if _, err := typedData.Hash(); err != nil {
+		t.Errorf("failed to hash message: %v", err)
+	}
+	for _, v := range []float64{1 << 53, -(1 << 53), 1e30} {
+		if _, err := intValue(v); err == nil {
+			t.Errorf("float %v accepted", v)
+		}
+	}
+	if n, err := intValue(float64(1<<53 - 1)); err != nil || n.Int64() != 1<<53-1 {
+		t.Errorf("float below 2^53 rejected: %v, %v", n, err)
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/math"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/signer/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/signer/storage"
+)
+
+const (
+	// maxPolicyHistory is the time signed transactions are remembered for, limiting
+	// the windows of spend and transaction count checks.
+	maxPolicyHistory = 30 * 24 * time.Hour
+
+	// maxPolicyAudit is the number of policy decisions kept in the audit trail.
+	maxPolicyAudit = 1000
+
+	// policyAuditPageSize is the number of audit entries stored under a single
+	// key, so that recording a decision only rewrites the latest page.
+	policyAuditPageSize = 100
+
+	// policyReservationTimeout is the time an approved transaction counts
+	// towards the limits before it is signed, after which it is assumed that
+	// signing failed.
+	policyReservationTimeout = time.Minute
+
+	policyAuditHeadKey    = "audit/head"
+	policyAuditPagePrefix = "audit/"
+	policyTxPrefix        = "txs/"
+)
+
+// policyTx is a signed transaction remembered for the spend and count checks.
+type policyTx struct {
+	Time  int64  `json:"time"`  // Unix time of signing
+	Value string `json:"value"` // Value and maximum fee in wei, in decimal
+}
+
+// policyReservation is a transaction approved by the rules but not signed yet,
+// which counts towards the limits until it is recorded or times out.
+type policyReservation struct {
+	account common.Address
+	nonce   uint64
+	cost    *big.Int
+	time    time.Time
+}
+
+// policyAuditHead locates the pages of the audit trail in the storage.
+type policyAuditHead struct {
+	First uint64 `json:"first"` // Number of the oldest page
+	Last  uint64 `json:"last"`  // Number of the latest page
+}
+
+// PolicyAuditEntry is a single policy decision or state change recorded in the
+// audit trail.
+type PolicyAuditEntry struct {
+	Time    int64  `json:"time"`
+	Check   string `json:"check"`
+	Account string `json:"account,omitempty"`
+	Details string `json:"details"`
+	Result  bool   `json:"result"`
+}
+
+// Policy implements the common auto-signing policies natively, so rule files
+// don't have to track state in javascript. It is available to the rules as the
+// `policy` object. The signed transactions and every decision are persisted in
+// the given storage, which is the encrypted storage when run from clef.
+type Policy struct {
+	storage storage.Storage
+	abidb   *core.AbiDb
+	now     func() time.Time // Overridable for testing
+
+	approveLock sync.Mutex          // Serializes rule evaluations with their reservations
+	approving   bool                // Whether a transaction is being evaluated
+	fee         *big.Int            // Maximum fee of the transaction being evaluated
+	feeFrom     common.Address      // Sender of the transaction being evaluated
+	reserved    []policyReservation // Approved transactions not signed yet
+
+	auditHead   policyAuditHead    // Location of the persisted audit trail
+	auditPage   []PolicyAuditEntry // Latest page of the audit trail
+	auditLoaded bool               // Whether the head and latest page were loaded
+	auditDirty  bool               // Whether the latest page has unpersisted entries
+
+	lock sync.Mutex
+}
+
+// NewPolicy creates a policy layer persisting its state in the given storage.
+// The optional ABI database is used to resolve method selectors to signatures.
+func NewPolicy(storage storage.Storage, abidb *core.AbiDb) *Policy {
+	return &Policy{
+		storage: storage,
+		abidb:   abidb,
+		now:     time.Now,
+	}
+}
+
+// WithinSpendLimit returns whether sending value wei from the given account
+// keeps the total sent in the last window seconds within the limit. Values may
+// be given in decimal or as hex string. The spend includes the maximum fee, gas
+// times gas price, of the transaction under approval and of the transactions
+// signed or approved before.
+func (p *Policy) WithinSpendLimit(account, value, limit string, window int64) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	amount, ok1 := math.ParseBig256(value)
+	max, ok2 := math.ParseBig256(limit)
+	if !ok1 || !ok2 {
+		p.audit("spend", account, fmt.Sprintf("invalid value %q or limit %q", value, limit), false)
+		return false
+	}
+	if p.approving && common.IsHexAddress(account) && common.HexToAddress(account) == p.feeFrom {
+		amount = new(big.Int).Add(amount, p.fee)
+	}
+	spent := new(big.Int)
+	for _, tx := range p.history(account, window) {
+		if v, ok := math.ParseBig256(tx.Value); ok {
+			spent.Add(spent, v)
+		}
+	}
+	result := new(big.Int).Add(spent, amount).Cmp(max) <= 0
+	p.audit("spend", account, fmt.Sprintf("value %v, spent %v in %ds, limit %v", amount, spent, window, max), result)
+	return result
+}
+
+// WithinTxLimit returns whether signing another transaction from the given
+// account keeps the number signed in the last window seconds within the limit.
+// A per day limit is checked with a window of 86400.
+func (p *Policy) WithinTxLimit(account string, limit, window int64) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	count := int64(len(p.history(account, window)))
+	result := count < limit
+	p.audit("txcount", account, fmt.Sprintf("signed %d in %ds, limit %d", count, window, limit), result)
+	return result
+}
+
+// RecipientAllowed returns whether the recipient is in the allowlist. Contract
+// creations, having no recipient, are never allowed.
+func (p *Policy) RecipientAllowed(to string, allowed []string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	result := false
+	if common.IsHexAddress(to) {
+		addr := common.HexToAddress(to)
+		for _, a := range allowed {
+			if common.IsHexAddress(a) && common.HexToAddress(a) == addr {
+				result = true
+				break
+			}
+		}
+	}
+	p.audit("recipient", "", fmt.Sprintf("recipient %q", to), result)
+	return result
+}
+
+// MethodAllowed returns whether the method called by the given call data is in
+// the allowlist. The allowlist may contain 4 byte selectors in hex, or method
+// signatures like transfer(address,uint256) which are matched with the help of
+// the ABI database. Calls without data, i.e. plain value transfers, are allowed.
+func (p *Policy) MethodAllowed(data string, allowed []string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	calldata, err := hexutil.Decode(data)
+	if data == "" || (err == nil && len(calldata) == 0) {
+		p.audit("method", "", "no call data", true)
+		return true
+	}
+	if err != nil || len(calldata) < 4 {
+		p.audit("method", "", fmt.Sprintf("invalid call data %q", data), false)
+		return false
+	}
+	selector := hexutil.Encode(calldata[:4])
+	signature := ""
+	if p.abidb != nil {
+		signature, _ = p.abidb.LookupMethodSelector(calldata[:4])
+	}
+	result := false
+	for _, a := range allowed {
+		if strings.ToLower(a) == selector || (signature != "" && a == signature) {
+			result = true
+			break
+		}
+	}
+	p.audit("method", "", fmt.Sprintf("selector %s, method %q", selector, signature), result)
+	return result
+}
+
+// WithinTimeWindow returns whether the current time of day is between start and
+// end, both given as HH:MM in UTC. Windows may wrap around midnight.
+func (p *Policy) WithinTimeWindow(start, end string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	from, err1 := time.Parse("15:04", start)
+	to, err2 := time.Parse("15:04", end)
+	if err1 != nil || err2 != nil {
+		p.audit("timewindow", "", fmt.Sprintf("invalid window %q-%q", start, end), false)
+		return false
+	}
+	now := p.now().UTC()
+	minute := now.Hour()*60 + now.Minute()
+	begin, finish := from.Hour()*60+from.Minute(), to.Hour()*60+to.Minute()
+
+	var result bool
+	if begin <= finish {
+		result = minute >= begin && minute < finish
+	} else {
+		result = minute >= begin || minute < finish
+	}
+	p.audit("timewindow", "", fmt.Sprintf("time %s, window %s-%s", now.Format("15:04"), start, end), result)
+	return result
+}
+
+// AuditTrail returns the recorded policy decisions, oldest first.
+func (p *Policy) AuditTrail() []PolicyAuditEntry {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.loadAudit()
+	var entries []PolicyAuditEntry
+	for page := p.auditHead.First; page < p.auditHead.Last; page++ {
+		entries = append(entries, p.readAuditPage(page)...)
+	}
+	return append(entries, p.auditPage...)
+}
+
+// beginApproval starts the evaluation of a transaction by the rules, during
+// which the spend checks include the given maximum fee of the transaction.
+// Evaluations are serialized, so that the checks of one transaction see the
+// reservations of all transactions approved before.
+func (p *Policy) beginApproval(from common.Address, fee *big.Int) {
+	p.approveLock.Lock()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.approving, p.feeFrom, p.fee = true, from, fee
+}
+
+// endApproval ends the evaluation of a transaction. If it was approved, its
+// cost is reserved until the signed transaction is recorded, so concurrent
+// requests cannot exceed the limits together. The audit entries of the
+// evaluation are persisted at once.
+func (p *Policy) endApproval(approved bool, from common.Address, nonce uint64, cost *big.Int) {
+	defer p.approveLock.Unlock()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if approved {
+		p.reserved = append(p.reserved, policyReservation{account: from, nonce: nonce, cost: cost, time: p.now()})
+	}
+	p.approving, p.feeFrom, p.fee = false, common.Address{}, nil
+	p.flushAudit()
+}
+
+// recordTx remembers a transaction signed from the given account for the
+// spend and count checks, replacing its reservation if it was approved by the
+// rules. The cost is the value plus the maximum fee of the transaction.
+func (p *Policy) recordTx(account common.Address, nonce uint64, cost *big.Int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for i, r := range p.reserved {
+		if r.account == account && r.nonce == nonce {
+			p.reserved = append(p.reserved[:i], p.reserved[i+1:]...)
+			break
+		}
+	}
+	key := policyTxPrefix + strings.ToLower(account.Hex())
+	txs := append(p.signed(account, int64(maxPolicyHistory/time.Second)), policyTx{
+		Time:  p.now().Unix(),
+		Value: cost.String(),
+	})
+	blob, err := json.Marshal(txs)
+	if err != nil {
+		log.Warn("Failed to encode policy history", "err", err)
+		return
+	}
+	p.storage.Put(key, string(blob))
+	p.audit("record", account.Hex(), fmt.Sprintf("signed tx with cost %v", cost), true)
+}
+
+// history returns the transactions signed or reserved from the given account
+// in the last window seconds.
+func (p *Policy) history(account string, window int64) []policyTx {
+	if !common.IsHexAddress(account) {
+		return nil
+	}
+	addr := common.HexToAddress(account)
+	txs := p.signed(addr, window)
+
+	now := p.now()
+	reserved := p.reserved[:0]
+	for _, r := range p.reserved {
+		if now.Sub(r.time) >= policyReservationTimeout {
+			continue
+		}
+		reserved = append(reserved, r)
+		if r.account == addr {
+			txs = append(txs, policyTx{Time: r.time.Unix(), Value: r.cost.String()})
+		}
+	}
+	p.reserved = reserved
+	return txs
+}
+
+// signed returns the persisted transactions signed from the given account in
+// the last window seconds.
+func (p *Policy) signed(account common.Address, window int64) []policyTx {
+	blob := p.storage.Get(policyTxPrefix + strings.ToLower(account.Hex()))
+	if blob == "" {
+		return nil
+	}
+	var txs []policyTx
+	if err := json.Unmarshal([]byte(blob), &txs); err != nil {
+		log.Warn("Failed to decode policy history", "account", account, "err", err)
+		return nil
+	}
+	cutoff := p.now().Unix() - window
+	var recent []policyTx
+	for _, tx := range txs {
+		if tx.Time > cutoff {
+			recent = append(recent, tx)
+		}
+	}
+	return recent
+}
+
+// audit logs a policy decision and appends it to the audit trail. The entries
+// recorded during the evaluation of a transaction are persisted when it ends,
+// others immediately.
+func (p *Policy) audit(check, account, details string, result bool) {
+	log.Info("Policy check", "check", check, "account", account, "details", details, "result", result)
+
+	p.loadAudit()
+	if len(p.auditPage) >= policyAuditPageSize {
+		p.flushAudit()
+		p.auditHead.Last++
+		p.auditPage = nil
+		for p.auditHead.Last-p.auditHead.First >= maxPolicyAudit/policyAuditPageSize {
+			p.storage.Put(p.auditPageKey(p.auditHead.First), "")
+			p.auditHead.First++
+		}
+		p.writeAuditHead()
+	}
+	p.auditPage = append(p.auditPage, PolicyAuditEntry{
+		Time:    p.now().Unix(),
+		Check:   check,
+		Account: account,
+		Details: details,
+		Result:  result,
+	})
+	p.auditDirty = true
+	if !p.approving {
+		p.flushAudit()
+	}
+}
+
+// loadAudit reads the head and the latest page of the persisted audit trail,
+// unless they were loaded before.
+func (p *Policy) loadAudit() {
+	if p.auditLoaded {
+		return
+	}
+	p.auditLoaded = true
+	if blob := p.storage.Get(policyAuditHeadKey); blob != "" {
+		if err := json.Unmarshal([]byte(blob), &p.auditHead); err != nil {
+			log.Warn("Failed to decode policy audit trail", "err", err)
+			p.auditHead = policyAuditHead{}
+		}
+	}
+	p.auditPage = p.readAuditPage(p.auditHead.Last)
+}
+
+// flushAudit persists the latest page of the audit trail if it has new entries.
+func (p *Policy) flushAudit() {
+	if !p.auditDirty {
+		return
+	}
+	blob, err := json.Marshal(p.auditPage)
+	if err != nil {
+		log.Warn("Failed to encode policy audit trail", "err", err)
+		return
+	}
+	p.storage.Put(p.auditPageKey(p.auditHead.Last), string(blob))
+	p.auditDirty = false
+}
+
+// writeAuditHead persists the location of the audit trail pages.
+func (p *Policy) writeAuditHead() {
+	blob, err := json.Marshal(p.auditHead)
+	if err != nil {
+		log.Warn("Failed to encode policy audit trail", "err", err)
+		return
+	}
+	p.storage.Put(policyAuditHeadKey, string(blob))
+}
+
+// readAuditPage reads a persisted page of the audit trail.
+func (p *Policy) readAuditPage(page uint64) []PolicyAuditEntry {
+	blob := p.storage.Get(p.auditPageKey(page))
+	if blob == "" {
+		return nil
+	}
+	var entries []PolicyAuditEntry
+	if err := json.Unmarshal([]byte(blob), &entries); err != nil {
+		log.Warn("Failed to decode policy audit trail", "page", page, "err", err)
+		return nil
+	}
+	return entries
+}
+
+func (p *Policy) auditPageKey(page uint64) string {
+	return fmt.Sprintf("%s%d", policyAuditPagePrefix, page)
+}
@@ -0,0 +1,297 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/internal/ethapi"
+	"github.com/Ethereum-Reloaded/ETHR-Go/signer/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/signer/storage"
+)
+
+var testPolicyAccount = common.HexToAddress("0x694267f14675d7e1b9494fd8d72fefe1755710fa")
+
+// newTestPolicy creates a policy backed by encrypted storage in a temporary
+// directory, with a controllable clock.
+func newTestPolicy(t *testing.T, abidb *core.AbiDb) (*Policy, *time.Time, func()) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := crypto.Keccak256([]byte("policy"))
+	store := storage.NewAESEncryptedStorage(filepath.Join(dir, "policy.json"), key)
+
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := NewPolicy(store, abidb)
+	policy.now = func() time.Time { return now }
+	return policy, &now, func() { os.RemoveAll(dir) }
+}
+
+func TestPolicySpendLimit(t *testing.T) {
+	policy, now, cleanup := newTestPolicy(t, nil)
+	defer cleanup()
+
+	account := testPolicyAccount.Hex()
+	if !policy.WithinSpendLimit(account, "0x64", "150", 3600) {
+		t.Fatalf("first spend rejected")
+	}
+	policy.recordTx(testPolicyAccount, 0, big.NewInt(100))
+
+	*now = now.Add(30 * time.Minute)
+	if policy.WithinSpendLimit(account, "51", "150", 3600) {
+		t.Errorf("spend above the limit accepted")
+	}
+	if !policy.WithinSpendLimit(account, "50", "150", 3600) {
+		t.Errorf("spend up to the limit rejected")
+	}
+	// Other accounts have their own limits
+	if !policy.WithinSpendLimit(common.HexToAddress("0x01").Hex(), "150", "150", 3600) {
+		t.Errorf("spend of other account rejected")
+	}
+	// Spends roll out of the window
+	*now = now.Add(31 * time.Minute)
+	if !policy.WithinSpendLimit(account, "150", "150", 3600) {
+		t.Errorf("spend rejected after the window passed")
+	}
+	if policy.WithinSpendLimit(account, "not a number", "150", 3600) {
+		t.Errorf("invalid value accepted")
+	}
+}
+
+func TestPolicyTxLimit(t *testing.T) {
+	policy, now, cleanup := newTestPolicy(t, nil)
+	defer cleanup()
+
+	account := testPolicyAccount.Hex()
+	for i := 0; i < 3; i++ {
+		if !policy.WithinTxLimit(account, 3, 86400) {
+			t.Fatalf("tx %d rejected below the limit", i)
+		}
+		policy.recordTx(testPolicyAccount, uint64(i), new(big.Int))
+		*now = now.Add(time.Hour)
+	}
+	if policy.WithinTxLimit(account, 3, 86400) {
+		t.Errorf("tx above the daily limit accepted")
+	}
+	*now = now.Add(22 * time.Hour)
+	if !policy.WithinTxLimit(account, 3, 86400) {
+		t.Errorf("tx rejected after the first one left the window")
+	}
+}
+
+func TestPolicyAllowlists(t *testing.T) {
+	abidb, _ := core.NewEmptyAbiDB()
+	transfer := common.FromHex("0xa9059cbb000000000000000000000000000000000000000000000000000000000000dead")
+	abidb.AddSignature("transfer(address,uint256)", transfer)
+
+	policy, _, cleanup := newTestPolicy(t, abidb)
+	defer cleanup()
+
+	allowed := []string{"0xae967917c465db8578ca9024c205720b1a3651a9"}
+	if !policy.RecipientAllowed("0xAE967917c465db8578ca9024c205720b1a3651A9", allowed) {
+		t.Errorf("allowed recipient rejected")
+	}
+	if policy.RecipientAllowed("0x0000000000000000000000000000000000001337", allowed) {
+		t.Errorf("unknown recipient accepted")
+	}
+	if policy.RecipientAllowed("null", allowed) {
+		t.Errorf("contract creation accepted")
+	}
+
+	tests := []struct {
+		data    string
+		allowed []string
+		result  bool
+	}{
+		{"", nil, true},
+		{"0x", nil, true},
+		{common.ToHex(transfer), []string{"transfer(address,uint256)"}, true},
+		{common.ToHex(transfer), []string{"0xA9059CBB"}, true},
+		{common.ToHex(transfer), []string{"approve(address,uint256)", "0x095ea7b3"}, false},
+		{"0x095ea7b3", []string{"transfer(address,uint256)"}, false},
+		{"0x0102", []string{"0x0102"}, false},
+	}
+	for i, tt := range tests {
+		if result := policy.MethodAllowed(tt.data, tt.allowed); result != tt.result {
+			t.Errorf("test %d: method allowed mismatch: have %v, want %v", i, result, tt.result)
+		}
+	}
+}
+
+func TestPolicyTimeWindow(t *testing.T) {
+	policy, now, cleanup := newTestPolicy(t, nil)
+	defer cleanup()
+
+	tests := []struct {
+		time       string
+		start, end string
+		result     bool
+	}{
+		{"12:00", "08:00", "18:00", true},
+		{"08:00", "08:00", "18:00", true},
+		{"18:00", "08:00", "18:00", false},
+		{"07:59", "08:00", "18:00", false},
+		{"23:30", "22:00", "06:00", true},
+		{"05:59", "22:00", "06:00", true},
+		{"12:00", "22:00", "06:00", false},
+		{"12:00", "8am", "18:00", false},
+	}
+	for i, tt := range tests {
+		at, _ := time.Parse("15:04", tt.time)
+		*now = time.Date(2018, 6, 1, at.Hour(), at.Minute(), 0, 0, time.UTC)
+		if result := policy.WithinTimeWindow(tt.start, tt.end); result != tt.result {
+			t.Errorf("test %d: window mismatch: have %v, want %v", i, result, tt.result)
+		}
+	}
+}
+
+// Tests that rules can use the policy, that signed transactions are recorded
+// and that the state and audit trail are persisted.
+func TestPolicyRules(t *testing.T) {
+	policy, _, cleanup := newTestPolicy(t, nil)
+	defer cleanup()
+
+	// The limit covers two transactions of 600 wei and their fees, except 1 wei
+	fee := new(big.Int).Mul(big.NewInt(21000), big.NewInt(2000000))
+	limit := new(big.Int).Add(big.NewInt(1199), new(big.Int).Mul(fee, big.NewInt(2)))
+
+	js := `function ApproveTx(r){
+    if (policy.WithinSpendLimit(r.transaction.from, r.transaction.value, "` + limit.String() + `", 86400) &&
+        policy.RecipientAllowed(r.transaction.to, ["0x000000000000000000000000000000000000dead"])) {
+        return "Approve"
+    }
+    return "Reject"
+}`
+	r, err := initRuleEngine(js)
+	if err != nil {
+		t.Fatalf("Couldn't create evaluator %v", err)
+	}
+	r.EnablePolicy(policy)
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	approve := func(value uint64) bool {
+		tx := dummyTxWithV(value)
+		tx.Transaction.From = common.NewMixedcaseAddress(from)
+		resp, err := r.ApproveTx(tx)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		return resp.Approved
+	}
+	if !approve(600) {
+		t.Fatalf("tx within the limit rejected")
+	}
+	// Record the signed transaction as the signer would
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	signed, err := types.SignTx(dummySigned(big.NewInt(600)), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.OnApprovedTx(ethapi.SignTransactionResult{Tx: signed})
+
+	if approve(600) {
+		t.Errorf("tx above the limit approved")
+	}
+	// A fresh policy on the same storage remembers the spend and its fee
+	reloaded := NewPolicy(policy.storage, nil)
+	reloaded.now = policy.now
+	rest := new(big.Int).Sub(limit, signed.Cost())
+	if !reloaded.WithinSpendLimit(from.Hex(), rest.String(), limit.String(), 86400) {
+		t.Errorf("spend up to the limit rejected")
+	}
+	if reloaded.WithinSpendLimit(from.Hex(), new(big.Int).Add(rest, common.Big1).String(), limit.String(), 86400) {
+		t.Errorf("spend not persisted")
+	}
+	trail := reloaded.AuditTrail()
+	if len(trail) == 0 {
+		t.Fatalf("empty audit trail")
+	}
+	var records int
+	for _, entry := range trail {
+		if entry.Check == "record" {
+			records++
+		}
+	}
+	if records != 1 {
+		t.Errorf("recorded transactions mismatch: have %d, want 1", records)
+	}
+	if last := trail[len(trail)-1]; last.Check != "spend" || last.Result {
+		t.Errorf("last audit entry mismatch: have %+v", last)
+	}
+}
+
+// Tests that approved transactions count towards the limits before they are
+// signed, until their reservation times out.
+func TestPolicyReservation(t *testing.T) {
+	policy, now, cleanup := newTestPolicy(t, nil)
+	defer cleanup()
+
+	js := `function ApproveTx(r){
+    if (policy.WithinTxLimit(r.transaction.from, 1, 86400)) {
+        return "Approve"
+    }
+    return "Reject"
+}`
+	r, err := initRuleEngine(js)
+	if err != nil {
+		t.Fatalf("Couldn't create evaluator %v", err)
+	}
+	r.EnablePolicy(policy)
+
+	results := make(chan bool, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			resp, _ := r.ApproveTx(dummyTxWithV(1))
+			results <- resp.Approved
+		}()
+	}
+	if first, second := <-results, <-results; first == second {
+		t.Fatalf("concurrent approvals mismatch: have %v and %v, want one approval", first, second)
+	}
+	*now = now.Add(policyReservationTimeout)
+	if resp, _ := r.ApproveTx(dummyTxWithV(1)); !resp.Approved {
+		t.Errorf("tx rejected after the reservation timed out")
+	}
+}
+
+// Tests that the audit trail is kept in pages and limited in length.
+func TestPolicyAuditPages(t *testing.T) {
+	policy, _, cleanup := newTestPolicy(t, nil)
+	defer cleanup()
+
+	for i := 0; i < maxPolicyAudit+policyAuditPageSize+10; i++ {
+		policy.WithinTxLimit(testPolicyAccount.Hex(), int64(i), 60)
+	}
+	trail := NewPolicy(policy.storage, nil).AuditTrail()
+	if len(trail) != maxPolicyAudit-policyAuditPageSize+10 {
+		t.Fatalf("audit trail length mismatch: have %d, want %d", len(trail), maxPolicyAudit-policyAuditPageSize+10)
+	}
+	if last := trail[len(trail)-1]; last.Details != fmt.Sprintf("signed 0 in 60s, limit %d", maxPolicyAudit+policyAuditPageSize+9) {
+		t.Errorf("last audit entry mismatch: have %+v", last)
+	}
+}
@@ -19,10 +19,12 @@ package rules
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
 	"strings"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
 	"github.com/Ethereum-Reloaded/ETHR-Go/internal/ethapi"
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
 	"github.com/Ethereum-Reloaded/ETHR-Go/signer/core"
@@ -52,7 +54,8 @@ type rulesetUI struct {
 	next        core.SignerUI // The next handler, for manual processing
 	storage     storage.Storage
 	credentials storage.Storage
-	jsRules     string // The rules to use
+	policy      *Policy // Optional native policy layer exposed to the rules
+	jsRules     string  // The rules to use
 }
 
 func NewRuleEvaluator(next core.SignerUI, jsbackend, credentialsBackend storage.Storage) (*rulesetUI, error) {
@@ -70,6 +73,13 @@ func (r *rulesetUI) Init(javascriptRules string) error {
 	r.jsRules = javascriptRules
 	return nil
 }
+
+// EnablePolicy makes the given policy layer available to the rules as `policy`,
+// and records the signed transactions into it.
+func (r *rulesetUI) EnablePolicy(policy *Policy) {
+	r.policy = policy
+}
+
 func (r *rulesetUI) execute(jsfunc string, jsarg interface{}) (otto.Value, error) {
 
 	// Instantiate a fresh vm engine every time
@@ -79,6 +89,9 @@ func (r *rulesetUI) execute(jsfunc string, jsarg interface{}) (otto.Value, error
 	consoleObj.Object().Set("log", consoleOutput)
 	consoleObj.Object().Set("error", consoleOutput)
 	vm.Set("storage", r.storage)
+	if r.policy != nil {
+		vm.Set("policy", r.policy)
+	}
 
 	// Load bootstrap libraries
 	script, err := vm.Compile("bignumber.js", BigNumber_JS)
@@ -141,7 +154,18 @@ func (r *rulesetUI) checkApproval(jsfunc string, jsarg []byte, err error) (bool,
 
 func (r *rulesetUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
 	jsonreq, err := json.Marshal(request)
-	approved, err := r.checkApproval("ApproveTx", jsonreq, err)
+	var approved bool
+	if r.policy != nil {
+		// Check the limits and reserve the approved transaction atomically
+		args := request.Transaction
+		from := args.From.Address()
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(uint64(args.Gas)), args.GasPrice.ToInt())
+		r.policy.beginApproval(from, fee)
+		approved, err = r.checkApproval("ApproveTx", jsonreq, err)
+		r.policy.endApproval(approved && err == nil, from, uint64(args.Nonce), new(big.Int).Add(args.Value.ToInt(), fee))
+	} else {
+		approved, err = r.checkApproval("ApproveTx", jsonreq, err)
+	}
 	if err != nil {
 		log.Info("Rule-based approval error, going to manual", "error", err)
 		return r.next.ApproveTx(request)
@@ -236,6 +260,17 @@ func (r *rulesetUI) OnSignerStartup(info core.StartupInfo) {
 }
 
 func (r *rulesetUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	if r.policy != nil && tx.Tx != nil {
+		var signer types.Signer = types.HomesteadSigner{}
+		if tx.Tx.Protected() {
+			signer = types.NewEIP155Signer(tx.Tx.ChainId())
+		}
+		if from, err := types.Sender(signer, tx.Tx); err != nil {
+			log.Warn("Failed to recover sender of signed transaction", "err", err)
+		} else {
+			r.policy.recordTx(from, tx.Tx.Nonce(), tx.Tx.Cost())
+		}
+	}
 	jsonTx, err := json.Marshal(tx)
 	if err != nil {
 		log.Warn("failed marshalling transaction", "tx", tx)
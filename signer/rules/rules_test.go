@@ -28,6 +28,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/core/types"
 	"github.com/Ethereum-Reloaded/ETHR-Go/internal/ethapi"
 	"github.com/Ethereum-Reloaded/ETHR-Go/signer/core"
+	"github.com/Ethereum-Reloaded/ETHR-Go/signer/eip712"
 	"github.com/Ethereum-Reloaded/ETHR-Go/signer/storage"
 )
 
@@ -629,3 +630,52 @@ function ApproveSignData(r){
 		t.Fatalf("Expected approved")
 	}
 }
+
+func TestSignTypedData(t *testing.T) {
+	js := `function ApproveSignData(r){
+    if (r.typed_data === undefined) {
+        return
+    }
+    if (r.typed_data.domain.name == "Ether Mail" && r.typed_data.primaryType == "Mail") {
+        return "Approve"
+    }
+    return "Reject"
+}`
+	r, err := initRuleEngine(js)
+	if err != nil {
+		t.Fatalf("Couldn't create evaluator %v", err)
+	}
+	addr, _ := mixAddr("0x694267f14675d7e1b9494fd8d72fefe1755710fa")
+	request := func(domain, primaryType string) *core.SignDataRequest {
+		data := &eip712.TypedData{
+			Types: eip712.Types{
+				"EIP712Domain": {{Name: "name", Type: "string"}},
+				primaryType:    {{Name: "contents", Type: "string"}},
+			},
+			PrimaryType: primaryType,
+			Domain:      eip712.TypedDataDomain{Name: domain},
+			Message:     map[string]interface{}{"contents": "Hello, Bob!"},
+		}
+		fields, err := data.Format()
+		if err != nil {
+			t.Fatalf("Failed to format typed data: %v", err)
+		}
+		return &core.SignDataRequest{Address: *addr, TypedData: data, Messages: fields}
+	}
+	resp, err := r.ApproveSignData(request("Ether Mail", "Mail"))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !resp.Approved {
+		t.Errorf("Expected approved")
+	}
+	for _, req := range []*core.SignDataRequest{request("Ether Mail", "Order"), request("Phishing", "Mail")} {
+		resp, err = r.ApproveSignData(req)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if resp.Approved {
+			t.Errorf("Expected rejection for domain %q and primary type %q", req.TypedData.Domain.Name, req.TypedData.PrimaryType)
+		}
+	}
+}
@@ -142,15 +142,17 @@ on top of the dpa
 it is the public interface of the dpa which is included in the ethereum stack
 */
 type Api struct {
-	dpa *storage.DPA
-	dns Resolver
+	dpa      *storage.DPA
+	dns      Resolver
+	resource *ResourceHandler
 }
 
 //the api constructor initialises
 func NewApi(dpa *storage.DPA, dns Resolver) (self *Api) {
 	self = &Api{
-		dpa: dpa,
-		dns: dns,
+		dpa:      dpa,
+		dns:      dns,
+		resource: NewResourceHandler(dpa),
 	}
 	return
 }
@@ -171,6 +173,34 @@ func (self *Api) Store(data io.Reader, size int64, wg *sync.WaitGroup) (key stor
 	return self.dpa.Store(data, size, wg, nil)
 }
 
+// StoreEncrypted stores the data encrypted, the returned key carries the
+// decryption key.
+func (self *Api) StoreEncrypted(data io.Reader, size int64, wg *sync.WaitGroup) (key storage.Key, err error) {
+	return self.dpa.StoreEncrypted(data, size, wg, nil)
+}
+
+// ResourceCreate creates a mutable resource from its signed metadata update
+// and returns the key of the resource.
+func (self *Api) ResourceCreate(update *storage.ResourceUpdate) (storage.Key, error) {
+	return self.resource.Create(update)
+}
+
+// ResourceUpdate publishes a signed update of a mutable resource.
+func (self *Api) ResourceUpdate(key storage.Key, update *storage.ResourceUpdate) (storage.Key, error) {
+	return self.resource.Update(key, update)
+}
+
+// ResourceLookup retrieves an update of a mutable resource, zero period and
+// version select the latest one.
+func (self *Api) ResourceLookup(key storage.Key, period, version uint32) (*storage.ResourceUpdate, error) {
+	return self.resource.Lookup(key, period, version)
+}
+
+// ResourceInfo describes a mutable resource and its latest update.
+func (self *Api) ResourceInfo(key storage.Key) (*Resource, error) {
+	return self.resource.Info(key)
+}
+
 type ErrResolve error
 
 // DNS Resolver
@@ -34,6 +34,7 @@ import (
 	"strings"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/api"
+	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/storage"
 )
 
 var (
@@ -52,12 +53,17 @@ type Client struct {
 	Gateway string
 }
 
-// UploadRaw uploads raw data to swarm and returns the resulting hash
-func (c *Client) UploadRaw(r io.Reader, size int64) (string, error) {
+// UploadRaw uploads raw data to swarm and returns the resulting hash, which
+// also carries the decryption key if toEncrypt is set
+func (c *Client) UploadRaw(r io.Reader, size int64, toEncrypt bool) (string, error) {
 	if size <= 0 {
 		return "", errors.New("data size must be greater than zero")
 	}
-	req, err := http.NewRequest("POST", c.Gateway+"/bzz-raw:/", r)
+	uri := c.Gateway + "/bzz-raw:/"
+	if toEncrypt {
+		uri += "?encrypt=true"
+	}
+	req, err := http.NewRequest("POST", uri, r)
 	if err != nil {
 		return "", err
 	}
@@ -124,12 +130,13 @@ func Open(path string) (*File, error) {
 // Upload uploads a file to swarm and either adds it to an existing manifest
 // (if the manifest argument is non-empty) or creates a new manifest containing
 // the file, returning the resulting manifest hash (the file will then be
-// available at bzz:/<hash>/<path>)
-func (c *Client) Upload(file *File, manifest string) (string, error) {
+// available at bzz:/<hash>/<path>). The file and new manifest are encrypted if
+// toEncrypt is set.
+func (c *Client) Upload(file *File, manifest string, toEncrypt bool) (string, error) {
 	if file.Size <= 0 {
 		return "", errors.New("file size must be greater than zero")
 	}
-	return c.TarUpload(manifest, &FileUploader{file})
+	return c.TarUpload(manifest, &FileUploader{file}, toEncrypt)
 }
 
 // Download downloads a file with the given path from the swarm manifest with
@@ -158,15 +165,16 @@ func (c *Client) Download(hash, path string) (*File, error) {
 // new manifest, returning the resulting manifest hash (files from the
 // directory will then be available at bzz:/<hash>/path/to/file), with
 // the file specified in defaultPath being uploaded to the root of the manifest
-// (i.e. bzz:/<hash>/)
-func (c *Client) UploadDirectory(dir, defaultPath, manifest string) (string, error) {
+// (i.e. bzz:/<hash>/). The files and new manifest are encrypted if toEncrypt is
+// set.
+func (c *Client) UploadDirectory(dir, defaultPath, manifest string, toEncrypt bool) (string, error) {
 	stat, err := os.Stat(dir)
 	if err != nil {
 		return "", err
 	} else if !stat.IsDir() {
 		return "", fmt.Errorf("not a directory: %s", dir)
 	}
-	return c.TarUpload(manifest, &DirectoryUploader{dir, defaultPath})
+	return c.TarUpload(manifest, &DirectoryUploader{dir, defaultPath}, toEncrypt)
 }
 
 // DownloadDirectory downloads the files contained in a swarm manifest under
@@ -228,13 +236,14 @@ func (c *Client) DownloadDirectory(hash, path, destDir string) error {
 	}
 }
 
-// UploadManifest uploads the given manifest to swarm
-func (c *Client) UploadManifest(m *api.Manifest) (string, error) {
+// UploadManifest uploads the given manifest to swarm, encrypted if toEncrypt is
+// set
+func (c *Client) UploadManifest(m *api.Manifest, toEncrypt bool) (string, error) {
 	data, err := json.Marshal(m)
 	if err != nil {
 		return "", err
 	}
-	return c.UploadRaw(bytes.NewReader(data), int64(len(data)))
+	return c.UploadRaw(bytes.NewReader(data), int64(len(data)), toEncrypt)
 }
 
 // DownloadManifest downloads a swarm manifest
@@ -284,6 +293,126 @@ func (c *Client) List(hash, prefix string) (*api.ManifestList, error) {
 	return &list, nil
 }
 
+// CreateResource creates a mutable resource from its signed metadata update
+// (see api.NewResourceCreate) and returns the key of the resource
+func (c *Client) CreateResource(update *storage.ResourceUpdate) (string, error) {
+	return c.postResource(c.Gateway+"/bzz-resource:/", update)
+}
+
+// UpdateResource publishes a signed update of the given resource and returns
+// the key of the update
+func (c *Client) UpdateResource(resource string, update *storage.ResourceUpdate) (string, error) {
+	return c.postResource(c.Gateway+"/bzz-resource:/"+resource, update)
+}
+
+func (c *Client) postResource(uri string, update *storage.ResourceUpdate) (string, error) {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return "", err
+	}
+	res, err := http.DefaultClient.Post(uri, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ResourceInfo returns the description of a mutable resource and its latest
+// update
+func (c *Client) ResourceInfo(resource string) (*api.Resource, error) {
+	res, err := http.DefaultClient.Get(c.Gateway + "/bzz-resource:/" + resource + "/meta")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	var info api.Resource
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// DownloadResource downloads the data of an update of a mutable resource, a
+// zero period selects the latest update and a zero version the latest version
+// of the period
+func (c *Client) DownloadResource(resource string, period, version uint32) (io.ReadCloser, error) {
+	uri := c.Gateway + "/bzz-resource:/" + resource
+	if period != 0 {
+		uri += "/" + strconv.FormatUint(uint64(period), 10)
+		if version != 0 {
+			uri += "/" + strconv.FormatUint(uint64(version), 10)
+		}
+	}
+	res, err := http.DefaultClient.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	return res.Body, nil
+}
+
+// Pin pins the content with the given hash on the swarm node, keeping all its
+// chunks from garbage collection
+func (c *Client) Pin(hash string) error {
+	res, err := http.DefaultClient.Post(c.Gateway+"/bzz-pin:/"+hash, "text/plain", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	return nil
+}
+
+// Unpin unpins the content with the given hash
+func (c *Client) Unpin(hash string) error {
+	req, err := http.NewRequest("DELETE", c.Gateway+"/bzz-pin:/"+hash, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	return nil
+}
+
+// PinList returns the records of all content pinned on the swarm node
+func (c *Client) PinList() ([]*storage.PinnedRoot, error) {
+	res, err := http.DefaultClient.Get(c.Gateway + "/bzz-pin:/")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	var pins []*storage.PinnedRoot
+	if err := json.NewDecoder(res.Body).Decode(&pins); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
 // Uploader uploads files to swarm using a provided UploadFn
 type Uploader interface {
 	Upload(UploadFn) error
@@ -349,11 +478,16 @@ func (f *FileUploader) Upload(upload UploadFn) error {
 type UploadFn func(file *File) error
 
 // TarUpload uses the given Uploader to upload files to swarm as a tar stream,
-// returning the resulting manifest hash
-func (c *Client) TarUpload(hash string, uploader Uploader) (string, error) {
+// returning the resulting manifest hash. The files are encrypted if toEncrypt
+// is set.
+func (c *Client) TarUpload(hash string, uploader Uploader, toEncrypt bool) (string, error) {
 	reqR, reqW := io.Pipe()
 	defer reqR.Close()
-	req, err := http.NewRequest("POST", c.Gateway+"/bzz:/"+hash, reqR)
+	scheme := "bzz"
+	if toEncrypt {
+		scheme = "bzz-encrypted"
+	}
+	req, err := http.NewRequest("POST", c.Gateway+"/"+scheme+":/"+hash, reqR)
 	if err != nil {
 		return "", err
 	}
@@ -24,13 +24,23 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/api"
 	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/testutil"
 )
 
 // TestClientUploadDownloadRaw test uploading and downloading raw data to swarm
 func TestClientUploadDownloadRaw(t *testing.T) {
+	testClientUploadDownloadRaw(false, t)
+}
+
+func TestClientUploadDownloadRawEncrypted(t *testing.T) {
+	testClientUploadDownloadRaw(true, t)
+}
+
+func testClientUploadDownloadRaw(toEncrypt bool, t *testing.T) {
 	srv := testutil.NewTestSwarmServer(t)
 	defer srv.Close()
 
@@ -38,10 +48,11 @@ func TestClientUploadDownloadRaw(t *testing.T) {
 
 	// upload some raw data
 	data := []byte("foo123")
-	hash, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)))
+	hash, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)), toEncrypt)
 	if err != nil {
 		t.Fatal(err)
 	}
+	checkHashLength(t, hash, toEncrypt)
 
 	// check we can download the same data
 	res, err := client.DownloadRaw(hash)
@@ -61,6 +72,14 @@ func TestClientUploadDownloadRaw(t *testing.T) {
 // TestClientUploadDownloadFiles test uploading and downloading files to swarm
 // manifests
 func TestClientUploadDownloadFiles(t *testing.T) {
+	testClientUploadDownloadFiles(false, t)
+}
+
+func TestClientUploadDownloadFilesEncrypted(t *testing.T) {
+	testClientUploadDownloadFiles(true, t)
+}
+
+func testClientUploadDownloadFiles(toEncrypt bool, t *testing.T) {
 	srv := testutil.NewTestSwarmServer(t)
 	defer srv.Close()
 
@@ -74,10 +93,11 @@ func TestClientUploadDownloadFiles(t *testing.T) {
 				Size:        int64(len(data)),
 			},
 		}
-		hash, err := client.Upload(file, manifest)
+		hash, err := client.Upload(file, manifest, toEncrypt)
 		if err != nil {
 			t.Fatal(err)
 		}
+		checkHashLength(t, hash, toEncrypt)
 		return hash
 	}
 	checkDownload := func(manifest, path string, expected []byte) {
@@ -124,6 +144,18 @@ func TestClientUploadDownloadFiles(t *testing.T) {
 	checkDownload(newHash, "some/other/path", otherData)
 }
 
+// checkHashLength checks the hash carries a decryption key if the content is
+// encrypted.
+func checkHashLength(t *testing.T, hash string, encrypted bool) {
+	want := 64
+	if encrypted {
+		want = 128
+	}
+	if len(hash) != want {
+		t.Fatalf("expected hash %s to be %d characters long, got %d", hash, want, len(hash))
+	}
+}
+
 var testDirFiles = []string{
 	"file1.txt",
 	"file2.txt",
@@ -159,6 +191,14 @@ func newTestDirectory(t *testing.T) string {
 // TestClientUploadDownloadDirectory tests uploading and downloading a
 // directory of files to a swarm manifest
 func TestClientUploadDownloadDirectory(t *testing.T) {
+	testClientUploadDownloadDirectory(false, t)
+}
+
+func TestClientUploadDownloadDirectoryEncrypted(t *testing.T) {
+	testClientUploadDownloadDirectory(true, t)
+}
+
+func testClientUploadDownloadDirectory(toEncrypt bool, t *testing.T) {
 	srv := testutil.NewTestSwarmServer(t)
 	defer srv.Close()
 
@@ -168,10 +208,11 @@ func TestClientUploadDownloadDirectory(t *testing.T) {
 	// upload the directory
 	client := NewClient(srv.URL)
 	defaultPath := filepath.Join(dir, testDirFiles[0])
-	hash, err := client.UploadDirectory(dir, defaultPath, "")
+	hash, err := client.UploadDirectory(dir, defaultPath, "", toEncrypt)
 	if err != nil {
 		t.Fatalf("error uploading directory: %s", err)
 	}
+	checkHashLength(t, hash, toEncrypt)
 
 	// check we can download the individual files
 	checkDownloadFile := func(path string, expected []byte) {
@@ -224,7 +265,7 @@ func TestClientFileList(t *testing.T) {
 	defer os.RemoveAll(dir)
 
 	client := NewClient(srv.URL)
-	hash, err := client.UploadDirectory(dir, "", "")
+	hash, err := client.UploadDirectory(dir, "", "", false)
 	if err != nil {
 		t.Fatalf("error uploading directory: %s", err)
 	}
@@ -323,3 +364,108 @@ func TestClientMultipartUpload(t *testing.T) {
 		checkDownloadFile(file)
 	}
 }
+
+// TestClientResource tests creating, updating and looking up a mutable
+// resource
+func TestClientResource(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	owner, _ := crypto.GenerateKey()
+	create := api.NewResourceCreate(api.ResourceTopic("feed"), uint64(time.Now().Unix()), 3600)
+	if err := create.Sign(owner); err != nil {
+		t.Fatal(err)
+	}
+	resource, err := client.CreateResource(create)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// publish two versions in the current period
+	for _, data := range []string{"foo", "bar"} {
+		info, err := client.ResourceInfo(resource)
+		if err != nil {
+			t.Fatal(err)
+		}
+		update := info.NextUpdate([]byte(data))
+		if err := update.Sign(owner); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.UpdateResource(resource, update); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := client.ResourceInfo(resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Owner != crypto.PubkeyToAddress(owner.PublicKey) || info.Period != 1 || info.Version != 2 {
+		t.Fatalf("unexpected resource info %+v", info)
+	}
+
+	// check the latest and the first version can be downloaded
+	for _, x := range []struct {
+		version uint32
+		data    string
+	}{{0, "bar"}, {1, "foo"}} {
+		res, err := client.DownloadResource(resource, 1, x.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotData, err := ioutil.ReadAll(res)
+		res.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(gotData) != x.data {
+			t.Fatalf("expected version %d to be %q, got %q", x.version, x.data, gotData)
+		}
+	}
+
+	// updates signed by someone else are rejected
+	other, _ := crypto.GenerateKey()
+	update := info.NextUpdate([]byte("baz"))
+	update.Sign(other)
+	if _, err := client.UpdateResource(resource, update); err == nil {
+		t.Fatal("expected error publishing update signed by another key")
+	}
+}
+
+// TestClientPin tests pinning, listing and unpinning encrypted content
+func TestClientPin(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	data := bytes.Repeat([]byte("foo123"), 1000)
+	hash, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Pin(hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Pin(hash); err == nil {
+		t.Fatal("expected pinning pinned content to fail")
+	}
+	pins, err := client.PinList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the root chunk and the 2 data chunks
+	if len(pins) != 1 || pins[0].Key.String() != hash || pins[0].Chunks != 3 {
+		t.Fatalf("unexpected pins %v", pins)
+	}
+	if err := client.Unpin(hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Unpin(hash); err == nil {
+		t.Fatal("expected unpinning unpinned content to fail")
+	}
+	if pins, err := client.PinList(); err != nil || len(pins) != 0 {
+		t.Fatalf("expected no pins, got %v (%v)", pins, err)
+	}
+}
@@ -21,6 +21,7 @@ package http
 
 import (
 	"archive/tar"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -45,25 +46,35 @@ import (
 
 //setup metrics
 var (
-	postRawCount     = metrics.NewRegisteredCounter("api.http.post.raw.count", nil)
-	postRawFail      = metrics.NewRegisteredCounter("api.http.post.raw.fail", nil)
-	postFilesCount   = metrics.NewRegisteredCounter("api.http.post.files.count", nil)
-	postFilesFail    = metrics.NewRegisteredCounter("api.http.post.files.fail", nil)
-	deleteCount      = metrics.NewRegisteredCounter("api.http.delete.count", nil)
-	deleteFail       = metrics.NewRegisteredCounter("api.http.delete.fail", nil)
-	getCount         = metrics.NewRegisteredCounter("api.http.get.count", nil)
-	getFail          = metrics.NewRegisteredCounter("api.http.get.fail", nil)
-	getFileCount     = metrics.NewRegisteredCounter("api.http.get.file.count", nil)
-	getFileNotFound  = metrics.NewRegisteredCounter("api.http.get.file.notfound", nil)
-	getFileFail      = metrics.NewRegisteredCounter("api.http.get.file.fail", nil)
-	getFilesCount    = metrics.NewRegisteredCounter("api.http.get.files.count", nil)
-	getFilesFail     = metrics.NewRegisteredCounter("api.http.get.files.fail", nil)
-	getListCount     = metrics.NewRegisteredCounter("api.http.get.list.count", nil)
-	getListFail      = metrics.NewRegisteredCounter("api.http.get.list.fail", nil)
-	requestCount     = metrics.NewRegisteredCounter("http.request.count", nil)
-	htmlRequestCount = metrics.NewRegisteredCounter("http.request.html.count", nil)
-	jsonRequestCount = metrics.NewRegisteredCounter("http.request.json.count", nil)
-	requestTimer     = metrics.NewRegisteredResettingTimer("http.request.time", nil)
+	postRawCount      = metrics.NewRegisteredCounter("api.http.post.raw.count", nil)
+	postRawFail       = metrics.NewRegisteredCounter("api.http.post.raw.fail", nil)
+	postFilesCount    = metrics.NewRegisteredCounter("api.http.post.files.count", nil)
+	postFilesFail     = metrics.NewRegisteredCounter("api.http.post.files.fail", nil)
+	deleteCount       = metrics.NewRegisteredCounter("api.http.delete.count", nil)
+	deleteFail        = metrics.NewRegisteredCounter("api.http.delete.fail", nil)
+	getCount          = metrics.NewRegisteredCounter("api.http.get.count", nil)
+	getFail           = metrics.NewRegisteredCounter("api.http.get.fail", nil)
+	getFileCount      = metrics.NewRegisteredCounter("api.http.get.file.count", nil)
+	getFileNotFound   = metrics.NewRegisteredCounter("api.http.get.file.notfound", nil)
+	getFileFail       = metrics.NewRegisteredCounter("api.http.get.file.fail", nil)
+	getFilesCount     = metrics.NewRegisteredCounter("api.http.get.files.count", nil)
+	getFilesFail      = metrics.NewRegisteredCounter("api.http.get.files.fail", nil)
+	getListCount      = metrics.NewRegisteredCounter("api.http.get.list.count", nil)
+	getListFail       = metrics.NewRegisteredCounter("api.http.get.list.fail", nil)
+	postResourceCount = metrics.NewRegisteredCounter("api.http.post.resource.count", nil)
+	postResourceFail  = metrics.NewRegisteredCounter("api.http.post.resource.fail", nil)
+	getResourceCount  = metrics.NewRegisteredCounter("api.http.get.resource.count", nil)
+	getResourceFail   = metrics.NewRegisteredCounter("api.http.get.resource.fail", nil)
+	postPinCount      = metrics.NewRegisteredCounter("api.http.post.pin.count", nil)
+	postPinFail       = metrics.NewRegisteredCounter("api.http.post.pin.fail", nil)
+	deletePinCount    = metrics.NewRegisteredCounter("api.http.delete.pin.count", nil)
+	deletePinFail     = metrics.NewRegisteredCounter("api.http.delete.pin.fail", nil)
+	getPinCount       = metrics.NewRegisteredCounter("api.http.get.pin.count", nil)
+	getPinFail        = metrics.NewRegisteredCounter("api.http.get.pin.fail", nil)
+	requestCount      = metrics.NewRegisteredCounter("http.request.count", nil)
+	htmlRequestCount  = metrics.NewRegisteredCounter("http.request.html.count", nil)
+	jsonRequestCount  = metrics.NewRegisteredCounter("http.request.json.count", nil)
+	requestTimer      = metrics.NewRegisteredResettingTimer("http.request.time", nil)
 )
 
 // ServerConfig is the basic configuration needed for the HTTP server and also
@@ -110,8 +121,16 @@ type Request struct {
 	uri *api.URI
 }
 
+// toEncrypt reports whether uploaded content should be encrypted, which is
+// requested either with the bzz-encrypted scheme or the encrypt query parameter
+func (r *Request) toEncrypt() bool {
+	return r.uri.Encrypted() || r.URL.Query().Get("encrypt") == "true"
+}
+
 // HandlePostRaw handles a POST request to a raw bzz-raw:/ URI, stores the request
-// body in swarm and returns the resulting storage key as a text/plain response
+// body in swarm and returns the resulting storage key as a text/plain response.
+// The content is encrypted if the URI has an encrypt=true query parameter, the
+// returned key then carries the decryption key.
 func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 	postRawCount.Inc(1)
 	if r.uri.Path != "" {
@@ -126,7 +145,11 @@ func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 		return
 	}
 
-	key, err := s.api.Store(r.Body, r.ContentLength, nil)
+	store := s.api.Store
+	if r.toEncrypt() {
+		store = s.api.StoreEncrypted
+	}
+	key, err := store(r.Body, r.ContentLength, nil)
 	if err != nil {
 		postRawFail.Inc(1)
 		s.Error(w, r, err)
@@ -143,7 +166,9 @@ func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 // bzz:/<hash>/<path> which contains either a single file or multiple files
 // (either a tar archive or multipart form), adds those files either to an
 // existing manifest or to a new manifest under <path> and returns the
-// resulting manifest hash as a text/plain response
+// resulting manifest hash as a text/plain response.
+// Requests to bzz-encrypted:/ or with an encrypt=true query parameter create an
+// encrypted manifest, and files added to encrypted manifests are encrypted.
 func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 	postFilesCount.Inc(1)
 	contentType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
@@ -161,6 +186,18 @@ func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 			s.Error(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
 			return
 		}
+		if r.toEncrypt() && !key.Encrypted() {
+			postFilesFail.Inc(1)
+			s.BadRequest(w, r, "cannot add encrypted content to an unencrypted manifest")
+			return
+		}
+	} else if r.toEncrypt() {
+		key, err = s.api.NewEncryptedManifest()
+		if err != nil {
+			postFilesFail.Inc(1)
+			s.Error(w, r, err)
+			return
+		}
 	} else {
 		key, err = s.api.NewManifest()
 		if err != nil {
@@ -644,6 +681,206 @@ func (s *Server) HandleGetFile(w http.ResponseWriter, r *Request) {
 	http.ServeContent(w, &r.Request, "", time.Now(), reader)
 }
 
+// HandlePostResource handles a POST request to bzz-resource:/ or
+// bzz-resource:/<resource> whose body is a JSON signed resource update. Without
+// an address the update creates a new resource and the resource key is
+// returned, otherwise the update is published for the given resource and the
+// key of the update is returned, both as text/plain responses.
+func (s *Server) HandlePostResource(w http.ResponseWriter, r *Request) {
+	postResourceCount.Inc(1)
+	if r.uri.Path != "" {
+		postResourceFail.Inc(1)
+		s.BadRequest(w, r, "resource POST request cannot contain a path")
+		return
+	}
+	var update storage.ResourceUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		postResourceFail.Inc(1)
+		s.BadRequest(w, r, fmt.Sprintf("invalid resource update: %s", err))
+		return
+	}
+
+	var key storage.Key
+	if r.uri.Addr == "" {
+		var err error
+		if key, err = s.api.ResourceCreate(&update); err != nil {
+			postResourceFail.Inc(1)
+			s.BadRequest(w, r, err.Error())
+			return
+		}
+	} else {
+		resource, err := s.api.Resolve(r.uri)
+		if err != nil {
+			postResourceFail.Inc(1)
+			s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
+			return
+		}
+		key, err = s.api.ResourceUpdate(resource, &update)
+		if err == api.ErrResourceNotFound {
+			postResourceFail.Inc(1)
+			s.NotFound(w, r, err)
+			return
+		}
+		if err != nil {
+			postResourceFail.Inc(1)
+			s.BadRequest(w, r, err.Error())
+			return
+		}
+	}
+	s.logDebug("resource update %s stored", key.Log())
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, key)
+}
+
+// HandleGetResource handles a GET request to bzz-resource:/<resource>/<path>
+// and responds with the data of the latest update if <path> is empty, of the
+// latest version of a period if <path> is <period>, or of a specific update if
+// <path> is <period>/<version>. The path "meta" responds with a JSON
+// description of the resource and its latest update.
+func (s *Server) HandleGetResource(w http.ResponseWriter, r *Request) {
+	getResourceCount.Inc(1)
+	key, err := s.api.Resolve(r.uri)
+	if err != nil {
+		getResourceFail.Inc(1)
+		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
+		return
+	}
+
+	if r.uri.Path == "meta" {
+		info, err := s.api.ResourceInfo(key)
+		if err != nil {
+			getResourceFail.Inc(1)
+			s.NotFound(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+		return
+	}
+
+	var period, version uint64
+	if r.uri.Path != "" {
+		parts := strings.Split(r.uri.Path, "/")
+		if len(parts) > 2 {
+			getResourceFail.Inc(1)
+			s.BadRequest(w, r, "invalid resource path")
+			return
+		}
+		if period, err = strconv.ParseUint(parts[0], 10, 32); err == nil && len(parts) == 2 {
+			version, err = strconv.ParseUint(parts[1], 10, 32)
+		}
+		if err != nil {
+			getResourceFail.Inc(1)
+			s.BadRequest(w, r, fmt.Sprintf("invalid resource path: %s", err))
+			return
+		}
+	}
+	update, err := s.api.ResourceLookup(key, uint32(period), uint32(version))
+	if err != nil {
+		getResourceFail.Inc(1)
+		s.NotFound(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, &r.Request, "", time.Now(), bytes.NewReader(update.Data))
+}
+
+// HandlePostPin handles a POST request to bzz-pin:/<key>, pinning all chunks
+// of the content and, if the content is a manifest, of its entries. It
+// responds with the key of the pinned content as a text/plain response.
+func (s *Server) HandlePostPin(w http.ResponseWriter, r *Request) {
+	postPinCount.Inc(1)
+	if r.uri.Addr == "" || r.uri.Path != "" {
+		postPinFail.Inc(1)
+		s.BadRequest(w, r, "pin request must contain an address and no path")
+		return
+	}
+	key, err := s.api.Resolve(r.uri)
+	if err != nil {
+		postPinFail.Inc(1)
+		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
+		return
+	}
+	if _, err := s.api.Pin(key); err != nil {
+		postPinFail.Inc(1)
+		switch err {
+		case api.ErrAlreadyPinned, storage.ErrPinQuotaExceeded:
+			s.BadRequest(w, r, err.Error())
+		case api.ErrNoPinStore:
+			s.Error(w, r, err)
+		default:
+			s.NotFound(w, r, fmt.Errorf("error pinning %s: %s", key, err))
+		}
+		return
+	}
+	s.logDebug("content %s pinned", key.Log())
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, key)
+}
+
+// HandleDeletePin handles a DELETE request to bzz-pin:/<key>, unpinning the
+// content so that its chunks are subject to garbage collection again.
+func (s *Server) HandleDeletePin(w http.ResponseWriter, r *Request) {
+	deletePinCount.Inc(1)
+	key, err := s.api.Resolve(r.uri)
+	if err != nil {
+		deletePinFail.Inc(1)
+		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
+		return
+	}
+	if err := s.api.Unpin(key); err == api.ErrNotPinned {
+		deletePinFail.Inc(1)
+		s.NotFound(w, r, err)
+		return
+	} else if err != nil {
+		deletePinFail.Inc(1)
+		s.Error(w, r, err)
+		return
+	}
+	s.logDebug("content %s unpinned", key.Log())
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleGetPin handles a GET request to bzz-pin:/ responding with a JSON list
+// of all pinned content, or to bzz-pin:/<key> responding with the JSON record
+// of the pinned content.
+func (s *Server) HandleGetPin(w http.ResponseWriter, r *Request) {
+	getPinCount.Inc(1)
+	if r.uri.Addr == "" {
+		pins, err := s.api.Pins()
+		if err != nil {
+			getPinFail.Inc(1)
+			s.Error(w, r, err)
+			return
+		}
+		if pins == nil {
+			pins = []*storage.PinnedRoot{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(pins)
+		return
+	}
+	key, err := s.api.Resolve(r.uri)
+	if err != nil {
+		getPinFail.Inc(1)
+		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
+		return
+	}
+	root, err := s.api.PinnedRoot(key)
+	if err != nil {
+		getPinFail.Inc(1)
+		s.NotFound(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(root)
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if metrics.Enabled {
 		//The increment for request count and request timer themselves have a flag check
@@ -682,6 +919,10 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		if uri.Raw() || uri.DeprecatedRaw() {
 			s.HandlePostRaw(w, req)
+		} else if uri.Resource() {
+			s.HandlePostResource(w, req)
+		} else if uri.Pin() {
+			s.HandlePostPin(w, req)
 		} else {
 			s.HandlePostFiles(w, req)
 		}
@@ -692,7 +933,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		//   new manifest leaving the existing one intact, so it isn't
 		//   strictly a traditional PUT request which replaces content
 		//   at a URI, and POST is more ubiquitous)
-		if uri.Raw() || uri.DeprecatedRaw() {
+		if uri.Raw() || uri.DeprecatedRaw() || uri.Resource() || uri.Pin() {
 			ShowError(w, req, fmt.Sprintf("No PUT to %s allowed.", uri), http.StatusBadRequest)
 			return
 		} else {
@@ -700,7 +941,11 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case "DELETE":
-		if uri.Raw() || uri.DeprecatedRaw() {
+		if uri.Pin() {
+			s.HandleDeletePin(w, req)
+			return
+		}
+		if uri.Raw() || uri.DeprecatedRaw() || uri.Resource() {
 			ShowError(w, req, fmt.Sprintf("No DELETE to %s allowed.", uri), http.StatusBadRequest)
 			return
 		}
@@ -717,6 +962,16 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if uri.Resource() {
+			s.HandleGetResource(w, req)
+			return
+		}
+
+		if uri.Pin() {
+			s.HandleGetPin(w, req)
+			return
+		}
+
 		if r.Header.Get("Accept") == "application/x-tar" {
 			s.HandleGetFiles(w, req)
 			return
@@ -18,6 +18,7 @@ package http_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -25,8 +26,10 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/api"
 	swarm "github.com/Ethereum-Reloaded/ETHR-Go/swarm/api/client"
 	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/storage"
@@ -279,7 +282,7 @@ func TestBzzRootRedirect(t *testing.T) {
 			Size:        int64(len(data)),
 		},
 	}
-	hash, err := client.Upload(file, "")
+	hash, err := client.Upload(file, "", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -318,3 +321,192 @@ func TestBzzRootRedirect(t *testing.T) {
 		t.Fatalf("expected response to equal %q, got %q", data, gotData)
 	}
 }
+
+// TestBzzEncrypted tests that content uploaded with encryption can be
+// retrieved with the returned key through both the bzz and bzz-encrypted
+// schemes, and that encrypted files cannot be added to unencrypted manifests.
+func TestBzzEncrypted(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := swarm.NewClient(srv.URL)
+	data := []byte("encrypted data")
+	upload := func(manifest string, toEncrypt bool) string {
+		file := &swarm.File{
+			ReadCloser: ioutil.NopCloser(bytes.NewReader(data)),
+			ManifestEntry: api.ManifestEntry{
+				Path:        "file.txt",
+				ContentType: "text/plain",
+				Size:        int64(len(data)),
+			},
+		}
+		hash, err := client.Upload(file, manifest, toEncrypt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	hash := upload("", true)
+
+	for _, scheme := range []string{"bzz", "bzz-encrypted"} {
+		res, err := http.Get(srv.URL + "/" + scheme + ":/" + hash + "/file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotData, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK || !bytes.Equal(gotData, data) {
+			t.Fatalf("%s: expected response to equal %q, got %s %q", scheme, data, res.Status, gotData)
+		}
+	}
+	// The manifest itself is encrypted, it is not readable without the key
+	res, err := http.Get(srv.URL + "/bzz:/" + hash[:64] + "/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		t.Fatalf("encrypted manifest readable without the decryption key")
+	}
+
+	plain := upload("", false)
+	res, err = http.Post(srv.URL+"/bzz-encrypted:/"+plain+"/other.txt", "text/plain", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected encrypted upload to unencrypted manifest to fail with %d, got %s", http.StatusBadRequest, res.Status)
+	}
+}
+
+func TestBzzResource(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	owner, _ := crypto.GenerateKey()
+	create := api.NewResourceCreate(api.ResourceTopic("feed"), uint64(time.Now().Unix()), 3600)
+	create.Sign(owner)
+	resource, err := swarm.NewClient(srv.URL).CreateResource(create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := &storage.ResourceUpdate{Topic: create.Topic, Period: 1, Version: 1, Data: []byte("data")}
+	update.Sign(owner)
+	body, _ := json.Marshal(update)
+	unknown := strings.Repeat("0", 64)
+
+	for _, x := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", resource, "", http.StatusNotFound},
+		{"GET", resource + "/meta", "", http.StatusOK},
+		{"GET", unknown + "/meta", "", http.StatusNotFound},
+		{"POST", resource + "/1", string(body), http.StatusBadRequest},
+		{"POST", resource, "invalid", http.StatusBadRequest},
+		{"POST", unknown, string(body), http.StatusNotFound},
+		{"POST", resource, string(body), http.StatusOK},
+		{"POST", resource, string(body), http.StatusBadRequest},
+		{"GET", resource, "", http.StatusOK},
+		{"GET", resource + "/1/1", "", http.StatusOK},
+		{"GET", resource + "/1/2", "", http.StatusNotFound},
+		{"GET", resource + "/x", "", http.StatusBadRequest},
+		{"GET", resource + "/1/2/3", "", http.StatusBadRequest},
+		{"DELETE", resource, "", http.StatusBadRequest},
+	} {
+		req, err := http.NewRequest(x.method, srv.URL+"/bzz-resource:/"+x.path, strings.NewReader(x.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotData, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != x.status {
+			t.Fatalf("%s %s: expected status %d, got %s", x.method, x.path, x.status, res.Status)
+		}
+		if x.method == "GET" && strings.HasPrefix(x.path, resource+"/1/1") && string(gotData) != "data" {
+			t.Fatalf("%s %s: expected %q, got %q", x.method, x.path, "data", gotData)
+		}
+	}
+}
+
+func TestBzzPin(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	hash, err := swarm.NewClient(srv.URL).UploadRaw(strings.NewReader("pinned data"), 11, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := strings.Repeat("0", 64)
+
+	for _, x := range []struct {
+		method, path string
+		status       int
+	}{
+		{"GET", hash, http.StatusNotFound},
+		{"DELETE", hash, http.StatusNotFound},
+		{"POST", hash + "/path", http.StatusBadRequest},
+		{"POST", unknown, http.StatusNotFound},
+		{"POST", hash, http.StatusOK},
+		{"POST", hash, http.StatusBadRequest},
+		{"GET", hash, http.StatusOK},
+		{"GET", "", http.StatusOK},
+		{"PUT", hash, http.StatusBadRequest},
+		{"DELETE", hash, http.StatusOK},
+		{"GET", hash, http.StatusNotFound},
+	} {
+		req, err := http.NewRequest(x.method, srv.URL+"/bzz-pin:/"+x.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var root storage.PinnedRoot
+		if x.method == "GET" && x.path == hash && res.StatusCode == http.StatusOK {
+			err = json.NewDecoder(res.Body).Decode(&root)
+		}
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != x.status {
+			t.Fatalf("%s %s: expected status %d, got %s", x.method, x.path, x.status, res.Status)
+		}
+		if x.method == "GET" && x.path == hash && x.status == http.StatusOK && root.Chunks != 1 {
+			t.Fatalf("expected 1 pinned chunk, got %+v", root)
+		}
+	}
+
+	// content can also be pinned through the admin RPC API
+	control := api.NewControl(srv.Api, nil)
+	if _, err := control.Pin(hash); err != nil {
+		t.Fatal(err)
+	}
+	if pins, err := control.Pins(); err != nil || len(pins) != 1 {
+		t.Fatalf("expected 1 pin, got %v (%v)", pins, err)
+	}
+	res, err := http.Get(srv.URL + "/bzz-pin:/" + hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("content pinned through RPC not listed: %s", res.Status)
+	}
+	if err := control.Unpin(hash); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -34,6 +34,10 @@ import (
 
 const (
 	ManifestType = "application/bzz-manifest+json"
+
+	// manifestSizeLimit is the size of the largest manifest loaded. Reading
+	// encrypted content without its key yields random sizes beyond it.
+	manifestSizeLimit = 64 * 1024 * 1024
 )
 
 // Manifest represents a swarm manifest
@@ -68,6 +72,17 @@ func (a *Api) NewManifest() (storage.Key, error) {
 	return a.Store(bytes.NewReader(data), int64(len(data)), &sync.WaitGroup{})
 }
 
+// NewEncryptedManifest creates and stores a new, empty encrypted manifest.
+// Entries added to it are encrypted as well.
+func (a *Api) NewEncryptedManifest() (storage.Key, error) {
+	var manifest Manifest
+	data, err := json.Marshal(&manifest)
+	if err != nil {
+		return nil, err
+	}
+	return a.StoreEncrypted(bytes.NewReader(data), int64(len(data)), &sync.WaitGroup{})
+}
+
 // ManifestWriter is used to add and remove entries from an underlying manifest
 type ManifestWriter struct {
 	api   *Api
@@ -83,9 +98,14 @@ func (a *Api) NewManifestWriter(key storage.Key, quitC chan bool) (*ManifestWrit
 	return &ManifestWriter{a, trie, quitC}, nil
 }
 
-// AddEntry stores the given data and adds the resulting key to the manifest
+// AddEntry stores the given data and adds the resulting key to the manifest.
+// The data is encrypted if the manifest is.
 func (m *ManifestWriter) AddEntry(data io.Reader, e *ManifestEntry) (storage.Key, error) {
-	key, err := m.api.Store(data, e.Size, nil)
+	store := m.api.Store
+	if m.trie.encrypted {
+		store = m.api.StoreEncrypted
+	}
+	key, err := store(data, e.Size, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -163,9 +183,10 @@ func (m *ManifestWalker) walk(trie *manifestTrie, prefix string, walkFn WalkFn)
 }
 
 type manifestTrie struct {
-	dpa     *storage.DPA
-	entries [257]*manifestTrieEntry // indexed by first character of basePath, entries[256] is the empty basePath entry
-	hash    storage.Key             // if hash != nil, it is stored
+	dpa       *storage.DPA
+	entries   [257]*manifestTrieEntry // indexed by first character of basePath, entries[256] is the empty basePath entry
+	hash      storage.Key             // if hash != nil, it is stored
+	encrypted bool                    // the trie is stored encrypted
 }
 
 func newManifestTrieEntry(entry *ManifestEntry, subtrie *manifestTrie) *manifestTrieEntry {
@@ -191,13 +212,16 @@ func loadManifest(dpa *storage.DPA, hash storage.Key, quitC chan bool) (trie *ma
 
 func readManifest(manifestReader storage.LazySectionReader, hash storage.Key, dpa *storage.DPA, quitC chan bool) (trie *manifestTrie, err error) { // non-recursive, subtrees are downloaded on-demand
 
-	// TODO check size for oversized manifests
 	size, err := manifestReader.Size(quitC)
 	if err != nil { // size == 0
 		// can't determine size means we don't have the root chunk
 		err = fmt.Errorf("Manifest not Found")
 		return
 	}
+	if size < 0 || size > manifestSizeLimit {
+		err = fmt.Errorf("Manifest %v is too large: %v bytes", hash.Log(), size)
+		return
+	}
 	manifestData := make([]byte, size)
 	read, err := manifestReader.Read(manifestData)
 	if int64(read) < size {
@@ -222,7 +246,8 @@ func readManifest(manifestReader storage.LazySectionReader, hash storage.Key, dp
 	log.Trace(fmt.Sprintf("Manifest %v has %d entries.", hash.Log(), len(man.Entries)))
 
 	trie = &manifestTrie{
-		dpa: dpa,
+		dpa:       dpa,
+		encrypted: hash.Encrypted(),
 	}
 	for _, entry := range man.Entries {
 		trie.addEntry(entry, quitC)
@@ -263,7 +288,8 @@ func (self *manifestTrie) addEntry(entry *manifestTrieEntry, quitC chan bool) {
 	commonPrefix := entry.Path[:cpl]
 
 	subtrie := &manifestTrie{
-		dpa: self.dpa,
+		dpa:       self.dpa,
+		encrypted: self.encrypted,
 	}
 	entry.Path = entry.Path[cpl:]
 	oldentry.Path = oldentry.Path[cpl:]
@@ -352,7 +378,11 @@ func (self *manifestTrie) recalcAndStore() error {
 
 	sr := bytes.NewReader(manifest)
 	wg := &sync.WaitGroup{}
-	key, err2 := self.dpa.Store(sr, int64(len(manifest)), wg, nil)
+	store := self.dpa.Store
+	if self.encrypted {
+		store = self.dpa.StoreEncrypted
+	}
+	key, err2 := store(sr, int64(len(manifest)), wg, nil)
 	wg.Wait()
 	self.hash = key
 	return err2
@@ -0,0 +1,162 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"errors"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/storage"
+)
+
+var (
+	ErrNotPinned     = errors.New("content not pinned")
+	ErrAlreadyPinned = errors.New("content already pinned")
+	ErrNoPinStore    = errors.New("pinning not supported by the local store")
+)
+
+// pinStore returns the local store pinned chunks are kept in.
+func (self *Api) pinStore() (*storage.DbStore, error) {
+	store := self.dpa.PinStore()
+	if store == nil {
+		return nil, ErrNoPinStore
+	}
+	return store, nil
+}
+
+// walkContent calls fn once for every chunk of the content with the given key.
+// If the content is a manifest, the chunks of all its entries are visited as
+// well, including those of its submanifests.
+func (self *Api) walkContent(key storage.Key, fn func(*storage.Chunk) error) error {
+	seen := make(map[string]bool)
+	walk := func(key storage.Key) error {
+		return self.dpa.Walk(key, func(chunk *storage.Chunk) error {
+			if seen[string(chunk.Key)] {
+				return nil
+			}
+			seen[string(chunk.Key)] = true
+			return fn(chunk)
+		})
+	}
+	if err := walk(key); err != nil {
+		return err
+	}
+	walker, err := self.NewManifestWalker(key, nil)
+	if err != nil {
+		// not a manifest, the content is a single file
+		return nil
+	}
+	return walker.Walk(func(entry *ManifestEntry) error {
+		if entry.Hash == "" {
+			return nil
+		}
+		return walk(storage.Key(common.Hex2Bytes(entry.Hash)))
+	})
+}
+
+// Pin pins all chunks of the content with the given key, so that they are
+// retrieved if not stored locally and never garbage collected. Chunks shared
+// by several pinned contents are reference counted.
+func (self *Api) Pin(key storage.Key) (*storage.PinnedRoot, error) {
+	store, err := self.pinStore()
+	if err != nil {
+		return nil, err
+	}
+	if store.PinnedRoot(key) != nil {
+		return nil, ErrAlreadyPinned
+	}
+	root := &storage.PinnedRoot{Key: key}
+	var pinned []storage.Key
+	err = self.walkContent(key, func(chunk *storage.Chunk) error {
+		if err := store.Pin(chunk); err != nil {
+			return err
+		}
+		pinned = append(pinned, chunk.Key)
+		root.Chunks++
+		root.Size += uint64(len(chunk.SData))
+		return nil
+	})
+	if err == nil {
+		err = store.PutPinnedRoot(root)
+	}
+	if err != nil {
+		for _, ckey := range pinned {
+			store.Unpin(ckey)
+		}
+		return nil, err
+	}
+	log.Debug("Pinned content", "key", key, "chunks", root.Chunks, "size", root.Size)
+	return root, nil
+}
+
+// Unpin releases the pins of the content with the given key, its chunks are
+// garbage collected unless they are pinned by other content. The pins are
+// released along with the record of the content in a single write.
+func (self *Api) Unpin(key storage.Key) error {
+	store, err := self.pinStore()
+	if err != nil {
+		return err
+	}
+	if store.PinnedRoot(key) == nil {
+		return ErrNotPinned
+	}
+	var chunks []storage.Key
+	err = self.walkContent(key, func(chunk *storage.Chunk) error {
+		chunks = append(chunks, chunk.Key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := store.UnpinRoot(key, chunks); err != nil {
+		return err
+	}
+	log.Debug("Unpinned content", "key", key)
+	return nil
+}
+
+// PinnedRoot returns the record of the pinned content with the given key.
+func (self *Api) PinnedRoot(key storage.Key) (*storage.PinnedRoot, error) {
+	store, err := self.pinStore()
+	if err != nil {
+		return nil, err
+	}
+	root := store.PinnedRoot(key)
+	if root == nil {
+		return nil, ErrNotPinned
+	}
+	return root, nil
+}
+
+// Pins returns the records of all pinned content.
+func (self *Api) Pins() ([]*storage.PinnedRoot, error) {
+	store, err := self.pinStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.PinnedRoots(), nil
+}
+
+// StoreStats returns the statistics of the local store.
+func (self *Api) StoreStats() (*storage.DbStats, error) {
+	store, err := self.pinStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Stats(), nil
+}
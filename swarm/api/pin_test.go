@@ -0,0 +1,94 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPin tests that pinning a manifest pins the chunks of its entries and
+// that chunks shared by pinned manifests stay pinned until both are unpinned.
+func TestPin(t *testing.T) {
+	testApi(t, func(api *Api) {
+		content := strings.Repeat("pinned content", 1000)
+		manifest1, err := api.Put(content, "text/plain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest2, err := api.Put(content, "text/html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentKey, err := api.Store(strings.NewReader(content), int64(len(content)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := api.dpa.PinStore()
+
+		root, err := api.Pin(manifest1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the manifest, the root chunk and the 4 data chunks of the content
+		if root.Chunks != 6 {
+			t.Fatalf("expected 6 chunks pinned, got %d", root.Chunks)
+		}
+		if _, err := api.Pin(manifest1); err != ErrAlreadyPinned {
+			t.Fatalf("expected %v, got %v", ErrAlreadyPinned, err)
+		}
+		if _, err := api.Pin(manifest2); err != nil {
+			t.Fatal(err)
+		}
+		if n := store.PinCount(contentKey); n != 2 {
+			t.Fatalf("expected content pinned twice, got %d", n)
+		}
+		if stats, _ := api.StoreStats(); stats.PinnedChunks != 7 || stats.PinnedRoots != 2 {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+		if pins, _ := api.Pins(); len(pins) != 2 {
+			t.Fatalf("expected 2 pinned roots, got %d", len(pins))
+		}
+
+		if err := api.Unpin(manifest1); err != nil {
+			t.Fatal(err)
+		}
+		if err := api.Unpin(manifest1); err != ErrNotPinned {
+			t.Fatalf("expected %v, got %v", ErrNotPinned, err)
+		}
+		if n := store.PinCount(contentKey); n != 1 {
+			t.Fatalf("expected content pinned once, got %d", n)
+		}
+		if _, err := api.PinnedRoot(manifest1); err != ErrNotPinned {
+			t.Fatalf("expected %v, got %v", ErrNotPinned, err)
+		}
+		if err := api.Unpin(manifest2); err != nil {
+			t.Fatal(err)
+		}
+		if stats, _ := api.StoreStats(); stats.PinnedChunks != 0 || stats.PinnedSize != 0 || stats.PinnedRoots != 0 {
+			t.Fatalf("unexpected stats after unpinning %+v", stats)
+		}
+
+		// content which is not a manifest is pinned on its own
+		if root, err := api.Pin(contentKey); err != nil || root.Chunks != 5 {
+			t.Fatalf("expected 5 chunks pinned, got %v (%v)", root, err)
+		}
+		if n := store.PinCount(contentKey); n != 1 {
+			t.Fatalf("expected content pinned once, got %d", n)
+		}
+	})
+}
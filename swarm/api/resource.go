@@ -0,0 +1,416 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/log"
+	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/storage"
+)
+
+/*
+Mutable resources are updatable references identified by the owner who signs
+their updates and a topic chosen by the owner. Time is divided into periods of
+a fixed frequency counted from the start time of the resource, period 1 being
+the first; within a period the owner may publish any number of versions,
+counted from 1.
+
+Every update is a chunk addressed by its owner, topic, period and version (see
+storage.ResourceUpdate), so the updates of a resource can be looked up without
+any index. Period 0 and version 0 hold the metadata of the resource (its start
+time and frequency), the key of this chunk is the key of the resource.
+*/
+
+// resourceMetadataLength is the length of the encoded resource metadata.
+const resourceMetadataLength = 16
+
+const (
+	// resourceLookupLimit is the maximum number of periods a lookup of the
+	// latest update searches back from the current period.
+	resourceLookupLimit = 100
+
+	// resourceProbeBatch is the number of periods probed in parallel when
+	// searching the latest update.
+	resourceProbeBatch = 25
+
+	// resourceProbeTimeout is the time a probe for an update which may not
+	// exist waits for its retrieval from the network.
+	resourceProbeTimeout = 500 * time.Millisecond
+)
+
+var (
+	ErrResourceNotFound      = errors.New("resource not found")
+	ErrResourceExists        = errors.New("resource update already exists")
+	ErrInvalidResourceUpdate = errors.New("invalid resource update")
+)
+
+// Resource describes a mutable resource and its latest update.
+type Resource struct {
+	Key           storage.Key    `json:"key"`
+	Owner         common.Address `json:"owner"`
+	Topic         common.Hash    `json:"topic"`
+	StartTime     uint64         `json:"startTime"`
+	Frequency     uint64         `json:"frequency"`
+	CurrentPeriod uint32         `json:"currentPeriod"`
+	Period        uint32         `json:"period"`  // period of the latest update, 0 if none
+	Version       uint32         `json:"version"` // version of the latest update, 0 if none
+}
+
+// NextUpdate returns the unsigned update publishing data as the next version
+// of the current period.
+func (r *Resource) NextUpdate(data []byte) *storage.ResourceUpdate {
+	version := uint32(1)
+	if r.Period == r.CurrentPeriod {
+		version = r.Version + 1
+	}
+	return &storage.ResourceUpdate{Topic: r.Topic, Period: r.CurrentPeriod, Version: version, Data: data}
+}
+
+// ResourceTopic returns the topic of a resource with the given name.
+func ResourceTopic(name string) common.Hash {
+	return crypto.Keccak256Hash([]byte(name))
+}
+
+// NewResourceCreate returns the unsigned update creating a resource with the
+// given topic, start time (unix seconds) and update frequency (seconds).
+func NewResourceCreate(topic common.Hash, startTime, frequency uint64) *storage.ResourceUpdate {
+	data := make([]byte, resourceMetadataLength)
+	binary.BigEndian.PutUint64(data, startTime)
+	binary.BigEndian.PutUint64(data[8:], frequency)
+	return &storage.ResourceUpdate{Topic: topic, Data: data}
+}
+
+// resourceMetadata is the metadata of a resource, stored at period 0.
+type resourceMetadata struct {
+	owner     common.Address
+	topic     common.Hash
+	startTime uint64
+	frequency uint64
+}
+
+func decodeResourceMetadata(data []byte) (startTime, frequency uint64, err error) {
+	if len(data) != resourceMetadataLength {
+		return 0, 0, ErrInvalidResourceUpdate
+	}
+	startTime, frequency = binary.BigEndian.Uint64(data), binary.BigEndian.Uint64(data[8:])
+	if frequency == 0 {
+		return 0, 0, ErrInvalidResourceUpdate
+	}
+	return startTime, frequency, nil
+}
+
+// period returns the period at the given time, 0 before the start time.
+func (m *resourceMetadata) period(now uint64) uint32 {
+	if now < m.startTime {
+		return 0
+	}
+	return uint32((now-m.startTime)/m.frequency) + 1
+}
+
+// resourceVersion is the period and version of an update.
+type resourceVersion struct {
+	period, version uint32
+}
+
+// ResourceHandler creates, updates and looks up mutable resources, storing
+// their updates through the DPA.
+type ResourceHandler struct {
+	dpa      *storage.DPA
+	timeFunc func() uint64
+
+	lock   sync.Mutex
+	latest map[string]resourceVersion // latest update found for each resource
+}
+
+// NewResourceHandler creates a resource handler on top of the DPA.
+func NewResourceHandler(dpa *storage.DPA) *ResourceHandler {
+	return &ResourceHandler{
+		dpa:      dpa,
+		timeFunc: func() uint64 { return uint64(time.Now().Unix()) },
+		latest:   make(map[string]resourceVersion),
+	}
+}
+
+// get retrieves and verifies the update stored under key.
+func (self *ResourceHandler) get(key storage.Key) (*storage.ResourceUpdate, error) {
+	chunk, err := self.dpa.Get(key)
+	return self.verify(key, chunk, err)
+}
+
+// probe retrieves and verifies the update stored under key like get, but
+// gives up after resourceProbeTimeout. It is used for updates which may not
+// exist.
+func (self *ResourceHandler) probe(key storage.Key) (*storage.ResourceUpdate, error) {
+	chunk, err := self.dpa.GetTimeout(key, resourceProbeTimeout)
+	return self.verify(key, chunk, err)
+}
+
+// verify parses the update of a retrieved chunk and checks it is stored under
+// key.
+func (self *ResourceHandler) verify(key storage.Key, chunk *storage.Chunk, err error) (*storage.ResourceUpdate, error) {
+	if err != nil || chunk == nil || chunk.SData == nil {
+		return nil, ErrResourceNotFound
+	}
+	update, err := storage.ParseResourceUpdate(chunk.SData)
+	if err != nil {
+		return nil, err
+	}
+	if ukey, err := update.Key(); err != nil || !bytes.Equal(ukey, key) {
+		return nil, ErrInvalidResourceUpdate
+	}
+	return update, nil
+}
+
+// put stores a signed update unless this node already stores an update under
+// its key. Updates known to other nodes only are not detected: nodes keep the
+// first update they receive for a key.
+func (self *ResourceHandler) put(update *storage.ResourceUpdate) (storage.Key, error) {
+	chunk, err := update.Chunk()
+	if err != nil {
+		return nil, err
+	}
+	if existing, err := self.dpa.GetLocal(chunk.Key); err == nil && existing != nil && existing.SData != nil {
+		return nil, ErrResourceExists
+	}
+	self.dpa.Put(chunk)
+	return chunk.Key, nil
+}
+
+// metadata retrieves the metadata of the resource with the given key.
+func (self *ResourceHandler) metadata(key storage.Key) (*resourceMetadata, error) {
+	update, err := self.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if update.Period != 0 || update.Version != 0 {
+		return nil, ErrInvalidResourceUpdate
+	}
+	startTime, frequency, err := decodeResourceMetadata(update.Data)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := update.Owner()
+	if err != nil {
+		return nil, err
+	}
+	return &resourceMetadata{owner: owner, topic: update.Topic, startTime: startTime, frequency: frequency}, nil
+}
+
+// Create stores the signed metadata update of a new resource (see
+// NewResourceCreate) and returns the key of the resource.
+func (self *ResourceHandler) Create(update *storage.ResourceUpdate) (storage.Key, error) {
+	if update.Period != 0 || update.Version != 0 {
+		return nil, ErrInvalidResourceUpdate
+	}
+	if _, _, err := decodeResourceMetadata(update.Data); err != nil {
+		return nil, err
+	}
+	key, err := self.put(update)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Created mutable resource", "key", key, "topic", update.Topic)
+	return key, nil
+}
+
+// Update stores a signed update of the resource with the given key and returns
+// the key of the update. The update must be signed by the owner of the
+// resource and its period must not be in the future.
+func (self *ResourceHandler) Update(key storage.Key, update *storage.ResourceUpdate) (storage.Key, error) {
+	meta, err := self.metadata(key)
+	if err != nil {
+		return nil, err
+	}
+	if update.Topic != meta.topic || update.Version == 0 {
+		return nil, ErrInvalidResourceUpdate
+	}
+	if current := meta.period(self.timeFunc()); update.Period == 0 || update.Period > current {
+		return nil, fmt.Errorf("invalid resource period %d, current period is %d", update.Period, current)
+	}
+	owner, err := update.Owner()
+	if err != nil {
+		return nil, err
+	}
+	if owner != meta.owner {
+		return nil, fmt.Errorf("resource update signed by %x, owner is %x", owner, meta.owner)
+	}
+	ukey, err := self.put(update)
+	if err != nil {
+		return nil, err
+	}
+	self.setLatest(key, resourceVersion{update.Period, update.Version})
+	return ukey, nil
+}
+
+// Lookup retrieves an update of the resource with the given key. A zero
+// period selects the latest update, a zero version the latest version of the
+// period.
+func (self *ResourceHandler) Lookup(key storage.Key, period, version uint32) (*storage.ResourceUpdate, error) {
+	meta, err := self.metadata(key)
+	if err != nil {
+		return nil, err
+	}
+	if period == 0 {
+		return self.lookupLatest(key, meta)
+	}
+	if version == 0 {
+		return self.lookupVersion(meta, period, 1)
+	}
+	return self.get(storage.ResourceKey(meta.owner, meta.topic, period, version))
+}
+
+// Info returns the description of the resource with the given key.
+func (self *ResourceHandler) Info(key storage.Key) (*Resource, error) {
+	meta, err := self.metadata(key)
+	if err != nil {
+		return nil, err
+	}
+	res := &Resource{
+		Key:           key,
+		Owner:         meta.owner,
+		Topic:         meta.topic,
+		StartTime:     meta.startTime,
+		Frequency:     meta.frequency,
+		CurrentPeriod: meta.period(self.timeFunc()),
+	}
+	latest, err := self.lookupLatest(key, meta)
+	switch err {
+	case nil:
+		res.Period, res.Version = latest.Period, latest.Version
+	case ErrResourceNotFound:
+	default:
+		return nil, err
+	}
+	return res, nil
+}
+
+// lookupLatest searches the latest update backwards from the current period,
+// down to the latest update found before and at most resourceLookupLimit
+// periods. The periods are probed in parallel batches of resourceProbeBatch.
+func (self *ResourceHandler) lookupLatest(key storage.Key, meta *resourceMetadata) (*storage.ResourceUpdate, error) {
+	hint := self.getLatest(key)
+
+	lowest := uint32(1)
+	current := meta.period(self.timeFunc())
+	if current > resourceLookupLimit {
+		lowest = current - resourceLookupLimit + 1
+	}
+	if hint.period > lowest {
+		lowest = hint.period
+	}
+	for high := current; high >= lowest && high > 0; {
+		low := lowest
+		if high-lowest >= resourceProbeBatch {
+			low = high - resourceProbeBatch + 1
+		}
+		period, err := self.probePeriods(meta, low, high, hint)
+		if err != nil {
+			return nil, err
+		}
+		if period > 0 {
+			from := uint32(1)
+			if period == hint.period {
+				from = hint.version
+			}
+			update, err := self.lookupVersion(meta, period, from)
+			if err != nil {
+				return nil, err
+			}
+			self.setLatest(key, resourceVersion{update.Period, update.Version})
+			return update, nil
+		}
+		high = low - 1
+	}
+	return nil, ErrResourceNotFound
+}
+
+// probePeriods probes the periods from low to high in parallel for their first
+// version, or the hinted version of the hinted period, and returns the highest
+// period with an update, 0 if there is none.
+func (self *ResourceHandler) probePeriods(meta *resourceMetadata, low, high uint32, hint resourceVersion) (uint32, error) {
+	errs := make([]error, high-low+1)
+	var wg sync.WaitGroup
+	for period := low; period <= high; period++ {
+		version := uint32(1)
+		if period == hint.period {
+			version = hint.version
+		}
+		wg.Add(1)
+		go func(period, version uint32) {
+			defer wg.Done()
+			_, errs[period-low] = self.probe(storage.ResourceKey(meta.owner, meta.topic, period, version))
+		}(period, version)
+	}
+	wg.Wait()
+
+	for i := len(errs) - 1; i >= 0; i-- {
+		switch errs[i] {
+		case nil:
+			return low + uint32(i), nil
+		case ErrResourceNotFound:
+		default:
+			return 0, errs[i]
+		}
+	}
+	return 0, nil
+}
+
+// lookupVersion returns the latest version of a period, searching versions
+// upwards from the given one. The versions after it are probed, so the search
+// ends shortly after the latest version.
+func (self *ResourceHandler) lookupVersion(meta *resourceMetadata, period, from uint32) (*storage.ResourceUpdate, error) {
+	latest, err := self.get(storage.ResourceKey(meta.owner, meta.topic, period, from))
+	if err != nil {
+		return nil, err
+	}
+	for version := from + 1; ; version++ {
+		update, err := self.probe(storage.ResourceKey(meta.owner, meta.topic, period, version))
+		if err == ErrResourceNotFound {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		latest = update
+	}
+	return latest, nil
+}
+
+func (self *ResourceHandler) getLatest(key storage.Key) resourceVersion {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.latest[string(key)]
+}
+
+// setLatest records v as latest update of the resource if it is newer than
+// the one recorded.
+func (self *ResourceHandler) setLatest(key storage.Key, v resourceVersion) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	old := self.latest[string(key)]
+	if v.period > old.period || (v.period == old.period && v.version > old.version) {
+		self.latest[string(key)] = v
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/storage"
+)
+
+func signResourceUpdate(t *testing.T, prv *ecdsa.PrivateKey, u *storage.ResourceUpdate) *storage.ResourceUpdate {
+	if err := u.Sign(prv); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestResource(t *testing.T) {
+	testApi(t, func(api *Api) {
+		now := uint64(1000)
+		api.resource.timeFunc = func() uint64 { return now }
+
+		owner, _ := crypto.GenerateKey()
+		topic := ResourceTopic("price-feed")
+
+		key, err := api.ResourceCreate(signResourceUpdate(t, owner, NewResourceCreate(topic, 1000, 60)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.ResourceCreate(signResourceUpdate(t, owner, NewResourceCreate(topic, 1000, 60))); err != ErrResourceExists {
+			t.Fatalf("expected %v creating the resource again, got %v", ErrResourceExists, err)
+		}
+
+		info, err := api.ResourceInfo(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Owner != crypto.PubkeyToAddress(owner.PublicKey) || info.Topic != topic || info.Frequency != 60 {
+			t.Fatalf("unexpected resource info %+v", info)
+		}
+		if info.CurrentPeriod != 1 || info.Period != 0 || info.Version != 0 {
+			t.Fatalf("unexpected periods of new resource %+v", info)
+		}
+		if _, err := api.ResourceLookup(key, 0, 0); err != ErrResourceNotFound {
+			t.Fatalf("expected %v looking up resource without updates, got %v", ErrResourceNotFound, err)
+		}
+
+		// Publish two versions in period 1 and one in period 3
+		update := func(data string) {
+			info, err := api.ResourceInfo(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := api.ResourceUpdate(key, signResourceUpdate(t, owner, info.NextUpdate([]byte(data)))); err != nil {
+				t.Fatalf("update %q: %v", data, err)
+			}
+		}
+		update("1.1")
+		update("1.2")
+		now += 150
+		update("3.1")
+
+		for _, x := range []struct {
+			period, version uint32
+			data            string
+		}{
+			{0, 0, "3.1"},
+			{1, 0, "1.2"},
+			{1, 1, "1.1"},
+			{3, 1, "3.1"},
+		} {
+			u, err := api.ResourceLookup(key, x.period, x.version)
+			if err != nil {
+				t.Fatalf("lookup %d/%d: %v", x.period, x.version, err)
+			}
+			if string(u.Data) != x.data {
+				t.Errorf("lookup %d/%d: expected %q, got %q", x.period, x.version, x.data, u.Data)
+			}
+		}
+		if _, err := api.ResourceLookup(key, 2, 0); err != ErrResourceNotFound {
+			t.Errorf("expected %v looking up empty period, got %v", ErrResourceNotFound, err)
+		}
+
+		// A fresh handler without hints finds the latest update too
+		now += 600
+		handler := NewResourceHandler(api.dpa)
+		handler.timeFunc = api.resource.timeFunc
+		info, err = handler.Info(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.CurrentPeriod != 13 || info.Period != 3 || info.Version != 1 {
+			t.Fatalf("unexpected resource info %+v", info)
+		}
+
+		// The search spans several batches of periods, but not beyond the
+		// lookup limit
+		now += 60 * (resourceLookupLimit + 2 - 13)
+		handler = NewResourceHandler(api.dpa)
+		handler.timeFunc = api.resource.timeFunc
+		if u, err := handler.Lookup(key, 0, 0); err != nil || string(u.Data) != "3.1" {
+			t.Fatalf("expected %q looking up %d periods back, got %v", "3.1", resourceLookupLimit-1, err)
+		}
+		now += 60
+		handler = NewResourceHandler(api.dpa)
+		handler.timeFunc = api.resource.timeFunc
+		if _, err := handler.Lookup(key, 0, 0); err != ErrResourceNotFound {
+			t.Fatalf("expected %v looking up beyond the limit, got %v", ErrResourceNotFound, err)
+		}
+	})
+}
+
+func TestResourceInvalidUpdates(t *testing.T) {
+	testApi(t, func(api *Api) {
+		api.resource.timeFunc = func() uint64 { return 1000 }
+
+		owner, _ := crypto.GenerateKey()
+		other, _ := crypto.GenerateKey()
+		topic := ResourceTopic("app")
+
+		key, err := api.ResourceCreate(signResourceUpdate(t, owner, NewResourceCreate(topic, 1000, 60)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.ResourceCreate(signResourceUpdate(t, owner, NewResourceCreate(topic, 1000, 0))); err != ErrInvalidResourceUpdate {
+			t.Errorf("expected %v creating resource with zero frequency, got %v", ErrInvalidResourceUpdate, err)
+		}
+		for name, u := range map[string]*storage.ResourceUpdate{
+			"other owner":   signResourceUpdate(t, other, &storage.ResourceUpdate{Topic: topic, Period: 1, Version: 1}),
+			"other topic":   signResourceUpdate(t, owner, &storage.ResourceUpdate{Topic: ResourceTopic("x"), Period: 1, Version: 1}),
+			"future period": signResourceUpdate(t, owner, &storage.ResourceUpdate{Topic: topic, Period: 2, Version: 1}),
+			"zero period":   signResourceUpdate(t, owner, &storage.ResourceUpdate{Topic: topic, Period: 0, Version: 1}),
+			"zero version":  signResourceUpdate(t, owner, &storage.ResourceUpdate{Topic: topic, Period: 1, Version: 0}),
+			"unsigned":      {Topic: topic, Period: 1, Version: 1},
+		} {
+			if _, err := api.ResourceUpdate(key, u); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+		u := signResourceUpdate(t, owner, &storage.ResourceUpdate{Topic: topic, Period: 1, Version: 1, Data: []byte("a")})
+		if _, err := api.ResourceUpdate(key, u); err != nil {
+			t.Fatal(err)
+		}
+		u = signResourceUpdate(t, owner, &storage.ResourceUpdate{Topic: topic, Period: 1, Version: 1, Data: []byte("b")})
+		if _, err := api.ResourceUpdate(key, u); err != ErrResourceExists {
+			t.Errorf("expected %v overwriting an update, got %v", ErrResourceExists, err)
+		}
+	})
+}
@@ -18,6 +18,7 @@ package api
 
 import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/network"
+	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/storage"
 )
 
 type Control struct {
@@ -44,3 +45,33 @@ func (self *Control) SwapEnabled(on bool) {
 func (self *Control) Hive() string {
 	return self.hive.String()
 }
+
+// StoreStats returns the statistics of the local chunk store, including the
+// pinned chunks and the garbage collected ones.
+func (self *Control) StoreStats() (*storage.DbStats, error) {
+	return self.api.StoreStats()
+}
+
+// Pin pins the content with the given address, keeping all its chunks and, if
+// it is a manifest, those of its entries from garbage collection.
+func (self *Control) Pin(addr string) (*storage.PinnedRoot, error) {
+	key, err := self.api.Resolve(&URI{Scheme: "bzz", Addr: addr})
+	if err != nil {
+		return nil, err
+	}
+	return self.api.Pin(key)
+}
+
+// Unpin unpins the content with the given address.
+func (self *Control) Unpin(addr string) error {
+	key, err := self.api.Resolve(&URI{Scheme: "bzz", Addr: addr})
+	if err != nil {
+		return err
+	}
+	return self.api.Unpin(key)
+}
+
+// Pins returns the records of all pinned content.
+func (self *Control) Pins() ([]*storage.PinnedRoot, error) {
+	return self.api.Pins()
+}
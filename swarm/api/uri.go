@@ -31,6 +31,12 @@ type URI struct {
 	// * bzz-immutable - immutable URI of an entry in a swarm manifest
 	//                   (address is not resolved)
 	// * bzz-list      -  list of all files contained in a swarm manifest
+	// * bzz-encrypted - an entry in a swarm manifest, uploads to it are
+	//                   encrypted
+	// * bzz-resource  - a mutable resource, the address is the key of the
+	//                   resource and the path selects an update
+	// * bzz-pin       - pinned content, the address is the key of the
+	//                   content
 	//
 	// Deprecated Schemes:
 	// * bzzr - raw swarm content
@@ -58,8 +64,8 @@ type URI struct {
 // * <scheme>://<addr>
 // * <scheme>://<addr>/<path>
 //
-// with scheme one of bzz, bzz-raw, bzz-immutable, bzz-list or bzz-hash
-// or deprecated ones bzzr and bzzi
+// with scheme one of bzz, bzz-raw, bzz-immutable, bzz-list, bzz-hash,
+// bzz-encrypted, bzz-resource or bzz-pin or deprecated ones bzzr and bzzi
 func Parse(rawuri string) (*URI, error) {
 	u, err := url.Parse(rawuri)
 	if err != nil {
@@ -69,7 +75,7 @@ func Parse(rawuri string) (*URI, error) {
 
 	// check the scheme is valid
 	switch uri.Scheme {
-	case "bzz", "bzz-raw", "bzz-immutable", "bzz-list", "bzz-hash", "bzzr", "bzzi":
+	case "bzz", "bzz-raw", "bzz-immutable", "bzz-list", "bzz-hash", "bzz-encrypted", "bzz-resource", "bzz-pin", "bzzr", "bzzi":
 	default:
 		return nil, fmt.Errorf("unknown scheme %q", u.Scheme)
 	}
@@ -104,6 +110,18 @@ func (u *URI) List() bool {
 	return u.Scheme == "bzz-list"
 }
 
+func (u *URI) Encrypted() bool {
+	return u.Scheme == "bzz-encrypted"
+}
+
+func (u *URI) Resource() bool {
+	return u.Scheme == "bzz-resource"
+}
+
+func (u *URI) Pin() bool {
+	return u.Scheme == "bzz-pin"
+}
+
 func (u *URI) DeprecatedRaw() bool {
 	return u.Scheme == "bzzr"
 }
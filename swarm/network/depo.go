@@ -17,7 +17,6 @@
 package network
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"time"
@@ -133,9 +132,7 @@ func (self *Depo) HandleStoreRequestMsg(req *storeRequestMsgData, p *peer) {
 		//return
 	}
 
-	hasher := self.hashfunc()
-	hasher.Write(req.SData)
-	if !bytes.Equal(hasher.Sum(nil), req.Key) {
+	if !storage.ValidChunk(self.hashfunc, req.Key, req.SData) {
 		// data does not validate, ignore
 		// TODO: peer should be penalised/dropped?
 		log.Warn(fmt.Sprintf("Depo.HandleStoreRequest: chunk invalid. store request ignored: %v", req))
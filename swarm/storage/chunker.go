@@ -24,7 +24,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto/sha3"
 	"github.com/Ethereum-Reloaded/ETHR-Go/metrics"
+	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/storage/encryption"
 )
 
 /*
@@ -50,6 +52,13 @@ data_{i} := size(subtree_{i}) || key_{j} || key_{j+1} .... || key_{j+n-1}
   key = hash(int64(size) + key(slice0) + key(slice1) + ...)
 
  The underlying hash function is configurable
+
+6 in encrypted mode every chunk is encrypted with its own random key before
+  hashing, and the key of a chunk is stored next to its hash, so references
+  (including the root key) are hashSize+32 bytes long and branching nodes hold
+  chunksize/(hashSize+32) children. The size and the data of a chunk are
+  encrypted with separate keystreams, and the data is padded to chunksize, so
+  all encrypted chunks have the same length
 */
 
 /*
@@ -73,8 +82,10 @@ var (
 type TreeChunker struct {
 	branches int64
 	hashFunc SwarmHasher
+	encrypt  bool // whether chunks are encrypted
 	// calculated
 	hashSize    int64        // self.hashFunc.New().Size()
+	refSize     int64        // hashSize, plus the key length if chunks are encrypted
 	chunkSize   int64        // hashSize* branches
 	workerCount int64        // the number of worker routines used
 	workerLock  sync.RWMutex // lock for the worker count
@@ -85,12 +96,80 @@ func NewTreeChunker(params *ChunkerParams) (self *TreeChunker) {
 	self.hashFunc = MakeHashFunc(params.Hash)
 	self.branches = params.Branches
 	self.hashSize = int64(self.hashFunc().Size())
+	self.refSize = self.hashSize
 	self.chunkSize = self.hashSize * self.branches
 	self.workerCount = 0
 
 	return
 }
 
+// NewEncryptingTreeChunker creates a TreeChunker which encrypts every chunk it
+// produces. Chunks keep their size, so branching nodes hold fewer children.
+func NewEncryptingTreeChunker(params *ChunkerParams) (self *TreeChunker) {
+	self = NewTreeChunker(params)
+	self.encrypt = true
+	self.refSize = self.hashSize + encryption.KeyLength
+	self.branches = self.chunkSize / self.refSize
+
+	return
+}
+
+// encryptChunk encrypts the 8 byte size and the data of a chunk with key. The
+// data is padded to chunkSize, and the size is encrypted with the keystream
+// following the one of the padded data.
+func encryptChunk(key encryption.Key, chunk []byte, chunkSize int64) ([]byte, error) {
+	data, err := encryption.New(key, int(chunkSize), 0, sha3.NewKeccak256).Encrypt(chunk[8:])
+	if err != nil {
+		return nil, err
+	}
+	span, err := encryption.New(key, 0, spanCounter(chunkSize), sha3.NewKeccak256).Encrypt(chunk[:8])
+	if err != nil {
+		return nil, err
+	}
+	return append(span, data...), nil
+}
+
+// decryptChunk decrypts a chunk encrypted by encryptChunk and cuts off the
+// padding, which is the part of the data not accounted for by the size.
+func decryptChunk(key []byte, chunk []byte, chunkSize, refSize int64) ([]byte, error) {
+	if int64(len(chunk)) != 8+chunkSize {
+		return nil, fmt.Errorf("invalid encrypted chunk length %d", len(chunk))
+	}
+	span, err := encryption.New(key, 0, spanCounter(chunkSize), sha3.NewKeccak256).Decrypt(chunk[:8])
+	if err != nil {
+		return nil, err
+	}
+	data, err := encryption.New(key, int(chunkSize), 0, sha3.NewKeccak256).Decrypt(chunk[8:])
+	if err != nil {
+		return nil, err
+	}
+	length := dataLength(int64(binary.LittleEndian.Uint64(span)), chunkSize, chunkSize/refSize, refSize)
+	if length < 0 || length > chunkSize {
+		return nil, fmt.Errorf("invalid decrypted chunk size %d", binary.LittleEndian.Uint64(span))
+	}
+	return append(span, data[:length]...), nil
+}
+
+// spanCounter returns the keystream counter of the size of an encrypted chunk,
+// which is the first one after the keystream of the padded data.
+func spanCounter(chunkSize int64) uint32 {
+	return uint32(chunkSize / int64(sha3.NewKeccak256().Size()))
+}
+
+// dataLength returns the length of the data of a chunk covering size bytes of
+// content: the content itself for leaf chunks, and the references to the
+// children for intermediate ones.
+func dataLength(size, chunkSize, branches, refSize int64) int64 {
+	if size <= chunkSize {
+		return size
+	}
+	treeSize := chunkSize
+	for treeSize <= (size-1)/branches {
+		treeSize *= branches
+	}
+	return (size + treeSize - 1) / treeSize * refSize
+}
+
 // func (self *TreeChunker) KeySize() int64 {
 // 	return self.hashSize
 // }
@@ -152,7 +231,7 @@ func (self *TreeChunker) Split(data io.Reader, size int64, chunkC chan *Chunk, s
 		depth++
 	}
 
-	key := make([]byte, self.hashFunc().Size())
+	key := make([]byte, self.refSize)
 	// this waitgroup member is released after the root hash is calculated
 	wg.Add(1)
 	//launch actual recursive function passing the waitgroups
@@ -214,7 +293,7 @@ func (self *TreeChunker) split(depth int, treeSize int64, key Key, data io.Reade
 	// intermediate chunk containing child nodes hashes
 	branchCnt := (size + treeSize - 1) / treeSize
 
-	var chunk = make([]byte, branchCnt*self.hashSize+8)
+	var chunk = make([]byte, branchCnt*self.refSize+8)
 	var pos, i int64
 
 	binary.LittleEndian.PutUint64(chunk[0:8], uint64(size))
@@ -229,7 +308,7 @@ func (self *TreeChunker) split(depth int, treeSize int64, key Key, data io.Reade
 			secSize = treeSize
 		}
 		// the hash of that data
-		subTreeKey := chunk[8+i*self.hashSize : 8+(i+1)*self.hashSize]
+		subTreeKey := chunk[8+i*self.refSize : 8+(i+1)*self.refSize]
 
 		childrenWg.Add(1)
 		self.split(depth-1, treeSize/self.branches, subTreeKey, data, secSize, jobC, chunkC, errC, quitC, childrenWg, swg, wwg)
@@ -272,7 +351,13 @@ func (self *TreeChunker) hashWorker(jobC chan *hashJob, chunkC chan *Chunk, errC
 				return
 			}
 			// now we got the hashes in the chunk, then hash the chunks
-			self.hashChunk(hasher, job, chunkC, swg)
+			if err := self.hashChunk(hasher, job, chunkC, swg); err != nil {
+				select {
+				case errC <- err:
+				case <-quitC:
+				}
+				return
+			}
 		case <-quitC:
 			return
 		}
@@ -282,20 +367,34 @@ func (self *TreeChunker) hashWorker(jobC chan *hashJob, chunkC chan *Chunk, errC
 // The treeChunkers own Hash hashes together
 // - the size (of the subtree encoded in the Chunk)
 // - the Chunk, ie. the contents read from the input reader
-func (self *TreeChunker) hashChunk(hasher SwarmHash, job *hashJob, chunkC chan *Chunk, swg *sync.WaitGroup) {
-	hasher.ResetWithLength(job.chunk[:8]) // 8 bytes of length
-	hasher.Write(job.chunk[8:])           // minus 8 []byte length
+// In encrypted mode the chunk is encrypted first, and the hash is taken of the
+// encrypted chunk. The size of the chunk is always the size of the plaintext.
+func (self *TreeChunker) hashChunk(hasher SwarmHash, job *hashJob, chunkC chan *Chunk, swg *sync.WaitGroup) error {
+	data := job.chunk
+	var key encryption.Key
+	if self.encrypt {
+		var err error
+		if key, err = encryption.GenerateRandomKey(); err != nil {
+			return err
+		}
+		if data, err = encryptChunk(key, job.chunk, self.chunkSize); err != nil {
+			return err
+		}
+	}
+	hasher.ResetWithLength(data[:8]) // 8 bytes of length
+	hasher.Write(data[8:])           // minus 8 []byte length
 	h := hasher.Sum(nil)
 
 	newChunk := &Chunk{
 		Key:   h,
-		SData: job.chunk,
-		Size:  job.size,
+		SData: data,
+		Size:  int64(binary.LittleEndian.Uint64(job.chunk[:8])),
 		wg:    swg,
 	}
 
 	// report hash of this chunk one level up (keys corresponds to the proper subslice of the parent chunk)
 	copy(job.key, h)
+	copy(job.key[len(h):], key)
 	// send off new chunk to storage
 	if chunkC != nil {
 		if swg != nil {
@@ -314,6 +413,7 @@ func (self *TreeChunker) hashChunk(hasher SwarmHash, job *hashJob, chunkC chan *
 		newChunkCounter.Inc(1)
 		chunkC <- newChunk
 	}
+	return nil
 }
 
 func (self *TreeChunker) Append(key Key, data io.Reader, chunkC chan *Chunk, swg, wwg *sync.WaitGroup) (Key, error) {
@@ -329,16 +429,29 @@ type LazyChunkReader struct {
 	chunkSize int64       // inherit from chunker
 	branches  int64       // inherit from chunker
 	hashSize  int64       // inherit from chunker
+	refSize   int64       // hashSize, plus the key length for encrypted content
 }
 
 // implements the Joiner interface
 func (self *TreeChunker) Join(key Key, chunkC chan *Chunk) LazySectionReader {
+	return newLazyChunkReader(key, chunkC, self.chunkSize, self.hashSize)
+}
+
+// newLazyChunkReader creates a reader for the content under the root key.
+// Keys carrying a decryption key after the hash refer to encrypted content,
+// which is decrypted transparently while reading.
+func newLazyChunkReader(key Key, chunkC chan *Chunk, chunkSize, hashSize int64) *LazyChunkReader {
+	refSize := hashSize
+	if int64(len(key)) == hashSize+encryption.KeyLength {
+		refSize += encryption.KeyLength
+	}
 	return &LazyChunkReader{
 		key:       key,
 		chunkC:    chunkC,
-		chunkSize: self.chunkSize,
-		branches:  self.branches,
-		hashSize:  self.hashSize,
+		chunkSize: chunkSize,
+		branches:  chunkSize / refSize,
+		hashSize:  hashSize,
+		refSize:   refSize,
 	}
 }
 
@@ -347,7 +460,7 @@ func (self *LazyChunkReader) Size(quitC chan bool) (n int64, err error) {
 	if self.chunk != nil {
 		return self.chunk.Size, nil
 	}
-	chunk := retrieve(self.key, self.chunkC, quitC)
+	chunk := self.retrieve(self.key, quitC)
 	if chunk == nil {
 		select {
 		case <-quitC:
@@ -449,8 +562,8 @@ func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, tr
 		}
 		wg.Add(1)
 		go func(j int64) {
-			childKey := chunk.SData[8+j*self.hashSize : 8+(j+1)*self.hashSize]
-			chunk := retrieve(childKey, self.chunkC, quitC)
+			childKey := chunk.SData[8+j*self.refSize : 8+(j+1)*self.refSize]
+			chunk := self.retrieve(childKey, quitC)
 			if chunk == nil {
 				select {
 				case errC <- fmt.Errorf("chunk %v-%v not found", off, off+treeSize):
@@ -466,6 +579,42 @@ func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, tr
 	} //for
 }
 
+// implements the Walker interface
+func (self *TreeChunker) Walk(key Key, chunkC chan *Chunk, fn func(*Chunk) error) error {
+	r := newLazyChunkReader(key, chunkC, self.chunkSize, self.hashSize)
+	return r.walk(key, fn)
+}
+
+// walk retrieves all chunks of the tree under ref and calls fn with each of
+// them as stored, parents before children.
+func (self *LazyChunkReader) walk(ref Key, fn func(*Chunk) error) error {
+	stored := retrieve(ref[:self.hashSize], self.chunkC, nil)
+	if stored == nil || len(stored.SData) < 8 {
+		return fmt.Errorf("chunk %v not found", ref.Log())
+	}
+	if err := fn(stored); err != nil {
+		return err
+	}
+	chunk := stored
+	if int64(len(ref)) > self.hashSize {
+		data, err := decryptChunk(ref[self.hashSize:], stored.SData, self.chunkSize, self.refSize)
+		if err != nil {
+			return fmt.Errorf("chunk %v: %v", ref.Log(), err)
+		}
+		chunk = &Chunk{Key: stored.Key, SData: data, Size: int64(binary.LittleEndian.Uint64(data[:8]))}
+	}
+	// only intermediate chunks cover more than a chunk of data
+	if chunk.Size <= self.chunkSize {
+		return nil
+	}
+	for off := int64(8); off+self.refSize <= int64(len(chunk.SData)); off += self.refSize {
+		if err := self.walk(Key(chunk.SData[off:off+self.refSize]), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // the helper method submits chunks for a key to a oueue (DPA) and
 // block until they time out or arrive
 // abort if quitC is readable
@@ -495,6 +644,27 @@ func retrieve(key Key, chunkC chan *Chunk, quitC chan bool) *Chunk {
 	return chunk
 }
 
+// retrieve fetches the chunk of a reference, decrypting it with the key
+// carried by the reference if there is one. The stored chunk is left intact.
+func (self *LazyChunkReader) retrieve(ref Key, quitC chan bool) *Chunk {
+	if int64(len(ref)) == self.hashSize {
+		return retrieve(ref, self.chunkC, quitC)
+	}
+	chunk := retrieve(ref[:self.hashSize], self.chunkC, quitC)
+	if chunk == nil {
+		return nil
+	}
+	data, err := decryptChunk(ref[self.hashSize:], chunk.SData, self.chunkSize, self.refSize)
+	if err != nil {
+		return nil
+	}
+	return &Chunk{
+		Key:   chunk.Key,
+		SData: data,
+		Size:  int64(binary.LittleEndian.Uint64(data[:8])),
+	}
+}
+
 // Read keeps a cursor so cannot be called simulateously, see ReadAt
 func (self *LazyChunkReader) Read(b []byte) (read int, err error) {
 	read, err = self.ReadAt(b, self.off)
@@ -363,6 +363,44 @@ func TestRandomData(t *testing.T) {
 
 }
 
+func TestEncryptedRandomData(t *testing.T) {
+	sizes := []int{1, 60, 4095, 4096, 4097, 8192, 12289, 262144, 262145, 2345678}
+	tester := &chunkerTester{t: t}
+
+	for _, hash := range []string{SHA3Hash, BMTHash} {
+		cp := NewChunkerParams()
+		cp.Hash = hash
+		chunker := NewEncryptingTreeChunker(cp)
+		for _, s := range sizes {
+			key := testRandomData(chunker, s, tester)
+			if !key.Encrypted() {
+				t.Fatalf("%s size %v: key length mismatch: have %d, want %d", hash, s, len(key), EncryptedKeyLength)
+			}
+			// No stored chunk may contain the plaintext or reveal its length
+			input := tester.inputs[uint64(s)]
+			for _, chunk := range tester.chunks {
+				if len(chunk.SData) > 8+32 && bytes.Contains(input, chunk.SData[8:8+32]) {
+					t.Fatalf("%s size %v: chunk %v stored in plaintext", hash, s, chunk.Key.Log())
+				}
+				if len(chunk.SData) != 8+4096 {
+					t.Fatalf("%s size %v: chunk %v not padded: have length %d, want %d", hash, s, chunk.Key.Log(), len(chunk.SData), 8+4096)
+				}
+				if chunk.Size <= 0 || chunk.Size > int64(s) {
+					t.Fatalf("%s size %v: chunk %v has invalid size %d", hash, s, chunk.Key.Log(), chunk.Size)
+				}
+			}
+			// The root chunk covers the whole content
+			if root := tester.chunks[key[:32].String()]; root == nil || root.Size != int64(s) {
+				t.Fatalf("%s size %v: root chunk size mismatch: have %v", hash, s, root)
+			}
+			// Content is encrypted with new keys on every upload
+			if again := testRandomData(chunker, s, tester); bytes.Equal(again, key) {
+				t.Fatalf("%s size %v: repeated upload has the same key", hash, s)
+			}
+		}
+	}
+}
+
 func XTestRandomBrokenData(t *testing.T) {
 	sizes := []int{1, 60, 83, 179, 253, 1024, 4095, 4096, 4097, 8191, 8192, 8193, 12287, 12288, 12289, 123456, 2345678}
 	tester := &chunkerTester{t: t}
@@ -18,7 +18,7 @@
 // DbStore implements the ChunkStore interface and is used by the DPA as
 // persistent storage of chunks
 // it implements purging based on access count allowing for external control of
-// max capacity
+// max capacity and size quota, pinned chunks are never purged
 
 package storage
 
@@ -32,6 +32,7 @@ import (
 	"io/ioutil"
 	"sync"
 
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
 	"github.com/Ethereum-Reloaded/ETHR-Go/metrics"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
@@ -53,7 +54,10 @@ const (
 	gcArrayFreeRatio = 0.1
 
 	// key prefixes for leveldb storage
-	kpIndex = 0
+	kpIndex   = 0
+	kpData    = 1
+	kpPin     = 9  // pin count of a chunk
+	kpPinRoot = 10 // pinned root
 )
 
 var (
@@ -61,6 +65,9 @@ var (
 	keyEntryCnt  = []byte{3}
 	keyDataIdx   = []byte{4}
 	keyGCPos     = []byte{5}
+	keyDataSize  = []byte{6}
+	keyPinCnt    = []byte{7}
+	keyPinSize   = []byte{8}
 )
 
 type gcItem struct {
@@ -75,6 +82,10 @@ type DbStore struct {
 	// this should be stored in db, accessed transactionally
 	entryCnt, accessCnt, dataIdx, capacity uint64
 
+	size, sizeQuota           uint64 // total size of the stored chunks, 0 quota is unlimited
+	pinCnt, pinSize, pinQuota uint64 // number and total size of the pinned chunks
+	gcCnt                     uint64 // number of chunks garbage collected since startup
+
 	gcPos, gcStartPos []byte
 	gcArray           []*gcItem
 
@@ -109,9 +120,29 @@ func NewDbStore(path string, hash SwarmHasher, capacity uint64, radius int) (s *
 	if s.gcPos == nil {
 		s.gcPos = s.gcStartPos
 	}
+	if data, err := s.db.Get(keyDataSize); err == nil {
+		s.size = BytesToU64(data)
+	} else {
+		// databases created before size accounting
+		s.size = s.dataSize()
+	}
+	data, _ = s.db.Get(keyPinCnt)
+	s.pinCnt = BytesToU64(data)
+	data, _ = s.db.Get(keyPinSize)
+	s.pinSize = BytesToU64(data)
 	return
 }
 
+// dataSize sums up the size of all the stored chunks.
+func (s *DbStore) dataSize() (size uint64) {
+	it := s.db.NewIterator()
+	defer it.Release()
+	for ok := it.Seek([]byte{kpData}); ok && it.Key()[0] == kpData; ok = it.Next() {
+		size += uint64(len(it.Value()))
+	}
+	return size
+}
+
 type dpaDBIndex struct {
 	Idx    uint64
 	Access uint64
@@ -148,7 +179,7 @@ func getIndexKey(hash Key) []byte {
 
 func getDataKey(idx uint64) []byte {
 	key := make([]byte, 9)
-	key[0] = 1
+	key[0] = kpData
 	binary.BigEndian.PutUint64(key[1:9], idx)
 
 	return key
@@ -210,7 +241,9 @@ func gcListSelect(list []*gcItem, left int, right int, n int) int {
 	}
 }
 
-func (s *DbStore) collectGarbage(ratio float32) {
+// collectGarbage removes the ratio of least accessed chunks from a sample of
+// the unpinned chunks and returns the number of chunks removed.
+func (s *DbStore) collectGarbage(ratio float32) int {
 	it := s.db.NewIterator()
 	it.Seek(s.gcPos)
 	if it.Valid() {
@@ -220,7 +253,7 @@ func (s *DbStore) collectGarbage(ratio float32) {
 	}
 	gcnt := 0
 
-	for (gcnt < gcArraySize) && (uint64(gcnt) < s.entryCnt) {
+	for visited := uint64(0); (gcnt < gcArraySize) && (visited < s.entryCnt); visited++ {
 
 		if (s.gcPos == nil) || (s.gcPos[0] != kpIndex) {
 			it.Seek(s.gcStartPos)
@@ -235,15 +268,19 @@ func (s *DbStore) collectGarbage(ratio float32) {
 			break
 		}
 
-		gci := new(gcItem)
-		gci.idxKey = s.gcPos
-		var index dpaDBIndex
-		decodeIndex(it.Value(), &index)
-		gci.idx = index.Idx
-		// the smaller, the more likely to be gc'd
-		gci.value = getIndexGCValue(&index)
-		s.gcArray[gcnt] = gci
-		gcnt++
+		// pinned chunks are never collected
+		if s.pinCount(s.gcPos[1:]) == 0 {
+			gci := new(gcItem)
+			// the iterator reuses the key buffer
+			gci.idxKey = common.CopyBytes(s.gcPos)
+			var index dpaDBIndex
+			decodeIndex(it.Value(), &index)
+			gci.idx = index.Idx
+			// the smaller, the more likely to be gc'd
+			gci.value = getIndexGCValue(&index)
+			s.gcArray[gcnt] = gci
+			gcnt++
+		}
 		it.Next()
 		if it.Valid() {
 			s.gcPos = it.Key()
@@ -253,22 +290,29 @@ func (s *DbStore) collectGarbage(ratio float32) {
 	}
 	it.Release()
 
+	if gcnt == 0 {
+		return 0
+	}
 	cutidx := gcListSelect(s.gcArray, 0, gcnt-1, int(float32(gcnt)*ratio))
 	cutval := s.gcArray[cutidx].value
 
 	// fmt.Print(gcnt, " ", s.entryCnt, " ")
 
 	// actual gc
+	removed := 0
 	for i := 0; i < gcnt; i++ {
 		if s.gcArray[i].value <= cutval {
 			gcCounter.Inc(1)
 			s.delete(s.gcArray[i].idx, s.gcArray[i].idxKey)
+			removed++
 		}
 	}
+	s.gcCnt += uint64(removed)
 
 	// fmt.Println(s.entryCnt)
 
 	s.db.Put(keyGCPos, s.gcPos)
+	return removed
 }
 
 // Export writes all chunks from the store to a tar archive, returning the
@@ -371,11 +415,8 @@ func (s *DbStore) Cleanup() {
 			s.delete(index.Idx, getIndexKey(key[1:]))
 			errorsFound++
 		} else {
-			hasher := s.hashfunc()
-			hasher.Write(data)
-			hash := hasher.Sum(nil)
-			if !bytes.Equal(hash, key[1:]) {
-				log.Warn(fmt.Sprintf("Found invalid chunk. Hash mismatch. hash=%x, key=%x", hash, key[:]))
+			if !ValidChunk(s.hashfunc, key[1:], data) {
+				log.Warn(fmt.Sprintf("Found invalid chunk. Hash mismatch. key=%x", key[:]))
 				s.delete(index.Idx, getIndexKey(key[1:]))
 				errorsFound++
 			}
@@ -388,6 +429,10 @@ func (s *DbStore) Cleanup() {
 
 func (s *DbStore) delete(idx uint64, idxKey []byte) {
 	batch := new(leveldb.Batch)
+	if data, err := s.db.Get(getDataKey(idx)); err == nil && uint64(len(data)) <= s.size {
+		s.size -= uint64(len(data))
+		batch.Put(keyDataSize, U64ToBytes(s.size))
+	}
 	batch.Delete(idxKey)
 	batch.Delete(getDataKey(idx))
 	dbStoreDeleteCounter.Inc(1)
@@ -406,6 +451,15 @@ func (s *DbStore) Put(chunk *Chunk) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	s.put(chunk)
+}
+
+// overQuota reports whether the store reached its capacity or size quota.
+func (s *DbStore) overQuota() bool {
+	return s.entryCnt >= s.capacity || (s.sizeQuota > 0 && s.size >= s.sizeQuota)
+}
+
+func (s *DbStore) put(chunk *Chunk) {
 	ikey := getIndexKey(chunk.Key)
 	var index dpaDBIndex
 
@@ -420,7 +474,7 @@ func (s *DbStore) Put(chunk *Chunk) {
 	data := encodeData(chunk)
 	//data := ethutil.Encode([]interface{}{entry})
 
-	if s.entryCnt >= s.capacity {
+	if s.overQuota() {
 		s.collectGarbage(gcArrayFreeRatio)
 	}
 
@@ -440,6 +494,8 @@ func (s *DbStore) Put(chunk *Chunk) {
 	s.dataIdx++
 	batch.Put(keyAccessCnt, U64ToBytes(s.accessCnt))
 	s.accessCnt++
+	s.size += uint64(len(data))
+	batch.Put(keyDataSize, U64ToBytes(s.size))
 
 	s.db.Write(batch)
 	if chunk.dbStored != nil {
@@ -484,10 +540,7 @@ func (s *DbStore) Get(key Key) (chunk *Chunk, err error) {
 			return
 		}
 
-		hasher := s.hashfunc()
-		hasher.Write(data)
-		hash := hasher.Sum(nil)
-		if !bytes.Equal(hash, key) {
+		if !ValidChunk(s.hashfunc, key, data) {
 			s.delete(index.Idx, getIndexKey(key))
 			log.Warn("Invalid Chunk in Database. Please repair with command: 'swarm cleandb'")
 		}
@@ -530,7 +583,9 @@ func (s *DbStore) setCapacity(c uint64) {
 			ratio = 1
 		}
 		for s.entryCnt > c {
-			s.collectGarbage(ratio)
+			if s.collectGarbage(ratio) == 0 {
+				break // only pinned chunks left
+			}
 		}
 	}
 }
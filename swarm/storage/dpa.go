@@ -32,6 +32,8 @@ It can store anything that has a byte slice representation, so files or serialis
 
 Storage: DPA calls the Chunker to segment the input datastream of any size to a merkle hashed tree of chunks. The key of the root block is returned to the client.
 
+Encrypted storage: DPA calls an encrypting chunker which encrypts every chunk with its own random key. The returned root key carries the key of the root chunk, so only clients knowing it can read the content.
+
 Retrieval: given the key of the root block, the DPA retrieves the block chunks and reconstructs the original data and passes it back as a lazy reader. A lazy reader is a reader with on-demand delayed processing, i.e. the chunks needed to reconstruct a large file are only fetched and processed if that particular part of the document is actually read.
 
 As the chunker produces chunks, DPA dispatches them to its own chunk store
@@ -57,6 +59,8 @@ type DPA struct {
 	retrieveC chan *Chunk
 	Chunker   Chunker
 
+	encChunker Chunker // chunker of encrypted uploads
+
 	lock    sync.Mutex
 	running bool
 	quitC   chan bool
@@ -82,6 +86,7 @@ func NewDPA(store ChunkStore, params *ChunkerParams) *DPA {
 	chunker := NewTreeChunker(params)
 	return &DPA{
 		Chunker:    chunker,
+		encChunker: NewEncryptingTreeChunker(params),
 		ChunkStore: store,
 	}
 }
@@ -100,6 +105,56 @@ func (self *DPA) Store(data io.Reader, size int64, swg *sync.WaitGroup, wwg *syn
 	return self.Chunker.Split(data, size, self.storeC, swg, wwg)
 }
 
+// Public API. Main entry point for encrypted document storage. The returned
+// key includes the decryption key of the root chunk, retrieval with Retrieve
+// decrypts the content transparently.
+func (self *DPA) StoreEncrypted(data io.Reader, size int64, swg *sync.WaitGroup, wwg *sync.WaitGroup) (key Key, err error) {
+	return self.encChunker.Split(data, size, self.storeC, swg, wwg)
+}
+
+// Walk calls fn with every chunk of the content under key, as stored, parents
+// before children. Chunks not found locally are retrieved from the network.
+func (self *DPA) Walk(key Key, fn func(*Chunk) error) error {
+	walker, ok := self.Chunker.(Walker)
+	if !ok {
+		return errors.New("chunker cannot walk content")
+	}
+	return walker.Walk(key, self.retrieveC, fn)
+}
+
+// GetLocal retrieves a chunk from the local store only, without requesting
+// it from the network.
+func (self *DPA) GetLocal(key Key) (*Chunk, error) {
+	if store, ok := self.ChunkStore.(*dpaChunkStore); ok {
+		return store.localStore.Get(key)
+	}
+	return self.Get(key)
+}
+
+// GetTimeout retrieves a chunk like Get, but waits at most timeout for its
+// retrieval from the network.
+func (self *DPA) GetTimeout(key Key, timeout time.Duration) (*Chunk, error) {
+	if store, ok := self.ChunkStore.(*dpaChunkStore); ok {
+		return store.getTimeout(key, timeout)
+	}
+	return self.Get(key)
+}
+
+// PinStore returns the persistent store of the DPA, which keeps pinned chunks,
+// or nil if it has none.
+func (self *DPA) PinStore() *DbStore {
+	store := self.ChunkStore
+	if dpaStore, ok := store.(*dpaChunkStore); ok {
+		store = dpaStore.localStore
+	}
+	if localStore, ok := store.(*LocalStore); ok {
+		if dbStore, ok := localStore.DbStore.(*DbStore); ok {
+			return dbStore
+		}
+	}
+	return nil
+}
+
 func (self *DPA) Start() {
 	self.lock.Lock()
 	defer self.lock.Unlock()
@@ -199,6 +254,12 @@ func NewDpaChunkStore(localStore, netStore ChunkStore) *dpaChunkStore {
 // Get is the entrypoint for local retrieve requests
 // waits for response or times out
 func (self *dpaChunkStore) Get(key Key) (chunk *Chunk, err error) {
+	return self.getTimeout(key, searchTimeout)
+}
+
+// getTimeout retrieves a chunk, waiting at most timeout for its retrieval
+// from the network.
+func (self *dpaChunkStore) getTimeout(key Key, timeout time.Duration) (chunk *Chunk, err error) {
 	chunk, err = self.netStore.Get(key)
 	// timeout := time.Now().Add(searchTimeout)
 	if chunk.SData != nil {
@@ -206,7 +267,7 @@ func (self *dpaChunkStore) Get(key Key) (chunk *Chunk, err error) {
 		return
 	}
 	// TODO: use self.timer time.Timer and reset with defer disableTimer
-	timer := time.After(searchTimeout)
+	timer := time.After(timeout)
 	select {
 	case <-timer:
 		log.Trace(fmt.Sprintf("DPA.Get: %v request time out ", key.Log()))
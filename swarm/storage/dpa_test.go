@@ -18,6 +18,7 @@ package storage
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io"
 	"io/ioutil"
 	"os"
@@ -141,3 +142,57 @@ func TestDPA_capacity(t *testing.T) {
 		t.Errorf("Comparison error after clearing memStore.")
 	}
 }
+
+func TestDPAEncrypted(t *testing.T) {
+	dbStore := initDbStore(t)
+	localStore := &LocalStore{
+		NewMemStore(dbStore, defaultCacheCapacity),
+		dbStore,
+	}
+	dpa := NewDPA(localStore, NewChunkerParams())
+	dpa.Start()
+	defer dpa.Stop()
+
+	size := int64(300000)
+	reader, slice := testDataReaderAndSlice(int(size))
+	wg := &sync.WaitGroup{}
+	key, err := dpa.StoreEncrypted(reader, size, wg, nil)
+	if err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+	wg.Wait()
+	if !key.Encrypted() {
+		t.Fatalf("key length mismatch: have %d, want %d", len(key), EncryptedKeyLength)
+	}
+	// The stored root chunk is encrypted and padded, but knows its size
+	root, err := localStore.Get(key[:32])
+	if err != nil {
+		t.Fatalf("root chunk not found: %v", err)
+	}
+	if int64(binary.LittleEndian.Uint64(root.SData[:8])) == size {
+		t.Errorf("root chunk not encrypted")
+	}
+	if len(root.SData) != 8+4096 {
+		t.Errorf("root chunk not padded: have length %d, want %d", len(root.SData), 8+4096)
+	}
+	if root.Size != size {
+		t.Errorf("root chunk size mismatch: have %d, want %d", root.Size, size)
+	}
+	resultReader := dpa.Retrieve(key)
+	if n, err := resultReader.Size(nil); err != nil || n != size {
+		t.Fatalf("size mismatch: have %d (%v), want %d", n, err, size)
+	}
+	// Reads at any offset decrypt transparently
+	part := make([]byte, 10000)
+	if _, err := resultReader.ReadAt(part, 260000); err != nil {
+		t.Fatalf("Retrieve error: %v", err)
+	}
+	if !bytes.Equal(part, slice[260000:270000]) {
+		t.Errorf("Comparison error.")
+	}
+	// Without the decryption key only the ciphertext can be read
+	plainReader := dpa.Retrieve(key[:32])
+	if n, _ := plainReader.ReadAt(part, 0); n > 0 && bytes.Equal(part[:n], slice[:n]) {
+		t.Errorf("content readable without the decryption key")
+	}
+}
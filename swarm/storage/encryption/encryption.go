@@ -0,0 +1,121 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package encryption implements the symmetric encryption of swarm chunks.
+//
+// Chunks are encrypted with a stream cipher built from a hash function in
+// counter mode: the keystream of the i-th segment is H(H(key || i)). Every
+// chunk is encrypted with its own random key, which the reference to the chunk
+// carries next to its hash, so the same key is never used twice. Data shorter
+// than the padding length is padded with random bytes before encryption, so
+// the ciphertext does not reveal the length of the plaintext.
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+// KeyLength is the length of a chunk encryption key.
+const KeyLength = 32
+
+// Key is a chunk encryption key.
+type Key []byte
+
+// Encryption encrypts and decrypts data with a fixed key.
+type Encryption struct {
+	key      Key
+	padding  int    // length of the ciphertext, no padding if zero
+	initCtr  uint32 // counter of the first keystream segment
+	hashFunc func() hash.Hash
+}
+
+// New creates an Encryption using the given key and the hash function of the
+// keystream. A non-zero padding is the length every ciphertext is padded to.
+// initCtr is the counter of the first keystream segment, which allows several
+// Encryptions with the same key to use disjoint keystreams.
+func New(key Key, padding int, initCtr uint32, hashFunc func() hash.Hash) *Encryption {
+	return &Encryption{key: key, padding: padding, initCtr: initCtr, hashFunc: hashFunc}
+}
+
+// GenerateRandomKey returns a new random key.
+func GenerateRandomKey() (Key, error) {
+	key := make(Key, KeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt returns the encrypted copy of data. The ciphertext is as long as the
+// padding if there is one, and as long as data otherwise.
+func (e *Encryption) Encrypt(data []byte) ([]byte, error) {
+	length := len(data)
+	if e.padding > 0 {
+		if length > e.padding {
+			return nil, fmt.Errorf("data length %d longer than padding %d", length, e.padding)
+		}
+		padded := make([]byte, e.padding)
+		copy(padded, data)
+		if _, err := rand.Read(padded[length:]); err != nil {
+			return nil, err
+		}
+		data = padded
+	}
+	return e.transform(data), nil
+}
+
+// Decrypt returns the decrypted copy of data, including the padding. Callers
+// know the length of the plaintext and cut the padding off themselves.
+func (e *Encryption) Decrypt(data []byte) ([]byte, error) {
+	if e.padding > 0 && len(data) != e.padding {
+		return nil, fmt.Errorf("data length %d different from padding %d", len(data), e.padding)
+	}
+	return e.transform(data), nil
+}
+
+// transform xors data with the keystream.
+func (e *Encryption) transform(data []byte) []byte {
+	out := make([]byte, len(data))
+	hasher := e.hashFunc()
+	segmentSize := hasher.Size()
+
+	ctr := make([]byte, 4)
+	for i := 0; i*segmentSize < len(data); i++ {
+		binary.LittleEndian.PutUint32(ctr, e.initCtr+uint32(i))
+
+		hasher.Reset()
+		hasher.Write(e.key)
+		hasher.Write(ctr)
+		segmentKey := hasher.Sum(nil)
+
+		hasher.Reset()
+		hasher.Write(segmentKey)
+		keystream := hasher.Sum(nil)
+
+		start := i * segmentSize
+		end := start + segmentSize
+		if end > len(data) {
+			end = len(data)
+		}
+		for j := start; j < end; j++ {
+			out[j] = data[j] ^ keystream[j-start]
+		}
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto/sha3"
+)
+
+func TestEncryptRoundtrip(t *testing.T) {
+	key, err := GenerateRandomKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := New(key, 0, 0, sha3.NewKeccak256)
+
+	for _, size := range []int{0, 1, 31, 32, 33, 4096, 4104} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		encrypted, err := enc.Encrypt(data)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if len(encrypted) != size {
+			t.Fatalf("size %d: ciphertext length mismatch: have %d", size, len(encrypted))
+		}
+		if size > 0 && bytes.Equal(encrypted, data) {
+			t.Errorf("size %d: ciphertext equals plaintext", size)
+		}
+		if decrypted, _ := enc.Decrypt(encrypted); !bytes.Equal(decrypted, data) {
+			t.Errorf("size %d: decrypted data mismatch", size)
+		}
+	}
+}
+
+func TestEncryptPadding(t *testing.T) {
+	key, _ := GenerateRandomKey()
+	enc := New(key, 4096, 0, sha3.NewKeccak256)
+
+	for _, size := range []int{0, 1, 33, 4096} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		encrypted, err := enc.Encrypt(data)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if len(encrypted) != 4096 {
+			t.Fatalf("size %d: ciphertext length mismatch: have %d, want 4096", size, len(encrypted))
+		}
+		decrypted, err := enc.Decrypt(encrypted)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(decrypted[:size], data) {
+			t.Errorf("size %d: decrypted data mismatch", size)
+		}
+	}
+	if _, err := enc.Encrypt(make([]byte, 4097)); err == nil {
+		t.Errorf("expected error for data longer than the padding")
+	}
+	if _, err := enc.Decrypt(make([]byte, 4095)); err == nil {
+		t.Errorf("expected error for ciphertext shorter than the padding")
+	}
+}
+
+func TestEncryptKeys(t *testing.T) {
+	data := make([]byte, 100)
+
+	key1, _ := GenerateRandomKey()
+	key2, _ := GenerateRandomKey()
+	if bytes.Equal(key1, key2) {
+		t.Fatalf("random keys are equal")
+	}
+	enc1, _ := New(key1, 0, 0, sha3.NewKeccak256).Encrypt(data)
+	enc2, _ := New(key2, 0, 0, sha3.NewKeccak256).Encrypt(data)
+	if bytes.Equal(enc1, enc2) {
+		t.Errorf("different keys produce the same ciphertext")
+	}
+	if bytes.Equal(enc1[:32], enc1[32:64]) {
+		t.Errorf("keystream repeats across segments")
+	}
+	// Decrypting with a wrong key fails
+	if dec, _ := New(key2, 0, 0, sha3.NewKeccak256).Decrypt(enc1); bytes.Equal(dec, data) {
+		t.Errorf("decrypted with the wrong key")
+	}
+	// Keystreams starting at different counters are disjoint
+	if enc3, _ := New(key1, 0, 1, sha3.NewKeccak256).Encrypt(data); bytes.Equal(enc3[:32], enc1[:32]) {
+		t.Errorf("keystream does not depend on the initial counter")
+	}
+}
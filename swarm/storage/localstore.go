@@ -40,6 +40,8 @@ func NewLocalStore(hash SwarmHasher, params *StoreParams) (*LocalStore, error) {
 	if err != nil {
 		return nil, err
 	}
+	dbStore.SetSizeQuota(params.DbSizeQuota)
+	dbStore.SetPinQuota(params.PinQuota)
 	return &LocalStore{
 		memStore: NewMemStore(dbStore, params.CacheCapacity),
 		DbStore:  dbStore,
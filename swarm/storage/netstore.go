@@ -53,6 +53,8 @@ type CloudStore interface {
 type StoreParams struct {
 	ChunkDbPath   string
 	DbCapacity    uint64
+	DbSizeQuota   uint64 // maximum size of the chunk database in bytes, 0 is unlimited
+	PinQuota      uint64 // maximum size of the pinned chunks in bytes, 0 is unlimited, DefaultPinQuota by default
 	CacheCapacity uint
 	Radius        int
 }
@@ -61,6 +63,7 @@ type StoreParams struct {
 func NewDefaultStoreParams() (self *StoreParams) {
 	return &StoreParams{
 		DbCapacity:    defaultDbCapacity,
+		PinQuota:      DefaultPinQuota,
 		CacheCapacity: defaultCacheCapacity,
 		Radius:        defaultRadius,
 	}
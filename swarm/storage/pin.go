@@ -0,0 +1,264 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"errors"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// DefaultPinQuota is the maximum total size of the pinned chunks unless
+// configured otherwise.
+const DefaultPinQuota = 1 << 30
+
+var (
+	// ErrPinQuotaExceeded is returned if pinning a chunk would exceed the
+	// pin quota of the store.
+	ErrPinQuotaExceeded = errors.New("pin quota exceeded")
+
+	errNotPinned = errors.New("chunk not pinned")
+)
+
+// PinnedRoot is the record of pinned content, all chunks of which are pinned.
+type PinnedRoot struct {
+	Key    Key    `json:"key"`
+	Chunks uint64 `json:"chunks"` // number of chunks pinned for the content
+	Size   uint64 `json:"size"`   // total size of the chunks
+}
+
+// DbStats are the statistics of a DbStore.
+type DbStats struct {
+	Entries      uint64 `json:"entries"`
+	Size         uint64 `json:"size"`
+	Capacity     uint64 `json:"capacity"`
+	SizeQuota    uint64 `json:"sizeQuota"`
+	PinnedChunks uint64 `json:"pinnedChunks"`
+	PinnedSize   uint64 `json:"pinnedSize"`
+	PinQuota     uint64 `json:"pinQuota"`
+	PinnedRoots  uint64 `json:"pinnedRoots"`
+	Collected    uint64 `json:"collected"` // chunks garbage collected since startup
+}
+
+func getPinKey(key Key) []byte {
+	return append([]byte{kpPin}, key...)
+}
+
+func getPinRootKey(key Key) []byte {
+	return append([]byte{kpPinRoot}, key...)
+}
+
+// pinCount returns the number of pins of a chunk, the lock must be held.
+func (s *DbStore) pinCount(key []byte) uint64 {
+	data, err := s.db.Get(getPinKey(key))
+	if err != nil {
+		return 0
+	}
+	return BytesToU64(data)
+}
+
+// PinCount returns the number of pinned roots which reference the chunk.
+func (s *DbStore) PinCount(key Key) uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.pinCount(key)
+}
+
+// Pin stores the chunk unless it is stored already and increments its pin
+// count. Pinned chunks are never garbage collected. Pinning a chunk not pinned
+// yet fails with ErrPinQuotaExceeded if the pinned chunks would exceed the pin
+// quota.
+func (s *DbStore) Pin(chunk *Chunk) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(chunk.SData) == 0 {
+		return errors.New("cannot pin chunk without data")
+	}
+	count := s.pinCount(chunk.Key)
+	size := uint64(len(chunk.SData))
+	if count == 0 && s.pinQuota > 0 && s.pinSize+size > s.pinQuota {
+		return ErrPinQuotaExceeded
+	}
+	// store the chunk first, the pin protects it from garbage collection
+	// from then on
+	s.put(&Chunk{Key: chunk.Key, SData: chunk.SData})
+
+	batch := new(leveldb.Batch)
+	batch.Put(getPinKey(chunk.Key), U64ToBytes(count+1))
+	if count == 0 {
+		s.pinCnt++
+		s.pinSize += size
+		batch.Put(keyPinCnt, U64ToBytes(s.pinCnt))
+		batch.Put(keyPinSize, U64ToBytes(s.pinSize))
+	}
+	return s.db.Write(batch)
+}
+
+// Unpin decrements the pin count of a chunk, which is subject to garbage
+// collection again once it is not pinned anymore.
+func (s *DbStore) Unpin(key Key) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.pinCount(key) == 0 {
+		return errNotPinned
+	}
+	batch := new(leveldb.Batch)
+	pinCnt, pinSize := s.unpin(batch, key, s.pinCnt, s.pinSize)
+	return s.writePins(batch, pinCnt, pinSize)
+}
+
+// UnpinRoot removes the record of pinned content and decrements the pin counts
+// of its chunks in a single write, so that a failure leaves the pins intact.
+// Chunks which are not pinned are skipped.
+func (s *DbStore) UnpinRoot(root Key, chunks []Key) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, err := s.db.Get(getPinRootKey(root)); err != nil {
+		return errNotPinned
+	}
+	batch := new(leveldb.Batch)
+	batch.Delete(getPinRootKey(root))
+	pinCnt, pinSize := s.pinCnt, s.pinSize
+	for _, key := range chunks {
+		if s.pinCount(key) > 0 {
+			pinCnt, pinSize = s.unpin(batch, key, pinCnt, pinSize)
+		}
+	}
+	return s.writePins(batch, pinCnt, pinSize)
+}
+
+// unpin adds the decrement of the pin count of a pinned chunk to the batch and
+// returns the updated pin totals. The lock must be held and the keys of a
+// batch must be distinct.
+func (s *DbStore) unpin(batch *leveldb.Batch, key Key, pinCnt, pinSize uint64) (uint64, uint64) {
+	count := s.pinCount(key)
+	if count > 1 {
+		batch.Put(getPinKey(key), U64ToBytes(count-1))
+		return pinCnt, pinSize
+	}
+	batch.Delete(getPinKey(key))
+	var index dpaDBIndex
+	if idata, err := s.db.Get(getIndexKey(key)); err == nil {
+		decodeIndex(idata, &index)
+		if data, err := s.db.Get(getDataKey(index.Idx)); err == nil && uint64(len(data)) <= pinSize {
+			pinSize -= uint64(len(data))
+		}
+	}
+	return pinCnt - 1, pinSize
+}
+
+// writePins writes the batch along with the pin totals and updates the totals
+// once written, the lock must be held.
+func (s *DbStore) writePins(batch *leveldb.Batch, pinCnt, pinSize uint64) error {
+	batch.Put(keyPinCnt, U64ToBytes(pinCnt))
+	batch.Put(keyPinSize, U64ToBytes(pinSize))
+	if err := s.db.Write(batch); err != nil {
+		return err
+	}
+	s.pinCnt, s.pinSize = pinCnt, pinSize
+	return nil
+}
+
+// PutPinnedRoot records pinned content.
+func (s *DbStore) PutPinnedRoot(root *PinnedRoot) error {
+	data, err := rlp.EncodeToBytes(root)
+	if err != nil {
+		return err
+	}
+	s.db.Put(getPinRootKey(root.Key), data)
+	return nil
+}
+
+// DeletePinnedRoot removes the record of pinned content.
+func (s *DbStore) DeletePinnedRoot(key Key) error {
+	return s.db.Delete(getPinRootKey(key))
+}
+
+// PinnedRoot returns the record of the pinned content with the given key, nil
+// if the content is not pinned.
+func (s *DbStore) PinnedRoot(key Key) *PinnedRoot {
+	data, err := s.db.Get(getPinRootKey(key))
+	if err != nil {
+		return nil
+	}
+	root := new(PinnedRoot)
+	if err := rlp.DecodeBytes(data, root); err != nil {
+		return nil
+	}
+	return root
+}
+
+// PinnedRoots returns the records of all pinned content.
+func (s *DbStore) PinnedRoots() []*PinnedRoot {
+	it := s.db.NewIterator()
+	defer it.Release()
+
+	var roots []*PinnedRoot
+	for ok := it.Seek([]byte{kpPinRoot}); ok && it.Key()[0] == kpPinRoot; ok = it.Next() {
+		root := new(PinnedRoot)
+		if err := rlp.DecodeBytes(it.Value(), root); err != nil {
+			continue
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// SetSizeQuota sets the maximum total size of the stored chunks, beyond which
+// unpinned chunks are garbage collected. A zero quota disables the limit.
+func (s *DbStore) SetSizeQuota(quota uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sizeQuota = quota
+	for s.sizeQuota > 0 && s.size > s.sizeQuota {
+		if s.collectGarbage(gcArrayFreeRatio) == 0 {
+			break // only pinned chunks left
+		}
+	}
+}
+
+// SetPinQuota sets the maximum total size of the pinned chunks. A zero quota
+// disables the limit.
+func (s *DbStore) SetPinQuota(quota uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pinQuota = quota
+}
+
+// Stats returns the statistics of the store.
+func (s *DbStore) Stats() *DbStats {
+	roots := uint64(len(s.PinnedRoots()))
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return &DbStats{
+		Entries:      s.entryCnt,
+		Size:         s.size,
+		Capacity:     s.capacity,
+		SizeQuota:    s.sizeQuota,
+		PinnedChunks: s.pinCnt,
+		PinnedSize:   s.pinSize,
+		PinQuota:     s.pinQuota,
+		PinnedRoots:  roots,
+		Collected:    s.gcCnt,
+	}
+}
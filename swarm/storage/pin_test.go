@@ -0,0 +1,244 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"testing"
+)
+
+func newTestChunk(hasher SwarmHasher, size int) *Chunk {
+	data := make([]byte, 8+size)
+	binary.LittleEndian.PutUint64(data, uint64(size))
+	rand.Read(data[8:])
+	h := hasher()
+	h.Write(data)
+	return &Chunk{Key: Key(h.Sum(nil)), SData: data, Size: int64(size)}
+}
+
+func TestDbStorePin(t *testing.T) {
+	m := initDbStore(t)
+	defer m.Close()
+
+	chunk := newTestChunk(m.hashfunc, 100)
+	if err := m.Pin(chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Pin(chunk); err != nil {
+		t.Fatal(err)
+	}
+	if n := m.PinCount(chunk.Key); n != 2 {
+		t.Fatalf("expected pin count 2, got %d", n)
+	}
+	// pinning stores the chunk
+	if _, err := m.Get(chunk.Key); err != nil {
+		t.Fatalf("pinned chunk not stored: %v", err)
+	}
+	stats := m.Stats()
+	if stats.PinnedChunks != 1 || stats.PinnedSize != 108 || stats.Entries != 1 || stats.Size != 108 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := m.Unpin(chunk.Key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Unpin(chunk.Key); err != errNotPinned {
+		t.Fatalf("expected %v unpinning unpinned chunk, got %v", errNotPinned, err)
+	}
+	if stats := m.Stats(); stats.PinnedChunks != 0 || stats.PinnedSize != 0 {
+		t.Fatalf("unexpected stats after unpinning %+v", stats)
+	}
+}
+
+func TestDbStorePinnedRoots(t *testing.T) {
+	m := initDbStore(t)
+	defer m.Close()
+
+	root := &PinnedRoot{Key: newTestChunk(m.hashfunc, 1).Key, Chunks: 3, Size: 1000}
+	if err := m.PutPinnedRoot(root); err != nil {
+		t.Fatal(err)
+	}
+	if r := m.PinnedRoot(root.Key); r == nil || r.Chunks != 3 || r.Size != 1000 {
+		t.Fatalf("unexpected pinned root %+v", r)
+	}
+	if roots := m.PinnedRoots(); len(roots) != 1 || roots[0].Key.Hex() != root.Key.Hex() {
+		t.Fatalf("unexpected pinned roots %v", roots)
+	}
+	if err := m.DeletePinnedRoot(root.Key); err != nil {
+		t.Fatal(err)
+	}
+	if m.PinnedRoot(root.Key) != nil || len(m.PinnedRoots()) != 0 {
+		t.Fatal("pinned root not deleted")
+	}
+}
+
+func TestDbStoreUnpinRoot(t *testing.T) {
+	m := initDbStore(t)
+	defer m.Close()
+
+	shared, own := newTestChunk(m.hashfunc, 100), newTestChunk(m.hashfunc, 100)
+	for _, chunk := range []*Chunk{shared, shared, own} {
+		if err := m.Pin(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := &PinnedRoot{Key: own.Key, Chunks: 2, Size: 216}
+	if err := m.PutPinnedRoot(root); err != nil {
+		t.Fatal(err)
+	}
+	chunks := []Key{shared.Key, own.Key}
+	if err := m.UnpinRoot(root.Key, chunks); err != nil {
+		t.Fatal(err)
+	}
+	if m.PinnedRoot(root.Key) != nil {
+		t.Fatal("pinned root not deleted")
+	}
+	if m.PinCount(shared.Key) != 1 || m.PinCount(own.Key) != 0 {
+		t.Fatalf("unexpected pin counts %d, %d", m.PinCount(shared.Key), m.PinCount(own.Key))
+	}
+	// unpinning again must not release the pins of other content
+	if err := m.UnpinRoot(root.Key, chunks); err != errNotPinned {
+		t.Fatalf("expected %v, got %v", errNotPinned, err)
+	}
+	if stats := m.Stats(); m.PinCount(shared.Key) != 1 || stats.PinnedChunks != 1 || stats.PinnedSize != 108 {
+		t.Fatalf("unexpected stats after unpinning twice %+v", stats)
+	}
+}
+
+func TestDbStoreGCPinned(t *testing.T) {
+	m := initDbStore(t)
+	defer m.Close()
+	m.setCapacity(100)
+
+	var pinned []*Chunk
+	for i := 0; i < 10; i++ {
+		chunk := newTestChunk(m.hashfunc, 100)
+		if err := m.Pin(chunk); err != nil {
+			t.Fatal(err)
+		}
+		pinned = append(pinned, chunk)
+	}
+	for i := 0; i < 1000; i++ {
+		m.Put(newTestChunk(m.hashfunc, 100))
+	}
+	if m.Stats().Collected == 0 {
+		t.Fatal("no chunks garbage collected")
+	}
+	for _, chunk := range pinned {
+		if _, err := m.Get(chunk.Key); err != nil {
+			t.Fatalf("pinned chunk %v garbage collected", chunk.Key)
+		}
+	}
+
+	// Reducing the capacity below the pinned chunks keeps them
+	m.setCapacity(5)
+	if n := m.Stats().Entries; n != 10 {
+		t.Fatalf("expected the 10 pinned entries to remain, got %d", n)
+	}
+}
+
+func TestDbStoreSizeQuota(t *testing.T) {
+	m := initDbStore(t)
+	defer m.Close()
+	m.SetSizeQuota(50 * 4104)
+
+	for i := 0; i < 500; i++ {
+		m.Put(newTestChunk(m.hashfunc, 4096))
+	}
+	stats := m.Stats()
+	if stats.Size > 51*4104 {
+		t.Fatalf("store size %d exceeds quota %d", stats.Size, stats.SizeQuota)
+	}
+	if stats.Size != uint64(stats.Entries)*4104 {
+		t.Fatalf("size %d does not match %d entries", stats.Size, stats.Entries)
+	}
+}
+
+func TestDbStorePinQuota(t *testing.T) {
+	m := initDbStore(t)
+	defer m.Close()
+	m.SetPinQuota(250)
+
+	first, second := newTestChunk(m.hashfunc, 100), newTestChunk(m.hashfunc, 200)
+	if err := m.Pin(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Pin(second); err != ErrPinQuotaExceeded {
+		t.Fatalf("expected %v, got %v", ErrPinQuotaExceeded, err)
+	}
+	// pinning a pinned chunk again takes no space
+	if err := m.Pin(first); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDPAWalk(t *testing.T) {
+	dbStore := initDbStore(t)
+	defer dbStore.Close()
+	dpa := NewDPA(&LocalStore{NewMemStore(dbStore, defaultCacheCapacity), dbStore}, NewChunkerParams())
+	dpa.Start()
+	defer dpa.Stop()
+
+	size := int64(300000)
+	for _, encrypted := range []bool{false, true} {
+		reader, _ := testDataReaderAndSlice(int(size))
+		store := dpa.Store
+		if encrypted {
+			store = dpa.StoreEncrypted
+		}
+		wg := &sync.WaitGroup{}
+		key, err := store(reader, size, wg, nil)
+		if err != nil {
+			t.Fatalf("Store error: %v", err)
+		}
+		wg.Wait()
+
+		var chunks, data int64
+		seen := make(map[string]bool)
+		err = dpa.Walk(key, func(chunk *Chunk) error {
+			if seen[string(chunk.Key)] {
+				t.Errorf("chunk %v visited twice", chunk.Key)
+			}
+			seen[string(chunk.Key)] = true
+			chunks++
+			data += int64(len(chunk.SData))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk error: %v", err)
+		}
+		// 74 data chunks referenced by a single root, or by two intermediate
+		// chunks if the references carry the decryption keys
+		want := int64(75)
+		if encrypted {
+			want = 77
+		}
+		if chunks != want {
+			t.Errorf("encrypted=%v: expected %d chunks, got %d", encrypted, want, chunks)
+		}
+		if data < size {
+			t.Errorf("encrypted=%v: walked %d bytes, less than the content size %d", encrypted, data, size)
+		}
+		if err := dpa.Walk(key, func(*Chunk) error { return errNotPinned }); err != errNotPinned {
+			t.Errorf("expected the callback error to abort the walk, got %v", err)
+		}
+	}
+}
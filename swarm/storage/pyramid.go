@@ -137,13 +137,11 @@ func NewPyramidChunker(params *ChunkerParams) (self *PyramidChunker) {
 }
 
 func (self *PyramidChunker) Join(key Key, chunkC chan *Chunk) LazySectionReader {
-	return &LazyChunkReader{
-		key:       key,
-		chunkC:    chunkC,
-		chunkSize: self.chunkSize,
-		branches:  self.branches,
-		hashSize:  self.hashSize,
-	}
+	return newLazyChunkReader(key, chunkC, self.chunkSize, self.hashSize)
+}
+
+func (self *PyramidChunker) Walk(key Key, chunkC chan *Chunk, fn func(*Chunk) error) error {
+	return newLazyChunkReader(key, chunkC, self.chunkSize, self.hashSize).walk(key, fn)
 }
 
 func (self *PyramidChunker) incrementWorkerCount() {
@@ -0,0 +1,172 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+)
+
+/*
+Resource update chunks carry the signed updates of mutable resources. Unlike
+content chunks they are not addressed by the hash of their data, but by
+
+	keccak256(topic | owner | period | version)
+
+so the address of any update can be computed from the owner and the topic of
+the resource alone. The data of such a chunk is
+
+	span (8 bytes LE) | topic (32) | period (4 BE) | version (4 BE) | signature (65) | data
+
+where the signature of the owner covers topic, period, version and data.
+*/
+
+// resourceHeaderLength is the length of the update fields before the data.
+const resourceHeaderLength = common.HashLength + 4 + 4 + 65
+
+// ResourceMaxDataLength is the maximum length of the data of a resource update.
+const ResourceMaxDataLength = int(DefaultBranches)*common.HashLength - resourceHeaderLength
+
+var (
+	errInvalidResourceChunk = errors.New("invalid resource update chunk")
+	errResourceNotSigned    = errors.New("resource update not signed")
+)
+
+// ResourceUpdate is a signed update of a mutable resource.
+type ResourceUpdate struct {
+	Topic     common.Hash   `json:"topic"`
+	Period    uint32        `json:"period"`
+	Version   uint32        `json:"version"`
+	Data      hexutil.Bytes `json:"data"`
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// ResourceKey returns the chunk key of the update of the given owner and topic
+// in the given period and version.
+func ResourceKey(owner common.Address, topic common.Hash, period, version uint32) Key {
+	buf := make([]byte, common.HashLength+common.AddressLength+8)
+	copy(buf, topic[:])
+	copy(buf[common.HashLength:], owner[:])
+	binary.BigEndian.PutUint32(buf[common.HashLength+common.AddressLength:], period)
+	binary.BigEndian.PutUint32(buf[common.HashLength+common.AddressLength+4:], version)
+	return Key(crypto.Keccak256(buf))
+}
+
+// digest returns the hash signed by the owner.
+func (u *ResourceUpdate) digest() []byte {
+	var pv [8]byte
+	binary.BigEndian.PutUint32(pv[:4], u.Period)
+	binary.BigEndian.PutUint32(pv[4:], u.Version)
+	return crypto.Keccak256(u.Topic[:], pv[:], u.Data)
+}
+
+// Sign signs the update with the private key of the owner.
+func (u *ResourceUpdate) Sign(prv *ecdsa.PrivateKey) error {
+	if len(u.Data) > ResourceMaxDataLength {
+		return fmt.Errorf("resource update data too large: %d > %d", len(u.Data), ResourceMaxDataLength)
+	}
+	sig, err := crypto.Sign(u.digest(), prv)
+	if err != nil {
+		return err
+	}
+	u.Signature = sig
+	return nil
+}
+
+// Owner recovers the address of the owner who signed the update.
+func (u *ResourceUpdate) Owner() (common.Address, error) {
+	if len(u.Signature) != 65 {
+		return common.Address{}, errResourceNotSigned
+	}
+	pub, err := crypto.SigToPub(u.digest(), u.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Key returns the chunk key of the update, derived from its signer.
+func (u *ResourceUpdate) Key() (Key, error) {
+	owner, err := u.Owner()
+	if err != nil {
+		return nil, err
+	}
+	return ResourceKey(owner, u.Topic, u.Period, u.Version), nil
+}
+
+// Chunk encodes the signed update as chunk.
+func (u *ResourceUpdate) Chunk() (*Chunk, error) {
+	if len(u.Data) > ResourceMaxDataLength {
+		return nil, fmt.Errorf("resource update data too large: %d > %d", len(u.Data), ResourceMaxDataLength)
+	}
+	key, err := u.Key()
+	if err != nil {
+		return nil, err
+	}
+	size := resourceHeaderLength + len(u.Data)
+	data := make([]byte, 8+size)
+	binary.LittleEndian.PutUint64(data, uint64(size))
+	copy(data[8:], u.Topic[:])
+	binary.BigEndian.PutUint32(data[8+common.HashLength:], u.Period)
+	binary.BigEndian.PutUint32(data[8+common.HashLength+4:], u.Version)
+	copy(data[8+common.HashLength+8:], u.Signature)
+	copy(data[8+resourceHeaderLength:], u.Data)
+
+	return &Chunk{Key: key, SData: data, Size: int64(size)}, nil
+}
+
+// ParseResourceUpdate decodes the data of a resource update chunk. The
+// signature is not verified, use Key to check it against the chunk key.
+func ParseResourceUpdate(data []byte) (*ResourceUpdate, error) {
+	if len(data) < 8+resourceHeaderLength {
+		return nil, errInvalidResourceChunk
+	}
+	if size := binary.LittleEndian.Uint64(data); size != uint64(len(data)-8) {
+		return nil, errInvalidResourceChunk
+	}
+	u := &ResourceUpdate{
+		Period:    binary.BigEndian.Uint32(data[8+common.HashLength:]),
+		Version:   binary.BigEndian.Uint32(data[8+common.HashLength+4:]),
+		Signature: common.CopyBytes(data[8+common.HashLength+8 : 8+resourceHeaderLength]),
+		Data:      common.CopyBytes(data[8+resourceHeaderLength:]),
+	}
+	copy(u.Topic[:], data[8:])
+	return u, nil
+}
+
+// ValidChunk reports whether data is valid content for the chunk key: either
+// its hash, or a resource update signed by the owner the key is derived from.
+func ValidChunk(hasher SwarmHasher, key Key, data []byte) bool {
+	h := hasher()
+	h.Write(data)
+	if bytes.Equal(h.Sum(nil), key) {
+		return true
+	}
+	u, err := ParseResourceUpdate(data)
+	if err != nil {
+		return false
+	}
+	ukey, err := u.Key()
+	return err == nil && bytes.Equal(ukey, key)
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+)
+
+func TestResourceUpdateChunk(t *testing.T) {
+	prv, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(prv.PublicKey)
+
+	u := &ResourceUpdate{
+		Topic:   crypto.Keccak256Hash([]byte("price-feed")),
+		Period:  3,
+		Version: 2,
+		Data:    []byte("42"),
+	}
+	if _, err := u.Chunk(); err != errResourceNotSigned {
+		t.Fatalf("unsigned update: expected %v, got %v", errResourceNotSigned, err)
+	}
+	if err := u.Sign(prv); err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := u.Chunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ResourceKey(owner, u.Topic, 3, 2); !bytes.Equal(chunk.Key, want) {
+		t.Fatalf("chunk key mismatch: have %v, want %v", chunk.Key, want)
+	}
+	hasher := MakeHashFunc(SHA3Hash)
+	if !ValidChunk(hasher, chunk.Key, chunk.SData) {
+		t.Fatal("resource chunk does not validate")
+	}
+
+	parsed, err := ParseResourceUpdate(chunk.SData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Topic != u.Topic || parsed.Period != 3 || parsed.Version != 2 || !bytes.Equal(parsed.Data, u.Data) {
+		t.Fatalf("parsed update mismatch: %+v", parsed)
+	}
+	if signer, err := parsed.Owner(); err != nil || signer != owner {
+		t.Fatalf("owner mismatch: have %x (%v), want %x", signer, err, owner)
+	}
+
+	// A chunk under the key of another version, or with tampered data, is invalid
+	if ValidChunk(hasher, ResourceKey(owner, u.Topic, 3, 3), chunk.SData) {
+		t.Error("chunk validates under the key of another version")
+	}
+	tampered := append([]byte{}, chunk.SData...)
+	tampered[len(tampered)-1] ^= 1
+	if ValidChunk(hasher, chunk.Key, tampered) {
+		t.Error("tampered chunk validates")
+	}
+}
+
+func TestResourceUpdateTooLarge(t *testing.T) {
+	prv, _ := crypto.GenerateKey()
+	u := &ResourceUpdate{Period: 1, Version: 1, Data: make([]byte, ResourceMaxDataLength)}
+	if err := u.Sign(prv); err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := u.Chunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunk.SData) != 8+int(DefaultBranches)*32 {
+		t.Fatalf("unexpected chunk length %d", len(chunk.SData))
+	}
+	u.Data = append(u.Data, 0)
+	if err := u.Sign(prv); err == nil {
+		t.Fatal("expected error signing oversized update")
+	}
+}
+
+func TestDbStoreResourceChunk(t *testing.T) {
+	m := initDbStore(t)
+	defer m.Close()
+
+	prv, _ := crypto.GenerateKey()
+	u := &ResourceUpdate{Period: 1, Version: 1, Data: []byte("data")}
+	u.Sign(prv)
+	chunk, err := u.Chunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Put(chunk)
+	stored, err := m.Get(chunk.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored.SData, chunk.SData) {
+		t.Fatal("stored resource chunk mismatch")
+	}
+	// Get deletes invalid chunks, so reading again must still succeed
+	if _, err := m.Get(chunk.Key); err != nil {
+		t.Fatal(err)
+	}
+}
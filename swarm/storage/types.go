@@ -27,6 +27,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/bmt"
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto/sha3"
+	"github.com/Ethereum-Reloaded/ETHR-Go/swarm/storage/encryption"
 )
 
 type Hasher func() hash.Hash
@@ -73,6 +74,15 @@ func (h Key) bits(i, j uint) uint {
 	return res
 }
 
+// EncryptedKeyLength is the length of keys referring to encrypted content,
+// holding the hash of the root chunk followed by its decryption key.
+const EncryptedKeyLength = common.HashLength + encryption.KeyLength
+
+// Encrypted reports whether the key refers to encrypted content.
+func (key Key) Encrypted() bool {
+	return len(key) == EncryptedKeyLength
+}
+
 func IsZeroKey(key Key) bool {
 	return len(key) == 0 || bytes.Equal(key, ZeroKey)
 }
@@ -116,8 +126,12 @@ func (key Key) MarshalJSON() (out []byte, err error) {
 
 func (key *Key) UnmarshalJSON(value []byte) error {
 	s := string(value)
-	*key = make([]byte, 32)
 	h := common.Hex2Bytes(s[1 : len(s)-1])
+	if len(h) == EncryptedKeyLength {
+		*key = h
+		return nil
+	}
+	*key = make([]byte, 32)
 	copy(*key, h)
 	return nil
 }
@@ -224,6 +238,17 @@ type Joiner interface {
 	Join(key Key, chunkC chan *Chunk) LazySectionReader
 }
 
+// Walker is implemented by chunkers which can enumerate the chunks of content.
+type Walker interface {
+	/*
+	   Walk retrieves all chunks of the content under the root key through the
+	   Chunk channel and calls the function with each chunk as it is stored,
+	   parents before children. Walking stops at the first error returned by the
+	   function or the first chunk not found.
+	*/
+	Walk(Key, chan *Chunk, func(*Chunk) error) error
+}
+
 type Chunker interface {
 	Joiner
 	Splitter
@@ -43,17 +43,14 @@ func NewTestSwarmServer(t *testing.T) *TestSwarmServer {
 		os.RemoveAll(dir)
 		t.Fatal(err)
 	}
-	chunker := storage.NewTreeChunker(storage.NewChunkerParams())
-	dpa := &storage.DPA{
-		Chunker:    chunker,
-		ChunkStore: localStore,
-	}
+	dpa := storage.NewDPA(localStore, storage.NewChunkerParams())
 	dpa.Start()
 	a := api.NewApi(dpa, nil)
 	srv := httptest.NewServer(httpapi.NewServer(a))
 	return &TestSwarmServer{
 		Server: srv,
 		Dpa:    dpa,
+		Api:    a,
 		dir:    dir,
 	}
 }
@@ -62,6 +59,7 @@ type TestSwarmServer struct {
 	*httptest.Server
 
 	Dpa *storage.DPA
+	Api *api.Api
 	dir string
 }
 
@@ -0,0 +1,63 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package mailserver
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter allows one request per interval for each peer.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time // time of the last request allowed for each peer
+}
+
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	return &rateLimiter{interval: interval, last: make(map[string]time.Time)}
+}
+
+// allow reports whether a request of the peer is allowed, and records it if so.
+func (l *rateLimiter) allow(peerID []byte) bool {
+	if l.interval <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := l.last[string(peerID)]; ok && now.Sub(last) < l.interval {
+		return false
+	}
+	l.last[string(peerID)] = now
+	return true
+}
+
+// expire forgets the peers whose requests are not limited anymore.
+func (l *rateLimiter) expire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for id, last := range l.last {
+		if now.Sub(last) >= l.interval {
+			delete(l.last, id)
+		}
+	}
+}
@@ -17,8 +17,11 @@
 package mailserver
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
@@ -29,11 +32,34 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
+const (
+	// DefaultMaxLimit is the maximum number of envelopes delivered per request
+	// unless configured otherwise.
+	DefaultMaxLimit = 1000
+
+	pruneCycle     = time.Minute // interval of pruning the archive and the rate limits
+	pruneBatchSize = 1000        // number of envelopes deleted per database write when pruning
+)
+
+// Config holds the limits of a mail server. It must be set before Init.
+type Config struct {
+	MaxLimit    uint32        // maximum number of envelopes delivered per paginated request, DefaultMaxLimit if zero
+	LegacyLimit uint32        // maximum number of envelopes delivered per non-paginated request, MaxLimit if zero
+	RateLimit   time.Duration // minimum interval between requests of a peer, unlimited if zero
+	Retention   time.Duration // age after which archived envelopes are pruned, kept forever if zero
+}
+
 type WMailServer struct {
+	Config
+
 	db  *leveldb.DB
 	w   *whisper.Whisper
 	pow float64
 	key []byte
+
+	limiter *rateLimiter
+	quit    chan struct{}
+	wg      sync.WaitGroup
 }
 
 type DBKey struct {
@@ -70,6 +96,13 @@ func (s *WMailServer) Init(shh *whisper.Whisper, path string, password string, p
 
 	s.w = shh
 	s.pow = pow
+	if s.MaxLimit == 0 {
+		s.MaxLimit = DefaultMaxLimit
+	}
+	if s.LegacyLimit == 0 {
+		s.LegacyLimit = s.MaxLimit
+	}
+	s.limiter = newRateLimiter(s.RateLimit)
 
 	MailServerKeyID, err := s.w.AddSymKeyFromPassword(password)
 	if err != nil {
@@ -79,15 +112,80 @@ func (s *WMailServer) Init(shh *whisper.Whisper, path string, password string, p
 	if err != nil {
 		return fmt.Errorf("save symmetric key: %s", err)
 	}
+
+	s.quit = make(chan struct{})
+	s.wg.Add(1)
+	go s.pruneLoop()
 	return nil
 }
 
 func (s *WMailServer) Close() {
+	if s.quit != nil {
+		close(s.quit)
+		s.wg.Wait()
+		s.quit = nil
+	}
 	if s.db != nil {
 		s.db.Close()
 	}
 }
 
+// pruneLoop periodically removes the envelopes older than the retention period
+// from the archive and forgets the peers not rate limited anymore.
+func (s *WMailServer) pruneLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(pruneCycle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if s.Retention > 0 {
+				cutoff := time.Now().Add(-s.Retention).Unix()
+				if n, err := s.Prune(uint32(cutoff)); err != nil {
+					log.Error(fmt.Sprintf("Pruning the mail server archive failed: %s", err))
+				} else if n > 0 {
+					log.Debug("Pruned mail server archive", "envelopes", n)
+				}
+			}
+			s.limiter.expire()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// Prune removes the archived envelopes sent before the given time and returns
+// their number. The envelopes are deleted in batches of pruneBatchSize.
+func (s *WMailServer) Prune(before uint32) (int, error) {
+	var zero common.Hash
+	ku := NewDbKey(before, zero)
+	i := s.db.NewIterator(&util.Range{Limit: ku.raw}, nil)
+	defer i.Release()
+
+	var (
+		batch  = new(leveldb.Batch)
+		pruned int
+	)
+	for i.Next() {
+		batch.Delete(common.CopyBytes(i.Key()))
+		if batch.Len() >= pruneBatchSize {
+			if err := s.db.Write(batch, nil); err != nil {
+				return pruned, err
+			}
+			pruned += batch.Len()
+			batch.Reset()
+		}
+	}
+	if err := i.Error(); err != nil {
+		return pruned, err
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		return pruned, err
+	}
+	return pruned + batch.Len(), nil
+}
+
 func (s *WMailServer) Archive(env *whisper.Envelope) {
 	key := NewDbKey(env.Expiry-env.TTL, env.Hash())
 	rawEnvelope, err := rlp.EncodeToBytes(env)
@@ -107,60 +205,146 @@ func (s *WMailServer) DeliverMail(peer *whisper.Peer, request *whisper.Envelope)
 		return
 	}
 
-	ok, lower, upper, bloom := s.validateRequest(peer.ID(), request)
-	if ok {
-		s.processRequest(peer, lower, upper, bloom)
+	decrypted := s.openRequest(peer.ID(), request)
+	if decrypted == nil {
+		return
+	}
+	if req, err := whisper.DecodeMailServerRequest(decrypted.Payload); err == nil {
+		s.processPagedRequest(peer, request.Hash(), req)
+		return
+	}
+
+	ok, lower, upper, bloom := s.parseRequest(decrypted.Payload)
+	if !ok {
+		return
+	}
+	if !s.limiter.allow(peer.ID()) {
+		log.Warn(fmt.Sprintf("Rate limited p2p request from peer %x", peer.ID()))
+		return
+	}
+	s.processRequest(peer, lower, upper, bloom)
+}
+
+// processPagedRequest delivers a page of the envelopes matching a paginated
+// request and completes the request with the cursor of the next page.
+func (s *WMailServer) processPagedRequest(peer *whisper.Peer, id common.Hash, req *whisper.MailServerRequest) {
+	response := &whisper.MailServerResponse{RequestID: id}
+	if !s.limiter.allow(peer.ID()) {
+		response.Error = "rate limit exceeded"
+	} else {
+		envelopes, cursor, err := s.query(peer, req)
+		if err != nil {
+			response.Error = err.Error()
+		}
+		response.Envelopes = uint32(len(envelopes))
+		response.Cursor = cursor
+	}
+	if err := s.w.SendMailServerResponse(peer, response); err != nil {
+		log.Error(fmt.Sprintf("Failed to complete p2p request: %s", err))
+	}
+}
+
+// query delivers the envelopes matching a paginated request to the peer, or
+// returns them if the peer is nil, along with the cursor of the next page.
+func (s *WMailServer) query(peer *whisper.Peer, req *whisper.MailServerRequest) ([]*whisper.Envelope, []byte, error) {
+	var zero common.Hash
+	kl := NewDbKey(req.Lower, zero)
+	ku := NewDbKey(req.Upper, zero)
+	start := kl.raw
+	if len(req.Cursor) > 0 {
+		if len(req.Cursor) != len(kl.raw) || bytes.Compare(req.Cursor, kl.raw) < 0 || bytes.Compare(req.Cursor, ku.raw) >= 0 {
+			return nil, nil, fmt.Errorf("invalid cursor %x", req.Cursor)
+		}
+		// continue right after the last envelope delivered
+		start = append(common.CopyBytes(req.Cursor), 0)
+	}
+	limit := s.MaxLimit
+	if req.Limit > 0 && req.Limit < limit {
+		limit = req.Limit
+	}
+	topics := make(map[whisper.TopicType]bool, len(req.Topics))
+	for _, topic := range req.Topics {
+		topics[topic] = true
 	}
+	match := func(envelope *whisper.Envelope) bool {
+		return len(topics) == 0 || topics[envelope.Topic]
+	}
+	return s.deliver(peer, start, ku.raw, match, limit)
 }
 
 func (s *WMailServer) processRequest(peer *whisper.Peer, lower, upper uint32, bloom []byte) []*whisper.Envelope {
-	ret := make([]*whisper.Envelope, 0)
-	var err error
 	var zero common.Hash
 	kl := NewDbKey(lower, zero)
 	ku := NewDbKey(upper, zero)
-	i := s.db.NewIterator(&util.Range{Start: kl.raw, Limit: ku.raw}, nil)
+	match := func(envelope *whisper.Envelope) bool {
+		return whisper.BloomFilterMatch(bloom, envelope.Bloom())
+	}
+	ret, cursor, _ := s.deliver(peer, kl.raw, ku.raw, match, s.LegacyLimit)
+	if cursor != nil {
+		log.Warn("Truncated p2p request at the legacy limit", "limit", s.LegacyLimit, "lower", lower, "upper", upper)
+	}
+	return ret
+}
+
+// deliver sends the archived envelopes between the start and limit keys which
+// satisfy match to the peer, at most max of them. If the peer is nil, the
+// envelopes are returned instead. If the maximum is reached, the key of the
+// last envelope is returned as cursor to continue from.
+func (s *WMailServer) deliver(peer *whisper.Peer, start, limit []byte, match func(*whisper.Envelope) bool, max uint32) ([]*whisper.Envelope, []byte, error) {
+	ret := make([]*whisper.Envelope, 0)
+	var cursor []byte
+	i := s.db.NewIterator(&util.Range{Start: start, Limit: limit}, nil)
 	defer i.Release()
 
 	for i.Next() {
 		var envelope whisper.Envelope
-		err = rlp.DecodeBytes(i.Value(), &envelope)
-		if err != nil {
+		if err := rlp.DecodeBytes(i.Value(), &envelope); err != nil {
 			log.Error(fmt.Sprintf("RLP decoding failed: %s", err))
+			continue
 		}
-
-		if whisper.BloomFilterMatch(bloom, envelope.Bloom()) {
-			if peer == nil {
-				// used for test purposes
-				ret = append(ret, &envelope)
-			} else {
-				err = s.w.SendP2PDirect(peer, &envelope)
-				if err != nil {
-					log.Error(fmt.Sprintf("Failed to send direct message to peer: %s", err))
-					return nil
-				}
+		if !match(&envelope) {
+			continue
+		}
+		if peer != nil {
+			if err := s.w.SendP2PDirect(peer, &envelope); err != nil {
+				log.Error(fmt.Sprintf("Failed to send direct message to peer: %s", err))
+				return nil, nil, err
 			}
 		}
+		// envelopes sent to the peer are collected as well, to be counted
+		ret = append(ret, &envelope)
+		if max > 0 && uint32(len(ret)) >= max {
+			cursor = common.CopyBytes(i.Key())
+			break
+		}
 	}
 
-	err = i.Error()
-	if err != nil {
+	if err := i.Error(); err != nil {
 		log.Error(fmt.Sprintf("Level DB iterator error: %s", err))
+		return ret, cursor, err
 	}
-
-	return ret
+	return ret, cursor, nil
 }
 
 func (s *WMailServer) validateRequest(peerID []byte, request *whisper.Envelope) (bool, uint32, uint32, []byte) {
-	if s.pow > 0.0 && request.PoW() < s.pow {
+	decrypted := s.openRequest(peerID, request)
+	if decrypted == nil {
 		return false, 0, 0, nil
 	}
+	return s.parseRequest(decrypted.Payload)
+}
+
+// openRequest checks the PoW and the signature of a request and decrypts it.
+func (s *WMailServer) openRequest(peerID []byte, request *whisper.Envelope) *whisper.ReceivedMessage {
+	if s.pow > 0.0 && request.PoW() < s.pow {
+		return nil
+	}
 
 	f := whisper.Filter{KeySym: s.key}
 	decrypted := request.Open(&f)
 	if decrypted == nil {
 		log.Warn(fmt.Sprintf("Failed to decrypt p2p request"))
-		return false, 0, 0, nil
+		return nil
 	}
 
 	src := crypto.FromECDSAPub(decrypted.Src)
@@ -172,11 +356,16 @@ func (s *WMailServer) validateRequest(peerID []byte, request *whisper.Envelope)
 	// if !bytes.Equal(peerID, src) {
 	if src == nil {
 		log.Warn(fmt.Sprintf("Wrong signature of p2p request"))
-		return false, 0, 0, nil
+		return nil
 	}
+	return decrypted
+}
 
+// parseRequest parses the payload of a request with a time range and an
+// optional bloom filter, which is not paginated.
+func (s *WMailServer) parseRequest(payload []byte) (bool, uint32, uint32, []byte) {
 	var bloom []byte
-	payloadSize := len(decrypted.Payload)
+	payloadSize := len(payload)
 	if payloadSize < 8 {
 		log.Warn(fmt.Sprintf("Undersized p2p request"))
 		return false, 0, 0, nil
@@ -186,10 +375,10 @@ func (s *WMailServer) validateRequest(peerID []byte, request *whisper.Envelope)
 		log.Warn(fmt.Sprintf("Undersized bloom filter in p2p request"))
 		return false, 0, 0, nil
 	} else {
-		bloom = decrypted.Payload[8 : 8+whisper.BloomFilterSize]
+		bloom = payload[8 : 8+whisper.BloomFilterSize]
 	}
 
-	lower := binary.BigEndian.Uint32(decrypted.Payload[:4])
-	upper := binary.BigEndian.Uint32(decrypted.Payload[4:8])
+	lower := binary.BigEndian.Uint32(payload[:4])
+	upper := binary.BigEndian.Uint32(payload[4:8])
 	return true, lower, upper, bloom
 }
@@ -20,13 +20,16 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"encoding/binary"
+	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/Ethereum-Reloaded/ETHR-Go/common"
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
 	whisper "github.com/Ethereum-Reloaded/ETHR-Go/whisper/whisperv6"
 )
 
@@ -210,3 +213,198 @@ func createRequest(t *testing.T, p *ServerTestParams) *whisper.Envelope {
 	}
 	return env
 }
+
+func newTestServer(t *testing.T, cfg Config) (*WMailServer, func()) {
+	dir, err := ioutil.TempDir("", "whisper-server-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &WMailServer{Config: cfg}
+	if err := server.Init(whisper.New(&whisper.DefaultConfig), dir, "password", powRequirement); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return server, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func generateTopicEnvelope(t *testing.T, topic whisper.TopicType, payload string) *whisper.Envelope {
+	h := crypto.Keccak256Hash([]byte("test sample data"))
+	params := &whisper.MessageParams{
+		KeySym:   h[:],
+		Topic:    topic,
+		Payload:  []byte(payload),
+		PoW:      powRequirement,
+		WorkTime: 2,
+	}
+	msg, err := whisper.NewSentMessage(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := msg.Wrap(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func TestMailServerPagination(t *testing.T) {
+	server, cleanup := newTestServer(t, Config{MaxLimit: 4})
+	defer cleanup()
+
+	topics := []whisper.TopicType{{0x01}, {0x02}}
+	archived := make(map[common.Hash]whisper.TopicType)
+	for i := 0; i < 10; i++ {
+		env := generateTopicEnvelope(t, topics[i%2], fmt.Sprintf("payload %d", i))
+		server.Archive(env)
+		archived[env.Hash()] = env.Topic
+	}
+	now := uint32(time.Now().Unix())
+
+	// pages of all topics, limited by the server
+	req := &whisper.MailServerRequest{Lower: now - 100, Upper: now + 100, Limit: 10}
+	seen := make(map[common.Hash]bool)
+	for page := 0; ; page++ {
+		envelopes, cursor, err := server.query(nil, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(envelopes) > 4 {
+			t.Fatalf("page %d: %d envelopes exceed the server limit", page, len(envelopes))
+		}
+		for _, env := range envelopes {
+			if seen[env.Hash()] {
+				t.Fatalf("page %d: envelope %x delivered twice", page, env.Hash())
+			}
+			seen[env.Hash()] = true
+		}
+		if len(cursor) == 0 {
+			break
+		}
+		req.Cursor = cursor
+	}
+	if len(seen) != len(archived) {
+		t.Fatalf("expected %d envelopes, got %d", len(archived), len(seen))
+	}
+
+	// pages of a single topic, limited by the request
+	req = &whisper.MailServerRequest{Lower: now - 100, Upper: now + 100, Topics: topics[1:], Limit: 2}
+	var count int
+	for {
+		envelopes, cursor, err := server.query(nil, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(envelopes) > 2 {
+			t.Fatalf("%d envelopes exceed the request limit", len(envelopes))
+		}
+		for _, env := range envelopes {
+			if env.Topic != topics[1] {
+				t.Fatalf("envelope with topic %x delivered", env.Topic)
+			}
+		}
+		count += len(envelopes)
+		if len(cursor) == 0 {
+			break
+		}
+		req.Cursor = cursor
+	}
+	if count != 5 {
+		t.Fatalf("expected 5 envelopes of the topic, got %d", count)
+	}
+
+	// requests with a bloom filter are truncated at the paginated limit by default
+	if server.LegacyLimit != 4 {
+		t.Fatalf("expected the legacy limit to default to the paginated limit, got %d", server.LegacyLimit)
+	}
+	if envelopes := server.processRequest(nil, now-100, now+100, whisper.MakeFullNodeBloom()); len(envelopes) != 4 {
+		t.Fatalf("expected 4 of %d envelopes of the legacy request, got %d", len(archived), len(envelopes))
+	}
+	server.LegacyLimit = 3
+	if envelopes := server.processRequest(nil, now-100, now+100, whisper.MakeFullNodeBloom()); len(envelopes) != 3 {
+		t.Fatalf("expected 3 envelopes of the limited legacy request, got %d", len(envelopes))
+	}
+
+	// cursors outside the time range are rejected
+	req = &whisper.MailServerRequest{Lower: now + 100, Upper: now + 200, Cursor: NewDbKey(now, common.Hash{}).raw}
+	if _, _, err := server.query(nil, req); err == nil {
+		t.Fatal("expected invalid cursor to be rejected")
+	}
+}
+
+func TestMailServerRequestFormat(t *testing.T) {
+	payload, err := rlp.EncodeToBytes(&whisper.MailServerRequest{Lower: 1, Upper: 2, Topics: []whisper.TopicType{{0x01}}, Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := whisper.DecodeMailServerRequest(payload)
+	if err != nil || req.Lower != 1 || req.Upper != 2 || len(req.Topics) != 1 || req.Limit != 3 {
+		t.Fatalf("unexpected request %+v (%v)", req, err)
+	}
+
+	// requests with a time range and bloom filter are not paginated
+	legacy := make([]byte, 8+whisper.BloomFilterSize)
+	binary.BigEndian.PutUint32(legacy, uint32(time.Now().Unix()))
+	if _, err := whisper.DecodeMailServerRequest(legacy); err == nil {
+		t.Fatal("legacy request decoded as paginated request")
+	}
+}
+
+func TestMailServerPrune(t *testing.T) {
+	server, cleanup := newTestServer(t, Config{})
+	defer cleanup()
+
+	env := generateTopicEnvelope(t, whisper.TopicType{0x01}, "payload")
+	server.Archive(env)
+	birth := env.Expiry - env.TTL
+
+	if n, err := server.Prune(birth); err != nil || n != 0 {
+		t.Fatalf("expected no envelopes pruned, got %d (%v)", n, err)
+	}
+	if n, err := server.Prune(birth + 1); err != nil || n != 1 {
+		t.Fatalf("expected 1 envelope pruned, got %d (%v)", n, err)
+	}
+	envelopes, _, err := server.query(nil, &whisper.MailServerRequest{Lower: birth - 1, Upper: birth + 1})
+	if err != nil || len(envelopes) != 0 {
+		t.Fatalf("expected pruned envelope not to be delivered, got %d (%v)", len(envelopes), err)
+	}
+
+	// archives larger than a batch are pruned in several writes
+	n := 2*pruneBatchSize + 10
+	for i := 0; i < n; i++ {
+		key := NewDbKey(birth, common.BytesToHash([]byte{byte(i >> 8), byte(i)}))
+		if err := server.db.Put(key.raw, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pruned, err := server.Prune(birth + 1); err != nil || pruned != n {
+		t.Fatalf("expected %d envelopes pruned, got %d (%v)", n, pruned, err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(50 * time.Millisecond)
+	peer1, peer2 := []byte{1}, []byte{2}
+
+	if !limiter.allow(peer1) || !limiter.allow(peer2) {
+		t.Fatal("first requests not allowed")
+	}
+	if limiter.allow(peer1) {
+		t.Fatal("request within the interval allowed")
+	}
+	time.Sleep(60 * time.Millisecond)
+	limiter.expire()
+	if len(limiter.last) != 0 {
+		t.Fatalf("expected peers to be forgotten, %d left", len(limiter.last))
+	}
+	if !limiter.allow(peer1) {
+		t.Fatal("request after the interval not allowed")
+	}
+
+	unlimited := newRateLimiter(0)
+	if !unlimited.allow(peer1) || !unlimited.allow(peer1) {
+		t.Fatal("unlimited requests not allowed")
+	}
+}
@@ -191,3 +191,14 @@ func (sc *Client) FilterMessages(ctx context.Context, id string) ([]*whisper.Mes
 	var messages []*whisper.Message
 	return messages, sc.c.CallContext(ctx, &messages, "shh_getFilterMessages", id)
 }
+
+// RequestMessages requests historic messages from a mail server peer and waits
+// for their delivery, the messages are received by filters allowing peer-to-peer
+// messages. The returned response carries the cursor of the next page.
+func (sc *Client) RequestMessages(ctx context.Context, req whisper.MessagesRequest) (*whisper.MailServerResponse, error) {
+	var response whisper.MailServerResponse
+	if err := sc.c.CallContext(ctx, &response, "shh_requestMessages", req); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
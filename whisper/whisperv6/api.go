@@ -29,6 +29,7 @@ import (
 	"github.com/Ethereum-Reloaded/ETHR-Go/crypto"
 	"github.com/Ethereum-Reloaded/ETHR-Go/log"
 	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
 	"github.com/Ethereum-Reloaded/ETHR-Go/rpc"
 )
 
@@ -317,6 +318,85 @@ func (api *PublicWhisperAPI) Post(ctx context.Context, req NewMessage) (hexutil.
 	return result, err
 }
 
+// MessagesRequest is a request of historic messages from a mail server,
+// posted through the RPC.
+type MessagesRequest struct {
+	MailServerPeer string        `json:"mailServerPeer"` // enode of the mail server, which must be a peer
+	SymKeyID       string        `json:"symKeyID"`       // key the mail server decrypts requests with
+	Sig            string        `json:"sig"`            // key pair signing the request, a random one if empty
+	From           uint32        `json:"from"`           // lower bound of the time range (unix seconds)
+	To             uint32        `json:"to"`             // upper bound of the time range, now if zero
+	Topics         []TopicType   `json:"topics"`         // topics of the requested messages, all if empty
+	Limit          uint32        `json:"limit"`          // maximum number of messages, the server limit if zero
+	Cursor         hexutil.Bytes `json:"cursor"`         // cursor returned for the previous page
+	PowTarget      float64       `json:"powTarget"`
+	PowTime        uint32        `json:"powTime"`
+	Timeout        uint32        `json:"timeout"` // seconds to wait for the mail server, DefaultMailServerTimeout if zero
+}
+
+// RequestMessages requests historic messages from a mail server and waits
+// until the mail server delivered them. The messages are received by the
+// filters allowing peer-to-peer messages, the returned response carries the
+// cursor to request the next page of messages with.
+func (api *PublicWhisperAPI) RequestMessages(ctx context.Context, req MessagesRequest) (*MailServerResponse, error) {
+	n, err := discover.ParseNode(req.MailServerPeer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mail server peer: %s", err)
+	}
+	if req.To == 0 {
+		req.To = uint32(time.Now().Unix())
+	}
+	payload, err := rlp.EncodeToBytes(&MailServerRequest{
+		Lower:  req.From,
+		Upper:  req.To,
+		Topics: req.Topics,
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	params := &MessageParams{
+		Payload:  payload,
+		WorkTime: req.PowTime,
+		PoW:      req.PowTarget,
+	}
+	if len(req.Topics) > 0 {
+		params.Topic = req.Topics[0]
+	}
+	if params.KeySym, err = api.w.GetSymKey(req.SymKeyID); err != nil {
+		return nil, err
+	}
+	// mail servers only accept signed requests
+	if len(req.Sig) > 0 {
+		params.Src, err = api.w.GetPrivateKey(req.Sig)
+	} else {
+		params.Src, err = crypto.GenerateKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := NewSentMessage(params)
+	if err != nil {
+		return nil, err
+	}
+	env, err := msg.Wrap(params)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := DefaultMailServerTimeout
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	return api.w.RequestMessages(n.ID[:], env, timeout)
+}
+
 //go:generate gencodec -type Criteria -field-override criteriaOverride -out gen_criteria_json.go
 
 // Criteria holds various filter options for inbound messages.
@@ -44,13 +44,14 @@ const (
 	ProtocolName       = "shh"     // Nickname of the protocol in geth
 
 	// whisper protocol message codes, according to EIP-627
-	statusCode           = 0   // used by whisper protocol
-	messagesCode         = 1   // normal whisper message
-	powRequirementCode   = 2   // PoW requirement
-	bloomFilterExCode    = 3   // bloom filter exchange
-	p2pRequestCode       = 126 // peer-to-peer message, used by Dapp protocol
-	p2pMessageCode       = 127 // peer-to-peer message (to be consumed by the peer, but not forwarded any further)
-	NumberOfMessageCodes = 128
+	statusCode             = 0   // used by whisper protocol
+	messagesCode           = 1   // normal whisper message
+	powRequirementCode     = 2   // PoW requirement
+	bloomFilterExCode      = 3   // bloom filter exchange
+	p2pRequestCompleteCode = 125 // completion of a peer-to-peer request, sent by mail servers
+	p2pRequestCode         = 126 // peer-to-peer message, used by Dapp protocol
+	p2pMessageCode         = 127 // peer-to-peer message (to be consumed by the peer, but not forwarded any further)
+	NumberOfMessageCodes   = 128
 
 	SizeMask      = byte(3) // mask used to extract the size of payload size field from the flags
 	signatureFlag = byte(4)
@@ -77,6 +78,8 @@ const (
 
 	DefaultTTL           = 50 // seconds
 	DefaultSyncAllowance = 10 // seconds
+
+	DefaultMailServerTimeout = 10 * time.Second // time to wait for the completion of a mail server request
 )
 
 type unknownVersionError uint64
@@ -90,7 +93,8 @@ func (e unknownVersionError) Error() string {
 // to the peers. Any implementation must ensure that both
 // functions are thread-safe. Also, they must return ASAP.
 // DeliverMail should use directMessagesCode for delivery,
-// in order to bypass the expiry checks.
+// in order to bypass the expiry checks, and may complete
+// the request with SendMailServerResponse.
 type MailServer interface {
 	Archive(env *Envelope)
 	DeliverMail(whisperPeer *Peer, request *Envelope)
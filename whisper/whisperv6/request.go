@@ -0,0 +1,113 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package whisperv6
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/common"
+	"github.com/Ethereum-Reloaded/ETHR-Go/common/hexutil"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p"
+	"github.com/Ethereum-Reloaded/ETHR-Go/rlp"
+)
+
+// ErrMailServerTimeout is returned if a mail server does not complete a request
+// in time.
+var ErrMailServerTimeout = errors.New("mail server request timed out")
+
+// MailServerRequest is the RLP encoded payload of a request of historic
+// messages sent to a mail server. The mail server delivers the archived
+// envelopes sent within the time range and matching the topics, at most Limit
+// of them, and completes the request with a MailServerResponse carrying the
+// cursor to request the next page with.
+type MailServerRequest struct {
+	Lower  uint32      // lower bound of the time range (unix seconds)
+	Upper  uint32      // upper bound of the time range (unix seconds, exclusive)
+	Topics []TopicType // topics of the requested envelopes, all topics if empty
+	Limit  uint32      // maximum number of envelopes, the server limit if zero
+	Cursor []byte      // cursor returned for the previous page, empty for the first
+}
+
+// DecodeMailServerRequest decodes the payload of a paginated mail server
+// request.
+func DecodeMailServerRequest(payload []byte) (*MailServerRequest, error) {
+	req := new(MailServerRequest)
+	if err := rlp.DecodeBytes(payload, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// MailServerResponse completes a mail server request once all the envelopes of
+// the page have been delivered.
+type MailServerResponse struct {
+	RequestID common.Hash   `json:"requestId"` // hash of the request envelope
+	Envelopes uint32        `json:"envelopes"` // number of envelopes delivered
+	Cursor    hexutil.Bytes `json:"cursor"`    // cursor of the next page, empty if all envelopes were delivered
+	Error     string        `json:"error"`     // reason the request was rejected, if it was
+}
+
+// SendMailServerResponse completes a request of the given peer, it is used by
+// mail servers after delivering the envelopes of the request.
+func (whisper *Whisper) SendMailServerResponse(peer *Peer, response *MailServerResponse) error {
+	return p2p.Send(peer.ws, p2pRequestCompleteCode, response)
+}
+
+// RequestMessages sends a request of historic messages to a mail server peer
+// (see RequestHistoricMessages) and waits for the mail server to complete it.
+// The delivered envelopes are processed as peer-to-peer messages.
+func (whisper *Whisper) RequestMessages(peerID []byte, request *Envelope, timeout time.Duration) (*MailServerResponse, error) {
+	id := request.Hash()
+	ch := make(chan *MailServerResponse, 1)
+	whisper.mailMu.Lock()
+	whisper.mailRequests[id] = ch
+	whisper.mailMu.Unlock()
+	defer func() {
+		whisper.mailMu.Lock()
+		delete(whisper.mailRequests, id)
+		whisper.mailMu.Unlock()
+	}()
+
+	if err := whisper.RequestHistoricMessages(peerID, request); err != nil {
+		return nil, err
+	}
+	select {
+	case response := <-ch:
+		if response.Error != "" {
+			return response, errors.New(response.Error)
+		}
+		return response, nil
+	case <-time.After(timeout):
+		return nil, ErrMailServerTimeout
+	case <-whisper.quit:
+		return nil, errors.New("whisper stopped")
+	}
+}
+
+// deliverMailServerResponse hands the response of a mail server to the
+// pending request it completes, responses of unknown requests are dropped.
+func (whisper *Whisper) deliverMailServerResponse(response *MailServerResponse) {
+	whisper.mailMu.Lock()
+	defer whisper.mailMu.Unlock()
+	if ch, ok := whisper.mailRequests[response.RequestID]; ok {
+		select {
+		case ch <- response:
+		default:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package whisperv6
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p"
+	"github.com/Ethereum-Reloaded/ETHR-Go/p2p/discover"
+)
+
+func TestRequestMessages(t *testing.T) {
+	w := New(&DefaultConfig)
+	id := discover.NodeID{1}
+	local, remote := p2p.MsgPipe()
+	defer local.Close()
+	p := newPeer(w, p2p.NewPeer(id, "mailserver", nil), local)
+	w.peers[p] = struct{}{}
+
+	// the mail server completes the first request and rejects the second
+	go func() {
+		for _, reason := range []string{"", "rate limit exceeded"} {
+			msg, err := remote.ReadMsg()
+			if err != nil {
+				return
+			}
+			var request Envelope
+			if msg.Code != p2pRequestCode || msg.Decode(&request) != nil {
+				return
+			}
+			w.deliverMailServerResponse(&MailServerResponse{RequestID: request.Hash(), Envelopes: 3, Cursor: []byte{1}, Error: reason})
+		}
+		// the third request is never completed
+		if msg, err := remote.ReadMsg(); err == nil {
+			msg.Discard()
+		}
+	}()
+
+	request := &Envelope{Expiry: 100, TTL: 10, Topic: TopicType{1}, Data: []byte{1}}
+	response, err := w.RequestMessages(id[:], request, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.RequestID != request.Hash() || response.Envelopes != 3 || len(response.Cursor) != 1 {
+		t.Fatalf("unexpected response %+v", response)
+	}
+	if !p.trusted {
+		t.Fatal("mail server peer not trusted")
+	}
+
+	request = &Envelope{Expiry: 100, TTL: 10, Topic: TopicType{1}, Data: []byte{2}}
+	if _, err := w.RequestMessages(id[:], request, time.Second); err == nil || err.Error() != "rate limit exceeded" {
+		t.Fatalf("expected rejected request, got %v", err)
+	}
+	request = &Envelope{Expiry: 100, TTL: 10, Topic: TopicType{1}, Data: []byte{3}}
+	if _, err := w.RequestMessages(id[:], request, 50*time.Millisecond); err != ErrMailServerTimeout {
+		t.Fatalf("expected %v, got %v", ErrMailServerTimeout, err)
+	}
+	if _, err := w.RequestMessages(discover.NodeID{2}.Bytes(), request, time.Second); err == nil {
+		t.Fatal("expected request to unknown peer to fail")
+	}
+	if len(w.mailRequests) != 0 {
+		t.Fatalf("%d requests left pending", len(w.mailRequests))
+	}
+}
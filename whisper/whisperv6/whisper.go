@@ -88,6 +88,9 @@ type Whisper struct {
 	stats   Statistics // Statistics of whisper node
 
 	mailServer MailServer // MailServer interface
+
+	mailMu       sync.Mutex                               // Mutex to sync the pending mail server requests
+	mailRequests map[common.Hash]chan *MailServerResponse // Mail server requests waiting for completion
 }
 
 // New creates a Whisper client ready to communicate through the Ethereum P2P network.
@@ -106,6 +109,7 @@ func New(cfg *Config) *Whisper {
 		p2pMsgQueue:   make(chan *Envelope, messageQueueLimit),
 		quit:          make(chan struct{}),
 		syncAllowance: DefaultSyncAllowance,
+		mailRequests:  make(map[common.Hash]chan *MailServerResponse),
 	}
 
 	whisper.filters = NewFilters(whisper)
@@ -723,6 +727,17 @@ func (whisper *Whisper) runMessageLoop(p *Peer, rw p2p.MsgReadWriter) error {
 				}
 				whisper.postEvent(&envelope, true)
 			}
+		case p2pRequestCompleteCode:
+			// completion of a mail server request, only accepted from the
+			// trusted peers requests were sent to
+			if p.trusted {
+				var response MailServerResponse
+				if err := packet.Decode(&response); err != nil {
+					log.Warn("failed to decode p2p request completion, peer will be disconnected", "peer", p.peer.ID(), "err", err)
+					return errors.New("invalid p2p request completion")
+				}
+				whisper.deliverMailServerResponse(&response)
+			}
 		case p2pRequestCode:
 			// Must be processed if mail server is implemented. Otherwise ignore.
 			if whisper.mailServer != nil {